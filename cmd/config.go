@@ -41,19 +41,87 @@ dumper = "{{ .tcpdp.dumper }}"
 proxyProtocol = {{ .tcpdp.proxyprotocol }}
 
 [probe]
+# Multiple interfaces are comma separated ( e.g. "eth0,eth1" )
 interface = "{{ .probe.interface }}"
 target = "{{ .probe.target }}"
 bufferSize = "{{ .probe.buffersize }}"
 immediateMode = {{ .probe.immediatemode }}
 snapshotLength = "{{ .probe.snapshotlength }}"
 internalBufferLength = {{ .probe.internalbufferlength }}
+# Number of packet handler workers. Packets are sharded by TCP connection. 0 is number of CPUs
+# With more than 1 worker, dumped lines of different connections are not in captured order
+shards = {{ .probe.shards }}
+# Max number of tracked TCP connections. The least recently used connection is evicted. 0 is unlimited
+maxConnections = {{ .probe.maxconnections }}
+# Tracked TCP connection without packets for this duration is evicted. "0" is no timeout
+# An evicted connection is dumped as mid-stream without username / database / character set, so set it longer than idle time of pooled connections
+connIdleTimeout = "{{ .probe.connidletimeout }}"
 filter = "{{ .probe.filter }}"
+# Capture VLAN / QinQ tagged packets and VXLAN / GRE / ERSPAN tunneled packets ( extend BPF )
+encapsulated = {{ .probe.encapsulated }}
+# Capture backend. "libpcap" or "afpacket" ( Linux only. TPACKET_V3 ring buffer of bufferSize )
+backend = "{{ .probe.backend }}"
+# Dump each target with its dumper ( instead of target and tcpdp.dumper ). Packets not matching any target are dumped by tcpdp.dumper
+# [[probe.targets]]
+# target = "3306"
+# dumper = "mysql"
+# [[probe.targets]]
+# target = "5432"
+# dumper = "pg"
+# Static mapping of username / database for connections seen mid-stream ( established before tcpdp starts )
+# [[probe.midStreamMapping]]
+# server = "db.example.com:3306"
+# client = "10.0.0.0/8"
+# username = "app"
+# database = "app_production"
+
+# Processes with the same fanoutGroup ( 1-65535 ) share packets of the interface. 0 is disabled
+# fanoutGroup + n is used for the n-th interface of multiple interfaces
+# fanoutType: "hash" ( by connection. recommended ), "lb", "cpu", "rollover", "random", "qm"
+[probe.afpacket]
+fanoutGroup = {{ .probe.afpacket.fanoutgroup }}
+fanoutType = "{{ .probe.afpacket.fanouttype }}"
+
+# Keep the last packets in memory and write them to pcap file when a trigger fires ( or SIGUSR1 )
+[probe.ringBuffer]
+enable = {{ .probe.ringbuffer.enable }}
+duration = "{{ .probe.ringbuffer.duration }}"
+# Size of exported pcap file ( packet data and 16 bytes record header per packet ). Memory usage is larger because packets are kept decoded
+size = "{{ .probe.ringbuffer.size }}"
+postTriggerDuration = "{{ .probe.ringbuffer.posttriggerduration }}"
+cooldown = "{{ .probe.ringbuffer.cooldown }}"
+dir = "{{ .probe.ringbuffer.dir }}"
+
+[probe.ringBuffer.trigger]
+dumperError = {{ .probe.ringbuffer.trigger.dumpererror }}
+# MySQL error code / PostgreSQL SQLSTATE of error response. "*" is any error
+errorCodes = [{{ range $i, $c := .probe.ringbuffer.trigger.errorcodes }}{{ if $i }}, {{ end }}{{ printf "%q" $c }}{{ end }}]
+queryPattern = {{ printf "%q" .probe.ringbuffer.trigger.querypattern }}
+# Response latency threshold. "0" is disabled
+latency = "{{ .probe.ringbuffer.trigger.latency }}"
 
 [proxy]
 useServerStarter = {{ .proxy.useserverstarter }}
 listenAddr = "{{ .proxy.listenaddr }}"
 remoteAddr = "{{ .proxy.remoteaddr }}"
 
+[mysql]
+maxQuerySize = {{ .mysql.maxquerysize }}
+
+[memcached]
+dumpValue = {{ .memcached.dumpvalue }}
+
+[framed]
+lengthSize = {{ .framed.lengthsize }}
+byteOrder = "{{ .framed.byteorder }}"
+lengthIncludesPrefix = {{ .framed.lengthincludesprefix }}
+delimiter = {{ printf "%q" .framed.delimiter }}
+maxFrameSize = {{ .framed.maxframesize }}
+
+[grpc]
+dumpMessage = {{ .grpc.dumpmessage }}
+protoDescriptorSet = "{{ .grpc.protodescriptorset }}"
+
 [log]
 dir = "{{ .log.dir }}"
 enable = {{ .log.enable }}
@@ -83,6 +151,22 @@ rotationHook = "{{ .dumplog.rotationhook }}"
 fileName = "{{ .dumplog.filename }}"
 {{ else -}}
 fileName = "{{ .dumplog.filename }}"
+{{- end }}
+
+# Write proxied connections as synthetic TCP/IP packets ( tcpdp proxy )
+[pcapLog]
+dir = "{{ .pcaplog.dir }}"
+enable = {{ .pcaplog.enable }}
+# pcap / pcapng
+format = "{{ .pcaplog.format }}"
+rotateEnable = {{ .pcaplog.rotateenable }}
+rotationTime = "{{ .pcaplog.rotationtime }}"
+rotationCount = {{ .pcaplog.rotationcount }}
+{{ if (ne .pcaplog.rotationhook "") -}}
+rotationHook = "{{ .pcaplog.rotationhook }}"
+fileName = "{{ .pcaplog.filename }}"
+{{ else -}}
+fileName = "{{ .pcaplog.filename }}"
 {{- end -}}
 `
 		tpl, err := template.New("config").Parse(cfgTemplate)
@@ -27,6 +27,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 
 	"github.com/k1LoW/tcpdp/server"
@@ -38,10 +39,12 @@ import (
 var (
 	probeDumper        string
 	probeProxyProtocol bool
+	probeTargetDumpers []string
 )
 
 const snaplenAuto = "auto"
 const snaplenDefault = 0xFFFF
+const encapsulationOverhead = 128 // outer headers of VLAN / VXLAN / GRE / ERSPAN
 
 // probeCmd represents the probe command
 var probeCmd = &cobra.Command{
@@ -56,6 +59,13 @@ var probeCmd = &cobra.Command{
 		if cfgFile == "" {
 			viper.Set("tcpdp.dumper", probeDumper)               // because share with `proxy`
 			viper.Set("tcpdp.proxyProtocol", probeProxyProtocol) // because share with `proxy`
+			if len(probeTargetDumpers) > 0 {
+				targets, err := server.ParseProbeTargets(probeTargetDumpers)
+				if err != nil {
+					logger.Fatal("target-dumper error.", zap.Error(err))
+				}
+				viper.Set("probe.targets", targets)
+			}
 		}
 		if logToStdout {
 			viper.Set("log.enable", true)
@@ -68,25 +78,41 @@ var probeCmd = &cobra.Command{
 		target := viper.GetString("probe.target")
 		device := viper.GetString("probe.interface")
 		snapshotLength := viper.GetString("probe.snapshotLength")
-		ifi, err := net.InterfaceByName(device)
-		if err != nil && !(device == "any" && runtime.GOOS == "linux") {
-			logger.Fatal("interface error.", zap.Error(err))
-		}
-		mtu := 1500
-		if device != "any" {
-			mtu = ifi.MTU
+		mtu := 0
+		for _, d := range strings.Split(device, ",") {
+			d = strings.TrimSpace(d)
+			ifi, err := net.InterfaceByName(d)
+			if err != nil && !(d == "any" && runtime.GOOS == "linux") {
+				logger.Fatal("interface error.", zap.Error(err), zap.String("interface", d))
+			}
+			ifMTU := 1500
+			if d != "any" {
+				ifMTU = ifi.MTU
+			}
+			// snapshot length for the largest MTU
+			if ifMTU > mtu {
+				mtu = ifMTU
+			}
 		}
 		if snapshotLength == snaplenAuto {
-			snapshotLength = fmt.Sprintf("%dB (auto)", mtu+14+4) // 14:Ethernet header 4:FCS
-			viper.Set("probe.snapshotLength", fmt.Sprintf("%dB", mtu+14+4))
+			snaplen := mtu + 14 + 4 // 14:Ethernet header 4:FCS
+			if viper.GetBool("probe.encapsulated") {
+				snaplen = snaplen + encapsulationOverhead
+			}
+			snapshotLength = fmt.Sprintf("%dB (auto)", snaplen)
+			viper.Set("probe.snapshotLength", fmt.Sprintf("%dB", snaplen))
 		}
 		internalBufferLength := viper.GetInt("probe.internalBufferLength")
+		shards := viper.GetInt("probe.shards")
+		if shards <= 0 {
+			shards = runtime.NumCPU()
+		}
 
 		defer logger.Sync()
 
 		signalChan := make(chan os.Signal, 1)
 		signal.Ignore()
-		signal.Notify(signalChan, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+		signal.Notify(signalChan, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGUSR1)
 
 		s, err := server.NewProbeServer(context.Background(), logger)
 		if err != nil {
@@ -94,31 +120,45 @@ var probeCmd = &cobra.Command{
 		}
 
 		pcapConfig := s.PcapConfig()
+		targets := []string{}
+		for _, t := range s.Targets() {
+			targets = append(targets, fmt.Sprintf("%s => %s", t.Target, t.Dumper))
+		}
 
 		logger.Info("Starting probe.",
 			zap.String("dumper", dumper),
 			zap.String("interface", pcapConfig.Device),
 			zap.String("mtu", fmt.Sprintf("%d", mtu)),
 			zap.String("probe_target_addr", target),
+			zap.Strings("targets", targets),
 			zap.String("filter", pcapConfig.Filter),
+			zap.Bool("encapsulated", viper.GetBool("probe.encapsulated")),
+			zap.String("backend", pcapConfig.Backend),
 			zap.String("buffer_size", pcapConfig.BufferSize),
 			zap.Bool("immediate_mode", pcapConfig.ImmediateMode),
 			zap.String("snapshot_length", pcapConfig.SnapshotLength),
 			zap.Int("internal_buffer_length", internalBufferLength),
+			zap.Int("shards", shards),
+			zap.Bool("ring_buffer", viper.GetBool("probe.ringBuffer.enable")),
 		)
 
 		go s.Start()
 
-		sc := <-signalChan
-
-		switch sc {
-		case syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM:
-			logger.Info("Shutting down probe...")
-			s.Shutdown()
-			<-s.ClosedChan
-		default:
-			logger.Info("Unexpected signal")
-			os.Exit(1)
+		for {
+			sc := <-signalChan
+
+			switch sc {
+			case syscall.SIGUSR1:
+				s.TriggerRingBuffer()
+			case syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM:
+				logger.Info("Shutting down probe...")
+				s.Shutdown()
+				<-s.ClosedChan
+				return
+			default:
+				logger.Info("Unexpected signal")
+				os.Exit(1)
+			}
 		}
 	},
 }
@@ -126,14 +166,19 @@ var probeCmd = &cobra.Command{
 func init() {
 	probeCmd.Flags().StringVarP(&cfgFile, "config", "c", "", "config file path")
 	probeCmd.Flags().StringP("target", "t", "", "target addr. (ex. \"localhost:80\", \"3306\")")
-	probeCmd.Flags().StringP("interface", "i", "", "interface")
+	probeCmd.Flags().StringP("interface", "i", "", "interface. multiple interfaces are comma separated (ex. \"eth0,eth1\")")
 	probeCmd.Flags().StringP("buffer-size", "B", "2MB", "buffer size (pcap_buffer_size)")
 	probeCmd.Flags().BoolP("immediate-mode", "", false, "immediate mode")
 	probeCmd.Flags().StringP("snapshot-length", "s", fmt.Sprintf("%dB", snaplenDefault), "snapshot length")
 	probeCmd.Flags().StringVarP(&probeDumper, "dumper", "d", "hex", "dumper")
+	probeCmd.Flags().StringSliceVarP(&probeTargetDumpers, "target-dumper", "", []string{}, "target and dumper instead of --target and --dumper (ex. \"3306=mysql,5432=pg\")")
 	probeCmd.Flags().BoolVarP(&logToStdout, "stdout", "", false, "output all log to STDOUT")
 	probeCmd.Flags().StringP("filter", "", "", "override Berkekey Packet Filter")
 	probeCmd.Flags().BoolVarP(&probeProxyProtocol, "proxy-protocol", "", false, "accept proxy protocol")
+	probeCmd.Flags().IntP("shards", "", 1, "number of packet handler workers. 0 is number of CPUs")
+	probeCmd.Flags().BoolP("encapsulated", "", false, "capture VLAN / VXLAN / GRE / ERSPAN encapsulated packets")
+	probeCmd.Flags().StringP("backend", "", "libpcap", "capture backend. \"libpcap\" or \"afpacket\" ( Linux only )")
+	probeCmd.Flags().BoolP("ring-buffer", "", false, "keep the last packets in memory and write them to pcap file when a trigger fires ( or SIGUSR1 )")
 
 	if err := viper.BindPFlag("probe.target", probeCmd.Flags().Lookup("target")); err != nil {
 		fmt.Println(err)
@@ -159,6 +204,22 @@ func init() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	if err := viper.BindPFlag("probe.shards", probeCmd.Flags().Lookup("shards")); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := viper.BindPFlag("probe.encapsulated", probeCmd.Flags().Lookup("encapsulated")); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := viper.BindPFlag("probe.backend", probeCmd.Flags().Lookup("backend")); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := viper.BindPFlag("probe.ringBuffer.enable", probeCmd.Flags().Lookup("ring-buffer")); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	rootCmd.AddCommand(probeCmd)
 }
@@ -90,6 +90,7 @@ var proxyCmd = &cobra.Command{
 				zap.String("remote_addr", remoteAddr),
 				zap.Bool("use_server_starter", useServerStarter),
 				zap.Bool("proxy_protocol", proxyProxyProtocol),
+				zap.Bool("pcap_log", viper.GetBool("pcapLog.enable")),
 			)
 		} else {
 			logger.Info(fmt.Sprintf("Starting proxy. %s:%d <-> %s:%d", lAddr.IP, lAddr.Port, rAddr.IP, rAddr.Port),
@@ -98,6 +99,7 @@ var proxyCmd = &cobra.Command{
 				zap.String("remote_addr", remoteAddr),
 				zap.Bool("use_server_starter", useServerStarter),
 				zap.Bool("proxy_protocol", proxyProxyProtocol),
+				zap.Bool("pcap_log", viper.GetBool("pcapLog.enable")),
 			)
 		}
 
@@ -131,6 +133,7 @@ func init() {
 	proxyCmd.Flags().BoolP("use-server-starter", "s", false, "use server_starter")
 	proxyCmd.Flags().BoolVarP(&logToStdout, "stdout", "", false, "output all log to STDOUT")
 	proxyCmd.Flags().BoolVarP(&proxyProxyProtocol, "proxy-protocol", "", false, "accept proxy protocol")
+	proxyCmd.Flags().BoolP("pcap-log", "", false, "write proxied connections to pcap file ( pcapLog )")
 
 	if err := viper.BindPFlag("proxy.listenAddr", proxyCmd.Flags().Lookup("listen")); err != nil {
 		fmt.Println(err)
@@ -144,6 +147,10 @@ func init() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	if err := viper.BindPFlag("pcapLog.enable", proxyCmd.Flags().Lookup("pcap-log")); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	rootCmd.AddCommand(proxyCmd)
 }
@@ -23,37 +23,41 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/google/gopacket"
-	"github.com/google/gopacket/pcap"
+
 	"github.com/k1LoW/tcpdp/dumper"
-	"github.com/k1LoW/tcpdp/dumper/conn"
-	"github.com/k1LoW/tcpdp/dumper/hex"
-	"github.com/k1LoW/tcpdp/dumper/mysql"
-	"github.com/k1LoW/tcpdp/dumper/pg"
+	"github.com/k1LoW/tcpdp/dumper/all"
 	"github.com/k1LoW/tcpdp/reader"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	readDumper string
-	readTarget string
+	readDumper          string
+	readTarget          string
+	readShards          int
+	readStart           string
+	readEnd             string
+	readMaxConnections  int
+	readConnIdleTimeout time.Duration
 )
 
 const readIternalBufferLength = 10000
 
 // readCmd represents the read command
 var readCmd = &cobra.Command{
-	Use:   "read [PCAP]",
+	Use:   "read [PCAP...]",
 	Short: "Read pcap file mode",
-	Long:  "Read pcap format file and dump.",
+	Long:  "Read pcap / pcapng format files ( gzip / zstd compressed ) and dump. Packets of multiple files are merged in timestamp order.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		fi, _ := os.Stdin.Stat()
 		if (fi.Mode() & os.ModeCharDevice) != 0 {
-			if len(args) != 1 {
+			if len(args) == 0 {
 				return fmt.Errorf("Error: %s", "requires pcap file path")
 			}
 		}
@@ -68,54 +72,37 @@ var readCmd = &cobra.Command{
 
 		defer logger.Sync()
 
-		var pcapFile string
-
-		fi, _ := os.Stdin.Stat()
-
-		if (fi.Mode() & os.ModeCharDevice) != 0 {
-			pcapFile = args[0]
-		} else {
-			pcap, _ := ioutil.ReadAll(os.Stdin)
-			tmpfile, _ := ioutil.TempFile("", "tcpdptmp")
-			defer func() {
-				if err := tmpfile.Close(); err != nil {
-					fmt.Println(err)
-					os.Exit(1)
-				}
-				if err := os.Remove(tmpfile.Name()); err != nil {
-					fmt.Println(err)
-					os.Exit(1)
-				}
-			}()
-			pcapFile = tmpfile.Name()
-			if _, err := tmpfile.Write(pcap); err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
+		start, end, err := parseReadTimeWindow(readStart, readEnd)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
 
-		handle, err := pcap.OpenOffline(pcapFile)
+		ins, closeFiles, err := openPcapFiles(args)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		defer handle.Close()
+		defer closeFiles()
 
-		var d dumper.Dumper
-		switch readDumper {
-		case "hex":
-			d = hex.NewDumper()
-		case "pg":
-			d = pg.NewDumper()
-		case "mysql":
-			d = mysql.NewDumper()
-		case "conn":
-			d = conn.NewDumper()
-		default:
-			d = hex.NewDumper()
+		// pcap / pcapng ( gzip / zstd compressed )
+		pr, err := reader.NewPcapReader(ins, start, end)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
+		packetSource := gopacket.NewPacketSource(pr, pr)
 
-		packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
+		if !all.Valid(readDumper) {
+			// unknown dumper is hex
+			fmt.Fprintf(os.Stderr, "unknown dumper %s, use hex dumper\n", readDumper)
+			readDumper = "hex"
+		}
+		d, err := all.New(readDumper)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -130,10 +117,23 @@ var readCmd = &cobra.Command{
 			[]dumper.DumpValue{},
 			logger,
 			readIternalBufferLength,
+			readShards,
+			readMaxConnections,
+			readConnIdleTimeout,
 			proxyProtocol,
 			enableInternal,
 		)
 
+		mappings := []reader.MidStreamMapping{}
+		if err := viper.UnmarshalKey("probe.midStreamMapping", &mappings); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := r.SetMidStreamMappings(mappings); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
 		t, err := reader.ParseTarget(readTarget)
 		if err != nil {
 			fmt.Println(err)
@@ -144,13 +144,83 @@ var readCmd = &cobra.Command{
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		if err := pr.Err(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
+// openPcapFiles open pcap files ( glob pattern ). STDIN is read when no file or "-" is specified
+func openPcapFiles(args []string) ([]io.Reader, func(), error) {
+	ins := []io.Reader{}
+	files := []*os.File{}
+	closeFiles := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	if len(args) == 0 {
+		return []io.Reader{os.Stdin}, closeFiles, nil
+	}
+	for _, arg := range args {
+		if arg == "-" {
+			ins = append(ins, os.Stdin)
+			continue
+		}
+		paths, err := filepath.Glob(arg)
+		if err != nil {
+			closeFiles()
+			return nil, nil, err
+		}
+		if len(paths) == 0 {
+			closeFiles()
+			return nil, nil, fmt.Errorf("no such file: %s", arg)
+		}
+		for _, path := range paths {
+			f, err := os.Open(filepath.Clean(path))
+			if err != nil {
+				closeFiles()
+				return nil, nil, err
+			}
+			files = append(files, f)
+			ins = append(ins, f)
+		}
+	}
+	return ins, closeFiles, nil
+}
+
+// parseReadTimeWindow parse --start / --end ( RFC3339 )
+func parseReadTimeWindow(s, e string) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+	if s != "" {
+		start, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid --start: %s", err)
+		}
+	}
+	if e != "" {
+		end, err = time.Parse(time.RFC3339, e)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid --end: %s", err)
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return start, end, fmt.Errorf("invalid time window: --start %s is not before --end %s", s, e)
+	}
+	return start, end, nil
+}
+
 func init() {
 	readCmd.Flags().StringVarP(&readTarget, "target", "t", "", "target addr. (ex. \"localhost:80\", \"3306\")")
 	readCmd.Flags().StringP("format", "f", "json", "STDOUT format. (\"console\", \"json\" , \"ltsv\") ")
 	readCmd.Flags().StringVarP(&readDumper, "dumper", "d", "hex", "dumper")
+	readCmd.Flags().IntVarP(&readShards, "shards", "", 1, "number of packet handler workers. 0 is number of CPUs")
+	readCmd.Flags().IntVarP(&readMaxConnections, "max-connections", "", 0, "max number of tracked TCP connections. 0 is unlimited")
+	readCmd.Flags().DurationVarP(&readConnIdleTimeout, "conn-idle-timeout", "", 0, "tracked TCP connection without packets for this duration ( in capture time ) is evicted. 0 is no timeout")
+	readCmd.Flags().StringVarP(&readStart, "start", "", "", "read packets captured at or after the time. (ex. \"2006-01-02T15:04:05+09:00\")")
+	readCmd.Flags().StringVarP(&readEnd, "end", "", "", "read packets captured before the time. (ex. \"2006-01-02T15:04:05+09:00\")")
 
 	if err := viper.BindPFlag("dumpLog.stdoutFormat", readCmd.Flags().Lookup("format")); err != nil {
 		fmt.Println(err)
@@ -69,8 +69,37 @@ func initConfig() {
 	viper.SetDefault("probe.bufferSize", "2MB")
 	viper.SetDefault("probe.immediateMode", false)
 	viper.SetDefault("probe.internalBufferLength", 10000)
+	viper.SetDefault("probe.shards", 1)
+	viper.SetDefault("probe.maxConnections", 100000)
+	viper.SetDefault("probe.connIdleTimeout", "0")
 	viper.SetDefault("probe.snapshotLength", fmt.Sprintf("%dB", snaplenDefault))
 	viper.SetDefault("probe.filter", "")
+	viper.SetDefault("probe.encapsulated", false)
+	viper.SetDefault("probe.backend", "libpcap")
+	viper.SetDefault("probe.afpacket.fanoutGroup", 0)
+	viper.SetDefault("probe.afpacket.fanoutType", "hash")
+	viper.SetDefault("probe.ringBuffer.enable", false)
+	viper.SetDefault("probe.ringBuffer.duration", "30s")
+	viper.SetDefault("probe.ringBuffer.size", "64MB")
+	viper.SetDefault("probe.ringBuffer.postTriggerDuration", "5s")
+	viper.SetDefault("probe.ringBuffer.cooldown", "1m")
+	viper.SetDefault("probe.ringBuffer.dir", ".")
+	viper.SetDefault("probe.ringBuffer.trigger.dumperError", true)
+	viper.SetDefault("probe.ringBuffer.trigger.errorCodes", []string{})
+	viper.SetDefault("probe.ringBuffer.trigger.queryPattern", "")
+	viper.SetDefault("probe.ringBuffer.trigger.latency", "0")
+
+	viper.SetDefault("mysql.maxQuerySize", 0)
+
+	viper.SetDefault("memcached.dumpValue", false)
+
+	viper.SetDefault("framed.lengthSize", 4)
+	viper.SetDefault("framed.byteOrder", "big")
+	viper.SetDefault("framed.lengthIncludesPrefix", false)
+	viper.SetDefault("framed.delimiter", "")
+	viper.SetDefault("framed.maxFrameSize", 65536)
+	viper.SetDefault("grpc.dumpMessage", false)
+	viper.SetDefault("grpc.protoDescriptorSet", "")
 
 	viper.SetDefault("log.dir", ".")
 	viper.SetDefault("log.enable", true)
@@ -93,6 +122,15 @@ func initConfig() {
 	viper.SetDefault("dumpLog.rotationHook", "")
 	viper.SetDefault("dumpLog.fileName", "dump.log")
 
+	viper.SetDefault("pcapLog.dir", ".")
+	viper.SetDefault("pcapLog.enable", false)
+	viper.SetDefault("pcapLog.format", "pcap")
+	viper.SetDefault("pcapLog.rotateEnable", true)
+	viper.SetDefault("pcapLog.rotationTime", "daily")
+	viper.SetDefault("pcapLog.rotationCount", 7)
+	viper.SetDefault("pcapLog.rotationHook", "")
+	viper.SetDefault("pcapLog.fileName", "dump.pcap")
+
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
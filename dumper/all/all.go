@@ -0,0 +1,92 @@
+package all
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/dumper/amqp"
+	"github.com/k1LoW/tcpdp/dumper/conn"
+	"github.com/k1LoW/tcpdp/dumper/cql"
+	"github.com/k1LoW/tcpdp/dumper/dns"
+	"github.com/k1LoW/tcpdp/dumper/framed"
+	"github.com/k1LoW/tcpdp/dumper/grpc"
+	"github.com/k1LoW/tcpdp/dumper/hex"
+	"github.com/k1LoW/tcpdp/dumper/kafka"
+	"github.com/k1LoW/tcpdp/dumper/ldap"
+	"github.com/k1LoW/tcpdp/dumper/memcached"
+	"github.com/k1LoW/tcpdp/dumper/mongodb"
+	"github.com/k1LoW/tcpdp/dumper/mqtt"
+	"github.com/k1LoW/tcpdp/dumper/mysql"
+	"github.com/k1LoW/tcpdp/dumper/pg"
+	"github.com/k1LoW/tcpdp/dumper/smtp"
+	"github.com/k1LoW/tcpdp/dumper/tds"
+	"github.com/pkg/errors"
+)
+
+// Names is names of all dumpers
+var Names = []string{
+	"hex",
+	"pg",
+	"mysql",
+	"mongodb",
+	"memcached",
+	"tds",
+	"kafka",
+	"dns",
+	"framed",
+	"grpc",
+	"amqp",
+	"mqtt",
+	"cql",
+	"ldap",
+	"smtp",
+	"conn",
+}
+
+// New return dumper of name
+func New(name string) (dumper.Dumper, error) {
+	switch name {
+	case "hex":
+		return hex.NewDumper(), nil
+	case "pg":
+		return pg.NewDumper(), nil
+	case "mysql":
+		return mysql.NewDumper(), nil
+	case "mongodb":
+		return mongodb.NewDumper(), nil
+	case "memcached":
+		return memcached.NewDumper(), nil
+	case "tds":
+		return tds.NewDumper(), nil
+	case "kafka":
+		return kafka.NewDumper(), nil
+	case "dns":
+		return dns.NewDumper(), nil
+	case "framed":
+		return framed.NewDumper()
+	case "grpc":
+		return grpc.NewDumper()
+	case "amqp":
+		return amqp.NewDumper(), nil
+	case "mqtt":
+		return mqtt.NewDumper(), nil
+	case "cql":
+		return cql.NewDumper(), nil
+	case "ldap":
+		return ldap.NewDumper(), nil
+	case "smtp":
+		return smtp.NewDumper(), nil
+	case "conn":
+		return conn.NewDumper(), nil
+	default:
+		return nil, errors.Errorf("unknown dumper: %s", name)
+	}
+}
+
+// Valid return whether name is dumper name
+func Valid(name string) bool {
+	for _, n := range Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
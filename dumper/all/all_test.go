@@ -0,0 +1,37 @@
+package all
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestNew(t *testing.T) {
+	viper.Set("framed.lengthSize", 4)
+	viper.Set("framed.byteOrder", "big")
+	viper.Set("framed.maxFrameSize", 65536)
+	defer viper.Reset()
+	for _, name := range Names {
+		d, err := New(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if d.Name() != name {
+			t.Errorf("got %s\nwant %s", d.Name(), name)
+		}
+		if !Valid(name) {
+			t.Errorf("%s: want valid", name)
+		}
+	}
+}
+
+func TestNewUnknown(t *testing.T) {
+	for _, name := range []string{"pgsql", "", "HEX"} {
+		if _, err := New(name); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+		if Valid(name) {
+			t.Errorf("%s: want invalid", name)
+		}
+	}
+}
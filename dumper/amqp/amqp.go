@@ -0,0 +1,212 @@
+package amqp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	client *stream
+	server *stream
+}
+
+// stream is AMQP frames of a direction
+type stream struct {
+	buffer  []byte                        // partial frame
+	skip    int                           // rest bytes of large content body frame
+	started bool                          // protocol header is checked
+	broken  bool                          // frame boundary is lost
+	pending map[uint16][]dumper.DumpValue // channel:method waiting for content header
+}
+
+// frame is AMQP frame ( without frame-end )
+type frame struct {
+	frameType uint8
+	channel   uint16
+	payload   []byte
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "amqp",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (a *Dumper) Name() string {
+	return a.name
+}
+
+// Dump AMQP methods
+func (a *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := a.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		a.Log(values)
+	}
+	return nil
+}
+
+// Read return the first method of byte to analyzed string ( use ReadFrames to read all methods )
+func (a *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := a.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return methods of byte to analyzed string
+// Methods with content ( basic.publish, basic.deliver, ... ) are returned with the content header
+func (a *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	s := internal.client
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		s = internal.server
+	}
+	for _, f := range s.readFrames(in) {
+		switch f.frameType {
+		case frameMethod:
+			if v, ok := s.pending[f.channel]; ok {
+				// content header is not captured
+				delete(s.pending, f.channel)
+				records = append(records, v)
+			}
+			values, hasContent := readMethod(f)
+			if hasContent {
+				s.pending[f.channel] = values
+				continue
+			}
+			records = append(records, values)
+		case frameHeader:
+			values, ok := s.pending[f.channel]
+			if !ok {
+				continue
+			}
+			delete(s.pending, f.channel)
+			records = append(records, append(values, readContentHeader(f)...))
+		}
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (a *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	a.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (a *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			client: newStream(),
+			server: newStream(),
+		},
+	}
+}
+
+func newStream() *stream {
+	return &stream{
+		pending: map[uint16][]dumper.DumpValue{},
+	}
+}
+
+// readFrames returns complete frames and cache the partial frame
+// https://www.rabbitmq.com/resources/specs/amqp0-9-1.pdf 4.2.3
+func (s *stream) readFrames(in []byte) []frame {
+	frames := []frame{}
+	if s.broken {
+		return frames
+	}
+	if s.skip > 0 {
+		if s.skip >= len(in) {
+			s.skip -= len(in)
+			return frames
+		}
+		in = in[s.skip:]
+		s.skip = 0
+	}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	if !s.started {
+		n := len(protocolHeader)
+		if len(buff) < n {
+			n = len(buff)
+		}
+		if bytes.Equal(buff[:n], []byte(protocolHeader[:n])) {
+			if len(buff) < protocolHeaderLength {
+				s.buffer = buff
+				return frames
+			}
+			buff = buff[protocolHeaderLength:]
+		}
+		s.started = true
+	}
+	for len(buff) >= frameHeaderLength {
+		frameType := buff[0]
+		size := int(binary.BigEndian.Uint32(buff[3:7]))
+		total := frameHeaderLength + size + 1
+		if frameType == frameBody && len(buff) < total {
+			// content body is not dumped
+			s.skip = total - len(buff)
+			return frames
+		}
+		if size > maxFrameSize {
+			s.broken = true
+			return frames
+		}
+		if len(buff) < total {
+			break
+		}
+		if buff[total-1] != frameEnd {
+			s.broken = true
+			return frames
+		}
+		frames = append(frames, frame{
+			frameType: frameType,
+			channel:   binary.BigEndian.Uint16(buff[1:3]),
+			payload:   buff[frameHeaderLength : total-1],
+		})
+		buff = buff[total:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return frames
+}
+
+func methodName(id methodID) string {
+	if n, ok := methodNames[id]; ok {
+		return n
+	}
+	return fmt.Sprintf("%d.%d", id.classID, id.methodID)
+}
@@ -0,0 +1,519 @@
+package amqp
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type amqpStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  [][]dumper.DumpValue
+}
+
+var amqpReadFramesTests = []struct {
+	description string
+	steps       []amqpStep
+}{
+	{
+		"Connection, publish and consume",
+		[]amqpStep{
+			{
+				[]byte{
+					0x41, 0x4d, 0x51, 0x50, 0x00, 0x00, 0x09, 0x01,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x61, 0x00, 0x0a, 0x00, 0x0a, 0x00, 0x09, 0x00, 0x00, 0x00,
+					0x3c, 0x07, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x53, 0x00, 0x00, 0x00, 0x08, 0x52, 0x61,
+					0x62, 0x62, 0x69, 0x74, 0x4d, 0x51, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74,
+					0x69, 0x65, 0x73, 0x46, 0x00, 0x00, 0x00, 0x15, 0x12, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68,
+					0x65, 0x72, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x73, 0x74, 0x01, 0x00, 0x00, 0x00,
+					0x0e, 0x50, 0x4c, 0x41, 0x49, 0x4e, 0x20, 0x41, 0x4d, 0x51, 0x50, 0x4c, 0x41, 0x49, 0x4e, 0x00,
+					0x00, 0x00, 0x05, 0x65, 0x6e, 0x5f, 0x55, 0x53, 0xce,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(0),
+						},
+						{
+							Key:   "method",
+							Value: "connection.start",
+						},
+						{
+							Key:   "server_properties",
+							Value: map[string]interface{}{"product": "RabbitMQ", "capabilities": map[string]interface{}{"publisher_confirms": true}},
+						},
+						{
+							Key:   "mechanisms",
+							Value: "PLAIN AMQPLAIN",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3b, 0x00, 0x0a, 0x00, 0x0b, 0x00, 0x00, 0x00, 0x17, 0x07,
+					0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x53, 0x00, 0x00, 0x00, 0x0a, 0x74, 0x63, 0x70, 0x64,
+					0x70, 0x2d, 0x74, 0x65, 0x73, 0x74, 0x05, 0x50, 0x4c, 0x41, 0x49, 0x4e, 0x00, 0x00, 0x00, 0x0c,
+					0x00, 0x67, 0x75, 0x65, 0x73, 0x74, 0x00, 0x67, 0x75, 0x65, 0x73, 0x74, 0x05, 0x65, 0x6e, 0x5f,
+					0x55, 0x53, 0xce,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(0),
+						},
+						{
+							Key:   "method",
+							Value: "connection.start-ok",
+						},
+						{
+							Key:   "client_properties",
+							Value: map[string]interface{}{"product": "tcpdp-test"},
+						},
+						{
+							Key:   "mechanism",
+							Value: "PLAIN",
+						},
+						{
+							Key:   "locale",
+							Value: "en_US",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x0a, 0x00, 0x1e, 0x07, 0xff, 0x00, 0x02, 0x00,
+					0x00, 0x00, 0x3c, 0xce,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(0),
+						},
+						{
+							Key:   "method",
+							Value: "connection.tune",
+						},
+						{
+							Key:   "channel_max",
+							Value: uint16(2047),
+						},
+						{
+							Key:   "frame_max",
+							Value: uint32(131072),
+						},
+						{
+							Key:   "heartbeat",
+							Value: uint16(60),
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x0a, 0x00, 0x1f, 0x07, 0xff, 0x00, 0x02, 0x00,
+					0x00, 0x00, 0x3c, 0xce, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00, 0x0a, 0x00, 0x28, 0x01,
+					0x2f, 0x00, 0x00, 0xce,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(0),
+						},
+						{
+							Key:   "method",
+							Value: "connection.tune-ok",
+						},
+						{
+							Key:   "channel_max",
+							Value: uint16(2047),
+						},
+						{
+							Key:   "frame_max",
+							Value: uint32(131072),
+						},
+						{
+							Key:   "heartbeat",
+							Value: uint16(60),
+						},
+					},
+					{
+						{
+							Key:   "channel",
+							Value: uint16(0),
+						},
+						{
+							Key:   "method",
+							Value: "connection.open",
+						},
+						{
+							Key:   "virtual_host",
+							Value: "/",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x05, 0x00, 0x14, 0x00, 0x0a, 0x00, 0xce,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(1),
+						},
+						{
+							Key:   "method",
+							Value: "channel.open",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x20, 0x00, 0x3c, 0x00, 0x28, 0x00, 0x00, 0x09, 0x61, 0x6d,
+					0x71, 0x2e, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x0e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x2e, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x01, 0xce, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00,
+					0x39, 0x00, 0x3c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0xb0, 0x40, 0x10,
+					0x61, 0x70, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x6a, 0x73, 0x6f, 0x6e,
+					0x00, 0x00, 0x00, 0x0d, 0x07, 0x78, 0x2d, 0x72, 0x65, 0x74, 0x72, 0x79, 0x49, 0x00, 0x00, 0x00,
+					0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x65, 0x53, 0xf1, 0x00, 0xce, 0x03, 0x00, 0x01, 0x00, 0x00,
+					0x00, 0x05, 0x7b, 0x22, 0x61, 0x22, 0x3a, 0xce,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(1),
+						},
+						{
+							Key:   "method",
+							Value: "basic.publish",
+						},
+						{
+							Key:   "exchange",
+							Value: "amq.topic",
+						},
+						{
+							Key:   "routing_key",
+							Value: "orders.created",
+						},
+						{
+							Key:   "mandatory",
+							Value: true,
+						},
+						{
+							Key:   "body_size",
+							Value: uint64(5),
+						},
+						{
+							Key:   "content_type",
+							Value: "application/json",
+						},
+						{
+							Key:   "headers",
+							Value: map[string]interface{}{"x-retry": int32(1)},
+						},
+						{
+							Key:   "delivery_mode",
+							Value: uint8(2),
+						},
+						{
+							Key:   "timestamp",
+							Value: time.Unix(1700000000, 0).UTC(),
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x19, 0x00, 0x3c, 0x00, 0x14, 0x00, 0x00, 0x06, 0x6f, 0x72,
+					0x64, 0x65, 0x72, 0x73, 0x06, 0x63, 0x74, 0x61, 0x67, 0x2d, 0x31, 0x04, 0x00, 0x00, 0x00, 0x00,
+					0xce,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(1),
+						},
+						{
+							Key:   "method",
+							Value: "basic.consume",
+						},
+						{
+							Key:   "queue",
+							Value: "orders",
+						},
+						{
+							Key:   "consumer_tag",
+							Value: "ctag-1",
+						},
+						{
+							Key:   "no_ack",
+							Value: false,
+						},
+						{
+							Key:   "exclusive",
+							Value: true,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x2d, 0x00, 0x3c, 0x00, 0x3c, 0x06, 0x63, 0x74, 0x61, 0x67,
+					0x2d, 0x31, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x09, 0x61, 0x6d, 0x71, 0x2e,
+					0x74, 0x6f, 0x70, 0x69, 0x63, 0x0e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x2e, 0x63, 0x72, 0x65,
+					0x61, 0x74, 0x65, 0x64, 0xce, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00, 0x1f, 0x00, 0x3c, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x80, 0x00, 0x10, 0x61, 0x70, 0x70, 0x6c, 0x69,
+					0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x6a, 0x73, 0x6f, 0x6e, 0xce, 0x03, 0x00, 0x01, 0x00,
+					0x00, 0x00, 0x05, 0x7b, 0x22, 0x61, 0x22, 0x3a, 0xce, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0xce,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(1),
+						},
+						{
+							Key:   "method",
+							Value: "basic.deliver",
+						},
+						{
+							Key:   "consumer_tag",
+							Value: "ctag-1",
+						},
+						{
+							Key:   "delivery_tag",
+							Value: uint64(1),
+						},
+						{
+							Key:   "redelivered",
+							Value: false,
+						},
+						{
+							Key:   "exchange",
+							Value: "amq.topic",
+						},
+						{
+							Key:   "routing_key",
+							Value: "orders.created",
+						},
+						{
+							Key:   "body_size",
+							Value: uint64(5),
+						},
+						{
+							Key:   "content_type",
+							Value: "application/json",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0d, 0x00, 0x3c, 0x00, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x01, 0x01, 0xce,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(1),
+						},
+						{
+							Key:   "method",
+							Value: "basic.ack",
+						},
+						{
+							Key:   "delivery_tag",
+							Value: uint64(1),
+						},
+						{
+							Key:   "multiple",
+							Value: true,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x2d, 0x00, 0x3c, 0x00, 0x3c, 0x06, 0x63, 0x74, 0x61, 0x67,
+					0x2d, 0x31, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x01, 0x09, 0x61, 0x6d, 0x71, 0x2e,
+					0x74, 0x6f, 0x70, 0x69, 0x63, 0x0e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x2e, 0x63, 0x72, 0x65,
+					0x61, 0x74, 0x65, 0x64, 0xce, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x3c, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0xce, 0x03, 0x00, 0x01, 0x00, 0x00,
+					0x00, 0x64, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(1),
+						},
+						{
+							Key:   "method",
+							Value: "basic.deliver",
+						},
+						{
+							Key:   "consumer_tag",
+							Value: "ctag-1",
+						},
+						{
+							Key:   "delivery_tag",
+							Value: uint64(2),
+						},
+						{
+							Key:   "redelivered",
+							Value: true,
+						},
+						{
+							Key:   "exchange",
+							Value: "amq.topic",
+						},
+						{
+							Key:   "routing_key",
+							Value: "orders.created",
+						},
+						{
+							Key:   "body_size",
+							Value: uint64(100),
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+					0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+					0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+					0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+					0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+					0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0xce, 0x01, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x23, 0x00, 0x0a, 0x00, 0x32, 0x01, 0x94, 0x18, 0x4e, 0x4f, 0x54, 0x5f, 0x46, 0x4f, 0x55,
+					0x4e, 0x44, 0x20, 0x2d, 0x20, 0x6e, 0x6f, 0x20, 0x71, 0x75, 0x65, 0x75, 0x65, 0x20, 0x27, 0x78,
+					0x27, 0x00, 0x32, 0x00, 0x0a, 0xce,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "channel",
+							Value: uint16(0),
+						},
+						{
+							Key:   "method",
+							Value: "connection.close",
+						},
+						{
+							Key:   "reply_code",
+							Value: uint16(404),
+						},
+						{
+							Key:   "reply_text",
+							Value: "NOT_FOUND - no queue 'x'",
+						},
+						{
+							Key:   "failing_method",
+							Value: "queue.declare",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Not AMQP",
+		[]amqpStep{
+			{
+				[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte("HTTP/1.1 400 Bad Request\r\n\r\n"),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+}
+
+func TestAMQPReadFrames(t *testing.T) {
+	for _, tt := range amqpReadFramesTests {
+		out := new(bytes.Buffer)
+		d := NewDumper()
+		d.logger = newTestLogger(out)
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
@@ -0,0 +1,134 @@
+package amqp
+
+// protocolHeader is the prefix of AMQP protocol header ( `AMQP` 0 0 9 1 )
+// https://www.rabbitmq.com/resources/specs/amqp0-9-1.pdf 4.2.2
+const protocolHeader = "AMQP"
+
+const protocolHeaderLength = 8
+
+// frameHeaderLength is length of type, channel and size
+const frameHeaderLength = 7
+
+// frameEnd is the last octet of frame
+const frameEnd = 0xce
+
+// maxFrameSize is max size of buffered frame. Larger content body frames are skipped
+const maxFrameSize = 1 << 20
+
+// https://www.rabbitmq.com/resources/specs/amqp0-9-1.pdf 4.2.3
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+)
+
+const (
+	classConnection = 10
+	classChannel    = 20
+	classExchange   = 40
+	classQueue      = 50
+	classBasic      = 60
+	classConfirm    = 85
+	classTx         = 90
+)
+
+// methodID is class-id and method-id
+type methodID struct {
+	classID  uint16
+	methodID uint16
+}
+
+// https://www.rabbitmq.com/amqp-0-9-1-reference.html
+var (
+	connectionStart   = methodID{classConnection, 10}
+	connectionStartOk = methodID{classConnection, 11}
+	connectionTune    = methodID{classConnection, 30}
+	connectionTuneOk  = methodID{classConnection, 31}
+	connectionOpen    = methodID{classConnection, 40}
+	connectionClose   = methodID{classConnection, 50}
+	channelClose      = methodID{classChannel, 40}
+	exchangeDeclare   = methodID{classExchange, 10}
+	queueDeclare      = methodID{classQueue, 10}
+	queueDeclareOk    = methodID{classQueue, 11}
+	queueBind         = methodID{classQueue, 20}
+	queueUnbind       = methodID{classQueue, 50}
+	basicQos          = methodID{classBasic, 10}
+	basicConsume      = methodID{classBasic, 20}
+	basicConsumeOk    = methodID{classBasic, 21}
+	basicCancel       = methodID{classBasic, 30}
+	basicCancelOk     = methodID{classBasic, 31}
+	basicPublish      = methodID{classBasic, 40}
+	basicReturn       = methodID{classBasic, 50}
+	basicDeliver      = methodID{classBasic, 60}
+	basicGet          = methodID{classBasic, 70}
+	basicGetOk        = methodID{classBasic, 71}
+	basicAck          = methodID{classBasic, 80}
+	basicReject       = methodID{classBasic, 90}
+	basicNack         = methodID{classBasic, 120}
+)
+
+var methodNames = map[methodID]string{
+	connectionStart:       "connection.start",
+	connectionStartOk:     "connection.start-ok",
+	{classConnection, 20}: "connection.secure",
+	{classConnection, 21}: "connection.secure-ok",
+	connectionTune:        "connection.tune",
+	connectionTuneOk:      "connection.tune-ok",
+	connectionOpen:        "connection.open",
+	{classConnection, 41}: "connection.open-ok",
+	connectionClose:       "connection.close",
+	{classConnection, 51}: "connection.close-ok",
+	{classConnection, 60}: "connection.blocked",
+	{classConnection, 61}: "connection.unblocked",
+	{classChannel, 10}:    "channel.open",
+	{classChannel, 11}:    "channel.open-ok",
+	{classChannel, 20}:    "channel.flow",
+	{classChannel, 21}:    "channel.flow-ok",
+	channelClose:          "channel.close",
+	{classChannel, 41}:    "channel.close-ok",
+	exchangeDeclare:       "exchange.declare",
+	{classExchange, 11}:   "exchange.declare-ok",
+	{classExchange, 20}:   "exchange.delete",
+	{classExchange, 21}:   "exchange.delete-ok",
+	{classExchange, 30}:   "exchange.bind",
+	{classExchange, 31}:   "exchange.bind-ok",
+	{classExchange, 40}:   "exchange.unbind",
+	{classExchange, 51}:   "exchange.unbind-ok",
+	queueDeclare:          "queue.declare",
+	queueDeclareOk:        "queue.declare-ok",
+	queueBind:             "queue.bind",
+	{classQueue, 21}:      "queue.bind-ok",
+	{classQueue, 30}:      "queue.purge",
+	{classQueue, 31}:      "queue.purge-ok",
+	{classQueue, 40}:      "queue.delete",
+	{classQueue, 41}:      "queue.delete-ok",
+	queueUnbind:           "queue.unbind",
+	{classQueue, 51}:      "queue.unbind-ok",
+	basicQos:              "basic.qos",
+	{classBasic, 11}:      "basic.qos-ok",
+	basicConsume:          "basic.consume",
+	basicConsumeOk:        "basic.consume-ok",
+	basicCancel:           "basic.cancel",
+	basicCancelOk:         "basic.cancel-ok",
+	basicPublish:          "basic.publish",
+	basicReturn:           "basic.return",
+	basicDeliver:          "basic.deliver",
+	basicGet:              "basic.get",
+	basicGetOk:            "basic.get-ok",
+	{classBasic, 72}:      "basic.get-empty",
+	basicAck:              "basic.ack",
+	basicReject:           "basic.reject",
+	{classBasic, 100}:     "basic.recover-async",
+	{classBasic, 110}:     "basic.recover",
+	{classBasic, 111}:     "basic.recover-ok",
+	basicNack:             "basic.nack",
+	{classConfirm, 10}:    "confirm.select",
+	{classConfirm, 11}:    "confirm.select-ok",
+	{classTx, 10}:         "tx.select",
+	{classTx, 11}:         "tx.select-ok",
+	{classTx, 20}:         "tx.commit",
+	{classTx, 21}:         "tx.commit-ok",
+	{classTx, 30}:         "tx.rollback",
+	{classTx, 31}:         "tx.rollback-ok",
+}
@@ -0,0 +1,178 @@
+package amqp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidFrame = errors.New("invalid AMQP frame")
+
+// maxTableDepth is max depth of nested field table
+const maxTableDepth = 16
+
+// decoder read domain types of AMQP 0-9-1
+// https://www.rabbitmq.com/resources/specs/amqp0-9-1.pdf 4.2.5
+type decoder struct {
+	buf  []byte
+	bits byte // current octet of packed bits
+	bitN uint // number of read bits in current octet ( 0: no octet )
+	err  error
+}
+
+func newDecoder(in []byte) *decoder {
+	return &decoder{
+		buf: in,
+	}
+}
+
+func (d *decoder) read(n int) []byte {
+	d.bitN = 0
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errInvalidFrame
+		d.buf = nil
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) octet() uint8 {
+	b := d.read(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) short() uint16 {
+	b := d.read(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *decoder) long() uint32 {
+	b := d.read(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *decoder) longlong() uint64 {
+	b := d.read(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// bit read a bit. Consecutive bits are packed into octets
+func (d *decoder) bit() bool {
+	if d.bitN == 0 || d.bitN == 8 {
+		d.bits = d.octet()
+	}
+	v := d.bits&(1<<d.bitN) > 0
+	d.bitN++
+	return v
+}
+
+func (d *decoder) shortstr() string {
+	return string(d.read(int(d.octet())))
+}
+
+func (d *decoder) longstr() []byte {
+	l := d.long()
+	if int64(l) > int64(len(d.buf)) {
+		d.err = errInvalidFrame
+		d.buf = nil
+		return nil
+	}
+	return d.read(int(l))
+}
+
+// table read field table
+func (d *decoder) table(depth int) map[string]interface{} {
+	t := map[string]interface{}{}
+	td := newDecoder(d.longstr())
+	for len(td.buf) > 0 && td.err == nil {
+		k := td.shortstr()
+		v := td.fieldValue(depth)
+		t[k] = v
+	}
+	if td.err != nil {
+		d.err = td.err
+	}
+	return t
+}
+
+// array read field array
+func (d *decoder) array(depth int) []interface{} {
+	a := []interface{}{}
+	ad := newDecoder(d.longstr())
+	for len(ad.buf) > 0 && ad.err == nil {
+		a = append(a, ad.fieldValue(depth))
+	}
+	if ad.err != nil {
+		d.err = ad.err
+	}
+	return a
+}
+
+// fieldValue read field value of table or array ( RabbitMQ field types )
+// https://www.rabbitmq.com/amqp-0-9-1-errata.html#section_3
+func (d *decoder) fieldValue(depth int) interface{} {
+	if depth >= maxTableDepth {
+		d.err = errInvalidFrame
+		return nil
+	}
+	switch d.octet() {
+	case 't':
+		return d.octet() != 0
+	case 'b':
+		return int8(d.octet())
+	case 'B':
+		return d.octet()
+	case 's':
+		return int16(d.short())
+	case 'u':
+		return d.short()
+	case 'I':
+		return int32(d.long())
+	case 'i':
+		return d.long()
+	case 'l':
+		return int64(d.longlong())
+	case 'f':
+		return math.Float32frombits(d.long())
+	case 'd':
+		return math.Float64frombits(d.longlong())
+	case 'D':
+		scale := d.octet()
+		v := int32(d.long())
+		return float64(v) / math.Pow10(int(scale))
+	case 'S':
+		return string(d.longstr())
+	case 'x':
+		return hex.EncodeToString(d.longstr())
+	case 'A':
+		return d.array(depth + 1)
+	case 'T':
+		return d.longlong()
+	case 'F':
+		return d.table(depth + 1)
+	case 'V':
+		return nil
+	}
+	d.err = errInvalidFrame
+	d.buf = nil
+	return nil
+}
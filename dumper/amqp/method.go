@@ -0,0 +1,218 @@
+package amqp
+
+import (
+	"time"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// readMethod returns values of method frame and whether content frames follow the method
+// https://www.rabbitmq.com/amqp-0-9-1-reference.html
+func readMethod(f frame) ([]dumper.DumpValue, bool) {
+	d := newDecoder(f.payload)
+	id := methodID{
+		classID:  d.short(),
+		methodID: d.short(),
+	}
+	values := []dumper.DumpValue{
+		{
+			Key:   "channel",
+			Value: f.channel,
+		},
+		{
+			Key:   "method",
+			Value: methodName(id),
+		},
+	}
+	if d.err != nil {
+		return values, false
+	}
+	args := []dumper.DumpValue{}
+	add := func(key string, value interface{}) {
+		args = append(args, dumper.DumpValue{
+			Key:   key,
+			Value: value,
+		})
+	}
+	hasContent := false
+	switch id {
+	case connectionStart:
+		_ = d.octet() // version-major
+		_ = d.octet() // version-minor
+		add("server_properties", d.table(0))
+		add("mechanisms", string(d.longstr()))
+	case connectionStartOk:
+		add("client_properties", d.table(0))
+		add("mechanism", d.shortstr())
+		_ = d.longstr() // response ( credentials )
+		add("locale", d.shortstr())
+	case connectionTune, connectionTuneOk:
+		add("channel_max", d.short())
+		add("frame_max", d.long())
+		add("heartbeat", d.short())
+	case connectionOpen:
+		add("virtual_host", d.shortstr())
+	case connectionClose, channelClose:
+		add("reply_code", d.short())
+		add("reply_text", d.shortstr())
+		failing := methodID{
+			classID:  d.short(),
+			methodID: d.short(),
+		}
+		if failing.classID > 0 {
+			add("failing_method", methodName(failing))
+		}
+	case exchangeDeclare:
+		_ = d.short() // reserved
+		add("exchange", d.shortstr())
+		add("exchange_type", d.shortstr())
+		_ = d.bit() // passive
+		add("durable", d.bit())
+		add("auto_delete", d.bit())
+	case queueDeclare:
+		_ = d.short() // reserved
+		add("queue", d.shortstr())
+		_ = d.bit() // passive
+		add("durable", d.bit())
+		add("exclusive", d.bit())
+		add("auto_delete", d.bit())
+	case queueDeclareOk:
+		add("queue", d.shortstr())
+		add("message_count", d.long())
+		add("consumer_count", d.long())
+	case queueBind, queueUnbind:
+		_ = d.short() // reserved
+		add("queue", d.shortstr())
+		add("exchange", d.shortstr())
+		add("routing_key", d.shortstr())
+	case basicQos:
+		_ = d.long() // prefetch-size
+		add("prefetch_count", d.short())
+		add("global", d.bit())
+	case basicConsume:
+		_ = d.short() // reserved
+		add("queue", d.shortstr())
+		add("consumer_tag", d.shortstr())
+		_ = d.bit() // no-local
+		add("no_ack", d.bit())
+		add("exclusive", d.bit())
+	case basicConsumeOk, basicCancel, basicCancelOk:
+		add("consumer_tag", d.shortstr())
+	case basicPublish:
+		_ = d.short() // reserved
+		add("exchange", d.shortstr())
+		add("routing_key", d.shortstr())
+		add("mandatory", d.bit())
+		hasContent = true
+	case basicReturn:
+		add("reply_code", d.short())
+		add("reply_text", d.shortstr())
+		add("exchange", d.shortstr())
+		add("routing_key", d.shortstr())
+		hasContent = true
+	case basicDeliver:
+		add("consumer_tag", d.shortstr())
+		add("delivery_tag", d.longlong())
+		add("redelivered", d.bit())
+		add("exchange", d.shortstr())
+		add("routing_key", d.shortstr())
+		hasContent = true
+	case basicGet:
+		_ = d.short() // reserved
+		add("queue", d.shortstr())
+		add("no_ack", d.bit())
+	case basicGetOk:
+		add("delivery_tag", d.longlong())
+		add("redelivered", d.bit())
+		add("exchange", d.shortstr())
+		add("routing_key", d.shortstr())
+		add("message_count", d.long())
+		hasContent = true
+	case basicAck:
+		add("delivery_tag", d.longlong())
+		add("multiple", d.bit())
+	case basicReject:
+		add("delivery_tag", d.longlong())
+		add("requeue", d.bit())
+	case basicNack:
+		add("delivery_tag", d.longlong())
+		add("multiple", d.bit())
+		add("requeue", d.bit())
+	}
+	if d.err != nil {
+		return values, hasContent
+	}
+	return append(values, args...), hasContent
+}
+
+// readContentHeader returns body size and properties of content header frame
+// https://www.rabbitmq.com/resources/specs/amqp0-9-1.pdf 4.2.6.1
+func readContentHeader(f frame) []dumper.DumpValue {
+	d := newDecoder(f.payload)
+	_ = d.short() // class-id
+	_ = d.short() // weight
+	values := []dumper.DumpValue{
+		{
+			Key:   "body_size",
+			Value: d.longlong(),
+		},
+	}
+	flags := d.short()
+	if d.err != nil {
+		return []dumper.DumpValue{}
+	}
+	add := func(key string, value interface{}) {
+		values = append(values, dumper.DumpValue{
+			Key:   key,
+			Value: value,
+		})
+	}
+	// properties of basic class
+	has := func(bit uint) bool {
+		return flags&(1<<bit) > 0
+	}
+	if has(15) {
+		add("content_type", d.shortstr())
+	}
+	if has(14) {
+		add("content_encoding", d.shortstr())
+	}
+	if has(13) {
+		add("headers", d.table(0))
+	}
+	if has(12) {
+		add("delivery_mode", d.octet())
+	}
+	if has(11) {
+		add("priority", d.octet())
+	}
+	if has(10) {
+		add("correlation_id", d.shortstr())
+	}
+	if has(9) {
+		add("reply_to", d.shortstr())
+	}
+	if has(8) {
+		add("expiration", d.shortstr())
+	}
+	if has(7) {
+		add("message_id", d.shortstr())
+	}
+	if has(6) {
+		add("timestamp", time.Unix(int64(d.longlong()), 0).UTC())
+	}
+	if has(5) {
+		add("message_type", d.shortstr())
+	}
+	if has(4) {
+		add("user_id", d.shortstr())
+	}
+	if has(3) {
+		add("app_id", d.shortstr())
+	}
+	if d.err != nil {
+		// properties are broken
+		return values[:1]
+	}
+	return values
+}
@@ -0,0 +1,165 @@
+package cql
+
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v5.spec
+
+const (
+	minVersion = 3
+	maxVersion = 5
+)
+
+// frameHeaderLength is length of frame ( envelope ) header of v3 and later
+const frameHeaderLength = 9
+
+// maxFrameSize is max size of buffered frame body. The rest of larger frame is skipped
+const maxFrameSize = 64 * 1024
+
+// maxDecompressedSize is max size of decompressed frame body
+const maxDecompressedSize = 16 * 1024 * 1024
+
+// segment header length of v5 ( including CRC24 )
+const (
+	segmentHeaderLength           = 6
+	compressedSegmentHeaderLength = 8
+	segmentTrailerLength          = 4 // CRC32
+)
+
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v4.spec#L145
+const (
+	flagCompression   = 0x01
+	flagTracing       = 0x02
+	flagCustomPayload = 0x04
+	flagWarning       = 0x08
+)
+
+const (
+	opError         = 0x00
+	opStartup       = 0x01
+	opReady         = 0x02
+	opAuthenticate  = 0x03
+	opOptions       = 0x05
+	opSupported     = 0x06
+	opQuery         = 0x07
+	opResult        = 0x08
+	opPrepare       = 0x09
+	opExecute       = 0x0a
+	opRegister      = 0x0b
+	opEvent         = 0x0c
+	opBatch         = 0x0d
+	opAuthChallenge = 0x0e
+	opAuthResponse  = 0x0f
+	opAuthSuccess   = 0x10
+)
+
+var opcodeNames = map[uint8]string{
+	opError:         "ERROR",
+	opStartup:       "STARTUP",
+	opReady:         "READY",
+	opAuthenticate:  "AUTHENTICATE",
+	opOptions:       "OPTIONS",
+	opSupported:     "SUPPORTED",
+	opQuery:         "QUERY",
+	opResult:        "RESULT",
+	opPrepare:       "PREPARE",
+	opExecute:       "EXECUTE",
+	opRegister:      "REGISTER",
+	opEvent:         "EVENT",
+	opBatch:         "BATCH",
+	opAuthChallenge: "AUTH_CHALLENGE",
+	opAuthResponse:  "AUTH_RESPONSE",
+	opAuthSuccess:   "AUTH_SUCCESS",
+}
+
+// flags of query parameters
+const (
+	queryFlagValues            = 0x01
+	queryFlagSkipMetadata      = 0x02
+	queryFlagPageSize          = 0x04
+	queryFlagPagingState       = 0x08
+	queryFlagSerialConsistency = 0x10
+	queryFlagTimestamp         = 0x20
+	queryFlagValueNames        = 0x40
+	queryFlagKeyspace          = 0x80
+	queryFlagNowInSeconds      = 0x100
+)
+
+var consistencyNames = map[uint16]string{
+	0x0000: "ANY",
+	0x0001: "ONE",
+	0x0002: "TWO",
+	0x0003: "THREE",
+	0x0004: "QUORUM",
+	0x0005: "ALL",
+	0x0006: "LOCAL_QUORUM",
+	0x0007: "EACH_QUORUM",
+	0x0008: "SERIAL",
+	0x0009: "LOCAL_SERIAL",
+	0x000a: "LOCAL_ONE",
+}
+
+var batchTypeNames = map[uint8]string{
+	0: "LOGGED",
+	1: "UNLOGGED",
+	2: "COUNTER",
+}
+
+const (
+	resultVoid         = 0x0001
+	resultRows         = 0x0002
+	resultSetKeyspace  = 0x0003
+	resultPrepared     = 0x0004
+	resultSchemaChange = 0x0005
+)
+
+var resultKindNames = map[int32]string{
+	resultVoid:         "Void",
+	resultRows:         "Rows",
+	resultSetKeyspace:  "Set_keyspace",
+	resultPrepared:     "Prepared",
+	resultSchemaChange: "Schema_change",
+}
+
+// flags of rows metadata
+const (
+	rowsFlagGlobalTablesSpec = 0x0001
+	rowsFlagHasMorePages     = 0x0002
+	rowsFlagNoMetadata       = 0x0004
+	rowsFlagMetadataChanged  = 0x0008
+)
+
+const errUnprepared = 0x2500
+
+var errorNames = map[int32]string{
+	0x0000:        "Server_error",
+	0x000a:        "Protocol_error",
+	0x0100:        "Bad_credentials",
+	0x1000:        "Unavailable",
+	0x1001:        "Overloaded",
+	0x1002:        "Is_bootstrapping",
+	0x1003:        "Truncate_error",
+	0x1100:        "Write_timeout",
+	0x1200:        "Read_timeout",
+	0x1300:        "Read_failure",
+	0x1400:        "Function_failure",
+	0x1500:        "Write_failure",
+	0x1600:        "CDC_write_failure",
+	0x1700:        "CAS_write_unknown",
+	0x2000:        "Syntax_error",
+	0x2100:        "Unauthorized",
+	0x2200:        "Invalid",
+	0x2300:        "Config_error",
+	0x2400:        "Already_exists",
+	errUnprepared: "Unprepared",
+}
+
+// option ids of data type
+const (
+	typeCustom = 0x0000
+	typeList   = 0x0020
+	typeMap    = 0x0021
+	typeSet    = 0x0022
+	typeUDT    = 0x0030
+	typeTuple  = 0x0031
+)
+
+// maxTypeDepth is max depth of nested data type
+const maxTypeDepth = 16
@@ -0,0 +1,139 @@
+package cql
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxStreams is max number of requests waiting for response
+const maxStreams = 32768
+
+// maxPrepared is max number of cached prepared statements per connection
+const maxPrepared = 10000
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	client      *stream
+	server      *stream
+	compression string            // COMPRESSION of STARTUP
+	requests    map[int16]request // stream id:request
+	prepared    map[string]string // prepared id:query
+}
+
+// request is request waiting for response
+type request struct {
+	opcode uint8
+	query  string
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "cql",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (c *Dumper) Name() string {
+	return c.name
+}
+
+// Dump requests and responses of CQL native protocol
+func (c *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := c.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		c.Log(values)
+	}
+	return nil
+}
+
+// Read return the first frame of byte to analyzed string ( use ReadFrames to read all frames )
+func (c *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := c.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return frames of byte to analyzed string
+func (c *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	s := internal.client
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		s = internal.server
+	}
+	if s.broken {
+		return records, nil
+	}
+
+	frames := []frame{}
+	if s.segments {
+		for _, p := range s.readSegments(in, internal.compression == "lz4") {
+			frames = append(frames, s.readFrames(p)...)
+		}
+	} else {
+		frames = s.readFrames(in)
+	}
+	for _, f := range frames {
+		if !s.segments && f.flags&flagCompression > 0 && !f.truncated {
+			body, err := decompress(internal.compression, f.body)
+			if err != nil {
+				continue
+			}
+			f.body = body
+		}
+		if f.response {
+			records = append(records, readResponse(f, &internal))
+			if f.version >= 5 && (f.opcode == opReady || f.opcode == opAuthSuccess) {
+				// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v5.spec#L74
+				internal.client.segments = true
+				internal.server.segments = true
+			}
+			continue
+		}
+		records = append(records, readRequest(f, &internal))
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (c *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	c.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (c *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			client:   &stream{},
+			server:   &stream{},
+			requests: map[int16]request{},
+			prepared: map[string]string{},
+		},
+	}
+}
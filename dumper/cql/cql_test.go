@@ -0,0 +1,917 @@
+package cql
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type cqlStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  [][]dumper.DumpValue
+}
+
+var cqlReadFramesTests = []struct {
+	description string
+	steps       []cqlStep
+}{
+	{
+		"v4 with lz4 compression",
+		[]cqlStep{
+			{
+				[]byte{
+					0x04, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(0),
+						},
+						{
+							Key:   "opcode",
+							Value: "OPTIONS",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x84, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x34, 0x00, 0x02, 0x00, 0x0b, 0x43, 0x4f, 0x4d,
+					0x50, 0x52, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x02, 0x00, 0x03, 0x6c, 0x7a, 0x34, 0x00,
+					0x06, 0x73, 0x6e, 0x61, 0x70, 0x70, 0x79, 0x00, 0x0b, 0x43, 0x51, 0x4c, 0x5f, 0x56, 0x45, 0x52,
+					0x53, 0x49, 0x4f, 0x4e, 0x00, 0x01, 0x00, 0x05, 0x33, 0x2e, 0x34, 0x2e, 0x35,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(0),
+						},
+						{
+							Key:   "opcode",
+							Value: "SUPPORTED",
+						},
+						{
+							Key:   "options",
+							Value: map[string][]string{"COMPRESSION": {"lz4", "snappy"}, "CQL_VERSION": {"3.4.5"}},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x04, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x28, 0x00, 0x02, 0x00, 0x0b, 0x43, 0x51, 0x4c,
+					0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x05, 0x33, 0x2e, 0x30, 0x2e, 0x30, 0x00,
+					0x0b, 0x43, 0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x03, 0x6c, 0x7a,
+					0x34,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "STARTUP",
+						},
+						{
+							Key:   "options",
+							Value: map[string]string{"CQL_VERSION": "3.0.0", "COMPRESSION": "lz4"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x84, 0x00, 0x00, 0x01, 0x03, 0x00, 0x00, 0x00, 0x31, 0x00, 0x2f, 0x6f, 0x72, 0x67, 0x2e, 0x61,
+					0x70, 0x61, 0x63, 0x68, 0x65, 0x2e, 0x63, 0x61, 0x73, 0x73, 0x61, 0x6e, 0x64, 0x72, 0x61, 0x2e,
+					0x61, 0x75, 0x74, 0x68, 0x2e, 0x50, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x41, 0x75, 0x74,
+					0x68, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x74, 0x6f, 0x72,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "AUTHENTICATE",
+						},
+						{
+							Key:   "authenticator",
+							Value: "org.apache.cassandra.auth.PasswordAuthenticator",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x04, 0x00, 0x00, 0x02, 0x0f, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x14, 0x00, 0x63, 0x61,
+					0x73, 0x73, 0x61, 0x6e, 0x64, 0x72, 0x61, 0x00, 0x63, 0x61, 0x73, 0x73, 0x61, 0x6e, 0x64, 0x72,
+					0x61,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(2),
+						},
+						{
+							Key:   "opcode",
+							Value: "AUTH_RESPONSE",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x84, 0x00, 0x00, 0x02, 0x10, 0x00, 0x00, 0x00, 0x04, 0xff, 0xff, 0xff, 0xff,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(2),
+						},
+						{
+							Key:   "opcode",
+							Value: "AUTH_SUCCESS",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x03, 0x09, 0x00, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x24, 0xf0, 0x15, 0x00,
+					0x00, 0x00, 0x20, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x2a, 0x20, 0x46, 0x52, 0x4f, 0x4d,
+					0x20, 0x75, 0x73, 0x65, 0x72, 0x73, 0x20, 0x57, 0x48, 0x45, 0x52, 0x45, 0x20, 0x69, 0x64, 0x20,
+					0x3d, 0x20, 0x3f,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(3),
+						},
+						{
+							Key:   "opcode",
+							Value: "PREPARE",
+						},
+						{
+							Key:   "query",
+							Value: "SELECT * FROM users WHERE id = ?",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x84, 0x01, 0x00, 0x03, 0x08, 0x00, 0x00, 0x00, 0x3f, 0x00, 0x00, 0x00, 0x42, 0xf7, 0x0b, 0x00,
+					0x00, 0x00, 0x04, 0x00, 0x10, 0x8a, 0x1f, 0x2b, 0x3c, 0x4d, 0x5e, 0x6f, 0x70, 0x81, 0x92, 0xa3,
+					0xb4, 0xc5, 0xd6, 0xe7, 0xf8, 0x00, 0x00, 0x00, 0x01, 0x04, 0x00, 0xf1, 0x02, 0x03, 0x61, 0x70,
+					0x70, 0x00, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x00, 0x02, 0x69, 0x64, 0x00, 0x09, 0x36, 0x00,
+					0x70, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(3),
+						},
+						{
+							Key:   "opcode",
+							Value: "RESULT",
+						},
+						{
+							Key:   "query",
+							Value: "SELECT * FROM users WHERE id = ?",
+						},
+						{
+							Key:   "result_kind",
+							Value: "Prepared",
+						},
+						{
+							Key:   "prepared_id",
+							Value: "8a1f2b3c4d5e6f708192a3b4c5d6e7f8",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x00, 0x29, 0x00, 0x00, 0x00, 0x23, 0xf0, 0x14, 0x00,
+					0x10, 0x8a, 0x1f, 0x2b, 0x3c, 0x4d, 0x5e, 0x6f, 0x70, 0x81, 0x92, 0xa3, 0xb4, 0xc5, 0xd6, 0xe7,
+					0xf8, 0x00, 0x06, 0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00,
+					0x00, 0x64,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(4),
+						},
+						{
+							Key:   "opcode",
+							Value: "EXECUTE",
+						},
+						{
+							Key:   "prepared_id",
+							Value: "8a1f2b3c4d5e6f708192a3b4c5d6e7f8",
+						},
+						{
+							Key:   "query",
+							Value: "SELECT * FROM users WHERE id = ?",
+						},
+						{
+							Key:   "consistency",
+							Value: "LOCAL_QUORUM",
+						},
+						{
+							Key:   "page_size",
+							Value: int32(100),
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x84, 0x09, 0x00, 0x04, 0x08, 0x00, 0x00, 0x00, 0x67, 0x00, 0x00, 0x00, 0x6c, 0xf1, 0x19, 0x00,
+					0x01, 0x00, 0x2c, 0x41,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x20, 0x71, 0x75, 0x65, 0x72, 0x79,
+					0x20, 0x75, 0x73, 0x65, 0x64, 0x20, 0x77, 0x69, 0x74, 0x68, 0x6f, 0x75, 0x74, 0x20, 0x70, 0x61,
+					0x72, 0x74, 0x69, 0x1d, 0x00, 0xb1, 0x6b, 0x65, 0x79, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+					0x01, 0x08, 0x00, 0xf3, 0x0c, 0x03, 0x61, 0x70, 0x70, 0x00, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73,
+					0x00, 0x02, 0x69, 0x64, 0x00, 0x09, 0x00, 0x04, 0x74, 0x61, 0x67, 0x73, 0x00, 0x20, 0x00, 0x0d,
+					0x24, 0x00, 0x13, 0x04, 0x08, 0x00, 0x50, 0x04, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(4),
+						},
+						{
+							Key:   "opcode",
+							Value: "RESULT",
+						},
+						{
+							Key:   "query",
+							Value: "SELECT * FROM users WHERE id = ?",
+						},
+						{
+							Key:   "result_kind",
+							Value: "Rows",
+						},
+						{
+							Key:   "rows_count",
+							Value: int32(1),
+						},
+						{
+							Key:   "warnings",
+							Value: []string{"Aggregation query used without partition key"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x04, 0x00, 0x00, 0x05, 0x0d, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00,
+					0x1c, 0x49, 0x4e, 0x53, 0x45, 0x52, 0x54, 0x20, 0x49, 0x4e, 0x54, 0x4f, 0x20, 0x74, 0x20, 0x28,
+					0x61, 0x29, 0x20, 0x56, 0x41, 0x4c, 0x55, 0x45, 0x53, 0x20, 0x28, 0x31, 0x29, 0x00, 0x00, 0x01,
+					0x00, 0x10, 0x8a, 0x1f, 0x2b, 0x3c, 0x4d, 0x5e, 0x6f, 0x70, 0x81, 0x92, 0xa3, 0xb4, 0xc5, 0xd6,
+					0xe7, 0xf8, 0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x02, 0x00, 0x04, 0x10, 0x00,
+					0x09,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(5),
+						},
+						{
+							Key:   "opcode",
+							Value: "BATCH",
+						},
+						{
+							Key:   "batch_type",
+							Value: "LOGGED",
+						},
+						{
+							Key:   "queries",
+							Value: []string{"INSERT INTO t (a) VALUES (1)", "SELECT * FROM users WHERE id = ?"},
+						},
+						{
+							Key:   "consistency",
+							Value: "QUORUM",
+						},
+						{
+							Key:   "serial_consistency",
+							Value: "LOCAL_SERIAL",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x84, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x2e, 0x00, 0x00, 0x11, 0x00, 0x00, 0x13, 0x4f,
+					0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x20, 0x74, 0x69, 0x6d, 0x65, 0x64, 0x20, 0x6f,
+					0x75, 0x74, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x09, 0x42, 0x41,
+					0x54, 0x43, 0x48, 0x5f, 0x4c, 0x4f, 0x47,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(5),
+						},
+						{
+							Key:   "opcode",
+							Value: "ERROR",
+						},
+						{
+							Key:   "error_code",
+							Value: int32(4352),
+						},
+						{
+							Key:   "error_name",
+							Value: "Write_timeout",
+						},
+						{
+							Key:   "error_message",
+							Value: "Operation timed out",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x04, 0x00, 0x00, 0x06, 0x07, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x07, 0x55, 0x53, 0x45,
+					0x20, 0x61, 0x70, 0x70, 0x00, 0x01, 0x00, 0x04, 0x00, 0x00, 0x07, 0x07, 0x00, 0x00, 0x00, 0x25,
+					0x00, 0x00, 0x00, 0x1e, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x6e, 0x6f, 0x77, 0x28, 0x29,
+					0x20, 0x46, 0x52, 0x4f, 0x4d, 0x20, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x2e, 0x6c, 0x6f, 0x63,
+					0x61, 0x6c, 0x00, 0x01, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(6),
+						},
+						{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						{
+							Key:   "query",
+							Value: "USE app",
+						},
+						{
+							Key:   "consistency",
+							Value: "ONE",
+						},
+					},
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(7),
+						},
+						{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						{
+							Key:   "query",
+							Value: "SELECT now() FROM system.local",
+						},
+						{
+							Key:   "consistency",
+							Value: "ONE",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x84, 0x01, 0x00, 0x06, 0x08, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x09, 0x90, 0x00, 0x00,
+					0x00, 0x03, 0x00, 0x03, 0x61, 0x70, 0x70, 0x84, 0x00, 0xff, 0xff, 0x0c, 0x00, 0x00, 0x00, 0x2a,
+					0x00, 0x0d, 0x53, 0x43, 0x48, 0x45, 0x4d, 0x41, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47, 0x45, 0x00,
+					0x07, 0x43, 0x52, 0x45, 0x41, 0x54, 0x45, 0x44, 0x00, 0x05, 0x54, 0x41, 0x42, 0x4c, 0x45, 0x00,
+					0x03, 0x61, 0x70, 0x70, 0x00, 0x04, 0x6c, 0x6f, 0x67, 0x73,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(6),
+						},
+						{
+							Key:   "opcode",
+							Value: "RESULT",
+						},
+						{
+							Key:   "query",
+							Value: "USE app",
+						},
+						{
+							Key:   "result_kind",
+							Value: "Set_keyspace",
+						},
+						{
+							Key:   "keyspace",
+							Value: "app",
+						},
+					},
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(-1),
+						},
+						{
+							Key:   "opcode",
+							Value: "EVENT",
+						},
+						{
+							Key:   "event_type",
+							Value: "SCHEMA_CHANGE",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"v3 with snappy compression",
+		[]cqlStep{
+			{
+				[]byte{
+					0x03, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x2b, 0x00, 0x02, 0x00, 0x0b, 0x43, 0x51, 0x4c,
+					0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x05, 0x33, 0x2e, 0x30, 0x2e, 0x30, 0x00,
+					0x0b, 0x43, 0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x06, 0x73, 0x6e,
+					0x61, 0x70, 0x70, 0x79,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "STARTUP",
+						},
+						{
+							Key:   "options",
+							Value: map[string]string{"CQL_VERSION": "3.0.0", "COMPRESSION": "snappy"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x83, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "READY",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x03, 0x01, 0x00, 0x02, 0x07, 0x00, 0x00, 0x00, 0x8a, 0x85, 0x01, 0xec, 0x00, 0x00, 0x00, 0x7c,
+					0x49, 0x4e, 0x53, 0x45, 0x52, 0x54, 0x20, 0x49, 0x4e, 0x54, 0x4f, 0x20, 0x6c, 0x6f, 0x67, 0x73,
+					0x20, 0x28, 0x69, 0x64, 0x2c, 0x20, 0x62, 0x6f, 0x64, 0x79, 0x29, 0x20, 0x56, 0x41, 0x4c, 0x55,
+					0x45, 0x53, 0x20, 0x28, 0x6e, 0x6f, 0x77, 0x28, 0x29, 0x2c, 0x20, 0x27, 0x61, 0x61, 0x61, 0x61,
+					0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0xec, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61,
+					0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61,
+					0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61,
+					0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61,
+					0x61, 0x61, 0x61, 0x61, 0x61, 0x30, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x27, 0x29, 0x00, 0x04,
+					0x10, 0x00, 0x08,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(2),
+						},
+						{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						{
+							Key:   "query",
+							Value: "INSERT INTO logs (id, body) VALUES (now(), 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa')",
+						},
+						{
+							Key:   "consistency",
+							Value: "QUORUM",
+						},
+						{
+							Key:   "serial_consistency",
+							Value: "SERIAL",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x83, 0x01, 0x00, 0x02, 0x08, 0x00, 0x00, 0x00, 0x06, 0x04, 0x0c, 0x00, 0x00, 0x00, 0x01,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(2),
+						},
+						{
+							Key:   "opcode",
+							Value: "RESULT",
+						},
+						{
+							Key:   "query",
+							Value: "INSERT INTO logs (id, body) VALUES (now(), 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa')",
+						},
+						{
+							Key:   "result_kind",
+							Value: "Void",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x03, 0x00, 0x00, 0x03, 0x0a, 0x00, 0x00, 0x00, 0x07, 0x00, 0x02, 0xca, 0xfe, 0x00, 0x01, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(3),
+						},
+						{
+							Key:   "opcode",
+							Value: "EXECUTE",
+						},
+						{
+							Key:   "prepared_id",
+							Value: "cafe",
+						},
+						{
+							Key:   "consistency",
+							Value: "ONE",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x83, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x2f, 0x00, 0x00, 0x25, 0x00, 0x00, 0x25, 0x50,
+					0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x64, 0x20, 0x71, 0x75, 0x65, 0x72, 0x79, 0x20, 0x77, 0x69,
+					0x74, 0x68, 0x20, 0x49, 0x44, 0x20, 0x63, 0x61, 0x66, 0x65, 0x20, 0x6e, 0x6f, 0x74, 0x20, 0x66,
+					0x6f, 0x75, 0x6e, 0x64, 0x00, 0x02, 0xca, 0xfe,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(3),
+						},
+						{
+							Key:   "opcode",
+							Value: "ERROR",
+						},
+						{
+							Key:   "error_code",
+							Value: int32(9472),
+						},
+						{
+							Key:   "error_name",
+							Value: "Unprepared",
+						},
+						{
+							Key:   "error_message",
+							Value: "Prepared query with ID cafe not found",
+						},
+						{
+							Key:   "prepared_id",
+							Value: "cafe",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"v5 with compressed segments",
+		[]cqlStep{
+			{
+				[]byte{
+					0x05, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x28, 0x00, 0x02, 0x00, 0x0b, 0x43, 0x51, 0x4c,
+					0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x05, 0x33, 0x2e, 0x30, 0x2e, 0x30, 0x00,
+					0x0b, 0x43, 0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x03, 0x6c, 0x7a,
+					0x34,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "STARTUP",
+						},
+						{
+							Key:   "options",
+							Value: map[string]string{"CQL_VERSION": "3.0.0", "COMPRESSION": "lz4"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x85, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "READY",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x5a, 0x00, 0xca, 0x00, 0x04, 0x2a, 0xe9, 0x97, 0xf1, 0x34, 0x05, 0x00, 0x00, 0x02, 0x07, 0x00,
+					0x00, 0x00, 0x5c, 0x00, 0x00, 0x00, 0x4e, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x72, 0x65,
+					0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x20, 0x46, 0x52,
+					0x4f, 0x4d, 0x20, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x2e, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x20,
+					0x57, 0x48, 0x45, 0x52, 0x45, 0x20, 0x6b, 0x65, 0x79, 0x20, 0x3d, 0x20, 0x27, 0x13, 0x00, 0x5a,
+					0x27, 0x20, 0x41, 0x4e, 0x44, 0x12, 0x00, 0xa0, 0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00,
+					0x13, 0x88, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(2),
+						},
+						{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						{
+							Key:   "query",
+							Value: "SELECT release_version FROM system.local WHERE key = 'local' AND key = 'local'",
+						},
+						{
+							Key:   "consistency",
+							Value: "ONE",
+						},
+						{
+							Key:   "page_size",
+							Value: int32(5000),
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x0c, 0x00, 0x00, 0x00, 0x00, 0x2c, 0xbf, 0xb1, 0x85, 0x00, 0x00, 0x02, 0x08, 0x00, 0x00, 0x00,
+					0x19, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x16, 0x00, 0x00, 0x00, 0x00, 0x11, 0x1e, 0x83,
+					0x02, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x05, 0x34,
+					0x2e, 0x31, 0x2e, 0x33, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(2),
+						},
+						{
+							Key:   "opcode",
+							Value: "RESULT",
+						},
+						{
+							Key:   "query",
+							Value: "SELECT release_version FROM system.local WHERE key = 'local' AND key = 'local'",
+						},
+						{
+							Key:   "result_kind",
+							Value: "Rows",
+						},
+						{
+							Key:   "rows_count",
+							Value: int32(1),
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"v5 with segments ( broken CRC24 )",
+		[]cqlStep{
+			{
+				[]byte{
+					0x05, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x16, 0x00, 0x01, 0x00, 0x0b, 0x43, 0x51, 0x4c,
+					0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x00, 0x05, 0x33, 0x2e, 0x30, 0x2e, 0x30,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "STARTUP",
+						},
+						{
+							Key:   "options",
+							Value: map[string]string{"CQL_VERSION": "3.0.0"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x85, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(1),
+						},
+						{
+							Key:   "opcode",
+							Value: "READY",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x34, 0x00, 0x02, 0xfe, 0xf0, 0x08, 0x05, 0x00, 0x00, 0x02, 0x05, 0x00, 0x00, 0x00, 0x00, 0x05,
+					0x00, 0x00, 0x03, 0x0b, 0x00, 0x00, 0x00, 0x22, 0x00, 0x02, 0x00, 0x0f, 0x54, 0x4f, 0x50, 0x4f,
+					0x4c, 0x4f, 0x47, 0x59, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47, 0x45, 0x00, 0x0d, 0x53, 0x54, 0x41,
+					0x54, 0x55, 0x53, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47, 0x45, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(2),
+						},
+						{
+							Key:   "opcode",
+							Value: "OPTIONS",
+						},
+					},
+					{
+						{
+							Key:   "stream_id",
+							Value: int16(3),
+						},
+						{
+							Key:   "opcode",
+							Value: "REGISTER",
+						},
+						{
+							Key:   "events",
+							Value: []string{"TOPOLOGY_CHANGE", "STATUS_CHANGE"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0xff, 0x00, 0x02, 0xa4, 0xc8, 0xc1, 0x85, 0x00, 0x00, 0x03, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+	{
+		"Not CQL",
+		[]cqlStep{
+			{
+				[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte("HTTP/1.1 400 Bad Request\r\n\r\n"),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+}
+
+func TestCQLReadFrames(t *testing.T) {
+	for _, tt := range cqlReadFramesTests {
+		out := new(bytes.Buffer)
+		d := NewDumper()
+		d.logger = newTestLogger(out)
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
@@ -0,0 +1,162 @@
+package cql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidFrame = errors.New("invalid CQL frame")
+
+// decoder read notations of CQL native protocol
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v4.spec#L211
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func newDecoder(in []byte) *decoder {
+	return &decoder{
+		buf: in,
+	}
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errInvalidFrame
+		d.buf = nil
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) byte() uint8 {
+	b := d.read(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) short() uint16 {
+	b := d.read(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *decoder) int() int32 {
+	b := d.read(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) string() string {
+	return string(d.read(int(d.short())))
+}
+
+func (d *decoder) longString() string {
+	return string(d.read(int(d.int())))
+}
+
+// bytes read [bytes] ( nil: null or unset value )
+func (d *decoder) bytes() []byte {
+	l := d.int()
+	if l < 0 {
+		return nil
+	}
+	return d.read(int(l))
+}
+
+func (d *decoder) shortBytes() []byte {
+	return d.read(int(d.short()))
+}
+
+// shortBytesHex read [short bytes] in hex ( prepared id )
+func (d *decoder) shortBytesHex() string {
+	return hex.EncodeToString(d.shortBytes())
+}
+
+func (d *decoder) stringList() []string {
+	n := int(d.short())
+	l := []string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		l = append(l, d.string())
+	}
+	return l
+}
+
+func (d *decoder) stringMap() map[string]string {
+	n := int(d.short())
+	m := map[string]string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		k := d.string()
+		m[k] = d.string()
+	}
+	return m
+}
+
+func (d *decoder) stringMultimap() map[string][]string {
+	n := int(d.short())
+	m := map[string][]string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		k := d.string()
+		m[k] = d.stringList()
+	}
+	return m
+}
+
+func (d *decoder) bytesMap() {
+	n := int(d.short())
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = d.string()
+		_ = d.bytes()
+	}
+}
+
+func (d *decoder) consistency() string {
+	c := d.short()
+	if n, ok := consistencyNames[c]; ok {
+		return n
+	}
+	return "UNKNOWN"
+}
+
+// option skip [option] of data type
+func (d *decoder) option(depth int) {
+	if depth >= maxTypeDepth {
+		d.err = errInvalidFrame
+		return
+	}
+	switch d.short() {
+	case typeCustom:
+		_ = d.string()
+	case typeList, typeSet:
+		d.option(depth + 1)
+	case typeMap:
+		d.option(depth + 1)
+		d.option(depth + 1)
+	case typeUDT:
+		_ = d.string() // keyspace
+		_ = d.string() // UDT name
+		n := int(d.short())
+		for i := 0; i < n && d.err == nil; i++ {
+			_ = d.string()
+			d.option(depth + 1)
+		}
+	case typeTuple:
+		n := int(d.short())
+		for i := 0; i < n && d.err == nil; i++ {
+			d.option(depth + 1)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package cql
+
+import (
+	"encoding/binary"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/pkg/errors"
+)
+
+// stream is CQL frames of a direction
+type stream struct {
+	segments      bool   // v5 segment framing ( after READY or AUTH_SUCCESS )
+	segmentBuffer []byte // partial segment
+	buffer        []byte // partial frame
+	skip          int    // rest bytes of large frame
+	broken        bool   // frame boundary is lost
+}
+
+// frame is CQL frame ( envelope of v5 )
+type frame struct {
+	version   uint8
+	response  bool
+	flags     uint8
+	streamID  int16
+	opcode    uint8
+	body      []byte
+	truncated bool // only the head of body is buffered
+}
+
+// readSegments returns payloads of complete segments and cache the partial segment
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v5.spec#L91
+func (s *stream) readSegments(in []byte, compressed bool) [][]byte {
+	payloads := [][]byte{}
+	buff := append(s.segmentBuffer, in...)
+	s.segmentBuffer = nil
+	headerLength := segmentHeaderLength
+	if compressed {
+		headerLength = compressedSegmentHeaderLength
+	}
+	for len(buff) >= headerLength {
+		var h uint64
+		for i := headerLength - 4; i >= 0; i-- {
+			h = h<<8 | uint64(buff[i])
+		}
+		crc := uint32(buff[headerLength-3]) | uint32(buff[headerLength-2])<<8 | uint32(buff[headerLength-1])<<16
+		if crc24(h, headerLength-3) != crc {
+			s.broken = true
+			return payloads
+		}
+		l := int(h & 0x1ffff)
+		total := headerLength + l + segmentTrailerLength
+		if len(buff) < total {
+			break
+		}
+		payload := buff[headerLength : headerLength+l]
+		if compressed {
+			if size := int((h >> 17) & 0x1ffff); size > 0 {
+				var err error
+				payload, err = decodeLZ4Block(payload, size)
+				if err != nil {
+					s.broken = true
+					return payloads
+				}
+			}
+		}
+		payloads = append(payloads, payload)
+		buff = buff[total:]
+	}
+	if len(buff) > 0 {
+		s.segmentBuffer = append([]byte{}, buff...)
+	}
+	return payloads
+}
+
+// readFrames returns complete frames and cache the partial frame
+// large frame is returned with only the head of body and the rest is skipped
+func (s *stream) readFrames(in []byte) []frame {
+	frames := []frame{}
+	if s.skip > 0 {
+		if len(in) <= s.skip {
+			s.skip -= len(in)
+			return frames
+		}
+		in = in[s.skip:]
+		s.skip = 0
+	}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) >= frameHeaderLength {
+		version := buff[0] & 0x7f
+		opcode := buff[4]
+		_, ok := opcodeNames[opcode]
+		l := int(int32(binary.BigEndian.Uint32(buff[5:9])))
+		if version < minVersion || version > maxVersion || !ok || l < 0 {
+			// not frame boundary
+			s.broken = true
+			return frames
+		}
+		f := frame{
+			version:  version,
+			response: buff[0]&0x80 > 0,
+			flags:    buff[1],
+			streamID: int16(binary.BigEndian.Uint16(buff[2:4])),
+			opcode:   opcode,
+		}
+		if l <= maxFrameSize {
+			if len(buff) < frameHeaderLength+l {
+				break
+			}
+			f.body = buff[frameHeaderLength : frameHeaderLength+l]
+			frames = append(frames, f)
+			buff = buff[frameHeaderLength+l:]
+			continue
+		}
+		if len(buff) < frameHeaderLength+maxFrameSize {
+			break
+		}
+		f.body = buff[frameHeaderLength : frameHeaderLength+maxFrameSize]
+		f.truncated = true
+		frames = append(frames, f)
+		if len(buff) < frameHeaderLength+l {
+			s.skip = frameHeaderLength + l - len(buff)
+			buff = nil
+			break
+		}
+		buff = buff[frameHeaderLength+l:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return frames
+}
+
+// decompress body of v3 / v4 frame
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v4.spec#L1101
+func decompress(compression string, in []byte) ([]byte, error) {
+	switch compression {
+	case "lz4":
+		if len(in) < 4 {
+			return nil, errInvalidLZ4Block
+		}
+		return decodeLZ4Block(in[4:], int(int32(binary.BigEndian.Uint32(in[0:4]))))
+	case "snappy":
+		size, err := snappy.DecodedLen(in)
+		if err != nil {
+			return nil, err
+		}
+		if size > maxDecompressedSize {
+			return nil, errors.Errorf("invalid snappy decompressed size: %d", size)
+		}
+		return snappy.Decode(nil, in)
+	}
+	return nil, errors.Errorf("unsupported compression: %s", compression)
+}
+
+// crc24 returns CRC24 of segment header
+// https://github.com/apache/cassandra/blob/trunk/src/java/org/apache/cassandra/net/Crc.java
+func crc24(bytes uint64, n int) uint32 {
+	crc := uint32(0x875060)
+	for ; n > 0; n-- {
+		crc ^= uint32(bytes&0xff) << 16
+		bytes >>= 8
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 > 0 {
+				crc ^= 0x1974f0b
+			}
+		}
+	}
+	return crc & 0xffffff
+}
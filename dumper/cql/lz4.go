@@ -0,0 +1,79 @@
+package cql
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidLZ4Block = errors.New("invalid LZ4 block")
+
+// decodeLZ4Block decode LZ4 block ( without frame format ) into size bytes
+// https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md
+func decodeLZ4Block(src []byte, size int) ([]byte, error) {
+	if size < 0 || size > maxDecompressedSize {
+		return nil, errors.Errorf("invalid LZ4 decompressed size: %d", size)
+	}
+	dst := make([]byte, 0, size)
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+		l, n, ok := readLZ4Length(src[i:], int(token>>4))
+		if !ok {
+			return nil, errInvalidLZ4Block
+		}
+		i += n
+		if l > len(src)-i || l > size-len(dst) {
+			return nil, errInvalidLZ4Block
+		}
+		dst = append(dst, src[i:i+l]...)
+		i += l
+		if i == len(src) {
+			// the last sequence has only literals
+			break
+		}
+		if len(src)-i < 2 {
+			return nil, errInvalidLZ4Block
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i : i+2]))
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errInvalidLZ4Block
+		}
+		l, n, ok = readLZ4Length(src[i:], int(token&0x0f))
+		if !ok {
+			return nil, errInvalidLZ4Block
+		}
+		i += n
+		l += 4 // minmatch
+		if l > size-len(dst) {
+			return nil, errInvalidLZ4Block
+		}
+		// match can overlap with itself
+		start := len(dst) - offset
+		for j := 0; j < l; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+	if len(dst) != size {
+		return nil, errInvalidLZ4Block
+	}
+	return dst, nil
+}
+
+// readLZ4Length read additional bytes of literal length or match length
+func readLZ4Length(src []byte, l int) (int, int, bool) {
+	if l != 15 {
+		return l, 0, true
+	}
+	for i, b := range src {
+		l += int(b)
+		if l > maxDecompressedSize {
+			return 0, 0, false
+		}
+		if b != 255 {
+			return l, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
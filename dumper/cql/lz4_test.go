@@ -0,0 +1,78 @@
+package cql
+
+import (
+	"bytes"
+	"testing"
+)
+
+var decodeLZ4BlockTests = []struct {
+	description string
+	in          []byte
+	size        int
+	expected    []byte
+	wantErr     bool
+}{
+	{
+		"literals only",
+		[]byte{0x50, 0x74, 0x63, 0x70, 0x64, 0x70},
+		5,
+		[]byte("tcpdp"),
+		false,
+	},
+	{
+		"overlapping match with extended length",
+		[]byte{
+			0x6f, 0x74, 0x63, 0x70, 0x64, 0x70, 0x20, 0x06, 0x00, 0xff, 0x50, 0xf0, 0x19, 0x00, 0x01, 0x02,
+			0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12,
+			0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20, 0x21, 0x22,
+			0x23, 0x24, 0x25, 0x26, 0x27,
+		},
+		400,
+		append(bytes.Repeat([]byte("tcpdp tcpdp tcpdp "), 20), []byte{
+			0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+			0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+			0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27,
+		}...),
+		false,
+	},
+	{
+		"offset out of range",
+		[]byte{0x10, 0x61, 0x05, 0x00, 0x00},
+		5,
+		nil,
+		true,
+	},
+	{
+		"decompressed size mismatch",
+		[]byte{0x50, 0x74, 0x63, 0x70, 0x64, 0x70},
+		6,
+		nil,
+		true,
+	},
+	{
+		"literals larger than size",
+		[]byte{0x50, 0x74, 0x63, 0x70, 0x64, 0x70},
+		4,
+		nil,
+		true,
+	},
+}
+
+func TestDecodeLZ4Block(t *testing.T) {
+	for _, tt := range decodeLZ4BlockTests {
+		actual, err := decodeLZ4Block(tt.in, tt.size)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", tt.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.description, err)
+			continue
+		}
+		if !bytes.Equal(actual, tt.expected) {
+			t.Errorf("%s:\nactual %#v\nwant %#v", tt.description, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package cql
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// readRequest returns values of request and remember it for response
+func readRequest(f frame, internal *connMetadataInternal) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		{
+			Key:   "stream_id",
+			Value: f.streamID,
+		},
+		{
+			Key:   "opcode",
+			Value: opcodeNames[f.opcode],
+		},
+	}
+	d := newDecoder(f.body)
+	if f.flags&flagCustomPayload > 0 {
+		d.bytesMap()
+	}
+	args := []dumper.DumpValue{}
+	add := func(key string, value interface{}) {
+		args = append(args, dumper.DumpValue{
+			Key:   key,
+			Value: value,
+		})
+	}
+	req := request{
+		opcode: f.opcode,
+	}
+	switch f.opcode {
+	case opStartup:
+		options := d.stringMap()
+		if d.err == nil {
+			internal.compression = options["COMPRESSION"]
+		}
+		add("options", options)
+	case opQuery:
+		req.query = d.longString()
+		add("query", req.query)
+		args = append(args, readQueryParameters(d, f.version)...)
+	case opPrepare:
+		req.query = d.longString()
+		add("query", req.query)
+		if f.version >= 5 && d.int()&0x01 > 0 {
+			add("keyspace", d.string())
+		}
+	case opExecute:
+		id := d.shortBytesHex()
+		if f.version >= 5 {
+			_ = d.shortBytes() // result metadata id
+		}
+		add("prepared_id", id)
+		if q, ok := internal.prepared[id]; ok {
+			req.query = q
+			add("query", q)
+		}
+		args = append(args, readQueryParameters(d, f.version)...)
+	case opBatch:
+		args = append(args, readBatch(d, f.version, internal)...)
+	case opRegister:
+		add("events", d.stringList())
+	}
+	if len(internal.requests) >= maxStreams {
+		// responses were not captured
+		internal.requests = map[int16]request{}
+	}
+	internal.requests[f.streamID] = req
+	if d.err != nil {
+		return values
+	}
+	return append(values, args...)
+}
+
+// readQueryParameters returns values of <query_parameters> of QUERY and EXECUTE
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v5.spec#L380
+func readQueryParameters(d *decoder, version uint8) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		{
+			Key:   "consistency",
+			Value: d.consistency(),
+		},
+	}
+	var flags uint32
+	if version >= 5 {
+		flags = uint32(d.int())
+	} else {
+		flags = uint32(d.byte())
+	}
+	if flags&queryFlagValues > 0 {
+		n := int(d.short())
+		for i := 0; i < n && d.err == nil; i++ {
+			if flags&queryFlagValueNames > 0 {
+				_ = d.string()
+			}
+			_ = d.bytes()
+		}
+	}
+	if flags&queryFlagPageSize > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "page_size",
+			Value: d.int(),
+		})
+	}
+	if flags&queryFlagPagingState > 0 {
+		_ = d.bytes()
+	}
+	if flags&queryFlagSerialConsistency > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "serial_consistency",
+			Value: d.consistency(),
+		})
+	}
+	if flags&queryFlagTimestamp > 0 {
+		_ = d.read(8)
+	}
+	if version >= 5 && flags&queryFlagKeyspace > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "keyspace",
+			Value: d.string(),
+		})
+	}
+	return values
+}
+
+// readBatch returns values of BATCH. Prepared statements are replaced with the query text
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v5.spec#L485
+func readBatch(d *decoder, version uint8, internal *connMetadataInternal) []dumper.DumpValue {
+	batchType, ok := batchTypeNames[d.byte()]
+	if !ok {
+		batchType = "UNKNOWN"
+	}
+	n := int(d.short())
+	queries := []string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		if d.byte() == 0 {
+			queries = append(queries, d.longString())
+		} else {
+			id := d.shortBytesHex()
+			q, ok := internal.prepared[id]
+			if !ok {
+				q = "<prepared " + id + ">"
+			}
+			queries = append(queries, q)
+		}
+		m := int(d.short())
+		for j := 0; j < m && d.err == nil; j++ {
+			_ = d.bytes()
+		}
+	}
+	values := []dumper.DumpValue{
+		{
+			Key:   "batch_type",
+			Value: batchType,
+		},
+		{
+			Key:   "queries",
+			Value: queries,
+		},
+		{
+			Key:   "consistency",
+			Value: d.consistency(),
+		},
+	}
+	var flags uint32
+	if version >= 5 {
+		flags = uint32(d.int())
+	} else {
+		flags = uint32(d.byte())
+	}
+	if flags&queryFlagSerialConsistency > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "serial_consistency",
+			Value: d.consistency(),
+		})
+	}
+	if flags&queryFlagTimestamp > 0 {
+		_ = d.read(8)
+	}
+	if version >= 5 && flags&queryFlagKeyspace > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "keyspace",
+			Value: d.string(),
+		})
+	}
+	return values
+}
+
+// readResponse returns values of response with query of the request
+func readResponse(f frame, internal *connMetadataInternal) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		{
+			Key:   "stream_id",
+			Value: f.streamID,
+		},
+		{
+			Key:   "opcode",
+			Value: opcodeNames[f.opcode],
+		},
+	}
+	req, ok := internal.requests[f.streamID]
+	if ok && f.opcode != opEvent {
+		delete(internal.requests, f.streamID)
+		if req.query != "" {
+			values = append(values, dumper.DumpValue{
+				Key:   "query",
+				Value: req.query,
+			})
+		}
+	}
+	d := newDecoder(f.body)
+	if f.flags&flagTracing > 0 {
+		_ = d.read(16) // tracing id
+	}
+	var warnings []string
+	if f.flags&flagWarning > 0 {
+		warnings = d.stringList()
+	}
+	if f.flags&flagCustomPayload > 0 {
+		d.bytesMap()
+	}
+	args := []dumper.DumpValue{}
+	add := func(key string, value interface{}) {
+		args = append(args, dumper.DumpValue{
+			Key:   key,
+			Value: value,
+		})
+	}
+	switch f.opcode {
+	case opError:
+		code := d.int()
+		add("error_code", code)
+		add("error_name", errorName(code))
+		add("error_message", d.string())
+		if code == errUnprepared {
+			add("prepared_id", d.shortBytesHex())
+		}
+	case opResult:
+		kind := d.int()
+		name, ok := resultKindNames[kind]
+		if !ok {
+			name = "UNKNOWN"
+		}
+		add("result_kind", name)
+		switch kind {
+		case resultRows:
+			readRowsMetadata(d, f.version)
+			add("rows_count", d.int())
+		case resultSetKeyspace:
+			add("keyspace", d.string())
+		case resultPrepared:
+			id := d.shortBytesHex()
+			add("prepared_id", id)
+			if d.err == nil && req.opcode == opPrepare {
+				if len(internal.prepared) >= maxPrepared {
+					internal.prepared = map[string]string{}
+				}
+				internal.prepared[id] = req.query
+			}
+		case resultSchemaChange:
+			add("change_type", d.string())
+			target := d.string()
+			add("target", target)
+			add("keyspace", d.string())
+			if target != "KEYSPACE" {
+				add("name", d.string())
+			}
+		}
+	case opAuthenticate:
+		add("authenticator", d.string())
+	case opSupported:
+		add("options", d.stringMultimap())
+	case opEvent:
+		add("event_type", d.string())
+	}
+	if warnings != nil {
+		add("warnings", warnings)
+	}
+	if d.err != nil {
+		return values
+	}
+	return append(values, args...)
+}
+
+// readRowsMetadata skip <metadata> of Rows
+// https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v5.spec#L635
+func readRowsMetadata(d *decoder, version uint8) {
+	flags := d.int()
+	n := int(d.int())
+	if flags&rowsFlagHasMorePages > 0 {
+		_ = d.bytes() // paging state
+	}
+	if version >= 5 && flags&rowsFlagMetadataChanged > 0 {
+		_ = d.shortBytes() // new metadata id
+	}
+	if flags&rowsFlagNoMetadata > 0 {
+		return
+	}
+	global := flags&rowsFlagGlobalTablesSpec > 0
+	if global {
+		_ = d.string() // keyspace
+		_ = d.string() // table
+	}
+	for i := 0; i < n && d.err == nil; i++ {
+		if !global {
+			_ = d.string() // keyspace
+			_ = d.string() // table
+		}
+		_ = d.string() // column name
+		d.option(0)
+	}
+}
+
+func errorName(code int32) string {
+	if n, ok := errorNames[code]; ok {
+		return n
+	}
+	return "UNKNOWN"
+}
@@ -0,0 +1,125 @@
+package dns
+
+import (
+	"fmt"
+)
+
+// https://www.iana.org/assignments/dns-parameters/dns-parameters.xhtml#dns-parameters-4
+var typeNames = map[uint16]string{
+	1:     "A",
+	2:     "NS",
+	5:     "CNAME",
+	6:     "SOA",
+	12:    "PTR",
+	13:    "HINFO",
+	15:    "MX",
+	16:    "TXT",
+	17:    "RP",
+	18:    "AFSDB",
+	24:    "SIG",
+	25:    "KEY",
+	28:    "AAAA",
+	29:    "LOC",
+	33:    "SRV",
+	35:    "NAPTR",
+	36:    "KX",
+	37:    "CERT",
+	39:    "DNAME",
+	41:    "OPT",
+	42:    "APL",
+	43:    "DS",
+	44:    "SSHFP",
+	45:    "IPSECKEY",
+	46:    "RRSIG",
+	47:    "NSEC",
+	48:    "DNSKEY",
+	49:    "DHCID",
+	50:    "NSEC3",
+	51:    "NSEC3PARAM",
+	52:    "TLSA",
+	53:    "SMIMEA",
+	55:    "HIP",
+	59:    "CDS",
+	60:    "CDNSKEY",
+	61:    "OPENPGPKEY",
+	62:    "CSYNC",
+	63:    "ZONEMD",
+	64:    "SVCB",
+	65:    "HTTPS",
+	99:    "SPF",
+	108:   "EUI48",
+	109:   "EUI64",
+	249:   "TKEY",
+	250:   "TSIG",
+	251:   "IXFR",
+	252:   "AXFR",
+	255:   "ANY",
+	256:   "URI",
+	257:   "CAA",
+	32769: "DLV",
+}
+
+// https://www.iana.org/assignments/dns-parameters/dns-parameters.xhtml#dns-parameters-2
+var classNames = map[uint16]string{
+	1:   "IN",
+	3:   "CH",
+	4:   "HS",
+	254: "NONE",
+	255: "ANY",
+}
+
+// https://www.iana.org/assignments/dns-parameters/dns-parameters.xhtml#dns-parameters-5
+var opcodeNames = map[uint8]string{
+	0: "QUERY",
+	1: "IQUERY",
+	2: "STATUS",
+	4: "NOTIFY",
+	5: "UPDATE",
+	6: "DSO",
+}
+
+// https://www.iana.org/assignments/dns-parameters/dns-parameters.xhtml#dns-parameters-6
+var rcodeNames = map[uint8]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+	11: "DSOTYPENI",
+}
+
+// typeName returns mnemonic of TYPE ( RFC 3597 format for unknown type )
+func typeName(t uint16) string {
+	if n, ok := typeNames[t]; ok {
+		return n
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// className returns mnemonic of CLASS ( RFC 3597 format for unknown class )
+func className(c uint16) string {
+	if n, ok := classNames[c]; ok {
+		return n
+	}
+	return fmt.Sprintf("CLASS%d", c)
+}
+
+func opcodeName(o uint8) string {
+	if n, ok := opcodeNames[o]; ok {
+		return n
+	}
+	return fmt.Sprintf("OPCODE%d", o)
+}
+
+func rcodeName(r uint8) string {
+	if n, ok := rcodeNames[r]; ok {
+		return n
+	}
+	return fmt.Sprintf("RCODE%d", r)
+}
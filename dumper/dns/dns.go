@@ -0,0 +1,194 @@
+package dns
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/dumper/framed"
+	"github.com/k1LoW/tcpdp/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxMessageSize is max size of DNS message over TCP ( 2-byte length prefix )
+const maxMessageSize = 65535
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+	framer *framed.Framer
+}
+
+type connMetadataInternal struct {
+	client framed.Stream
+	server framed.Stream
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	// DNS message over TCP is prefixed with 2-byte length ( https://tools.ietf.org/html/rfc1035#section-4.2.2 )
+	framer, _ := framed.NewLengthFramer(2, binary.BigEndian, false, maxMessageSize)
+	dumper := &Dumper{
+		name:   "dns",
+		logger: logger.NewQueryLogger(),
+		framer: framer,
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (d *Dumper) Name() string {
+	return d.name
+}
+
+// Dump DNS messages
+func (d *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := d.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		d.Log(values)
+	}
+	return nil
+}
+
+// Read return the first message of byte to analyzed string ( use ReadFrames to read all messages )
+func (d *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := d.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return messages of byte to analyzed string
+func (d *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	s := &internal.client
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		s = &internal.server
+	}
+	for _, frame := range d.framer.Split(s, in) {
+		msg := &layers.DNS{}
+		if err := msg.DecodeFromBytes(frame.Data, gopacket.NilDecodeFeedback); err != nil {
+			// not DNS message
+			continue
+		}
+		records = append(records, messageValues(msg))
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (d *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	d.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (d *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal:   connMetadataInternal{},
+	}
+}
+
+// https://tools.ietf.org/html/rfc1035#section-4.1
+func messageValues(msg *layers.DNS) []dumper.DumpValue {
+	messageType := "query"
+	if msg.QR {
+		messageType = "response"
+	}
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "message_type",
+			Value: messageType,
+		},
+		dumper.DumpValue{
+			Key:   "id",
+			Value: msg.ID,
+		},
+		dumper.DumpValue{
+			Key:   "opcode",
+			Value: opcodeName(uint8(msg.OpCode)),
+		},
+	}
+	if len(msg.Questions) > 0 {
+		q := msg.Questions[0]
+		values = append(values, dumper.DumpValue{
+			Key:   "qname",
+			Value: string(q.Name),
+		}, dumper.DumpValue{
+			Key:   "qtype",
+			Value: typeName(uint16(q.Type)),
+		}, dumper.DumpValue{
+			Key:   "qclass",
+			Value: className(uint16(q.Class)),
+		})
+	}
+	if !msg.QR {
+		return values
+	}
+	answers := []string{}
+	for _, rr := range msg.Answers {
+		answers = append(answers, formatResourceRecord(rr))
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "rcode",
+		Value: rcodeName(uint8(msg.ResponseCode)),
+	}, dumper.DumpValue{
+		Key:   "answers",
+		Value: answers,
+	})
+}
+
+// formatResourceRecord returns resource record in zone file format
+func formatResourceRecord(rr layers.DNSResourceRecord) string {
+	return fmt.Sprintf("%s %d %s %s %s", rr.Name, rr.TTL, className(uint16(rr.Class)), typeName(uint16(rr.Type)), formatRData(rr))
+}
+
+func formatRData(rr layers.DNSResourceRecord) string {
+	switch rr.Type {
+	case layers.DNSTypeA, layers.DNSTypeAAAA:
+		if rr.IP != nil {
+			return rr.IP.String()
+		}
+	case layers.DNSTypeNS:
+		return string(rr.NS)
+	case layers.DNSTypeCNAME:
+		return string(rr.CNAME)
+	case layers.DNSTypePTR:
+		return string(rr.PTR)
+	case layers.DNSTypeMX:
+		return fmt.Sprintf("%d %s", rr.MX.Preference, rr.MX.Name)
+	case layers.DNSTypeTXT:
+		txts := []string{}
+		for _, t := range rr.TXTs {
+			txts = append(txts, strconv.Quote(string(t)))
+		}
+		return strings.Join(txts, " ")
+	case layers.DNSTypeSOA:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", rr.SOA.MName, rr.SOA.RName, rr.SOA.Serial, rr.SOA.Refresh, rr.SOA.Retry, rr.SOA.Expire, rr.SOA.Minimum)
+	case layers.DNSTypeSRV:
+		return fmt.Sprintf("%d %d %d %s", rr.SRV.Priority, rr.SRV.Weight, rr.SRV.Port, rr.SRV.Name)
+	}
+	// https://tools.ietf.org/html/rfc3597#section-5
+	return fmt.Sprintf("\\# %d %x", len(rr.Data), rr.Data)
+}
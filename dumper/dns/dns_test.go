@@ -0,0 +1,316 @@
+package dns
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type dnsStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  [][]dumper.DumpValue
+}
+
+var dnsReadFramesTests = []struct {
+	description string
+	steps       []dnsStep
+}{
+	{
+		"A query and response split across reads",
+		[]dnsStep{
+			{
+				[]byte{
+					0x00, 0x1d, 0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, 0x65,
+					0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x03, 0x63, 0x6f, 0x6d, 0x00, 0x00, 0x01, 0x00, 0x01,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "message_type",
+							Value: "query",
+						},
+						dumper.DumpValue{
+							Key:   "id",
+							Value: uint16(0x1234),
+						},
+						dumper.DumpValue{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						dumper.DumpValue{
+							Key:   "qname",
+							Value: "example.com",
+						},
+						dumper.DumpValue{
+							Key:   "qtype",
+							Value: "A",
+						},
+						dumper.DumpValue{
+							Key:   "qclass",
+							Value: "IN",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x4a, 0x12, 0x34, 0x81, 0x80, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x07, 0x65,
+					0x78, 0x61, 0x6d, 0x70,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x6c, 0x65, 0x03, 0x63, 0x6f, 0x6d, 0x00, 0x00, 0x01, 0x00, 0x01, 0xc0, 0x0c, 0x00, 0x05, 0x00,
+					0x01, 0x00, 0x00, 0x01, 0x2c, 0x00, 0x11, 0x03, 0x77, 0x77, 0x77, 0x07, 0x65, 0x78, 0x61, 0x6d,
+					0x70, 0x6c, 0x65, 0x03, 0x6e, 0x65, 0x74, 0x00, 0xc0, 0x29, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00,
+					0x00, 0x3c, 0x00, 0x04, 0x5d, 0xb8, 0xd8, 0x22,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "message_type",
+							Value: "response",
+						},
+						dumper.DumpValue{
+							Key:   "id",
+							Value: uint16(0x1234),
+						},
+						dumper.DumpValue{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						dumper.DumpValue{
+							Key:   "qname",
+							Value: "example.com",
+						},
+						dumper.DumpValue{
+							Key:   "qtype",
+							Value: "A",
+						},
+						dumper.DumpValue{
+							Key:   "qclass",
+							Value: "IN",
+						},
+						dumper.DumpValue{
+							Key:   "rcode",
+							Value: "NOERROR",
+						},
+						dumper.DumpValue{
+							Key: "answers",
+							Value: []string{
+								"example.com 300 IN CNAME www.example.net",
+								"www.example.net 60 IN A 93.184.216.34",
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Pipelined queries and out-of-order responses",
+		[]dnsStep{
+			{
+				[]byte{
+					0x00, 0x1d, 0x00, 0x02, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, 0x65,
+					0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x03, 0x63, 0x6f, 0x6d, 0x00, 0x00, 0x0f, 0x00, 0x01, 0x00,
+					0x20, 0x00, 0x03, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x6e, 0x78,
+					0x07, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x03, 0x63, 0x6f, 0x6d, 0x00, 0x00, 0x1c, 0x00,
+					0x01,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "message_type",
+							Value: "query",
+						},
+						dumper.DumpValue{
+							Key:   "id",
+							Value: uint16(2),
+						},
+						dumper.DumpValue{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						dumper.DumpValue{
+							Key:   "qname",
+							Value: "example.com",
+						},
+						dumper.DumpValue{
+							Key:   "qtype",
+							Value: "MX",
+						},
+						dumper.DumpValue{
+							Key:   "qclass",
+							Value: "IN",
+						},
+					},
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "message_type",
+							Value: "query",
+						},
+						dumper.DumpValue{
+							Key:   "id",
+							Value: uint16(3),
+						},
+						dumper.DumpValue{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						dumper.DumpValue{
+							Key:   "qname",
+							Value: "nx.example.com",
+						},
+						dumper.DumpValue{
+							Key:   "qtype",
+							Value: "AAAA",
+						},
+						dumper.DumpValue{
+							Key:   "qclass",
+							Value: "IN",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x20, 0x00, 0x03, 0x81, 0x83, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x6e,
+					0x78, 0x07, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x03, 0x63, 0x6f, 0x6d, 0x00, 0x00, 0x1c,
+					0x00, 0x01, 0x00, 0x4b, 0x00, 0x02, 0x81, 0x80, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00,
+					0x07, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x03, 0x63, 0x6f, 0x6d, 0x00, 0x00, 0x0f, 0x00,
+					0x01, 0xc0, 0x0c, 0x00, 0x0f, 0x00, 0x01, 0x00, 0x00, 0x0e, 0x10, 0x00, 0x14, 0x00, 0x0a, 0x04,
+					0x6d, 0x61, 0x69, 0x6c, 0x07, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x03, 0x63, 0x6f, 0x6d,
+					0x00, 0xc0, 0x0c, 0x00, 0x63, 0x00, 0x01, 0x00, 0x00, 0x0e, 0x10, 0x00, 0x02, 0x01, 0x02,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "message_type",
+							Value: "response",
+						},
+						dumper.DumpValue{
+							Key:   "id",
+							Value: uint16(3),
+						},
+						dumper.DumpValue{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						dumper.DumpValue{
+							Key:   "qname",
+							Value: "nx.example.com",
+						},
+						dumper.DumpValue{
+							Key:   "qtype",
+							Value: "AAAA",
+						},
+						dumper.DumpValue{
+							Key:   "qclass",
+							Value: "IN",
+						},
+						dumper.DumpValue{
+							Key:   "rcode",
+							Value: "NXDOMAIN",
+						},
+						dumper.DumpValue{
+							Key:   "answers",
+							Value: []string{},
+						},
+					},
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "message_type",
+							Value: "response",
+						},
+						dumper.DumpValue{
+							Key:   "id",
+							Value: uint16(2),
+						},
+						dumper.DumpValue{
+							Key:   "opcode",
+							Value: "QUERY",
+						},
+						dumper.DumpValue{
+							Key:   "qname",
+							Value: "example.com",
+						},
+						dumper.DumpValue{
+							Key:   "qtype",
+							Value: "MX",
+						},
+						dumper.DumpValue{
+							Key:   "qclass",
+							Value: "IN",
+						},
+						dumper.DumpValue{
+							Key:   "rcode",
+							Value: "NOERROR",
+						},
+						dumper.DumpValue{
+							Key: "answers",
+							Value: []string{
+								"example.com 3600 IN MX 10 mail.example.com",
+								"example.com 3600 IN SPF \\# 2 0102",
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestDNSReadFrames(t *testing.T) {
+	for _, tt := range dnsReadFramesTests {
+		out := new(bytes.Buffer)
+		d := NewDumper()
+		d.logger = newTestLogger(out)
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
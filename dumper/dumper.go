@@ -1,5 +1,7 @@
 package dumper
 
+import "time"
+
 // Direction of TCP commnication
 type Direction int
 
@@ -38,6 +40,8 @@ type ConnMetadata struct {
 	DumpValues []DumpValue
 	Internal   interface{} // internal metadata for dumper
 	Fin        bool
+	Ts         time.Time // capture timestamp of the packet being read ( zero in proxy mode )
+	MidStream  bool      // connection is seen mid-stream ( the handshake is not captured )
 }
 
 // Dumper interface
@@ -48,3 +52,13 @@ type Dumper interface {
 	Log(values []DumpValue)
 	NewConnMetadata() *ConnMetadata
 }
+
+// FrameReader is optional interface of Dumper that returns a record per frame ( message ) of a read
+type FrameReader interface {
+	ReadFrames(in []byte, direction Direction, connMetadata *ConnMetadata) ([][]DumpValue, error)
+}
+
+// ErrorResponseReader is optional interface of Dumper that returns error code of error response from server ( MySQL error code, PostgreSQL SQLSTATE )
+type ErrorResponseReader interface {
+	ReadErrorResponse(in []byte) (string, bool)
+}
@@ -0,0 +1,132 @@
+package framed
+
+import (
+	"encoding/binary"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/dumper/hex"
+	"github.com/k1LoW/tcpdp/logger"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+	framer *Framer
+}
+
+type connMetadataInternal struct {
+	client Stream
+	server Stream
+}
+
+// NewDumper returns a Dumper configured by `framed.*`
+func NewDumper() (*Dumper, error) {
+	var (
+		framer *Framer
+		err    error
+	)
+	maxFrameSize := viper.GetInt("framed.maxFrameSize")
+	if delimiter := viper.GetString("framed.delimiter"); delimiter != "" {
+		framer, err = NewDelimiterFramer([]byte(delimiter), maxFrameSize)
+	} else {
+		var byteOrder binary.ByteOrder
+		byteOrder, err = ParseByteOrder(viper.GetString("framed.byteOrder"))
+		if err != nil {
+			return nil, err
+		}
+		framer, err = NewLengthFramer(viper.GetInt("framed.lengthSize"), byteOrder, viper.GetBool("framed.lengthIncludesPrefix"), maxFrameSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dumper := &Dumper{
+		name:   "framed",
+		logger: logger.NewHexLogger(),
+		framer: framer,
+	}
+	return dumper, nil
+}
+
+// Name return dumper name
+func (f *Dumper) Name() string {
+	return f.name
+}
+
+// Dump frames of TCP stream
+func (f *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := f.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		f.Log(values)
+	}
+	return nil
+}
+
+// Read return the first frame of byte to analyzed string ( use ReadFrames to read all frames )
+func (f *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := f.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return frames of byte to analyzed string
+func (f *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	s := &internal.client
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		s = &internal.server
+	}
+	for _, frame := range f.framer.Split(s, in) {
+		records = append(records, frameValues(frame))
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (f *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	f.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (f *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal:   connMetadataInternal{},
+	}
+}
+
+func frameValues(frame Frame) []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	if frame.Size >= 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "frame_size",
+			Value: frame.Size,
+		})
+	}
+	if frame.Truncated {
+		values = append(values, dumper.DumpValue{
+			Key:   "frame_truncated",
+			Value: true,
+		})
+	}
+	return append(values, hex.DumpValues(frame.Data)...)
+}
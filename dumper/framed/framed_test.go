@@ -0,0 +1,124 @@
+package framed
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+type framedStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  [][]dumper.DumpValue
+}
+
+var framedReadFramesTests = []struct {
+	description string
+	framer      *Framer
+	steps       []framedStep
+}{
+	{
+		"Frames of both directions",
+		&Framer{lengthSize: 2, byteOrder: binary.BigEndian, maxFrameSize: 4},
+		[]framedStep{
+			{
+				[]byte{0x00, 0x02, 'h', 'i', 0x00},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "frame_size",
+							Value: 2,
+						},
+						dumper.DumpValue{
+							Key:   "bytes",
+							Value: "68 69",
+						},
+						dumper.DumpValue{
+							Key:   "ascii",
+							Value: "hi",
+						},
+					},
+				},
+			},
+			{
+				[]byte{0x00, 0x06, 'h', 'e', 'l', 'l', 'o', '!'},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "frame_size",
+							Value: 6,
+						},
+						dumper.DumpValue{
+							Key:   "frame_truncated",
+							Value: true,
+						},
+						dumper.DumpValue{
+							Key:   "bytes",
+							Value: "68 65 6c 6c",
+						},
+						dumper.DumpValue{
+							Key:   "ascii",
+							Value: "hell",
+						},
+					},
+				},
+			},
+			{
+				[]byte{0x01, 'a', 0x00, 0x01, 'b'},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "frame_size",
+							Value: 1,
+						},
+						dumper.DumpValue{
+							Key:   "bytes",
+							Value: "61",
+						},
+						dumper.DumpValue{
+							Key:   "ascii",
+							Value: "a",
+						},
+					},
+					[]dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "frame_size",
+							Value: 1,
+						},
+						dumper.DumpValue{
+							Key:   "bytes",
+							Value: "62",
+						},
+						dumper.DumpValue{
+							Key:   "ascii",
+							Value: "b",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestFramedReadFrames(t *testing.T) {
+	for _, tt := range framedReadFramesTests {
+		d := &Dumper{
+			framer: tt.framer,
+		}
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
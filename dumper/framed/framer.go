@@ -0,0 +1,192 @@
+package framed
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Framer split TCP stream into frames by length prefix or delimiter
+type Framer struct {
+	lengthSize           int // 0: split by delimiter
+	byteOrder            binary.ByteOrder
+	lengthIncludesPrefix bool
+	delimiter            []byte
+	maxFrameSize         int
+}
+
+// Stream is buffered TCP stream of a direction
+type Stream struct {
+	buffer  []byte // partial frame
+	skip    int    // rest bytes of large frame
+	discard bool   // discard bytes until next delimiter
+}
+
+// Frame is a frame ( without length prefix or delimiter )
+type Frame struct {
+	Data      []byte
+	Size      int  // -1: unknown ( truncated frame split by delimiter )
+	Truncated bool // Data is only the head of frame
+}
+
+// NewLengthFramer returns a Framer split stream on N-byte length prefix
+func NewLengthFramer(lengthSize int, byteOrder binary.ByteOrder, lengthIncludesPrefix bool, maxFrameSize int) (*Framer, error) {
+	switch lengthSize {
+	case 1, 2, 3, 4, 8:
+	default:
+		return nil, errors.Errorf("unsupported length prefix size: %d", lengthSize)
+	}
+	if maxFrameSize <= 0 {
+		return nil, errors.Errorf("invalid max frame size: %d", maxFrameSize)
+	}
+	return &Framer{
+		lengthSize:           lengthSize,
+		byteOrder:            byteOrder,
+		lengthIncludesPrefix: lengthIncludesPrefix,
+		maxFrameSize:         maxFrameSize,
+	}, nil
+}
+
+// NewDelimiterFramer returns a Framer split stream on delimiter
+func NewDelimiterFramer(delimiter []byte, maxFrameSize int) (*Framer, error) {
+	if len(delimiter) == 0 {
+		return nil, errors.New("empty delimiter")
+	}
+	if maxFrameSize <= 0 {
+		return nil, errors.Errorf("invalid max frame size: %d", maxFrameSize)
+	}
+	return &Framer{
+		delimiter:    delimiter,
+		maxFrameSize: maxFrameSize,
+	}, nil
+}
+
+// ParseByteOrder parse `big` or `little`
+func ParseByteOrder(s string) (binary.ByteOrder, error) {
+	switch s {
+	case "big", "":
+		return binary.BigEndian, nil
+	case "little":
+		return binary.LittleEndian, nil
+	default:
+		return nil, errors.Errorf("unsupported byte order: %s", s)
+	}
+}
+
+// Split returns complete frames and cache the partial frame
+// frame larger than maxFrameSize is returned with only the head and the rest is skipped
+func (f *Framer) Split(s *Stream, in []byte) []Frame {
+	if f.lengthSize == 0 {
+		return f.splitByDelimiter(s, in)
+	}
+	return f.splitByLength(s, in)
+}
+
+func (f *Framer) splitByLength(s *Stream, in []byte) []Frame {
+	frames := []Frame{}
+	if s.skip > 0 {
+		if len(in) <= s.skip {
+			s.skip -= len(in)
+			return frames
+		}
+		in = in[s.skip:]
+		s.skip = 0
+	}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) >= f.lengthSize {
+		size, ok := f.frameSize(buff[:f.lengthSize])
+		if !ok {
+			// not frame boundary
+			buff = nil
+			break
+		}
+		if size <= f.maxFrameSize {
+			if len(buff) < f.lengthSize+size {
+				break
+			}
+			frames = append(frames, Frame{Data: buff[f.lengthSize : f.lengthSize+size], Size: size})
+			buff = buff[f.lengthSize+size:]
+			continue
+		}
+		if len(buff) < f.lengthSize+f.maxFrameSize {
+			break
+		}
+		frames = append(frames, Frame{Data: buff[f.lengthSize : f.lengthSize+f.maxFrameSize], Size: size, Truncated: true})
+		if len(buff) < f.lengthSize+size {
+			s.skip = f.lengthSize + size - len(buff)
+			buff = nil
+			break
+		}
+		buff = buff[f.lengthSize+size:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return frames
+}
+
+// frameSize returns size of frame body
+func (f *Framer) frameSize(b []byte) (int, bool) {
+	var l uint64
+	switch f.lengthSize {
+	case 1:
+		l = uint64(b[0])
+	case 2:
+		l = uint64(f.byteOrder.Uint16(b))
+	case 3:
+		if f.byteOrder == binary.LittleEndian {
+			l = uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16
+		} else {
+			l = uint64(b[2]) | uint64(b[1])<<8 | uint64(b[0])<<16
+		}
+	case 4:
+		l = uint64(f.byteOrder.Uint32(b))
+	case 8:
+		l = f.byteOrder.Uint64(b)
+	}
+	if f.lengthIncludesPrefix {
+		if l < uint64(f.lengthSize) {
+			return 0, false
+		}
+		l -= uint64(f.lengthSize)
+	}
+	if l > uint64(int(^uint(0)>>1)-f.lengthSize) {
+		return 0, false
+	}
+	return int(l), true
+}
+
+func (f *Framer) splitByDelimiter(s *Stream, in []byte) []Frame {
+	frames := []Frame{}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) > 0 {
+		i := bytes.Index(buff, f.delimiter)
+		if i < 0 {
+			break
+		}
+		if s.discard {
+			// the rest of truncated frame
+			s.discard = false
+		} else {
+			frames = append(frames, Frame{Data: buff[:i], Size: i})
+		}
+		buff = buff[i+len(f.delimiter):]
+	}
+	if !s.discard && len(buff) > f.maxFrameSize {
+		frames = append(frames, Frame{Data: buff[:f.maxFrameSize], Size: -1, Truncated: true})
+		s.discard = true
+	}
+	if s.discard {
+		// keep the tail that may be the head of delimiter
+		if len(buff) >= len(f.delimiter) {
+			buff = buff[len(buff)-len(f.delimiter)+1:]
+		}
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return frames
+}
@@ -0,0 +1,148 @@
+package framed
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+var framerSplitTests = []struct {
+	description string
+	framer      *Framer
+	in          [][]byte
+	expected    [][]Frame
+}{
+	{
+		"4-byte big-endian length prefix",
+		&Framer{lengthSize: 4, byteOrder: binary.BigEndian, maxFrameSize: 1024},
+		[][]byte{
+			[]byte{0x00, 0x00, 0x00, 0x03, 'f', 'o', 'o', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 'b'},
+			[]byte{'a', 'r'},
+		},
+		[][]Frame{
+			[]Frame{
+				Frame{Data: []byte("foo"), Size: 3},
+				Frame{Data: []byte{}, Size: 0},
+			},
+			[]Frame{
+				Frame{Data: []byte("bar"), Size: 3},
+			},
+		},
+	},
+	{
+		"2-byte little-endian length prefix including the prefix",
+		&Framer{lengthSize: 2, byteOrder: binary.LittleEndian, lengthIncludesPrefix: true, maxFrameSize: 1024},
+		[][]byte{
+			[]byte{0x05},
+			[]byte{0x00, 'f', 'o', 'o', 0x01, 0x00},
+		},
+		[][]Frame{
+			[]Frame{},
+			[]Frame{
+				Frame{Data: []byte("foo"), Size: 3},
+			},
+		},
+	},
+	{
+		"3-byte little-endian length prefix",
+		&Framer{lengthSize: 3, byteOrder: binary.LittleEndian, maxFrameSize: 1024},
+		[][]byte{
+			[]byte{0x02, 0x00, 0x00, 'o', 'k'},
+		},
+		[][]Frame{
+			[]Frame{
+				Frame{Data: []byte("ok"), Size: 2},
+			},
+		},
+	},
+	{
+		"Frame larger than maxFrameSize",
+		&Framer{lengthSize: 1, byteOrder: binary.BigEndian, maxFrameSize: 4},
+		[][]byte{
+			[]byte{0x0a, '0', '1', '2'},
+			[]byte{'3', '4', '5'},
+			[]byte{'6', '7', '8', '9', 0x02, 'o', 'k'},
+		},
+		[][]Frame{
+			[]Frame{},
+			[]Frame{
+				Frame{Data: []byte("0123"), Size: 10, Truncated: true},
+			},
+			[]Frame{
+				Frame{Data: []byte("ok"), Size: 2},
+			},
+		},
+	},
+	{
+		"Delimiter",
+		&Framer{delimiter: []byte("\r\n"), maxFrameSize: 1024},
+		[][]byte{
+			[]byte("foo\r\nba"),
+			[]byte("r\r"),
+			[]byte("\n"),
+		},
+		[][]Frame{
+			[]Frame{
+				Frame{Data: []byte("foo"), Size: 3},
+			},
+			[]Frame{},
+			[]Frame{
+				Frame{Data: []byte("bar"), Size: 3},
+			},
+		},
+	},
+	{
+		"Delimited frame larger than maxFrameSize",
+		&Framer{delimiter: []byte("\r\n"), maxFrameSize: 4},
+		[][]byte{
+			[]byte("abcdefg\r"),
+			[]byte("\nxy\r\n"),
+		},
+		[][]Frame{
+			[]Frame{
+				Frame{Data: []byte("abcd"), Size: -1, Truncated: true},
+			},
+			[]Frame{
+				Frame{Data: []byte("xy"), Size: 2},
+			},
+		},
+	},
+}
+
+func TestFramerSplit(t *testing.T) {
+	for _, tt := range framerSplitTests {
+		s := &Stream{}
+		for i, in := range tt.in {
+			actual := tt.framer.Split(s, in)
+			if !reflect.DeepEqual(actual, tt.expected[i]) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, tt.expected[i])
+			}
+		}
+	}
+}
+
+var newFramerErrorTests = []struct {
+	description string
+	new         func() (*Framer, error)
+}{
+	{
+		"Unsupported length prefix size",
+		func() (*Framer, error) { return NewLengthFramer(5, binary.BigEndian, false, 1024) },
+	},
+	{
+		"Invalid max frame size",
+		func() (*Framer, error) { return NewLengthFramer(4, binary.BigEndian, false, 0) },
+	},
+	{
+		"Empty delimiter",
+		func() (*Framer, error) { return NewDelimiterFramer([]byte{}, 1024) },
+	},
+}
+
+func TestNewFramerError(t *testing.T) {
+	for _, tt := range newFramerErrorTests {
+		if _, err := tt.new(); err == nil {
+			t.Errorf("%s: want error", tt.description)
+		}
+	}
+}
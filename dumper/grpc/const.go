@@ -0,0 +1,91 @@
+package grpc
+
+// clientPreface is HTTP/2 connection preface of client
+// https://tools.ietf.org/html/rfc7540#section-3.5
+const clientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// https://tools.ietf.org/html/rfc7540#section-4.1
+const frameHeaderLength = 9
+
+// maxFrameSize is max of SETTINGS_MAX_FRAME_SIZE
+const maxFrameSize = 1<<24 - 1
+
+// https://tools.ietf.org/html/rfc7540#section-6
+const (
+	frameData         = 0x0
+	frameHeaders      = 0x1
+	framePriority     = 0x2
+	frameRSTStream    = 0x3
+	frameSettings     = 0x4
+	framePushPromise  = 0x5
+	framePing         = 0x6
+	frameGoAway       = 0x7
+	frameWindowUpdate = 0x8
+	frameContinuation = 0x9
+)
+
+const (
+	flagEndStream  = 0x1
+	flagAck        = 0x1
+	flagEndHeaders = 0x4
+	flagPadded     = 0x8
+	flagPriority   = 0x20
+)
+
+// https://tools.ietf.org/html/rfc7540#section-6.5.2
+const settingsHeaderTableSize = 0x1
+
+// initialHeaderTableSize is default of SETTINGS_HEADER_TABLE_SIZE
+const initialHeaderTableSize = 4096
+
+// maxHeaderBlockSize is max size of buffered header block ( HEADERS + CONTINUATION )
+const maxHeaderBlockSize = 1 << 20
+
+// maxStreams is max number of streams waiting for the end
+const maxStreams = 1000
+
+// grpcMessageHeaderLength is length of Length-Prefixed-Message header ( Compressed-Flag + Message-Length )
+// https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+const grpcMessageHeaderLength = 5
+
+// maxMessageSize is max size of buffered message for dump
+const maxMessageSize = 64 * 1024
+
+// https://tools.ietf.org/html/rfc7540#section-7
+var errorCodeNames = map[uint32]string{
+	0x0: "NO_ERROR",
+	0x1: "PROTOCOL_ERROR",
+	0x2: "INTERNAL_ERROR",
+	0x3: "FLOW_CONTROL_ERROR",
+	0x4: "SETTINGS_TIMEOUT",
+	0x5: "STREAM_CLOSED",
+	0x6: "FRAME_SIZE_ERROR",
+	0x7: "REFUSED_STREAM",
+	0x8: "CANCEL",
+	0x9: "COMPRESSION_ERROR",
+	0xa: "CONNECT_ERROR",
+	0xb: "ENHANCE_YOUR_CALM",
+	0xc: "INADEQUATE_SECURITY",
+	0xd: "HTTP_1_1_REQUIRED",
+}
+
+// https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+var grpcStatusNames = map[int]string{
+	0:  "OK",
+	1:  "CANCELLED",
+	2:  "UNKNOWN",
+	3:  "INVALID_ARGUMENT",
+	4:  "DEADLINE_EXCEEDED",
+	5:  "NOT_FOUND",
+	6:  "ALREADY_EXISTS",
+	7:  "PERMISSION_DENIED",
+	8:  "RESOURCE_EXHAUSTED",
+	9:  "FAILED_PRECONDITION",
+	10: "ABORTED",
+	11: "OUT_OF_RANGE",
+	12: "UNIMPLEMENTED",
+	13: "INTERNAL",
+	14: "UNAVAILABLE",
+	15: "DATA_LOSS",
+	16: "UNAUTHENTICATED",
+}
@@ -0,0 +1,365 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Dumper struct
+type Dumper struct {
+	name        string
+	logger      *zap.Logger
+	dumpMessage bool
+	files       *protoregistry.Files // nil: dump messages by field number
+}
+
+type connMetadataInternal struct {
+	client        *side
+	server        *side
+	prefaceBuffer []byte // partial connection preface
+	prefaceRead   bool
+	invalid       bool // not HTTP/2 with prior knowledge
+	calls         map[uint32]*call
+}
+
+// NewDumper returns a Dumper configured by `grpc.*`
+func NewDumper() (*Dumper, error) {
+	var files *protoregistry.Files
+	if path := viper.GetString("grpc.protoDescriptorSet"); path != "" {
+		var err error
+		files, err = loadDescriptorSet(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dumper := &Dumper{
+		name:        "grpc",
+		logger:      logger.NewQueryLogger(),
+		dumpMessage: viper.GetBool("grpc.dumpMessage"),
+		files:       files,
+	}
+	return dumper, nil
+}
+
+// Name return dumper name
+func (g *Dumper) Name() string {
+	return g.name
+}
+
+// Dump gRPC calls
+func (g *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := g.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		g.Log(values)
+	}
+	return nil
+}
+
+// Read return the first finished call of byte to analyzed string ( use ReadFrames to read all calls )
+func (g *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := g.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return finished calls of byte to analyzed string
+func (g *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.invalid {
+		return records, nil
+	}
+	fromServer := direction == dumper.RemoteToClient || direction == dumper.DstToSrc
+	now := connMetadata.Ts
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s := internal.client
+	if fromServer {
+		s = internal.server
+	} else if !internal.prefaceRead {
+		buff := append(internal.prefaceBuffer, in...)
+		n := len(clientPreface)
+		if len(buff) < n {
+			n = len(buff)
+		}
+		if !bytes.Equal(buff[:n], []byte(clientPreface[:n])) {
+			// h2c upgrade, TLS or mid-stream
+			internal.invalid = true
+			internal.prefaceBuffer = nil
+			connMetadata.Internal = internal
+			return records, nil
+		}
+		if len(buff) < len(clientPreface) {
+			internal.prefaceBuffer = buff
+			connMetadata.Internal = internal
+			return records, nil
+		}
+		internal.prefaceRead = true
+		internal.prefaceBuffer = nil
+		in = buff[len(clientPreface):]
+	}
+
+	for _, f := range s.readFrames(in) {
+		if v := g.readFrame(f, fromServer, s, &internal, now); v != nil {
+			records = append(records, v)
+		}
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (g *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	g.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (g *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			client: newSide(),
+			server: newSide(),
+			calls:  map[uint32]*call{},
+		},
+	}
+}
+
+// readFrame read a frame and returns values when the call is finished
+func (g *Dumper) readFrame(f frame, fromServer bool, s *side, internal *connMetadataInternal, now time.Time) []dumper.DumpValue {
+	if s.headerPending && f.frameType != frameContinuation {
+		// CONTINUATION must follow HEADERS without END_HEADERS
+		s.broken = true
+	}
+	switch f.frameType {
+	case frameHeaders, framePushPromise, frameContinuation:
+		if s.broken {
+			return nil
+		}
+		fields, ok := s.readHeaderBlock(f)
+		if !ok || s.headerPushed {
+			return nil
+		}
+		c, exist := internal.calls[s.headerStreamID]
+		if fromServer {
+			if !exist {
+				return nil
+			}
+			c.setResponseHeaders(fields)
+			if s.headerFlags&flagEndStream > 0 {
+				return g.finish(c, internal, now)
+			}
+			return nil
+		}
+		if !exist {
+			if len(internal.calls) >= maxStreams {
+				// responses were not captured
+				internal.calls = map[uint32]*call{}
+			}
+			c = &call{
+				streamID: s.headerStreamID,
+				start:    now,
+			}
+			internal.calls[s.headerStreamID] = c
+		}
+		c.setRequestHeaders(fields)
+	case frameData:
+		c, ok := internal.calls[f.streamID]
+		if !ok {
+			return nil
+		}
+		p, ok := dataPayload(f)
+		if !ok {
+			return nil
+		}
+		if fromServer {
+			c.responseData += len(p)
+			if c.isGRPC() {
+				c.response.write(p, g.dumpMessage)
+			}
+			if f.flags&flagEndStream > 0 {
+				return g.finish(c, internal, now)
+			}
+			return nil
+		}
+		c.requestData += len(p)
+		if c.isGRPC() {
+			c.request.write(p, g.dumpMessage)
+		}
+	case frameRSTStream:
+		c, ok := internal.calls[f.streamID]
+		if !ok || len(f.payload) < 4 {
+			return nil
+		}
+		c.rstStreamError = errorCodeName(binary.BigEndian.Uint32(f.payload[0:4]))
+		return g.finish(c, internal, now)
+	case frameSettings:
+		if size, ok := readSettings(f); ok {
+			// SETTINGS_HEADER_TABLE_SIZE limits the encoder of the peer
+			peer := internal.server
+			if fromServer {
+				peer = internal.client
+			}
+			peer.decoder.SetAllowedMaxDynamicTableSize(size)
+		}
+	}
+	return nil
+}
+
+// finish returns values of the call and forget it
+func (g *Dumper) finish(c *call, internal *connMetadataInternal, now time.Time) []dumper.DumpValue {
+	delete(internal.calls, c.streamID)
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "stream_id",
+			Value: c.streamID,
+		},
+		dumper.DumpValue{
+			Key:   "authority",
+			Value: c.authority,
+		},
+		dumper.DumpValue{
+			Key:   "http_method",
+			Value: c.httpMethod,
+		},
+		dumper.DumpValue{
+			Key:   "path",
+			Value: c.path,
+		},
+	}
+	grpc := c.isGRPC()
+	if service, method, ok := splitPath(c.path); ok && grpc {
+		values = append(values, dumper.DumpValue{
+			Key:   "service",
+			Value: service,
+		}, dumper.DumpValue{
+			Key:   "method",
+			Value: method,
+		})
+	}
+	if status, err := strconv.Atoi(c.httpStatus); err == nil {
+		values = append(values, dumper.DumpValue{
+			Key:   "http_status",
+			Value: status,
+		})
+	}
+	if status, err := strconv.Atoi(c.grpcStatus); err == nil {
+		values = append(values, dumper.DumpValue{
+			Key:   "grpc_status",
+			Value: status,
+		}, dumper.DumpValue{
+			Key:   "grpc_status_name",
+			Value: grpcStatusName(status),
+		})
+	}
+	if c.grpcMessage != "" {
+		message, err := url.PathUnescape(c.grpcMessage)
+		if err != nil {
+			message = c.grpcMessage
+		}
+		values = append(values, dumper.DumpValue{
+			Key:   "grpc_message",
+			Value: message,
+		})
+	}
+	if c.rstStreamError != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "rst_stream_error",
+			Value: c.rstStreamError,
+		})
+	}
+	if grpc {
+		values = append(values, dumper.DumpValue{
+			Key:   "request_message_count",
+			Value: c.request.count,
+		}, dumper.DumpValue{
+			Key:   "request_message_size",
+			Value: c.request.size,
+		}, dumper.DumpValue{
+			Key:   "response_message_count",
+			Value: c.response.count,
+		}, dumper.DumpValue{
+			Key:   "response_message_size",
+			Value: c.response.size,
+		})
+		if g.dumpMessage {
+			values = append(values, g.messageValues(c)...)
+		}
+	} else {
+		values = append(values, dumper.DumpValue{
+			Key:   "request_data_size",
+			Value: c.requestData,
+		}, dumper.DumpValue{
+			Key:   "response_data_size",
+			Value: c.responseData,
+		})
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "latency",
+		Value: now.Sub(c.start),
+	})
+}
+
+// messageValues returns the first request message and the first response message
+func (g *Dumper) messageValues(c *call) []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	var input, output protoreflect.MessageDescriptor
+	if md, ok := findMethod(g.files, c.path); ok {
+		input = md.Input()
+		output = md.Output()
+	}
+	if b, err := c.request.firstMessage(c.requestEncoding); err == nil {
+		values = append(values, dumper.DumpValue{
+			Key:   "request_message",
+			Value: decodeMessage(input, b),
+		})
+	}
+	if b, err := c.response.firstMessage(c.responseEncoding); err == nil {
+		values = append(values, dumper.DumpValue{
+			Key:   "response_message",
+			Value: decodeMessage(output, b),
+		})
+	}
+	return values
+}
+
+func errorCodeName(code uint32) string {
+	if n, ok := errorCodeNames[code]; ok {
+		return n
+	}
+	return strconv.FormatUint(uint64(code), 10)
+}
+
+func grpcStatusName(status int) string {
+	if n, ok := grpcStatusNames[status]; ok {
+		return n
+	}
+	return "UNKNOWN"
+}
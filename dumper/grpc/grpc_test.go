@@ -0,0 +1,495 @@
+package grpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var baseTs = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+type grpcStep struct {
+	in        []byte
+	direction dumper.Direction
+	ts        time.Time
+	expected  [][]dumper.DumpValue
+}
+
+var grpcReadFramesTests = []struct {
+	description string
+	dumpMessage bool
+	steps       []grpcStep
+}{
+	{
+		"Unary calls ( HPACK dynamic table, CONTINUATION and trailers-only response ) and RST_STREAM",
+		true,
+		[]grpcStep{
+			{
+				[]byte{
+					0x50, 0x52, 0x49, 0x20, 0x2a, 0x20, 0x48, 0x54, 0x54, 0x50, 0x2f, 0x32, 0x2e, 0x30, 0x0d, 0x0a,
+					0x0d, 0x0a, 0x53, 0x4d, 0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x00, 0x06, 0x04, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x04, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00, 0x3e, 0x01, 0x04, 0x00, 0x00, 0x00, 0x01,
+					0x83, 0x86, 0x45, 0x95, 0x62, 0x72, 0xd1, 0x41, 0xfc, 0x1e, 0xca, 0x24, 0x5f, 0x15, 0x85, 0x2a,
+					0x4b, 0x63, 0x1b, 0x87, 0xeb, 0x19, 0x68, 0xa0, 0xff, 0x41, 0x8b, 0xa0, 0xe4, 0x1d, 0x13, 0x9d,
+					0x09, 0xb8, 0xd8, 0x00, 0xd8, 0x7f, 0x5f, 0x8b, 0x1d, 0x75, 0xd0, 0x62, 0x0d, 0x26, 0x3d, 0x4c,
+					0x4d, 0x65, 0x64, 0x40, 0x02, 0x74, 0x65, 0x86, 0x4d, 0x83, 0x35, 0x05, 0xb1, 0x1f, 0x00, 0x00,
+					0x0c, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x07, 0x0a, 0x05, 0x77, 0x6f,
+					0x72, 0x6c, 0x64,
+				},
+				dumper.SrcToDst,
+				baseTs,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x01, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x0e, 0x01, 0x04, 0x00, 0x00, 0x00, 0x01, 0x88, 0x5f, 0x8b, 0x1d, 0x75,
+					0xd0, 0x62, 0x0d, 0x26, 0x3d, 0x4c, 0x4d, 0x65, 0x64, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x0d, 0x0a, 0x0b, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77,
+					0x6f, 0x72, 0x6c, 0x64, 0x00, 0x00, 0x18, 0x01, 0x05, 0x00, 0x00, 0x00, 0x01, 0x40, 0x88, 0x9a,
+					0xca, 0xc8, 0xb2, 0x12, 0x34, 0xda, 0x8f, 0x01, 0x30, 0x40, 0x89, 0x9a, 0xca, 0xc8, 0xb5, 0x25,
+					0x42, 0x07, 0x31, 0x7f, 0x00,
+				},
+				dumper.DstToSrc,
+				baseTs.Add(1500 * time.Microsecond),
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: uint32(1),
+						},
+						{
+							Key:   "authority",
+							Value: "localhost:50051",
+						},
+						{
+							Key:   "http_method",
+							Value: "POST",
+						},
+						{
+							Key:   "path",
+							Value: "/helloworld.Greeter/SayHello",
+						},
+						{
+							Key:   "service",
+							Value: "helloworld.Greeter",
+						},
+						{
+							Key:   "method",
+							Value: "SayHello",
+						},
+						{
+							Key:   "http_status",
+							Value: 200,
+						},
+						{
+							Key:   "grpc_status",
+							Value: 0,
+						},
+						{
+							Key:   "grpc_status_name",
+							Value: "OK",
+						},
+						{
+							Key:   "request_message_count",
+							Value: 1,
+						},
+						{
+							Key:   "request_message_size",
+							Value: 7,
+						},
+						{
+							Key:   "response_message_count",
+							Value: 1,
+						},
+						{
+							Key:   "response_message_size",
+							Value: 13,
+						},
+						{
+							Key:   "request_message",
+							Value: map[string]interface{}{"1": "world"},
+						},
+						{
+							Key:   "response_message",
+							Value: map[string]interface{}{"1": "Hello world"},
+						},
+						{
+							Key:   "latency",
+							Value: 1500 * time.Microsecond,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x00, 0x00, 0x03, 0x83, 0x86, 0xc1, 0x00, 0x00, 0x11, 0x09,
+					0x04, 0x00, 0x00, 0x00, 0x03, 0xc0, 0xbf, 0xbe, 0x40, 0x89, 0x9a, 0xca, 0xc8, 0xb2, 0x4d, 0x49,
+					0x4f, 0x6a, 0x7f, 0x02, 0x31, 0x53, 0x00, 0x00, 0x0a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x03, 0x00,
+					0x00, 0x00, 0x00, 0x05, 0x0a, 0x03, 0x62, 0x6f, 0x62,
+				},
+				dumper.SrcToDst,
+				baseTs.Add(time.Second),
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x16, 0x01, 0x05, 0x00, 0x00, 0x00, 0x03, 0x88, 0xc0, 0x7f, 0x00, 0x01, 0x35, 0x7f,
+					0x00, 0x8d, 0xb5, 0x05, 0xb1, 0x51, 0x02, 0xa3, 0xa5, 0x51, 0x02, 0x53, 0xdb, 0x54, 0x9f,
+				},
+				dumper.DstToSrc,
+				baseTs.Add(time.Second + time.Millisecond),
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: uint32(3),
+						},
+						{
+							Key:   "authority",
+							Value: "localhost:50051",
+						},
+						{
+							Key:   "http_method",
+							Value: "POST",
+						},
+						{
+							Key:   "path",
+							Value: "/helloworld.Greeter/SayHello",
+						},
+						{
+							Key:   "service",
+							Value: "helloworld.Greeter",
+						},
+						{
+							Key:   "method",
+							Value: "SayHello",
+						},
+						{
+							Key:   "http_status",
+							Value: 200,
+						},
+						{
+							Key:   "grpc_status",
+							Value: 5,
+						},
+						{
+							Key:   "grpc_status_name",
+							Value: "NOT_FOUND",
+						},
+						{
+							Key:   "grpc_message",
+							Value: "user not found",
+						},
+						{
+							Key:   "request_message_count",
+							Value: 1,
+						},
+						{
+							Key:   "request_message_size",
+							Value: 5,
+						},
+						{
+							Key:   "response_message_count",
+							Value: 0,
+						},
+						{
+							Key:   "response_message_size",
+							Value: 0,
+						},
+						{
+							Key:   "request_message",
+							Value: map[string]interface{}{"1": "bob"},
+						},
+						{
+							Key:   "latency",
+							Value: time.Millisecond,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x06, 0x01, 0x04, 0x00, 0x00, 0x00, 0x05, 0x83, 0x86, 0xc2, 0xc1, 0xc0, 0xbf, 0x00,
+					0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				baseTs.Add(2 * time.Second),
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x03, 0x0a, 0x01, 0x78, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x04, 0x03, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x08,
+				},
+				dumper.SrcToDst,
+				baseTs.Add(3 * time.Second),
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: uint32(5),
+						},
+						{
+							Key:   "authority",
+							Value: "localhost:50051",
+						},
+						{
+							Key:   "http_method",
+							Value: "POST",
+						},
+						{
+							Key:   "path",
+							Value: "/helloworld.Greeter/SayHello",
+						},
+						{
+							Key:   "service",
+							Value: "helloworld.Greeter",
+						},
+						{
+							Key:   "method",
+							Value: "SayHello",
+						},
+						{
+							Key:   "rst_stream_error",
+							Value: "CANCEL",
+						},
+						{
+							Key:   "request_message_count",
+							Value: 2,
+						},
+						{
+							Key:   "request_message_size",
+							Value: 3,
+						},
+						{
+							Key:   "response_message_count",
+							Value: 0,
+						},
+						{
+							Key:   "response_message_size",
+							Value: 0,
+						},
+						{
+							Key:   "request_message",
+							Value: map[string]interface{}{"1": "x"},
+						},
+						{
+							Key:   "latency",
+							Value: time.Second,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Plain HTTP/2 request",
+		false,
+		[]grpcStep{
+			{
+				[]byte{
+					0x50, 0x52, 0x49, 0x20, 0x2a, 0x20, 0x48, 0x54, 0x54, 0x50, 0x2f, 0x32, 0x2e, 0x30, 0x0d, 0x0a,
+					0x0d, 0x0a, 0x53, 0x4d, 0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x0d, 0x01, 0x05, 0x00, 0x00, 0x00, 0x01, 0x82, 0x86, 0x85, 0x41, 0x88, 0x2f,
+					0x91, 0xd3, 0x5d, 0x05, 0x5c, 0x87, 0xa7,
+				},
+				dumper.SrcToDst,
+				baseTs,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0a, 0x01, 0x04, 0x00, 0x00,
+					0x00, 0x01, 0x8d, 0x5f, 0x87, 0x49, 0x7c, 0xa5, 0x89, 0xd3, 0x4d, 0x1f, 0x00, 0x00, 0x0d, 0x00,
+					0x09, 0x00, 0x00, 0x00, 0x01, 0x03, 0x6e, 0x6f, 0x74, 0x20, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x00,
+					0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				baseTs.Add(time.Millisecond),
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "stream_id",
+							Value: uint32(1),
+						},
+						{
+							Key:   "authority",
+							Value: "example.com",
+						},
+						{
+							Key:   "http_method",
+							Value: "GET",
+						},
+						{
+							Key:   "path",
+							Value: "/index.html",
+						},
+						{
+							Key:   "http_status",
+							Value: 404,
+						},
+						{
+							Key:   "request_data_size",
+							Value: 0,
+						},
+						{
+							Key:   "response_data_size",
+							Value: 9,
+						},
+						{
+							Key:   "latency",
+							Value: time.Millisecond,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Not HTTP/2 with prior knowledge",
+		false,
+		[]grpcStep{
+			{
+				[]byte("GET / HTTP/1.1\r\nHost: example.com\r\nUpgrade: h2c\r\n\r\n"),
+				dumper.SrcToDst,
+				baseTs,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"),
+				dumper.DstToSrc,
+				baseTs,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+}
+
+func TestGrpcReadFrames(t *testing.T) {
+	for _, tt := range grpcReadFramesTests {
+		d := &Dumper{
+			dumpMessage: tt.dumpMessage,
+		}
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			connMetadata.Ts = s.ts
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
+
+func TestGrpcReadFramesWithDescriptor(t *testing.T) {
+	files := newTestFiles(t)
+	d := &Dumper{
+		dumpMessage: true,
+		files:       files,
+	}
+	connMetadata := d.NewConnMetadata()
+	in := []byte{
+		0x50, 0x52, 0x49, 0x20, 0x2a, 0x20, 0x48, 0x54, 0x54, 0x50, 0x2f, 0x32, 0x2e, 0x30, 0x0d, 0x0a,
+		0x0d, 0x0a, 0x53, 0x4d, 0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x00, 0x06, 0x04, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x04, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00, 0x3e, 0x01, 0x04, 0x00, 0x00, 0x00, 0x01,
+		0x83, 0x86, 0x45, 0x95, 0x62, 0x72, 0xd1, 0x41, 0xfc, 0x1e, 0xca, 0x24, 0x5f, 0x15, 0x85, 0x2a,
+		0x4b, 0x63, 0x1b, 0x87, 0xeb, 0x19, 0x68, 0xa0, 0xff, 0x41, 0x8b, 0xa0, 0xe4, 0x1d, 0x13, 0x9d,
+		0x09, 0xb8, 0xd8, 0x00, 0xd8, 0x7f, 0x5f, 0x8b, 0x1d, 0x75, 0xd0, 0x62, 0x0d, 0x26, 0x3d, 0x4c,
+		0x4d, 0x65, 0x64, 0x40, 0x02, 0x74, 0x65, 0x86, 0x4d, 0x83, 0x35, 0x05, 0xb1, 0x1f, 0x00, 0x00,
+		0x0c, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x07, 0x0a, 0x05, 0x77, 0x6f,
+		0x72, 0x6c, 0x64,
+	}
+	if _, err := d.ReadFrames(in, dumper.SrcToDst, connMetadata); err != nil {
+		t.Fatal(err)
+	}
+	in = []byte{
+		0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x0e, 0x01, 0x04, 0x00, 0x00, 0x00, 0x01, 0x88, 0x5f, 0x8b, 0x1d, 0x75,
+		0xd0, 0x62, 0x0d, 0x26, 0x3d, 0x4c, 0x4d, 0x65, 0x64, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x0d, 0x0a, 0x0b, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77,
+		0x6f, 0x72, 0x6c, 0x64, 0x00, 0x00, 0x18, 0x01, 0x05, 0x00, 0x00, 0x00, 0x01, 0x40, 0x88, 0x9a,
+		0xca, 0xc8, 0xb2, 0x12, 0x34, 0xda, 0x8f, 0x01, 0x30, 0x40, 0x89, 0x9a, 0xca, 0xc8, 0xb5, 0x25,
+		0x42, 0x07, 0x31, 0x7f, 0x00,
+	}
+	actual, err := d.ReadFrames(in, dumper.DstToSrc, connMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 1 {
+		t.Fatalf("actual %#v", actual)
+	}
+	want := map[string]interface{}{
+		"request_message":  map[string]interface{}{"name": "world"},
+		"response_message": map[string]interface{}{"message": "Hello world"},
+	}
+	for _, kv := range actual[0] {
+		w, ok := want[kv.Key]
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(kv.Value, w) {
+			t.Errorf("%s:\nactual %#v\nwant %#v", kv.Key, kv.Value, w)
+		}
+		delete(want, kv.Key)
+	}
+	if len(want) > 0 {
+		t.Errorf("not found %#v", want)
+	}
+}
+
+// newTestFiles returns descriptors of helloworld.proto
+func newTestFiles(t *testing.T) *protoregistry.Files {
+	str := func(s string) *string { return &s }
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	typ := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	num := int32(1)
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    str("helloworld.proto"),
+				Package: str("helloworld"),
+				Syntax:  str("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: str("HelloRequest"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: str("name"), JsonName: str("name"), Number: &num, Label: &label, Type: &typ},
+						},
+					},
+					{
+						Name: str("HelloReply"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: str("message"), JsonName: str("message"), Number: &num, Label: &label, Type: &typ},
+						},
+					},
+				},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{
+						Name: str("Greeter"),
+						Method: []*descriptorpb.MethodDescriptorProto{
+							{
+								Name:       str("SayHello"),
+								InputType:  str(".helloworld.HelloRequest"),
+								OutputType: str(".helloworld.HelloReply"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
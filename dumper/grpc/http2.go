@@ -0,0 +1,225 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+// side is HTTP/2 frames of a direction
+type side struct {
+	buffer         []byte // partial frame
+	decoder        *hpack.Decoder
+	broken         bool   // HPACK context is lost
+	headerBlock    []byte // header block fragments waiting for END_HEADERS
+	headerStreamID uint32
+	headerFlags    uint8
+	headerPushed   bool // header block of PUSH_PROMISE
+	headerPending  bool
+}
+
+// call is HTTP/2 stream ( gRPC call )
+type call struct {
+	streamID         uint32
+	start            time.Time
+	httpMethod       string
+	path             string
+	authority        string
+	contentType      string
+	requestEncoding  string
+	responseEncoding string
+	httpStatus       string
+	grpcStatus       string
+	grpcMessage      string
+	rstStreamError   string
+	requestData      int
+	responseData     int
+	request          messages
+	response         messages
+}
+
+type frame struct {
+	frameType uint8
+	flags     uint8
+	streamID  uint32
+	payload   []byte
+}
+
+func newSide() *side {
+	return &side{
+		decoder: hpack.NewDecoder(initialHeaderTableSize, nil),
+	}
+}
+
+// readFrames returns complete frames and cache the partial frame
+func (s *side) readFrames(in []byte) []frame {
+	frames := []frame{}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) >= frameHeaderLength {
+		l := int(buff[0])<<16 | int(buff[1])<<8 | int(buff[2])
+		if len(buff) < frameHeaderLength+l {
+			break
+		}
+		frames = append(frames, frame{
+			frameType: buff[3],
+			flags:     buff[4],
+			streamID:  binary.BigEndian.Uint32(buff[5:9]) & 0x7fffffff,
+			payload:   buff[frameHeaderLength : frameHeaderLength+l],
+		})
+		buff = buff[frameHeaderLength+l:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return frames
+}
+
+// headerBlockFragment returns header block fragment of HEADERS or PUSH_PROMISE
+// https://tools.ietf.org/html/rfc7540#section-6.2
+func headerBlockFragment(f frame) ([]byte, bool) {
+	p := f.payload
+	padLength := 0
+	if f.flags&flagPadded > 0 {
+		if len(p) < 1 {
+			return nil, false
+		}
+		padLength = int(p[0])
+		p = p[1:]
+	}
+	switch f.frameType {
+	case frameHeaders:
+		if f.flags&flagPriority > 0 {
+			if len(p) < 5 {
+				return nil, false
+			}
+			p = p[5:]
+		}
+	case framePushPromise:
+		if len(p) < 4 {
+			return nil, false
+		}
+		p = p[4:]
+	}
+	if len(p) < padLength {
+		return nil, false
+	}
+	return p[:len(p)-padLength], true
+}
+
+// dataPayload returns payload of DATA without padding
+// https://tools.ietf.org/html/rfc7540#section-6.1
+func dataPayload(f frame) ([]byte, bool) {
+	p := f.payload
+	if f.flags&flagPadded == 0 {
+		return p, true
+	}
+	if len(p) < 1 || len(p)-1 < int(p[0]) {
+		return nil, false
+	}
+	return p[1 : len(p)-int(p[0])], true
+}
+
+// readHeaderBlock buffer HEADERS / PUSH_PROMISE / CONTINUATION and returns decoded header fields at END_HEADERS
+func (s *side) readHeaderBlock(f frame) ([]hpack.HeaderField, bool) {
+	switch f.frameType {
+	case frameHeaders, framePushPromise:
+		fragment, ok := headerBlockFragment(f)
+		if !ok {
+			s.broken = true
+			return nil, false
+		}
+		s.headerBlock = append([]byte{}, fragment...)
+		s.headerStreamID = f.streamID
+		s.headerFlags = f.flags
+		s.headerPushed = f.frameType == framePushPromise
+		s.headerPending = true
+	case frameContinuation:
+		if !s.headerPending || f.streamID != s.headerStreamID {
+			s.broken = true
+			return nil, false
+		}
+		s.headerBlock = append(s.headerBlock, f.payload...)
+		s.headerFlags |= f.flags & flagEndHeaders
+	}
+	if len(s.headerBlock) > maxHeaderBlockSize {
+		s.broken = true
+	}
+	if f.flags&flagEndHeaders == 0 || s.broken {
+		return nil, false
+	}
+	s.headerPending = false
+	block := s.headerBlock
+	s.headerBlock = nil
+	fields, err := s.decoder.DecodeFull(block)
+	if err != nil {
+		// HPACK dynamic table can not be recovered
+		s.broken = true
+		return nil, false
+	}
+	return fields, true
+}
+
+// readSettings returns SETTINGS_HEADER_TABLE_SIZE
+// https://tools.ietf.org/html/rfc7540#section-6.5
+func readSettings(f frame) (uint32, bool) {
+	if f.flags&flagAck > 0 {
+		return 0, false
+	}
+	var (
+		size  uint32
+		found bool
+	)
+	for p := f.payload; len(p) >= 6; p = p[6:] {
+		if binary.BigEndian.Uint16(p[0:2]) == settingsHeaderTableSize {
+			size = binary.BigEndian.Uint32(p[2:6])
+			found = true
+		}
+	}
+	return size, found
+}
+
+// setRequestHeaders set request headers ( or trailers ) of client
+func (c *call) setRequestHeaders(fields []hpack.HeaderField) {
+	for _, hf := range fields {
+		switch hf.Name {
+		case ":method":
+			c.httpMethod = hf.Value
+		case ":path":
+			c.path = hf.Value
+		case ":authority":
+			c.authority = hf.Value
+		case "content-type":
+			c.contentType = hf.Value
+		case "grpc-encoding":
+			c.requestEncoding = hf.Value
+		}
+	}
+}
+
+// setResponseHeaders set response headers or trailers of server
+func (c *call) setResponseHeaders(fields []hpack.HeaderField) {
+	for _, hf := range fields {
+		switch hf.Name {
+		case ":status":
+			c.httpStatus = hf.Value
+		case "content-type":
+			if c.contentType == "" {
+				c.contentType = hf.Value
+			}
+		case "grpc-encoding":
+			c.responseEncoding = hf.Value
+		case "grpc-status":
+			c.grpcStatus = hf.Value
+		case "grpc-message":
+			c.grpcMessage = hf.Value
+		}
+	}
+}
+
+// isGRPC returns true when content-type is application/grpc ( application/grpc+proto, ... )
+func (c *call) isGRPC() bool {
+	return c.contentType == "application/grpc" || strings.HasPrefix(c.contentType, "application/grpc+") || strings.HasPrefix(c.contentType, "application/grpc;")
+}
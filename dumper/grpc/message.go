@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// messages is Length-Prefixed-Messages of a direction of gRPC call
+// https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+type messages struct {
+	header     []byte // partial Length-Prefixed-Message header
+	remaining  int    // rest bytes of current message
+	count      int
+	size       int
+	capturing  bool
+	first      []byte // the first message for dump
+	compressed bool   // the first message is compressed
+}
+
+// write DATA payload
+func (m *messages) write(p []byte, capture bool) {
+	for len(p) > 0 {
+		if m.remaining == 0 {
+			n := grpcMessageHeaderLength - len(m.header)
+			if n > len(p) {
+				n = len(p)
+			}
+			m.header = append(m.header, p[:n]...)
+			p = p[n:]
+			if len(m.header) < grpcMessageHeaderLength {
+				return
+			}
+			l := int(binary.BigEndian.Uint32(m.header[1:5]))
+			m.count++
+			m.size += l
+			m.remaining = l
+			if m.count == 1 && capture && l <= maxMessageSize {
+				m.capturing = true
+				m.compressed = m.header[0]&0x1 > 0
+				m.first = make([]byte, 0, l)
+			}
+			m.header = nil
+			if l == 0 {
+				m.capturing = false
+			}
+			continue
+		}
+		n := m.remaining
+		if n > len(p) {
+			n = len(p)
+		}
+		if m.capturing {
+			m.first = append(m.first, p[:n]...)
+		}
+		m.remaining -= n
+		p = p[n:]
+		if m.remaining == 0 {
+			m.capturing = false
+		}
+	}
+}
+
+// firstMessage returns the first message ( decompressed )
+func (m *messages) firstMessage(encoding string) ([]byte, error) {
+	if m.first == nil || m.capturing {
+		return nil, errors.New("message is not captured")
+	}
+	if !m.compressed {
+		return m.first, nil
+	}
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(m.first))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(m.first))
+	default:
+		return nil, errors.Errorf("unsupported grpc-encoding: %s", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, maxMessageSize))
+}
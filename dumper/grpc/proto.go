@@ -0,0 +1,176 @@
+package grpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxRawDepth is max depth of nested message of raw dump
+const maxRawDepth = 16
+
+// loadDescriptorSet load FileDescriptorSet ( `protoc --include_imports --descriptor_set_out` )
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, errors.Wrapf(err, "invalid FileDescriptorSet: %s", path)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid FileDescriptorSet: %s", path)
+	}
+	return files, nil
+}
+
+// findMethod returns method descriptor of :path ( /package.Service/Method )
+func findMethod(files *protoregistry.Files, path string) (protoreflect.MethodDescriptor, bool) {
+	if files == nil {
+		return nil, false
+	}
+	service, method, ok := splitPath(path)
+	if !ok {
+		return nil, false
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, false
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, false
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	return md, md != nil
+}
+
+// splitPath split :path into service and method
+func splitPath(path string) (string, string, bool) {
+	s := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(s) != 2 || s[0] == "" || s[1] == "" {
+		return "", "", false
+	}
+	return s[0], s[1], true
+}
+
+// decodeMessage decode message by descriptor, or dump fields by field number when descriptor is not found
+func decodeMessage(md protoreflect.MessageDescriptor, b []byte) interface{} {
+	if md != nil {
+		msg := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(b, msg); err == nil {
+			if j, err := protojson.Marshal(msg); err == nil {
+				var v interface{}
+				if err := json.Unmarshal(j, &v); err == nil {
+					return v
+				}
+			}
+		}
+	}
+	if v, ok := decodeRaw(b, 0); ok {
+		return v
+	}
+	return hex.EncodeToString(b)
+}
+
+// decodeRaw dump fields by field number ( like `protoc --decode_raw` )
+func decodeRaw(b []byte, depth int) (map[string]interface{}, bool) {
+	fields := map[string]interface{}{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, false
+		}
+		b = b[n:]
+		var v interface{}
+		switch typ {
+		case protowire.VarintType:
+			u, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, false
+			}
+			v = u
+			b = b[n:]
+		case protowire.Fixed32Type:
+			u, n := protowire.ConsumeFixed32(b)
+			if n < 0 {
+				return nil, false
+			}
+			v = u
+			b = b[n:]
+		case protowire.Fixed64Type:
+			u, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return nil, false
+			}
+			v = u
+			b = b[n:]
+		case protowire.BytesType:
+			bb, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, false
+			}
+			v = decodeRawBytes(bb, depth)
+			b = b[n:]
+		default:
+			// groups are not supported
+			return nil, false
+		}
+		key := strconv.Itoa(int(num))
+		switch e := fields[key].(type) {
+		case nil:
+			fields[key] = v
+		case []interface{}:
+			fields[key] = append(e, v)
+		default:
+			fields[key] = []interface{}{e, v}
+		}
+	}
+	return fields, true
+}
+
+// decodeRawBytes guess length-delimited field is string, nested message or bytes
+func decodeRawBytes(b []byte, depth int) interface{} {
+	if isPrintable(b) {
+		return string(b)
+	}
+	if depth < maxRawDepth && len(b) > 0 {
+		if m, ok := decodeRaw(b, depth+1); ok {
+			return m
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+// isPrintable returns true when b is printable UTF-8 string ( not starting with control character )
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for i, r := range string(b) {
+		if unicode.IsPrint(r) {
+			continue
+		}
+		if i > 0 && (r == '\t' || r == '\r' || r == '\n') {
+			continue
+		}
+		return false
+	}
+	return true
+}
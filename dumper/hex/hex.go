@@ -52,6 +52,11 @@ func (h *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumpe
 
 // Read return byte to analyzed string
 func (h *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	return DumpValues(in), nil
+}
+
+// DumpValues return hex dump ( bytes and ascii ) of byte
+func DumpValues(in []byte) []dumper.DumpValue {
 	hexdump := strings.Split(hex.Dump(in), "\n")
 	byteString := []string{}
 	ascii := []string{}
@@ -72,7 +77,7 @@ func (h *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumpe
 			Key:   "ascii",
 			Value: strings.Join(ascii, ""),
 		},
-	}, nil
+	}
 }
 
 // Log values
@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidMessage = errors.New("invalid Kafka message")
+
+// decoder read primitive types of Kafka protocol
+// https://kafka.apache.org/protocol.html#protocol_types
+type decoder struct {
+	buf      []byte
+	flexible bool // compact types and tagged fields
+	err      error
+}
+
+func newDecoder(in []byte, flexible bool) *decoder {
+	return &decoder{
+		buf:      in,
+		flexible: flexible,
+	}
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errInvalidMessage
+		d.buf = nil
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	b := d.read(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (d *decoder) bool() bool {
+	return d.int8() != 0
+}
+
+func (d *decoder) int16() int16 {
+	b := d.read(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (d *decoder) int32() int32 {
+	b := d.read(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) int64() int64 {
+	b := d.read(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errInvalidMessage
+		d.buf = nil
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// length read length of STRING, BYTES and ARRAY ( -1: null )
+func (d *decoder) length(size int) int {
+	var l int
+	if d.flexible {
+		l = int(d.uvarint()) - 1
+	} else {
+		switch size {
+		case 2:
+			l = int(d.int16())
+		case 4:
+			l = int(d.int32())
+		}
+	}
+	if d.err != nil || l < -1 {
+		d.err = errInvalidMessage
+		return -1
+	}
+	return l
+}
+
+func (d *decoder) nullableString() (string, bool) {
+	l := d.length(2)
+	if l < 0 {
+		return "", false
+	}
+	return string(d.read(l)), d.err == nil
+}
+
+func (d *decoder) string() string {
+	s, _ := d.nullableString()
+	return s
+}
+
+func (d *decoder) bytes() []byte {
+	l := d.length(4)
+	if l < 0 {
+		return nil
+	}
+	return d.read(l)
+}
+
+// arrayLength read length of ARRAY ( -1: null )
+func (d *decoder) arrayLength() int {
+	l := d.length(4)
+	if l > len(d.buf) {
+		// each element has at least 1 byte
+		d.err = errInvalidMessage
+		d.buf = nil
+		return -1
+	}
+	return l
+}
+
+func (d *decoder) stringArray() []string {
+	l := d.arrayLength()
+	if l < 0 {
+		return nil
+	}
+	s := []string{}
+	for i := 0; i < l && d.err == nil; i++ {
+		s = append(s, d.string())
+	}
+	return s
+}
+
+func (d *decoder) int32Array() {
+	l := d.arrayLength()
+	for i := 0; i < l && d.err == nil; i++ {
+		_ = d.int32()
+	}
+}
+
+// uuid read UUID and returns it in base64 ( same as Kafka tools )
+func (d *decoder) uuid() string {
+	b := d.read(16)
+	if b == nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// taggedFields skip TAGGED_FIELDS of flexible versions
+func (d *decoder) taggedFields() {
+	if !d.flexible {
+		return
+	}
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		_ = d.uvarint() // tag
+		_ = d.read(int(d.uvarint()))
+	}
+}
@@ -0,0 +1,300 @@
+package kafka
+
+import (
+	"encoding/binary"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	client   stream
+	server   stream
+	requests map[int32]request // correlationID:request
+}
+
+// request is API of request waiting for response
+type request struct {
+	apiKey     int16
+	apiVersion int16
+}
+
+// stream is Kafka messages of a direction
+type stream struct {
+	buffer []byte // partial message
+	skip   int    // rest bytes of large message
+}
+
+// message is Kafka request or response ( without size )
+type message struct {
+	data      []byte
+	size      int
+	truncated bool // only header is buffered
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "kafka",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (k *Dumper) Name() string {
+	return k.name
+}
+
+// Dump request and response of Kafka
+func (k *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := k.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		k.Log(values)
+	}
+	return nil
+}
+
+// Read return the first message of byte to analyzed string ( use ReadFrames to read all messages )
+func (k *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := k.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return requests or responses of byte to analyzed string
+func (k *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	fromServer := direction == dumper.RemoteToClient || direction == dumper.DstToSrc
+
+	if fromServer {
+		for _, msg := range internal.server.readMessages(in, false) {
+			if v := readResponse(msg, &internal); len(v) > 0 {
+				records = append(records, v)
+			}
+		}
+	} else {
+		for _, msg := range internal.client.readMessages(in, true) {
+			if v := readRequest(msg, &internal); len(v) > 0 {
+				records = append(records, v)
+			}
+		}
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (k *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	k.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (k *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			requests: map[int32]request{},
+		},
+	}
+}
+
+// readMessages returns complete messages and cache the partial message
+// large message is returned with only header and the rest is skipped
+func (s *stream) readMessages(in []byte, isRequest bool) []message {
+	msgs := []message{}
+	if s.skip > 0 {
+		if len(in) <= s.skip {
+			s.skip -= len(in)
+			return msgs
+		}
+		in = in[s.skip:]
+		s.skip = 0
+	}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) >= 4 {
+		size := int(int32(binary.BigEndian.Uint32(buff[0:4])))
+		if size < 4 || (isRequest && !validRequestHeader(buff[4:])) {
+			// not message boundary
+			buff = nil
+			break
+		}
+		if size <= maxMessageSize {
+			if len(buff) < 4+size {
+				break
+			}
+			msgs = append(msgs, message{data: buff[4 : 4+size], size: size})
+			buff = buff[4+size:]
+			continue
+		}
+		l := maxHeaderLength
+		if len(buff) < 4+l {
+			break
+		}
+		msgs = append(msgs, message{data: buff[4 : 4+l], size: size, truncated: true})
+		if len(buff) < 4+size {
+			s.skip = 4 + size - len(buff)
+			buff = nil
+			break
+		}
+		buff = buff[4+size:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return msgs
+}
+
+// validRequestHeader validate api_key and api_version of request header
+func validRequestHeader(in []byte) bool {
+	if len(in) < 4 {
+		return true
+	}
+	apiKey := int16(binary.BigEndian.Uint16(in[0:2]))
+	apiVersion := int16(binary.BigEndian.Uint16(in[2:4]))
+	_, ok := apis[apiKey]
+	return ok && apiVersion >= 0 && apiVersion <= maxAPIVersion
+}
+
+// https://kafka.apache.org/protocol.html#protocol_messages
+func readRequest(msg message, internal *connMetadataInternal) []dumper.DumpValue {
+	d := newDecoder(msg.data, false)
+	apiKey := d.int16()
+	apiVersion := d.int16()
+	correlationID := d.int32()
+	clientID, _ := d.nullableString()
+	if d.err != nil {
+		return []dumper.DumpValue{}
+	}
+	a := apis[apiKey]
+	d.flexible = a.flexible(apiVersion)
+	d.taggedFields()
+
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "api_key",
+			Value: apiKey,
+		},
+		dumper.DumpValue{
+			Key:   "api_name",
+			Value: a.name,
+		},
+		dumper.DumpValue{
+			Key:   "api_version",
+			Value: apiVersion,
+		},
+		dumper.DumpValue{
+			Key:   "correlation_id",
+			Value: correlationID,
+		},
+		dumper.DumpValue{
+			Key:   "client_id",
+			Value: clientID,
+		},
+		dumper.DumpValue{
+			Key:   "message_size",
+			Value: msg.size,
+		},
+	}
+
+	if read, ok := requestReaders[apiKey]; ok && !msg.truncated && d.err == nil {
+		values = append(values, read(d, apiVersion)...)
+	}
+
+	if apiKey == apiProduce && lookupValue(values, "acks") == int16(0) {
+		// no response
+		return values
+	}
+	if internal.requests == nil {
+		internal.requests = map[int32]request{}
+	}
+	if len(internal.requests) >= maxRequests {
+		// responses were not captured
+		internal.requests = map[int32]request{}
+	}
+	internal.requests[correlationID] = request{
+		apiKey:     apiKey,
+		apiVersion: apiVersion,
+	}
+	return values
+}
+
+func readResponse(msg message, internal *connMetadataInternal) []dumper.DumpValue {
+	d := newDecoder(msg.data, false)
+	correlationID := d.int32()
+	req, ok := internal.requests[correlationID]
+	if d.err != nil || !ok {
+		return []dumper.DumpValue{}
+	}
+	delete(internal.requests, correlationID)
+	a := apis[req.apiKey]
+	d.flexible = a.flexible(req.apiVersion)
+	if req.apiKey != apiVersions {
+		// ApiVersions response always uses response header v0
+		d.taggedFields()
+	}
+
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "api_key",
+			Value: req.apiKey,
+		},
+		dumper.DumpValue{
+			Key:   "api_name",
+			Value: a.name,
+		},
+		dumper.DumpValue{
+			Key:   "api_version",
+			Value: req.apiVersion,
+		},
+		dumper.DumpValue{
+			Key:   "correlation_id",
+			Value: correlationID,
+		},
+		dumper.DumpValue{
+			Key:   "message_size",
+			Value: msg.size,
+		},
+	}
+
+	read, ok := responseReaders[req.apiKey]
+	if !ok || msg.truncated || d.err != nil {
+		return values
+	}
+	r := &response{}
+	read(d, req.apiVersion, r)
+	return append(values, r.dumpValues()...)
+}
+
+func lookupValue(values []dumper.DumpValue, key string) interface{} {
+	for _, kv := range values {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,793 @@
+package kafka
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type kafkaStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  []dumper.DumpValue
+}
+
+var kafkaReadTests = []struct {
+	description string
+	steps       []kafkaStep
+}{
+	{
+		"Produce v3 request and response",
+		[]kafkaStep{
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x7a, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x05, 0x74, 0x63,
+					0x70, 0x64, 0x70, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x75, 0x30, 0x00, 0x00, 0x00, 0x01, 0x00,
+					0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x4b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3f, 0x00,
+					0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff,
+					0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x02,
+					0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(0),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Produce",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(3),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(1),
+					},
+					dumper.DumpValue{
+						Key:   "client_id",
+						Value: "tcpdp",
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 122,
+					},
+					dumper.DumpValue{
+						Key:   "acks",
+						Value: int16(-1),
+					},
+					dumper.DumpValue{
+						Key:   "topics",
+						Value: []string{"orders"},
+					},
+					dumper.DumpValue{
+						Key: "partitions",
+						Value: []map[string]interface{}{
+							map[string]interface{}{
+								"topic":        "orders",
+								"partition":    int32(0),
+								"record_count": 2,
+								"bytes":        75,
+							},
+						},
+					},
+					dumper.DumpValue{
+						Key:   "record_count",
+						Value: 2,
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x2e, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x6f, 0x72,
+					0x64, 0x65, 0x72, 0x73, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00,
+					0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(0),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Produce",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(3),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(1),
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 46,
+					},
+					dumper.DumpValue{
+						Key:   "error_code",
+						Value: int16(0),
+					},
+				},
+			},
+		},
+	},
+	{
+		"Produce v9 ( flexible version ) request with acks=0 and Metadata v1",
+		[]kafkaStep{
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0xc4, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x02, 0x00, 0x05, 0x74, 0x63,
+					0x70, 0x64, 0x70, 0x00, 0x06, 0x74, 0x78, 0x6e, 0x2d, 0x31, 0x00, 0x00, 0x00, 0x00, 0x75, 0x30,
+					0x02, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x02, 0x00, 0x00, 0x00, 0x01, 0x97, 0x01, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x02,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+					0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x02, 0x06, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+					0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x02, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(0),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Produce",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(9),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(2),
+					},
+					dumper.DumpValue{
+						Key:   "client_id",
+						Value: "tcpdp",
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 196,
+					},
+					dumper.DumpValue{
+						Key:   "transactional_id",
+						Value: "txn-1",
+					},
+					dumper.DumpValue{
+						Key:   "acks",
+						Value: int16(0),
+					},
+					dumper.DumpValue{
+						Key:   "topics",
+						Value: []string{"orders"},
+					},
+					dumper.DumpValue{
+						Key: "partitions",
+						Value: []map[string]interface{}{
+							map[string]interface{}{
+								"topic":        "orders",
+								"partition":    int32(1),
+								"record_count": 4,
+								"bytes":        150,
+							},
+						},
+					},
+					dumper.DumpValue{
+						Key:   "record_count",
+						Value: 4,
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x1b, 0x00, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x03, 0x00, 0x05, 0x74, 0x63,
+					0x70, 0x64, 0x70, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(3),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Metadata",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(1),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(3),
+					},
+					dumper.DumpValue{
+						Key:   "client_id",
+						Value: "tcpdp",
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 27,
+					},
+					dumper.DumpValue{
+						Key:   "topics",
+						Value: []string{"orders"},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x49, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01,
+					0x00, 0x06, 0x6b, 0x61, 0x66, 0x6b, 0x61, 0x31, 0x00, 0x00, 0x23, 0x84, 0xff, 0xff, 0x00, 0x00,
+					0x00, 0x02, 0x00, 0x06, 0x6b, 0x61, 0x66, 0x6b, 0x61, 0x32, 0x00, 0x00, 0x23, 0x84, 0x00, 0x06,
+					0x72, 0x61, 0x63, 0x6b, 0x2d, 0x62, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x03,
+					0x00, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(3),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Metadata",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(1),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(3),
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 73,
+					},
+					dumper.DumpValue{
+						Key:   "error_code",
+						Value: int16(3),
+					},
+					dumper.DumpValue{
+						Key:   "error_name",
+						Value: "UNKNOWN_TOPIC_OR_PARTITION",
+					},
+					dumper.DumpValue{
+						Key:   "brokers",
+						Value: []string{"kafka1:9092", "kafka2:9092"},
+					},
+					dumper.DumpValue{
+						Key:   "topics",
+						Value: []string{"orders"},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Fetch v11 response split across reads",
+		[]kafkaStep{
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x5f, 0x00, 0x01, 0x00, 0x0b, 0x00, 0x00, 0x00, 0x04, 0x00, 0x0a, 0x63, 0x6f,
+					0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x2d, 0x31, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x01, 0xf4,
+					0x00, 0x00, 0x00, 0x01, 0x03, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff,
+					0xff, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x00, 0x00, 0x00,
+					0x01, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(1),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Fetch",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(11),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(4),
+					},
+					dumper.DumpValue{
+						Key:   "client_id",
+						Value: "consumer-1",
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 95,
+					},
+					dumper.DumpValue{
+						Key:   "max_wait_ms",
+						Value: int32(500),
+					},
+					dumper.DumpValue{
+						Key:   "min_bytes",
+						Value: int32(1),
+					},
+					dumper.DumpValue{
+						Key:   "topics",
+						Value: []string{"orders"},
+					},
+					dumper.DumpValue{
+						Key: "partitions",
+						Value: []map[string]interface{}{
+							map[string]interface{}{
+								"topic":        "orders",
+								"partition":    int32(0),
+								"fetch_offset": int64(10),
+							},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x93, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x4b, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+					0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x02, 0x06, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(1),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Fetch",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(11),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(4),
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 147,
+					},
+					dumper.DumpValue{
+						Key:   "error_code",
+						Value: int16(0),
+					},
+					dumper.DumpValue{
+						Key:   "topics",
+						Value: []string{"orders"},
+					},
+					dumper.DumpValue{
+						Key:   "record_count",
+						Value: 2,
+					},
+					dumper.DumpValue{
+						Key:   "bytes",
+						Value: 75,
+					},
+				},
+			},
+		},
+	},
+	{
+		"JoinGroup v6 ( flexible version ) error response",
+		[]kafkaStep{
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x37, 0x00, 0x0b, 0x00, 0x06, 0x00, 0x00, 0x00, 0x05, 0x00, 0x0a, 0x63, 0x6f,
+					0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x2d, 0x31, 0x00, 0x03, 0x67, 0x31, 0x00, 0x00, 0x27, 0x10,
+					0x00, 0x04, 0x93, 0xe0, 0x01, 0x00, 0x09, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x02,
+					0x06, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x03, 0x00, 0x01, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(11),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "JoinGroup",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(6),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(5),
+					},
+					dumper.DumpValue{
+						Key:   "client_id",
+						Value: "consumer-1",
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 55,
+					},
+					dumper.DumpValue{
+						Key:   "group_id",
+						Value: "g1",
+					},
+					dumper.DumpValue{
+						Key:   "member_id",
+						Value: "",
+					},
+					dumper.DumpValue{
+						Key:   "protocol_type",
+						Value: "consumer",
+					},
+					dumper.DumpValue{
+						Key:   "protocols",
+						Value: []string{"range"},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x22, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4f, 0xff,
+					0xff, 0xff, 0xff, 0x00, 0x01, 0x0f, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x2d, 0x31,
+					0x2d, 0x61, 0x62, 0x63, 0x01, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(11),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "JoinGroup",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(6),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(5),
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 34,
+					},
+					dumper.DumpValue{
+						Key:   "error_code",
+						Value: int16(79),
+					},
+					dumper.DumpValue{
+						Key:   "error_name",
+						Value: "MEMBER_ID_REQUIRED",
+					},
+					dumper.DumpValue{
+						Key:   "generation_id",
+						Value: int32(-1),
+					},
+					dumper.DumpValue{
+						Key:   "protocol_name",
+						Value: "",
+					},
+					dumper.DumpValue{
+						Key:   "leader",
+						Value: "",
+					},
+					dumper.DumpValue{
+						Key:   "member_id",
+						Value: "consumer-1-abc",
+					},
+				},
+			},
+		},
+	},
+	{
+		"ApiVersions v3 ( response header v0 ) and pipelined Heartbeat",
+		[]kafkaStep{
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x1d, 0x00, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x06, 0x00, 0x05, 0x74, 0x63,
+					0x70, 0x64, 0x70, 0x00, 0x06, 0x74, 0x63, 0x70, 0x64, 0x70, 0x06, 0x31, 0x2e, 0x30, 0x2e, 0x30,
+					0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(18),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "ApiVersions",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(3),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(6),
+					},
+					dumper.DumpValue{
+						Key:   "client_id",
+						Value: "tcpdp",
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 29,
+					},
+					dumper.DumpValue{
+						Key:   "client_software_name",
+						Value: "tcpdp",
+					},
+					dumper.DumpValue{
+						Key:   "client_software_version",
+						Value: "1.0.0",
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x13, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x0b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(18),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "ApiVersions",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(3),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(6),
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 19,
+					},
+					dumper.DumpValue{
+						Key:   "error_code",
+						Value: int16(0),
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x17, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, 0x00, 0x01, 0x63, 0x00,
+					0x02, 0x67, 0x31, 0x00, 0x00, 0x00, 0x03, 0x00, 0x02, 0x6d, 0x31, 0x00, 0x00, 0x00, 0x17, 0x00,
+					0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00, 0x01, 0x63, 0x00, 0x02, 0x67, 0x31, 0x00, 0x00,
+					0x00, 0x03, 0x00, 0x02, 0x6d, 0x31,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(12),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Heartbeat",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(0),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(7),
+					},
+					dumper.DumpValue{
+						Key:   "client_id",
+						Value: "c",
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 23,
+					},
+					dumper.DumpValue{
+						Key:   "group_id",
+						Value: "g1",
+					},
+					dumper.DumpValue{
+						Key:   "generation_id",
+						Value: int32(3),
+					},
+					dumper.DumpValue{
+						Key:   "member_id",
+						Value: "m1",
+					},
+				},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x07, 0x00, 0x1b, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00,
+					0x00, 0x08, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "api_key",
+						Value: int16(12),
+					},
+					dumper.DumpValue{
+						Key:   "api_name",
+						Value: "Heartbeat",
+					},
+					dumper.DumpValue{
+						Key:   "api_version",
+						Value: int16(0),
+					},
+					dumper.DumpValue{
+						Key:   "correlation_id",
+						Value: int32(7),
+					},
+					dumper.DumpValue{
+						Key:   "message_size",
+						Value: 6,
+					},
+					dumper.DumpValue{
+						Key:   "error_code",
+						Value: int16(27),
+					},
+					dumper.DumpValue{
+						Key:   "error_name",
+						Value: "REBALANCE_IN_PROGRESS",
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestKafkaRead(t *testing.T) {
+	for _, tt := range kafkaReadTests {
+		out := new(bytes.Buffer)
+		d := &Dumper{
+			logger: newTestLogger(out),
+		}
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.Read(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if len(actual) == 0 && len(s.expected) == 0 {
+				continue
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+		internal := connMetadata.Internal.(connMetadataInternal)
+		if _, ok := internal.requests[2]; ok {
+			t.Errorf("%s: Produce request with acks=0 should not wait for response", tt.description)
+		}
+	}
+}
+
+func TestKafkaReadFrames(t *testing.T) {
+	// pipelined Heartbeat requests and responses
+	requests := []byte{
+		0x00, 0x00, 0x00, 0x17, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, 0x00, 0x01, 0x63, 0x00,
+		0x02, 0x67, 0x31, 0x00, 0x00, 0x00, 0x03, 0x00, 0x02, 0x6d, 0x31, 0x00, 0x00, 0x00, 0x17, 0x00,
+		0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00, 0x01, 0x63, 0x00, 0x02, 0x67, 0x31, 0x00, 0x00,
+		0x00, 0x03, 0x00, 0x02, 0x6d, 0x31,
+	}
+	responses := []byte{
+		0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x07, 0x00, 0x1b, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00,
+		0x00, 0x08, 0x00, 0x00,
+	}
+	tests := []struct {
+		in        []byte
+		direction dumper.Direction
+		expected  []int32 // correlation_id of records
+	}{
+		{requests, dumper.SrcToDst, []int32{7, 8}},
+		{responses, dumper.DstToSrc, []int32{7, 8}},
+	}
+
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+	for _, tt := range tests {
+		records, err := d.ReadFrames(tt.in, tt.direction, connMetadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := []int32{}
+		for _, r := range records {
+			for _, v := range r {
+				if v.Key == "correlation_id" {
+					actual = append(actual, v.Value.(int32))
+				}
+			}
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("actual %v\nwant %v", actual, tt.expected)
+		}
+	}
+	if internal := connMetadata.Internal.(connMetadataInternal); len(internal.requests) != 0 {
+		t.Errorf("actual %v\nwant no requests waiting for response", internal.requests)
+	}
+
+	connMetadata = d.NewConnMetadata()
+	for _, tt := range tests {
+		if err := d.Dump(tt.in, tt.direction, connMetadata, []dumper.DumpValue{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if actual := bytes.Count(out.Bytes(), []byte("\n")); actual != 4 {
+		t.Errorf("actual %d lines\nwant %d", actual, 4)
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
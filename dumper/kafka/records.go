@@ -0,0 +1,37 @@
+package kafka
+
+import (
+	"encoding/binary"
+)
+
+const (
+	batchHeaderLength = 12 // baseOffset + length
+	magicOffset       = 16
+	recordCountOffset = 57
+)
+
+// countRecords count records of RecordBatch ( magic v2 ) or MessageSet ( magic v0, v1 )
+// https://kafka.apache.org/documentation/#recordbatch
+func countRecords(b []byte) int {
+	count := 0
+	for len(b) >= batchHeaderLength {
+		l := int(int32(binary.BigEndian.Uint32(b[8:12])))
+		if l < 0 || len(b) < batchHeaderLength+l || len(b) <= magicOffset {
+			// partial batch ( Fetch response may contain it )
+			break
+		}
+		switch b[magicOffset] {
+		case 2:
+			if len(b) < recordCountOffset+4 {
+				return count
+			}
+			if n := int(int32(binary.BigEndian.Uint32(b[recordCountOffset : recordCountOffset+4]))); n > 0 {
+				count += n
+			}
+		default:
+			count++
+		}
+		b = b[batchHeaderLength+l:]
+	}
+	return count
+}
@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"testing"
+)
+
+var countRecordsTests = []struct {
+	description string
+	in          []byte
+	expected    int
+}{
+	{
+		"RecordBatch ( magic v2 )",
+		[]byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x32, 0x00, 0x00, 0x00, 0x00,
+			0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x03, 0x00,
+		},
+		3,
+	},
+	{
+		"MessageSet ( magic v1 ) with 2 messages",
+		[]byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x00,
+		},
+		2,
+	},
+	{
+		"Partial batch at the end of Fetch response",
+		[]byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x01,
+		},
+		1,
+	},
+	{
+		"Empty",
+		[]byte{},
+		0,
+	},
+}
+
+func TestCountRecords(t *testing.T) {
+	for _, tt := range countRecordsTests {
+		actual := countRecords(tt.in)
+		if actual != tt.expected {
+			t.Errorf("%s:\nactual %#v\nwant %#v", tt.description, actual, tt.expected)
+		}
+	}
+}
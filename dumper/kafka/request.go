@@ -0,0 +1,528 @@
+package kafka
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// requestReaders read body of request
+var requestReaders = map[int16]func(d *decoder, version int16) []dumper.DumpValue{
+	apiProduce:                readProduceRequest,
+	apiFetch:                  readFetchRequest,
+	apiMetadata:               readMetadataRequest,
+	apiOffsetCommit:           readOffsetCommitRequest,
+	apiOffsetFetch:            readOffsetFetchRequest,
+	apiFindCoordinator:        readFindCoordinatorRequest,
+	apiJoinGroup:              readJoinGroupRequest,
+	apiHeartbeat:              readHeartbeatRequest,
+	apiLeaveGroup:             readLeaveGroupRequest,
+	apiSyncGroup:              readSyncGroupRequest,
+	apiDescribeGroups:         readDescribeGroupsRequest,
+	apiSaslHandshake:          readSaslHandshakeRequest,
+	apiVersions:               readAPIVersionsRequest,
+	apiDeleteGroups:           readDeleteGroupsRequest,
+	apiConsumerGroupHeartbeat: readConsumerGroupHeartbeatRequest,
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_Produce
+func readProduceRequest(d *decoder, version int16) []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	if version >= 3 {
+		if transactionalID, ok := d.nullableString(); ok {
+			values = append(values, dumper.DumpValue{
+				Key:   "transactional_id",
+				Value: transactionalID,
+			})
+		}
+	}
+	acks := d.int16()
+	_ = d.int32() // timeout_ms
+	if d.err != nil {
+		return values
+	}
+	values = append(values, dumper.DumpValue{
+		Key:   "acks",
+		Value: acks,
+	})
+
+	topics := []string{}
+	partitions := []map[string]interface{}{}
+	total := 0
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		topic := readTopic(d, version >= 13)
+		topics = append(topics, topic)
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			partition := d.int32()
+			records := d.bytes()
+			d.taggedFields()
+			if d.err != nil {
+				break
+			}
+			count := countRecords(records)
+			total += count
+			partitions = append(partitions, map[string]interface{}{
+				"topic":        topic,
+				"partition":    partition,
+				"record_count": count,
+				"bytes":        len(records),
+			})
+		}
+		d.taggedFields()
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "topics",
+		Value: topics,
+	}, dumper.DumpValue{
+		Key:   "partitions",
+		Value: partitions,
+	}, dumper.DumpValue{
+		Key:   "record_count",
+		Value: total,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_Fetch
+func readFetchRequest(d *decoder, version int16) []dumper.DumpValue {
+	if version <= 14 {
+		_ = d.int32() // replica_id
+	}
+	maxWaitMs := d.int32()
+	minBytes := d.int32()
+	if version >= 3 {
+		_ = d.int32() // max_bytes
+	}
+	if version >= 4 {
+		_ = d.int8() // isolation_level
+	}
+	if version >= 7 {
+		_ = d.int32() // session_id
+		_ = d.int32() // session_epoch
+	}
+	if d.err != nil {
+		return []dumper.DumpValue{}
+	}
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "max_wait_ms",
+			Value: maxWaitMs,
+		},
+		dumper.DumpValue{
+			Key:   "min_bytes",
+			Value: minBytes,
+		},
+	}
+
+	topics := []string{}
+	partitions := []map[string]interface{}{}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		topic := readTopic(d, version >= 13)
+		topics = append(topics, topic)
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			partition := d.int32()
+			if version >= 9 {
+				_ = d.int32() // current_leader_epoch
+			}
+			fetchOffset := d.int64()
+			if version >= 12 {
+				_ = d.int32() // last_fetched_epoch
+			}
+			if version >= 5 {
+				_ = d.int64() // log_start_offset
+			}
+			_ = d.int32() // partition_max_bytes
+			d.taggedFields()
+			if d.err != nil {
+				break
+			}
+			partitions = append(partitions, map[string]interface{}{
+				"topic":        topic,
+				"partition":    partition,
+				"fetch_offset": fetchOffset,
+			})
+		}
+		d.taggedFields()
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "topics",
+		Value: topics,
+	}, dumper.DumpValue{
+		Key:   "partitions",
+		Value: partitions,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_Metadata
+func readMetadataRequest(d *decoder, version int16) []dumper.DumpValue {
+	n := d.arrayLength()
+	if n < 0 {
+		// all topics
+		return []dumper.DumpValue{}
+	}
+	topics := []string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		if version >= 10 {
+			_ = d.uuid() // topic_id
+			if name, ok := d.nullableString(); ok {
+				topics = append(topics, name)
+			}
+		} else {
+			topics = append(topics, d.string())
+		}
+		d.taggedFields()
+	}
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "topics",
+			Value: topics,
+		},
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_OffsetCommit
+func readOffsetCommitRequest(d *decoder, version int16) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "group_id",
+			Value: d.string(),
+		},
+	}
+	if version >= 1 {
+		generationID := d.int32()
+		memberID := d.string()
+		values = append(values, dumper.DumpValue{
+			Key:   "generation_id",
+			Value: generationID,
+		}, dumper.DumpValue{
+			Key:   "member_id",
+			Value: memberID,
+		})
+	}
+	if version >= 7 {
+		values = appendGroupInstanceID(values, d)
+	}
+	if version >= 2 && version <= 4 {
+		_ = d.int64() // retention_time_ms
+	}
+	if d.err != nil {
+		return values
+	}
+
+	topics := []string{}
+	partitions := []map[string]interface{}{}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		topic := d.string()
+		topics = append(topics, topic)
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			partition := d.int32()
+			offset := d.int64()
+			if version >= 6 {
+				_ = d.int32() // committed_leader_epoch
+			}
+			if version == 1 {
+				_ = d.int64() // commit_timestamp
+			}
+			_, _ = d.nullableString() // committed_metadata
+			d.taggedFields()
+			if d.err != nil {
+				break
+			}
+			partitions = append(partitions, map[string]interface{}{
+				"topic":     topic,
+				"partition": partition,
+				"offset":    offset,
+			})
+		}
+		d.taggedFields()
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "topics",
+		Value: topics,
+	}, dumper.DumpValue{
+		Key:   "partitions",
+		Value: partitions,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_OffsetFetch
+func readOffsetFetchRequest(d *decoder, version int16) []dumper.DumpValue {
+	if version <= 7 {
+		values := []dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "group_id",
+				Value: d.string(),
+			},
+		}
+		if topics := readOffsetFetchTopics(d); topics != nil {
+			values = append(values, dumper.DumpValue{
+				Key:   "topics",
+				Value: topics,
+			})
+		}
+		return values
+	}
+	groupIDs := []string{}
+	topics := []string{}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		groupIDs = append(groupIDs, d.string())
+		if version >= 9 {
+			_, _ = d.nullableString() // member_id
+			_ = d.int32()             // member_epoch
+		}
+		topics = append(topics, readOffsetFetchTopics(d)...)
+		d.taggedFields()
+	}
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "group_ids",
+			Value: groupIDs,
+		},
+		dumper.DumpValue{
+			Key:   "topics",
+			Value: topics,
+		},
+	}
+}
+
+func readOffsetFetchTopics(d *decoder) []string {
+	n := d.arrayLength()
+	if n < 0 {
+		// all topics
+		return nil
+	}
+	topics := []string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		topics = append(topics, d.string())
+		d.int32Array() // partition_indexes
+		d.taggedFields()
+	}
+	return topics
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_FindCoordinator
+func readFindCoordinatorRequest(d *decoder, version int16) []dumper.DumpValue {
+	var keys []string
+	if version <= 3 {
+		keys = []string{d.string()}
+	}
+	keyType := int8(0)
+	if version >= 1 {
+		keyType = d.int8()
+	}
+	if version >= 4 {
+		keys = d.stringArray()
+	}
+	if d.err != nil {
+		return []dumper.DumpValue{}
+	}
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "key_type",
+			Value: keyType,
+		},
+		dumper.DumpValue{
+			Key:   "coordinator_keys",
+			Value: keys,
+		},
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_JoinGroup
+func readJoinGroupRequest(d *decoder, version int16) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "group_id",
+			Value: d.string(),
+		},
+	}
+	_ = d.int32() // session_timeout_ms
+	if version >= 1 {
+		_ = d.int32() // rebalance_timeout_ms
+	}
+	values = append(values, dumper.DumpValue{
+		Key:   "member_id",
+		Value: d.string(),
+	})
+	if version >= 5 {
+		values = appendGroupInstanceID(values, d)
+	}
+	protocolType := d.string()
+	protocols := []string{}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		protocols = append(protocols, d.string())
+		_ = d.bytes() // metadata
+		d.taggedFields()
+	}
+	if d.err != nil {
+		return values
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "protocol_type",
+		Value: protocolType,
+	}, dumper.DumpValue{
+		Key:   "protocols",
+		Value: protocols,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_Heartbeat
+func readHeartbeatRequest(d *decoder, version int16) []dumper.DumpValue {
+	values := readGroupMember(d)
+	if version >= 3 {
+		values = appendGroupInstanceID(values, d)
+	}
+	return values
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_LeaveGroup
+func readLeaveGroupRequest(d *decoder, version int16) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "group_id",
+			Value: d.string(),
+		},
+	}
+	if version <= 2 {
+		return append(values, dumper.DumpValue{
+			Key:   "member_id",
+			Value: d.string(),
+		})
+	}
+	memberIDs := []string{}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		memberIDs = append(memberIDs, d.string())
+		_, _ = d.nullableString() // group_instance_id
+		if version >= 5 {
+			_, _ = d.nullableString() // reason
+		}
+		d.taggedFields()
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "member_ids",
+		Value: memberIDs,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_SyncGroup
+func readSyncGroupRequest(d *decoder, version int16) []dumper.DumpValue {
+	values := readGroupMember(d)
+	if version >= 3 {
+		values = appendGroupInstanceID(values, d)
+	}
+	return values
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_DescribeGroups
+func readDescribeGroupsRequest(d *decoder, version int16) []dumper.DumpValue {
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "group_ids",
+			Value: d.stringArray(),
+		},
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_DeleteGroups
+func readDeleteGroupsRequest(d *decoder, version int16) []dumper.DumpValue {
+	return readDescribeGroupsRequest(d, version)
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_ConsumerGroupHeartbeat
+func readConsumerGroupHeartbeatRequest(d *decoder, version int16) []dumper.DumpValue {
+	groupID := d.string()
+	memberID := d.string()
+	memberEpoch := d.int32()
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "group_id",
+			Value: groupID,
+		},
+		dumper.DumpValue{
+			Key:   "member_id",
+			Value: memberID,
+		},
+		dumper.DumpValue{
+			Key:   "member_epoch",
+			Value: memberEpoch,
+		},
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_SaslHandshake
+func readSaslHandshakeRequest(d *decoder, version int16) []dumper.DumpValue {
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "sasl_mechanism",
+			Value: d.string(),
+		},
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_ApiVersions
+func readAPIVersionsRequest(d *decoder, version int16) []dumper.DumpValue {
+	if version < 3 {
+		return []dumper.DumpValue{}
+	}
+	name := d.string()
+	ver := d.string()
+	if d.err != nil {
+		return []dumper.DumpValue{}
+	}
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "client_software_name",
+			Value: name,
+		},
+		dumper.DumpValue{
+			Key:   "client_software_version",
+			Value: ver,
+		},
+	}
+}
+
+// readGroupMember read group_id, generation_id and member_id
+func readGroupMember(d *decoder) []dumper.DumpValue {
+	groupID := d.string()
+	generationID := d.int32()
+	memberID := d.string()
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "group_id",
+			Value: groupID,
+		},
+		dumper.DumpValue{
+			Key:   "generation_id",
+			Value: generationID,
+		},
+		dumper.DumpValue{
+			Key:   "member_id",
+			Value: memberID,
+		},
+	}
+}
+
+func appendGroupInstanceID(values []dumper.DumpValue, d *decoder) []dumper.DumpValue {
+	groupInstanceID, ok := d.nullableString()
+	if !ok {
+		return values
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "group_instance_id",
+		Value: groupInstanceID,
+	})
+}
+
+// readTopic read topic name or topic ID
+func readTopic(d *decoder, topicID bool) string {
+	if topicID {
+		return d.uuid()
+	}
+	return d.string()
+}
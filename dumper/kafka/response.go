@@ -0,0 +1,515 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// response is decoded body of response
+type response struct {
+	errorDecoded bool
+	errorCode    int16 // first non-zero error code
+	errorMessage string
+	values       []dumper.DumpValue
+}
+
+func (r *response) setError(code int16) {
+	r.errorDecoded = true
+	if r.errorCode == 0 {
+		r.errorCode = code
+	}
+}
+
+func (r *response) setErrorMessage(message string, ok bool) {
+	if ok && message != "" && r.errorMessage == "" {
+		r.errorMessage = message
+	}
+}
+
+func (r *response) dumpValues() []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	if r.errorDecoded {
+		values = append(values, dumper.DumpValue{
+			Key:   "error_code",
+			Value: r.errorCode,
+		})
+		if r.errorCode != 0 {
+			values = append(values, dumper.DumpValue{
+				Key:   "error_name",
+				Value: errorName(r.errorCode),
+			})
+		}
+	}
+	if r.errorMessage != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "error_message",
+			Value: r.errorMessage,
+		})
+	}
+	return append(values, r.values...)
+}
+
+// responseReaders read body of response
+var responseReaders = map[int16]func(d *decoder, version int16, r *response){
+	apiProduce:                readProduceResponse,
+	apiFetch:                  readFetchResponse,
+	apiMetadata:               readMetadataResponse,
+	apiOffsetCommit:           readOffsetCommitResponse,
+	apiOffsetFetch:            readOffsetFetchResponse,
+	apiFindCoordinator:        readFindCoordinatorResponse,
+	apiJoinGroup:              readJoinGroupResponse,
+	apiHeartbeat:              readThrottleAndErrorResponse(1),
+	apiLeaveGroup:             readLeaveGroupResponse,
+	apiSyncGroup:              readThrottleAndErrorResponse(1),
+	apiDescribeGroups:         readDescribeGroupsResponse,
+	apiListGroups:             readListGroupsResponse,
+	apiSaslHandshake:          readThrottleAndErrorResponse(-1),
+	apiVersions:               readThrottleAndErrorResponse(-1),
+	apiSaslAuthenticate:       readSaslAuthenticateResponse,
+	apiDeleteGroups:           readDeleteGroupsResponse,
+	apiConsumerGroupHeartbeat: readConsumerGroupHeartbeatResponse,
+}
+
+// readThrottleAndErrorResponse returns reader of response starts with [throttle_time_ms] error_code
+func readThrottleAndErrorResponse(throttleVersion int16) func(d *decoder, version int16, r *response) {
+	return func(d *decoder, version int16, r *response) {
+		if throttleVersion >= 0 && version >= throttleVersion {
+			_ = d.int32() // throttle_time_ms
+		}
+		code := d.int16()
+		if d.err == nil {
+			r.setError(code)
+		}
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_Produce
+func readProduceResponse(d *decoder, version int16, r *response) {
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = readTopic(d, version >= 13)
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			_ = d.int32() // index
+			code := d.int16()
+			if d.err != nil {
+				return
+			}
+			r.setError(code)
+			_ = d.int64() // base_offset
+			if version >= 2 {
+				_ = d.int64() // log_append_time_ms
+			}
+			if version >= 5 {
+				_ = d.int64() // log_start_offset
+			}
+			if version >= 8 {
+				rn := d.arrayLength() // record_errors
+				for k := 0; k < rn && d.err == nil; k++ {
+					_ = d.int32()             // batch_index
+					_, _ = d.nullableString() // batch_index_error_message
+					d.taggedFields()
+				}
+				r.setErrorMessage(d.nullableString())
+			}
+			d.taggedFields()
+		}
+		d.taggedFields()
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_Fetch
+func readFetchResponse(d *decoder, version int16, r *response) {
+	if version >= 1 {
+		_ = d.int32() // throttle_time_ms
+	}
+	if version >= 7 {
+		code := d.int16()
+		_ = d.int32() // session_id
+		if d.err != nil {
+			return
+		}
+		r.setError(code)
+	}
+	topics := []string{}
+	total := 0
+	bytes := 0
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		topics = append(topics, readTopic(d, version >= 13))
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			_ = d.int32() // partition_index
+			code := d.int16()
+			if d.err != nil {
+				break
+			}
+			r.setError(code)
+			_ = d.int64() // high_watermark
+			if version >= 4 {
+				_ = d.int64() // last_stable_offset
+			}
+			if version >= 5 {
+				_ = d.int64() // log_start_offset
+			}
+			if version >= 4 {
+				an := d.arrayLength() // aborted_transactions
+				for k := 0; k < an && d.err == nil; k++ {
+					_ = d.int64() // producer_id
+					_ = d.int64() // first_offset
+					d.taggedFields()
+				}
+			}
+			if version >= 11 {
+				_ = d.int32() // preferred_read_replica
+			}
+			records := d.bytes()
+			d.taggedFields()
+			if d.err != nil {
+				break
+			}
+			total += countRecords(records)
+			bytes += len(records)
+		}
+		d.taggedFields()
+	}
+	r.values = append(r.values, dumper.DumpValue{
+		Key:   "topics",
+		Value: topics,
+	}, dumper.DumpValue{
+		Key:   "record_count",
+		Value: total,
+	}, dumper.DumpValue{
+		Key:   "bytes",
+		Value: bytes,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_Metadata
+func readMetadataResponse(d *decoder, version int16, r *response) {
+	if version >= 3 {
+		_ = d.int32() // throttle_time_ms
+	}
+	brokers := []string{}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = d.int32() // node_id
+		host := d.string()
+		port := d.int32()
+		if version >= 1 {
+			_, _ = d.nullableString() // rack
+		}
+		d.taggedFields()
+		if d.err == nil {
+			brokers = append(brokers, fmt.Sprintf("%s:%d", host, port))
+		}
+	}
+	r.values = append(r.values, dumper.DumpValue{
+		Key:   "brokers",
+		Value: brokers,
+	})
+	if version >= 2 {
+		_, _ = d.nullableString() // cluster_id
+	}
+	if version >= 1 {
+		_ = d.int32() // controller_id
+	}
+	topics := []string{}
+	n = d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		code := d.int16()
+		name, _ := d.nullableString()
+		if version >= 10 {
+			_ = d.uuid() // topic_id
+		}
+		if version >= 1 {
+			_ = d.bool() // is_internal
+		}
+		if d.err != nil {
+			break
+		}
+		r.setError(code)
+		topics = append(topics, name)
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			code := d.int16()
+			_ = d.int32() // partition_index
+			_ = d.int32() // leader_id
+			if version >= 7 {
+				_ = d.int32() // leader_epoch
+			}
+			d.int32Array() // replica_nodes
+			d.int32Array() // isr_nodes
+			if version >= 5 {
+				d.int32Array() // offline_replicas
+			}
+			d.taggedFields()
+			if d.err == nil {
+				r.setError(code)
+			}
+		}
+		if version >= 8 {
+			_ = d.int32() // topic_authorized_operations
+		}
+		d.taggedFields()
+	}
+	r.values = append(r.values, dumper.DumpValue{
+		Key:   "topics",
+		Value: topics,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_OffsetCommit
+func readOffsetCommitResponse(d *decoder, version int16, r *response) {
+	if version >= 3 {
+		_ = d.int32() // throttle_time_ms
+	}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = d.string() // name
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			_ = d.int32() // partition_index
+			code := d.int16()
+			d.taggedFields()
+			if d.err == nil {
+				r.setError(code)
+			}
+		}
+		d.taggedFields()
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_OffsetFetch
+func readOffsetFetchResponse(d *decoder, version int16, r *response) {
+	if version >= 3 {
+		_ = d.int32() // throttle_time_ms
+	}
+	if version <= 7 {
+		readOffsetFetchResponseTopics(d, version, r)
+		if version >= 2 {
+			code := d.int16()
+			if d.err == nil {
+				r.setError(code)
+			}
+		}
+		return
+	}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = d.string() // group_id
+		readOffsetFetchResponseTopics(d, version, r)
+		code := d.int16()
+		d.taggedFields()
+		if d.err == nil {
+			r.setError(code)
+		}
+	}
+}
+
+func readOffsetFetchResponseTopics(d *decoder, version int16, r *response) {
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = d.string() // name
+		m := d.arrayLength()
+		for j := 0; j < m && d.err == nil; j++ {
+			_ = d.int32() // partition_index
+			_ = d.int64() // committed_offset
+			if version >= 5 {
+				_ = d.int32() // committed_leader_epoch
+			}
+			_, _ = d.nullableString() // metadata
+			code := d.int16()
+			d.taggedFields()
+			if d.err == nil {
+				r.setError(code)
+			}
+		}
+		d.taggedFields()
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_FindCoordinator
+func readFindCoordinatorResponse(d *decoder, version int16, r *response) {
+	if version >= 1 {
+		_ = d.int32() // throttle_time_ms
+	}
+	coordinators := []string{}
+	if version <= 3 {
+		code := d.int16()
+		if version >= 1 {
+			r.setErrorMessage(d.nullableString())
+		}
+		_ = d.int32() // node_id
+		host := d.string()
+		port := d.int32()
+		if d.err != nil {
+			return
+		}
+		r.setError(code)
+		if code == 0 {
+			coordinators = append(coordinators, fmt.Sprintf("%s:%d", host, port))
+		}
+	} else {
+		n := d.arrayLength()
+		for i := 0; i < n && d.err == nil; i++ {
+			_ = d.string() // key
+			_ = d.int32()  // node_id
+			host := d.string()
+			port := d.int32()
+			code := d.int16()
+			r.setErrorMessage(d.nullableString())
+			d.taggedFields()
+			if d.err != nil {
+				break
+			}
+			r.setError(code)
+			if code == 0 {
+				coordinators = append(coordinators, fmt.Sprintf("%s:%d", host, port))
+			}
+		}
+	}
+	r.values = append(r.values, dumper.DumpValue{
+		Key:   "coordinators",
+		Value: coordinators,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_JoinGroup
+func readJoinGroupResponse(d *decoder, version int16, r *response) {
+	if version >= 2 {
+		_ = d.int32() // throttle_time_ms
+	}
+	code := d.int16()
+	generationID := d.int32()
+	if version >= 7 {
+		_, _ = d.nullableString() // protocol_type
+	}
+	protocolName, _ := d.nullableString()
+	leader := d.string()
+	if version >= 9 {
+		_ = d.bool() // skip_assignment
+	}
+	memberID := d.string()
+	if d.err != nil {
+		return
+	}
+	r.setError(code)
+	r.values = append(r.values, dumper.DumpValue{
+		Key:   "generation_id",
+		Value: generationID,
+	}, dumper.DumpValue{
+		Key:   "protocol_name",
+		Value: protocolName,
+	}, dumper.DumpValue{
+		Key:   "leader",
+		Value: leader,
+	}, dumper.DumpValue{
+		Key:   "member_id",
+		Value: memberID,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_LeaveGroup
+func readLeaveGroupResponse(d *decoder, version int16, r *response) {
+	readThrottleAndErrorResponse(1)(d, version, r)
+	if version < 3 {
+		return
+	}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = d.string()            // member_id
+		_, _ = d.nullableString() // group_instance_id
+		code := d.int16()
+		d.taggedFields()
+		if d.err == nil {
+			r.setError(code)
+		}
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_DescribeGroups
+func readDescribeGroupsResponse(d *decoder, version int16, r *response) {
+	if version >= 1 {
+		_ = d.int32() // throttle_time_ms
+	}
+	// decode only error_code of the first group ( members are not decoded )
+	if n := d.arrayLength(); n > 0 {
+		code := d.int16()
+		if d.err == nil {
+			r.setError(code)
+		}
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_ListGroups
+func readListGroupsResponse(d *decoder, version int16, r *response) {
+	readThrottleAndErrorResponse(1)(d, version, r)
+	groupIDs := []string{}
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		groupID := d.string()
+		_ = d.string() // protocol_type
+		if version >= 4 {
+			_ = d.string() // group_state
+		}
+		if version >= 5 {
+			_ = d.string() // group_type
+		}
+		d.taggedFields()
+		if d.err == nil {
+			groupIDs = append(groupIDs, groupID)
+		}
+	}
+	r.values = append(r.values, dumper.DumpValue{
+		Key:   "group_ids",
+		Value: groupIDs,
+	})
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_SaslAuthenticate
+func readSaslAuthenticateResponse(d *decoder, version int16, r *response) {
+	code := d.int16()
+	message, ok := d.nullableString()
+	if d.err != nil {
+		return
+	}
+	r.setError(code)
+	r.setErrorMessage(message, ok)
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_DeleteGroups
+func readDeleteGroupsResponse(d *decoder, version int16, r *response) {
+	_ = d.int32() // throttle_time_ms
+	n := d.arrayLength()
+	for i := 0; i < n && d.err == nil; i++ {
+		_ = d.string() // group_id
+		code := d.int16()
+		d.taggedFields()
+		if d.err == nil {
+			r.setError(code)
+		}
+	}
+}
+
+// https://kafka.apache.org/protocol.html#The_Messages_ConsumerGroupHeartbeat
+func readConsumerGroupHeartbeatResponse(d *decoder, version int16, r *response) {
+	_ = d.int32() // throttle_time_ms
+	code := d.int16()
+	message, ok := d.nullableString()
+	memberID, _ := d.nullableString()
+	memberEpoch := d.int32()
+	if d.err != nil {
+		return
+	}
+	r.setError(code)
+	r.setErrorMessage(message, ok)
+	r.values = append(r.values, dumper.DumpValue{
+		Key:   "member_id",
+		Value: memberID,
+	}, dumper.DumpValue{
+		Key:   "member_epoch",
+		Value: memberEpoch,
+	})
+}
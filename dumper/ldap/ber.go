@@ -0,0 +1,90 @@
+package ldap
+
+// element is BER encoded element ( definite length only )
+// https://tools.ietf.org/html/rfc4511#section-5.1
+type element struct {
+	class       uint8
+	constructed bool
+	tag         int
+	value       []byte
+}
+
+// readElement returns the first element and the rest
+func readElement(b []byte) (element, []byte, bool) {
+	e, headerLength, l, ok := readElementHeader(b)
+	if !ok || len(b) < headerLength+l {
+		return element{}, nil, false
+	}
+	e.value = b[headerLength : headerLength+l]
+	return e, b[headerLength+l:], true
+}
+
+// readElementHeader returns identifier, header length and content length of element
+func readElementHeader(b []byte) (element, int, int, bool) {
+	if len(b) < 2 {
+		return element{}, 0, 0, false
+	}
+	e := element{
+		class:       b[0] & 0xc0,
+		constructed: b[0]&0x20 > 0,
+		tag:         int(b[0] & 0x1f),
+	}
+	if e.tag == 0x1f {
+		// high-tag-number form is not used in LDAP
+		return element{}, 0, 0, false
+	}
+	if b[1]&0x80 == 0 {
+		return e, 2, int(b[1]), true
+	}
+	n := int(b[1] & 0x7f)
+	if n == 0 || n > 4 || len(b) < 2+n {
+		// indefinite length is not allowed in LDAP
+		return element{}, 0, 0, false
+	}
+	l := 0
+	for _, c := range b[2 : 2+n] {
+		l = l<<8 | int(c)
+	}
+	if l < 0 {
+		return element{}, 0, 0, false
+	}
+	return e, 2 + n, l, true
+}
+
+// readElements returns elements of constructed element
+func readElements(b []byte) ([]element, bool) {
+	elements := []element{}
+	for len(b) > 0 {
+		e, rest, ok := readElement(b)
+		if !ok {
+			return elements, false
+		}
+		elements = append(elements, e)
+		b = rest
+	}
+	return elements, true
+}
+
+func (e element) is(class uint8, tag int) bool {
+	return e.class == class && e.tag == tag
+}
+
+// int returns INTEGER or ENUMERATED
+func (e element) int() (int64, bool) {
+	if len(e.value) == 0 || len(e.value) > 8 {
+		return 0, false
+	}
+	v := int64(int8(e.value[0]))
+	for _, c := range e.value[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, true
+}
+
+func (e element) bool() bool {
+	return len(e.value) > 0 && e.value[0] != 0
+}
+
+func (e element) string() string {
+	return string(e.value)
+}
@@ -0,0 +1,147 @@
+package ldap
+
+// maxMessageSize is max size of buffered LDAPMessage. The rest of larger message is skipped
+const maxMessageSize = 64 * 1024
+
+// maxFilterDepth is max depth of nested filter
+const maxFilterDepth = 32
+
+// maxSearches is max number of searches waiting for SearchResultDone
+const maxSearches = 1000
+
+// oidStartTLS is requestName of StartTLS extended operation
+// https://tools.ietf.org/html/rfc4511#section-4.14.1
+const oidStartTLS = "1.3.6.1.4.1.1466.20037"
+
+// BER classes
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+)
+
+// universal tags
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x10
+	tagSet         = 0x11
+)
+
+// protocolOp ( application tag number )
+// https://tools.ietf.org/html/rfc4511#section-4.2
+const (
+	opBindRequest           = 0
+	opBindResponse          = 1
+	opUnbindRequest         = 2
+	opSearchRequest         = 3
+	opSearchResultEntry     = 4
+	opSearchResultDone      = 5
+	opModifyRequest         = 6
+	opModifyResponse        = 7
+	opAddRequest            = 8
+	opAddResponse           = 9
+	opDelRequest            = 10
+	opDelResponse           = 11
+	opModifyDNRequest       = 12
+	opModifyDNResponse      = 13
+	opCompareRequest        = 14
+	opCompareResponse       = 15
+	opAbandonRequest        = 16
+	opSearchResultReference = 19
+	opExtendedRequest       = 23
+	opExtendedResponse      = 24
+	opIntermediateResponse  = 25
+)
+
+var operationNames = map[int]string{
+	opBindRequest:           "BindRequest",
+	opBindResponse:          "BindResponse",
+	opUnbindRequest:         "UnbindRequest",
+	opSearchRequest:         "SearchRequest",
+	opSearchResultEntry:     "SearchResultEntry",
+	opSearchResultDone:      "SearchResultDone",
+	opModifyRequest:         "ModifyRequest",
+	opModifyResponse:        "ModifyResponse",
+	opAddRequest:            "AddRequest",
+	opAddResponse:           "AddResponse",
+	opDelRequest:            "DelRequest",
+	opDelResponse:           "DelResponse",
+	opModifyDNRequest:       "ModifyDNRequest",
+	opModifyDNResponse:      "ModifyDNResponse",
+	opCompareRequest:        "CompareRequest",
+	opCompareResponse:       "CompareResponse",
+	opAbandonRequest:        "AbandonRequest",
+	opSearchResultReference: "SearchResultReference",
+	opExtendedRequest:       "ExtendedRequest",
+	opExtendedResponse:      "ExtendedResponse",
+	opIntermediateResponse:  "IntermediateResponse",
+}
+
+var scopeNames = map[int64]string{
+	0: "baseObject",
+	1: "singleLevel",
+	2: "wholeSubtree",
+	3: "subordinateSubtree",
+}
+
+var modifyOperationNames = map[int64]string{
+	0: "add",
+	1: "delete",
+	2: "replace",
+	3: "increment",
+}
+
+// https://tools.ietf.org/html/rfc4511#appendix-A
+var resultCodeNames = map[int64]string{
+	0:  "success",
+	1:  "operationsError",
+	2:  "protocolError",
+	3:  "timeLimitExceeded",
+	4:  "sizeLimitExceeded",
+	5:  "compareFalse",
+	6:  "compareTrue",
+	7:  "authMethodNotSupported",
+	8:  "strongerAuthRequired",
+	10: "referral",
+	11: "adminLimitExceeded",
+	12: "unavailableCriticalExtension",
+	13: "confidentialityRequired",
+	14: "saslBindInProgress",
+	16: "noSuchAttribute",
+	17: "undefinedAttributeType",
+	18: "inappropriateMatching",
+	19: "constraintViolation",
+	20: "attributeOrValueExists",
+	21: "invalidAttributeSyntax",
+	32: "noSuchObject",
+	33: "aliasProblem",
+	34: "invalidDNSyntax",
+	36: "aliasDereferencingProblem",
+	48: "inappropriateAuthentication",
+	49: "invalidCredentials",
+	50: "insufficientAccessRights",
+	51: "busy",
+	52: "unavailable",
+	53: "unwillingToPerform",
+	54: "loopDetect",
+	64: "namingViolation",
+	65: "objectClassViolation",
+	66: "notAllowedOnNonLeaf",
+	67: "notAllowedOnRDN",
+	68: "entryAlreadyExists",
+	69: "objectClassModsProhibited",
+	71: "affectsMultipleDSAs",
+	80: "other",
+}
+
+// well-known extended operations
+var extendedOperationNames = map[string]string{
+	oidStartTLS:               "StartTLS",
+	"1.3.6.1.4.1.4203.1.11.1": "PasswordModify",
+	"1.3.6.1.4.1.4203.1.11.3": "WhoAmI",
+	"1.3.6.1.1.8":             "Cancel",
+	"1.3.6.1.4.1.1466.20036":  "NoticeOfDisconnection",
+}
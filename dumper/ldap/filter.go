@@ -0,0 +1,116 @@
+package ldap
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// filterString returns string representation of search filter
+// https://tools.ietf.org/html/rfc4515
+func filterString(e element, depth int) (string, bool) {
+	if depth >= maxFilterDepth || e.class != classContext {
+		return "", false
+	}
+	switch e.tag {
+	case 0, 1, 2:
+		// and / or / not
+		op := map[int]string{0: "&", 1: "|", 2: "!"}[e.tag]
+		children, ok := readElements(e.value)
+		if !ok || (e.tag == 2 && len(children) != 1) {
+			return "", false
+		}
+		s := "(" + op
+		for _, c := range children {
+			f, ok := filterString(c, depth+1)
+			if !ok {
+				return "", false
+			}
+			s += f
+		}
+		return s + ")", true
+	case 3, 5, 6, 8:
+		// equalityMatch / greaterOrEqual / lessOrEqual / approxMatch
+		op := map[int]string{3: "=", 5: ">=", 6: "<=", 8: "~="}[e.tag]
+		ava, ok := readElements(e.value)
+		if !ok || len(ava) != 2 {
+			return "", false
+		}
+		return "(" + ava[0].string() + op + escapeValue(ava[1].value) + ")", true
+	case 4:
+		// substrings
+		s, ok := readElements(e.value)
+		if !ok || len(s) != 2 {
+			return "", false
+		}
+		subs, ok := readElements(s[1].value)
+		if !ok {
+			return "", false
+		}
+		initial, final := "", ""
+		middle := []string{}
+		for _, sub := range subs {
+			switch sub.tag {
+			case 0:
+				initial = escapeValue(sub.value)
+			case 1:
+				middle = append(middle, escapeValue(sub.value))
+			case 2:
+				final = escapeValue(sub.value)
+			}
+		}
+		v := initial + "*"
+		for _, a := range middle {
+			v += a + "*"
+		}
+		return "(" + s[0].string() + "=" + v + final + ")", true
+	case 7:
+		// present
+		return "(" + e.string() + "=*)", true
+	case 9:
+		// extensibleMatch
+		parts, ok := readElements(e.value)
+		if !ok {
+			return "", false
+		}
+		var rule, attr, value string
+		dn := false
+		for _, p := range parts {
+			switch p.tag {
+			case 1:
+				rule = p.string()
+			case 2:
+				attr = p.string()
+			case 3:
+				value = escapeValue(p.value)
+			case 4:
+				dn = p.bool()
+			}
+		}
+		s := "(" + attr
+		if dn {
+			s += ":dn"
+		}
+		if rule != "" {
+			s += ":" + rule
+		}
+		return s + ":=" + value + ")", true
+	}
+	return "", false
+}
+
+// escapeValue escape assertion value ( UTF-8 is not escaped )
+// https://tools.ietf.org/html/rfc4515#section-3
+func escapeValue(b []byte) string {
+	var sb strings.Builder
+	printable := utf8.Valid(b)
+	for _, c := range b {
+		switch {
+		case c == '*', c == '(', c == ')', c == '\\', c < 0x20, c == 0x7f, c > 0x7f && !printable:
+			sb.WriteString(fmt.Sprintf("\\%02x", c))
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,185 @@
+package ldap
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	client     *stream
+	server     *stream
+	entries    map[int64]int // messageID:number of SearchResultEntry
+	startTLSID int64         // messageID of StartTLS request ( 0: none )
+	tls        bool          // TLS is started
+}
+
+// stream is LDAPMessages of a direction
+type stream struct {
+	buffer []byte // partial message
+	skip   int    // rest bytes of large message
+	broken bool   // message boundary is lost
+}
+
+// message is LDAPMessage
+type message struct {
+	data      []byte
+	truncated bool // only the head is buffered
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "ldap",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (l *Dumper) Name() string {
+	return l.name
+}
+
+// Dump LDAP operations
+func (l *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := l.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		l.Log(values)
+	}
+	return nil
+}
+
+// Read return the first operation of byte to analyzed string ( use ReadFrames to read all operations )
+func (l *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := l.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return operations of byte to analyzed string
+// SearchResultEntry and SearchResultReference are counted into SearchResultDone
+func (l *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.tls {
+		return records, nil
+	}
+	fromServer := direction == dumper.RemoteToClient || direction == dumper.DstToSrc
+	s := internal.client
+	if fromServer {
+		s = internal.server
+	}
+	for _, msg := range s.readMessages(in) {
+		var values []dumper.DumpValue
+		if fromServer {
+			values = readResponse(msg, &internal)
+		} else {
+			values = readRequest(msg, &internal)
+		}
+		if len(values) > 0 {
+			records = append(records, values)
+		}
+		if internal.tls {
+			// the rest is TLS
+			break
+		}
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (l *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	l.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (l *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			client:  &stream{},
+			server:  &stream{},
+			entries: map[int64]int{},
+		},
+	}
+}
+
+// readMessages returns complete messages and cache the partial message
+// large message is returned with only the head and the rest is skipped
+func (s *stream) readMessages(in []byte) []message {
+	msgs := []message{}
+	if s.broken {
+		return msgs
+	}
+	if s.skip > 0 {
+		if len(in) <= s.skip {
+			s.skip -= len(in)
+			return msgs
+		}
+		in = in[s.skip:]
+		s.skip = 0
+	}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) > 0 {
+		if buff[0] != 0x30 {
+			// not LDAPMessage ( SEQUENCE ) boundary. SASL security layer, TLS or mid-stream
+			s.broken = true
+			return msgs
+		}
+		_, headerLength, l, ok := readElementHeader(buff)
+		if !ok {
+			if len(buff) > 6 {
+				s.broken = true
+				return msgs
+			}
+			break
+		}
+		size := headerLength + l
+		if l <= maxMessageSize {
+			if len(buff) < size {
+				break
+			}
+			msgs = append(msgs, message{data: buff[:size]})
+			buff = buff[size:]
+			continue
+		}
+		if len(buff) < headerLength+maxMessageSize {
+			break
+		}
+		msgs = append(msgs, message{data: buff[:headerLength+maxMessageSize], truncated: true})
+		if len(buff) < size {
+			s.skip = size - len(buff)
+			buff = nil
+			break
+		}
+		buff = buff[size:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return msgs
+}
@@ -0,0 +1,804 @@
+package ldap
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type ldapStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  [][]dumper.DumpValue
+}
+
+var ldapReadFramesTests = []struct {
+	description string
+	steps       []ldapStep
+}{
+	{
+		"Bind, search and update",
+		[]ldapStep{
+			{
+				[]byte{
+					0x30, 0x2c, 0x02, 0x01, 0x01, 0x60, 0x27, 0x02, 0x01, 0x03, 0x04, 0x1a, 0x63, 0x6e, 0x3d, 0x61,
+					0x64, 0x6d, 0x69, 0x6e, 0x2c, 0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c,
+					0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x80, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(1),
+						},
+						{
+							Key:   "operation",
+							Value: "BindRequest",
+						},
+						{
+							Key:   "version",
+							Value: int64(3),
+						},
+						{
+							Key:   "bind_dn",
+							Value: "cn=admin,dc=example,dc=com",
+						},
+						{
+							Key:   "auth_method",
+							Value: "simple",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x0c, 0x02, 0x01, 0x01, 0x61, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(1),
+						},
+						{
+							Key:   "operation",
+							Value: "BindResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(0),
+						},
+						{
+							Key:   "result_name",
+							Value: "success",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x81, 0xca, 0x02, 0x01, 0x02, 0x63, 0x81, 0xc4, 0x04, 0x11, 0x64, 0x63, 0x3d, 0x65, 0x78,
+					0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x0a, 0x01, 0x02, 0x0a,
+					0x01, 0x00, 0x02, 0x01, 0x64, 0x02, 0x01, 0x00, 0x01, 0x01, 0x00, 0xa0, 0x81, 0x93, 0xa3, 0x15,
+					0x04, 0x0b, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x04, 0x06, 0x70,
+					0x65, 0x72, 0x73, 0x6f, 0x6e, 0xa1, 0x29, 0xa4, 0x0f, 0x04, 0x03, 0x75, 0x69, 0x64, 0x30, 0x08,
+					0x80, 0x02, 0x61, 0x6c, 0x82, 0x02, 0x63, 0x65, 0xa4, 0x16, 0x04, 0x04, 0x6d, 0x61, 0x69, 0x6c,
+					0x30, 0x0e, 0x82, 0x0c, 0x40, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d,
+					0xa2, 0x0b, 0xa3, 0x09, 0x04, 0x02, 0x63, 0x6e, 0x04, 0x03, 0x61, 0x2a, 0x62, 0xa5, 0x09, 0x04,
+					0x03, 0x61, 0x67, 0x65, 0x04, 0x02, 0x33, 0x30, 0xa8, 0x0b, 0x04, 0x02, 0x73, 0x6e, 0x04, 0x05,
+					0x73, 0x6d, 0x69, 0x74, 0x68, 0x87, 0x0f, 0x74, 0x65, 0x6c, 0x65, 0x70, 0x68, 0x6f, 0x6e, 0x65,
+					0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0xa9, 0x19, 0x81, 0x0e, 0x63, 0x61, 0x73, 0x65, 0x45, 0x78,
+					0x61, 0x63, 0x74, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x82, 0x02, 0x63, 0x6e, 0x83, 0x03, 0x42, 0x6f,
+					0x62, 0x30, 0x0a, 0x04, 0x02, 0x63, 0x6e, 0x04, 0x04, 0x6d, 0x61, 0x69, 0x6c,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(2),
+						},
+						{
+							Key:   "operation",
+							Value: "SearchRequest",
+						},
+						{
+							Key:   "base_dn",
+							Value: "dc=example,dc=com",
+						},
+						{
+							Key:   "scope",
+							Value: "wholeSubtree",
+						},
+						{
+							Key:   "size_limit",
+							Value: int64(100),
+						},
+						{
+							Key:   "time_limit",
+							Value: int64(0),
+						},
+						{
+							Key:   "filter",
+							Value: "(&(objectClass=person)(|(uid=al*ce)(mail=*@example.com))(!(cn=a\\2ab))(age>=30)(sn~=smith)(telephoneNumber=*)(cn:caseExactMatch:=Bob))",
+						},
+						{
+							Key:   "attributes",
+							Value: []string{"cn", "mail"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x3d, 0x02, 0x01, 0x02, 0x64, 0x38, 0x04, 0x25, 0x75, 0x69, 0x64, 0x3d, 0x61, 0x6c, 0x69,
+					0x63, 0x65, 0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d,
+					0x30, 0x0f, 0x30, 0x0d, 0x04, 0x02, 0x63, 0x6e, 0x31, 0x07, 0x04, 0x05, 0x41, 0x6c, 0x69, 0x63,
+					0x65, 0x30, 0x3e, 0x02, 0x01, 0x02, 0x64, 0x39, 0x04, 0x26, 0x75, 0x69, 0x64, 0x3d, 0x61, 0x6c,
+					0x69, 0x63, 0x69, 0x61, 0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64,
+					0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d,
+					0x30, 0x0f, 0x30, 0x0d, 0x04, 0x02, 0x63, 0x6e, 0x31, 0x07, 0x04, 0x05, 0x41, 0x6c, 0x69, 0x63,
+					0x65, 0x30, 0x31, 0x02, 0x01, 0x02, 0x73, 0x2c, 0x04, 0x2a, 0x6c, 0x64, 0x61, 0x70, 0x3a, 0x2f,
+					0x2f, 0x6c, 0x64, 0x61, 0x70, 0x32, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63,
+					0x6f, 0x6d, 0x2f, 0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63,
+					0x3d, 0x63, 0x6f, 0x6d, 0x30, 0x0c, 0x02, 0x01, 0x02, 0x65, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00,
+					0x04, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(2),
+						},
+						{
+							Key:   "operation",
+							Value: "SearchResultDone",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(0),
+						},
+						{
+							Key:   "result_name",
+							Value: "success",
+						},
+						{
+							Key:   "entries_count",
+							Value: 2,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x69, 0x02, 0x01, 0x03, 0x66, 0x64, 0x04, 0x25, 0x75, 0x69, 0x64, 0x3d, 0x61, 0x6c, 0x69,
+					0x63, 0x65, 0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d,
+					0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x30, 0x3b,
+					0x30, 0x20, 0x0a, 0x01, 0x02, 0x30, 0x1b, 0x04, 0x04, 0x6d, 0x61, 0x69, 0x6c, 0x31, 0x13, 0x04,
+					0x11, 0x61, 0x6c, 0x69, 0x63, 0x65, 0x40, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63,
+					0x6f, 0x6d, 0x30, 0x17, 0x0a, 0x01, 0x00, 0x30, 0x12, 0x04, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72,
+					0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x31, 0x03, 0x04, 0x01, 0x78,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(3),
+						},
+						{
+							Key:   "operation",
+							Value: "ModifyRequest",
+						},
+						{
+							Key:   "dn",
+							Value: "uid=alice,ou=people,dc=example,dc=com",
+						},
+						{
+							Key:   "changes",
+							Value: []string{"replace: mail", "add: description"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x1b, 0x02, 0x01, 0x03, 0x67, 0x16, 0x0a, 0x01, 0x32, 0x04, 0x00, 0x04, 0x0f, 0x6e, 0x6f,
+					0x20, 0x77, 0x72, 0x69, 0x74, 0x65, 0x20, 0x61, 0x63, 0x63, 0x65, 0x73, 0x73,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(3),
+						},
+						{
+							Key:   "operation",
+							Value: "ModifyResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(50),
+						},
+						{
+							Key:   "result_name",
+							Value: "insufficientAccessRights",
+						},
+						{
+							Key:   "diagnostic_message",
+							Value: "no write access",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x52, 0x02, 0x01, 0x04, 0x68, 0x4d, 0x04, 0x23, 0x75, 0x69, 0x64, 0x3d, 0x62, 0x6f, 0x62,
+					0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x65, 0x78,
+					0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x30, 0x26, 0x30, 0x17,
+					0x04, 0x0b, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x31, 0x08, 0x04,
+					0x06, 0x70, 0x65, 0x72, 0x73, 0x6f, 0x6e, 0x30, 0x0b, 0x04, 0x02, 0x63, 0x6e, 0x31, 0x05, 0x04,
+					0x03, 0x42, 0x6f, 0x62, 0x30, 0x2a, 0x02, 0x01, 0x05, 0x4a, 0x25, 0x75, 0x69, 0x64, 0x3d, 0x63,
+					0x61, 0x72, 0x6f, 0x6c, 0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64,
+					0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d,
+					0x30, 0x55, 0x02, 0x01, 0x06, 0x6c, 0x50, 0x04, 0x24, 0x75, 0x69, 0x64, 0x3d, 0x64, 0x61, 0x76,
+					0x65, 0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x65,
+					0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x04, 0x09, 0x75,
+					0x69, 0x64, 0x3d, 0x64, 0x61, 0x76, 0x69, 0x64, 0x01, 0x01, 0xff, 0x80, 0x1a, 0x6f, 0x75, 0x3d,
+					0x73, 0x74, 0x61, 0x66, 0x66, 0x2c, 0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65,
+					0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(4),
+						},
+						{
+							Key:   "operation",
+							Value: "AddRequest",
+						},
+						{
+							Key:   "dn",
+							Value: "uid=bob,ou=people,dc=example,dc=com",
+						},
+						{
+							Key:   "attributes",
+							Value: []string{"objectClass", "cn"},
+						},
+					},
+					{
+						{
+							Key:   "message_id",
+							Value: int64(5),
+						},
+						{
+							Key:   "operation",
+							Value: "DelRequest",
+						},
+						{
+							Key:   "dn",
+							Value: "uid=carol,ou=people,dc=example,dc=com",
+						},
+					},
+					{
+						{
+							Key:   "message_id",
+							Value: int64(6),
+						},
+						{
+							Key:   "operation",
+							Value: "ModifyDNRequest",
+						},
+						{
+							Key:   "dn",
+							Value: "uid=dave,ou=people,dc=example,dc=com",
+						},
+						{
+							Key:   "new_rdn",
+							Value: "uid=david",
+						},
+						{
+							Key:   "delete_old_rdn",
+							Value: true,
+						},
+						{
+							Key:   "new_superior",
+							Value: "ou=staff,dc=example,dc=com",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x27, 0x02, 0x01, 0x04, 0x69, 0x22, 0x0a, 0x01, 0x44, 0x04, 0x1b, 0x6f, 0x75, 0x3d, 0x70,
+					0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65,
+					0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x04, 0x00, 0x30, 0x0c, 0x02, 0x01, 0x05, 0x6b, 0x07,
+					0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00, 0x30, 0x0c, 0x02, 0x01, 0x06, 0x6d, 0x07, 0x0a, 0x01,
+					0x00, 0x04, 0x00, 0x04, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(4),
+						},
+						{
+							Key:   "operation",
+							Value: "AddResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(68),
+						},
+						{
+							Key:   "result_name",
+							Value: "entryAlreadyExists",
+						},
+						{
+							Key:   "matched_dn",
+							Value: "ou=people,dc=example,dc=com",
+						},
+					},
+					{
+						{
+							Key:   "message_id",
+							Value: int64(5),
+						},
+						{
+							Key:   "operation",
+							Value: "DelResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(0),
+						},
+						{
+							Key:   "result_name",
+							Value: "success",
+						},
+					},
+					{
+						{
+							Key:   "message_id",
+							Value: int64(6),
+						},
+						{
+							Key:   "operation",
+							Value: "ModifyDNResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(0),
+						},
+						{
+							Key:   "result_name",
+							Value: "success",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x36, 0x02, 0x01, 0x0a, 0x63, 0x31, 0x04, 0x11, 0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d,
+					0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x0a, 0x01, 0x00, 0x0a, 0x01, 0x00,
+					0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x01, 0x01, 0x00, 0x87, 0x0b, 0x6f, 0x62, 0x6a, 0x65, 0x63,
+					0x74, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x30, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(10),
+						},
+						{
+							Key:   "operation",
+							Value: "SearchRequest",
+						},
+						{
+							Key:   "base_dn",
+							Value: "dc=example,dc=com",
+						},
+						{
+							Key:   "scope",
+							Value: "baseObject",
+						},
+						{
+							Key:   "size_limit",
+							Value: int64(0),
+						},
+						{
+							Key:   "time_limit",
+							Value: int64(0),
+						},
+						{
+							Key:   "filter",
+							Value: "(objectClass=*)",
+						},
+						{
+							Key:   "attributes",
+							Value: []string{},
+						},
+					},
+				},
+			},
+			{
+				append([]byte{0x30, 0x83, 0x01, 0x11, 0xbe, 0x02, 0x01, 0x0a, 0x64, 0x83, 0x01, 0x11, 0xb6, 0x04, 0x25, 0x75, 0x69, 0x64, 0x3d, 0x61, 0x6c, 0x69, 0x63, 0x65, 0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x30, 0x83, 0x01, 0x11, 0x8a, 0x30, 0x83, 0x01, 0x11, 0x85, 0x04, 0x09, 0x6a, 0x70, 0x65, 0x67, 0x50, 0x68, 0x6f, 0x74, 0x6f, 0x31, 0x83, 0x01, 0x11, 0x75, 0x04, 0x83, 0x01, 0x11, 0x70}, make([]byte, 40000)...),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				append(make([]byte, 30000), []byte{0x30, 0x0c, 0x02, 0x01, 0x0a, 0x65, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00}...),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(10),
+						},
+						{
+							Key:   "operation",
+							Value: "SearchResultDone",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(0),
+						},
+						{
+							Key:   "result_name",
+							Value: "success",
+						},
+						{
+							Key:   "entries_count",
+							Value: 1,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x44, 0x02, 0x01, 0x07, 0x6e, 0x3f, 0x04, 0x25, 0x75, 0x69, 0x64, 0x3d, 0x61, 0x6c, 0x69,
+					0x63, 0x65, 0x2c, 0x6f, 0x75, 0x3d, 0x70, 0x65, 0x6f, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d,
+					0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x30, 0x16,
+					0x04, 0x0c, 0x75, 0x73, 0x65, 0x72, 0x50, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x04, 0x06,
+					0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x30, 0x06, 0x02, 0x01, 0x08, 0x50, 0x01, 0x02, 0x30, 0x05,
+					0x02, 0x01, 0x09, 0x42, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(7),
+						},
+						{
+							Key:   "operation",
+							Value: "CompareRequest",
+						},
+						{
+							Key:   "dn",
+							Value: "uid=alice,ou=people,dc=example,dc=com",
+						},
+						{
+							Key:   "attribute",
+							Value: "userPassword",
+						},
+					},
+					{
+						{
+							Key:   "message_id",
+							Value: int64(8),
+						},
+						{
+							Key:   "operation",
+							Value: "AbandonRequest",
+						},
+						{
+							Key:   "abandon_id",
+							Value: int64(2),
+						},
+					},
+					{
+						{
+							Key:   "message_id",
+							Value: int64(9),
+						},
+						{
+							Key:   "operation",
+							Value: "UnbindRequest",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"StartTLS",
+		[]ldapStep{
+			{
+				[]byte{
+					0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16, 0x31, 0x2e, 0x33, 0x2e, 0x36, 0x2e, 0x31,
+					0x2e, 0x34, 0x2e, 0x31, 0x2e, 0x31, 0x34, 0x36, 0x36, 0x2e, 0x32, 0x30, 0x30, 0x33, 0x37,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(1),
+						},
+						{
+							Key:   "operation",
+							Value: "ExtendedRequest",
+						},
+						{
+							Key:   "request_name",
+							Value: "1.3.6.1.4.1.1466.20037",
+						},
+						{
+							Key:   "extended_operation",
+							Value: "StartTLS",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x24, 0x02, 0x01, 0x01, 0x78, 0x1f, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00, 0x8a, 0x16,
+					0x31, 0x2e, 0x33, 0x2e, 0x36, 0x2e, 0x31, 0x2e, 0x34, 0x2e, 0x31, 0x2e, 0x31, 0x34, 0x36, 0x36,
+					0x2e, 0x32, 0x30, 0x30, 0x33, 0x37,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(1),
+						},
+						{
+							Key:   "operation",
+							Value: "ExtendedResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(0),
+						},
+						{
+							Key:   "result_name",
+							Value: "success",
+						},
+						{
+							Key:   "response_name",
+							Value: "1.3.6.1.4.1.1466.20037",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x16, 0x03, 0x01, 0x00, 0xa5, 0x01, 0x00, 0x00, 0xa1, 0x03, 0x03, 0x30, 0x2c, 0x02, 0x01, 0x01,
+					0x60, 0x27, 0x02, 0x01, 0x03, 0x04, 0x1a, 0x63, 0x6e, 0x3d, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2c,
+					0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c, 0x64, 0x63, 0x3d, 0x63, 0x6f,
+					0x6d, 0x80, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x16, 0x03, 0x03, 0x00, 0x7a, 0x02, 0x00, 0x00, 0x76, 0x03, 0x03, 0x30, 0x0c, 0x02, 0x01, 0x01,
+					0x61, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+	{
+		"SASL bind and invalid credentials",
+		[]ldapStep{
+			{
+				[]byte{
+					0x30, 0x18, 0x02, 0x01, 0x01, 0x60, 0x13, 0x02, 0x01, 0x03, 0x04, 0x00, 0xa3, 0x0c, 0x04, 0x06,
+					0x47, 0x53, 0x53, 0x41, 0x50, 0x49, 0x04, 0x02, 0x60, 0x82,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(1),
+						},
+						{
+							Key:   "operation",
+							Value: "BindRequest",
+						},
+						{
+							Key:   "version",
+							Value: int64(3),
+						},
+						{
+							Key:   "bind_dn",
+							Value: "",
+						},
+						{
+							Key:   "auth_method",
+							Value: "sasl",
+						},
+						{
+							Key:   "sasl_mechanism",
+							Value: "GSSAPI",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x10, 0x02, 0x01, 0x01, 0x61, 0x0b, 0x0a, 0x01, 0x0e, 0x04, 0x00, 0x04, 0x00, 0x87, 0x02,
+					0x60, 0x81,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(1),
+						},
+						{
+							Key:   "operation",
+							Value: "BindResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(14),
+						},
+						{
+							Key:   "result_name",
+							Value: "saslBindInProgress",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x2b, 0x02, 0x01, 0x02, 0x60, 0x26, 0x02, 0x01, 0x03, 0x04, 0x1a, 0x63, 0x6e, 0x3d, 0x61,
+					0x64, 0x6d, 0x69, 0x6e, 0x2c, 0x64, 0x63, 0x3d, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2c,
+					0x64, 0x63, 0x3d, 0x63, 0x6f, 0x6d, 0x80, 0x05, 0x77, 0x72, 0x6f, 0x6e, 0x67,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(2),
+						},
+						{
+							Key:   "operation",
+							Value: "BindRequest",
+						},
+						{
+							Key:   "version",
+							Value: int64(3),
+						},
+						{
+							Key:   "bind_dn",
+							Value: "cn=admin,dc=example,dc=com",
+						},
+						{
+							Key:   "auth_method",
+							Value: "simple",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x2c, 0x02, 0x01, 0x02, 0x61, 0x27, 0x0a, 0x01, 0x31, 0x04, 0x00, 0x04, 0x20, 0x38, 0x30,
+					0x30, 0x39, 0x30, 0x33, 0x30, 0x38, 0x3a, 0x20, 0x4c, 0x64, 0x61, 0x70, 0x45, 0x72, 0x72, 0x3a,
+					0x20, 0x44, 0x53, 0x49, 0x44, 0x2d, 0x30, 0x43, 0x30, 0x39, 0x30, 0x34, 0x34, 0x45,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "message_id",
+							Value: int64(2),
+						},
+						{
+							Key:   "operation",
+							Value: "BindResponse",
+						},
+						{
+							Key:   "result_code",
+							Value: int64(49),
+						},
+						{
+							Key:   "result_name",
+							Value: "invalidCredentials",
+						},
+						{
+							Key:   "diagnostic_message",
+							Value: "80090308: LdapErr: DSID-0C09044E",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Not LDAP",
+		[]ldapStep{
+			{
+				[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte("HTTP/1.1 400 Bad Request\r\n\r\n"),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+}
+
+func TestLDAPReadFrames(t *testing.T) {
+	for _, tt := range ldapReadFramesTests {
+		out := new(bytes.Buffer)
+		d := NewDumper()
+		d.logger = newTestLogger(out)
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
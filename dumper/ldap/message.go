@@ -0,0 +1,337 @@
+package ldap
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// readMessage returns messageID and protocolOp of LDAPMessage
+// protocolOp has no value when the message is truncated
+// https://tools.ietf.org/html/rfc4511#section-4.1.1
+func readMessage(msg message) (int64, element, bool) {
+	_, headerLength, _, ok := readElementHeader(msg.data)
+	if !ok {
+		return 0, element{}, false
+	}
+	e, rest, ok := readElement(msg.data[headerLength:])
+	if !ok || !e.is(classUniversal, tagInteger) {
+		return 0, element{}, false
+	}
+	id, ok := e.int()
+	if !ok {
+		return 0, element{}, false
+	}
+	op, _, ok := readElement(rest)
+	if !ok {
+		if !msg.truncated {
+			return 0, element{}, false
+		}
+		op, _, _, ok = readElementHeader(rest)
+		if !ok {
+			return 0, element{}, false
+		}
+	}
+	if op.class != classApplication {
+		return 0, element{}, false
+	}
+	return id, op, true
+}
+
+// readRequest returns values of request
+func readRequest(msg message, internal *connMetadataInternal) []dumper.DumpValue {
+	id, op, ok := readMessage(msg)
+	if !ok {
+		return []dumper.DumpValue{}
+	}
+	values := []dumper.DumpValue{
+		{
+			Key:   "message_id",
+			Value: id,
+		},
+		{
+			Key:   "operation",
+			Value: operationName(op.tag),
+		},
+	}
+	if op.tag == opSearchRequest {
+		if len(internal.entries) >= maxSearches {
+			// SearchResultDone were not captured
+			internal.entries = map[int64]int{}
+		}
+		internal.entries[id] = 0
+	}
+	if msg.truncated {
+		return values
+	}
+	args, ok := readRequestOp(id, op, internal)
+	if !ok {
+		return values
+	}
+	return append(values, args...)
+}
+
+// readRequestOp returns values of protocolOp of request
+func readRequestOp(id int64, op element, internal *connMetadataInternal) ([]dumper.DumpValue, bool) {
+	args := []dumper.DumpValue{}
+	add := func(key string, value interface{}) {
+		args = append(args, dumper.DumpValue{
+			Key:   key,
+			Value: value,
+		})
+	}
+	var children []element
+	if op.constructed {
+		var ok bool
+		children, ok = readElements(op.value)
+		if !ok {
+			return nil, false
+		}
+	}
+	switch op.tag {
+	case opBindRequest:
+		// https://tools.ietf.org/html/rfc4511#section-4.2
+		if len(children) < 3 {
+			return nil, false
+		}
+		version, _ := children[0].int()
+		add("version", version)
+		add("bind_dn", children[1].string())
+		auth := children[2]
+		switch {
+		case auth.is(classContext, 0):
+			// password is not dumped
+			add("auth_method", "simple")
+		case auth.is(classContext, 3):
+			add("auth_method", "sasl")
+			if sasl, ok := readElements(auth.value); ok && len(sasl) > 0 {
+				add("sasl_mechanism", sasl[0].string())
+			}
+		}
+	case opSearchRequest:
+		// https://tools.ietf.org/html/rfc4511#section-4.5.1
+		if len(children) < 8 {
+			return nil, false
+		}
+		add("base_dn", children[0].string())
+		scope, _ := children[1].int()
+		add("scope", scopeName(scope))
+		sizeLimit, _ := children[3].int()
+		add("size_limit", sizeLimit)
+		timeLimit, _ := children[4].int()
+		add("time_limit", timeLimit)
+		filter, ok := filterString(children[6], 0)
+		if !ok {
+			return nil, false
+		}
+		add("filter", filter)
+		attrs, ok := readElements(children[7].value)
+		if !ok {
+			return nil, false
+		}
+		attributes := []string{}
+		for _, a := range attrs {
+			attributes = append(attributes, a.string())
+		}
+		add("attributes", attributes)
+	case opModifyRequest:
+		// https://tools.ietf.org/html/rfc4511#section-4.6
+		if len(children) < 2 {
+			return nil, false
+		}
+		add("dn", children[0].string())
+		changes, ok := readElements(children[1].value)
+		if !ok {
+			return nil, false
+		}
+		mods := []string{}
+		for _, c := range changes {
+			mod, ok := readElements(c.value)
+			if !ok || len(mod) < 2 {
+				return nil, false
+			}
+			operation, _ := mod[0].int()
+			attr, ok := readElements(mod[1].value)
+			if !ok || len(attr) < 1 {
+				return nil, false
+			}
+			name, ok := modifyOperationNames[operation]
+			if !ok {
+				name = "unknown"
+			}
+			mods = append(mods, name+": "+attr[0].string())
+		}
+		add("changes", mods)
+	case opAddRequest:
+		// https://tools.ietf.org/html/rfc4511#section-4.7
+		if len(children) < 2 {
+			return nil, false
+		}
+		add("dn", children[0].string())
+		attrs, ok := readElements(children[1].value)
+		if !ok {
+			return nil, false
+		}
+		attributes := []string{}
+		for _, a := range attrs {
+			attr, ok := readElements(a.value)
+			if !ok || len(attr) < 1 {
+				return nil, false
+			}
+			attributes = append(attributes, attr[0].string())
+		}
+		add("attributes", attributes)
+	case opDelRequest:
+		add("dn", op.string())
+	case opModifyDNRequest:
+		// https://tools.ietf.org/html/rfc4511#section-4.9
+		if len(children) < 3 {
+			return nil, false
+		}
+		add("dn", children[0].string())
+		add("new_rdn", children[1].string())
+		add("delete_old_rdn", children[2].bool())
+		if len(children) > 3 && children[3].is(classContext, 0) {
+			add("new_superior", children[3].string())
+		}
+	case opCompareRequest:
+		// assertion value is not dumped
+		if len(children) < 2 {
+			return nil, false
+		}
+		add("dn", children[0].string())
+		ava, ok := readElements(children[1].value)
+		if !ok || len(ava) < 1 {
+			return nil, false
+		}
+		add("attribute", ava[0].string())
+	case opAbandonRequest:
+		abandonID, ok := op.int()
+		if !ok {
+			return nil, false
+		}
+		add("abandon_id", abandonID)
+	case opExtendedRequest:
+		// requestValue is not dumped ( PasswordModify has passwords )
+		if len(children) < 1 || !children[0].is(classContext, 0) {
+			return nil, false
+		}
+		oid := children[0].string()
+		add("request_name", oid)
+		if name, ok := extendedOperationNames[oid]; ok {
+			add("extended_operation", name)
+		}
+		if oid == oidStartTLS {
+			internal.startTLSID = id
+		}
+	}
+	return args, true
+}
+
+// readResponse returns values of response
+// SearchResultEntry and SearchResultReference returns no values
+func readResponse(msg message, internal *connMetadataInternal) []dumper.DumpValue {
+	id, op, ok := readMessage(msg)
+	if !ok {
+		return []dumper.DumpValue{}
+	}
+	switch op.tag {
+	case opSearchResultEntry:
+		if n, ok := internal.entries[id]; ok {
+			internal.entries[id] = n + 1
+		}
+		return []dumper.DumpValue{}
+	case opSearchResultReference:
+		return []dumper.DumpValue{}
+	}
+	values := []dumper.DumpValue{
+		{
+			Key:   "message_id",
+			Value: id,
+		},
+		{
+			Key:   "operation",
+			Value: operationName(op.tag),
+		},
+	}
+	if !msg.truncated && op.tag != opIntermediateResponse {
+		values = append(values, readResult(id, op, internal)...)
+	}
+	if op.tag == opSearchResultDone {
+		if n, ok := internal.entries[id]; ok {
+			delete(internal.entries, id)
+			values = append(values, dumper.DumpValue{
+				Key:   "entries_count",
+				Value: n,
+			})
+		}
+	}
+	return values
+}
+
+// readResult returns values of LDAPResult
+// https://tools.ietf.org/html/rfc4511#section-4.1.9
+func readResult(id int64, op element, internal *connMetadataInternal) []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	children, ok := readElements(op.value)
+	if !ok || len(children) < 3 {
+		return values
+	}
+	code, ok := children[0].int()
+	if !ok {
+		return values
+	}
+	values = append(values, dumper.DumpValue{
+		Key:   "result_code",
+		Value: code,
+	}, dumper.DumpValue{
+		Key:   "result_name",
+		Value: resultName(code),
+	})
+	if dn := children[1].string(); dn != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "matched_dn",
+			Value: dn,
+		})
+	}
+	if message := children[2].string(); message != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "diagnostic_message",
+			Value: message,
+		})
+	}
+	if op.tag == opExtendedResponse {
+		for _, c := range children[3:] {
+			if c.is(classContext, 10) {
+				values = append(values, dumper.DumpValue{
+					Key:   "response_name",
+					Value: c.string(),
+				})
+			}
+		}
+		if internal.startTLSID == id {
+			internal.startTLSID = 0
+			internal.tls = code == 0
+		}
+	}
+	return values
+}
+
+func operationName(tag int) string {
+	if n, ok := operationNames[tag]; ok {
+		return n
+	}
+	return "Unknown"
+}
+
+func scopeName(scope int64) string {
+	if n, ok := scopeNames[scope]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+func resultName(code int64) string {
+	if n, ok := resultCodeNames[code]; ok {
+		return n
+	}
+	return "unknown"
+}
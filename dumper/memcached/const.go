@@ -0,0 +1,164 @@
+package memcached
+
+// https://github.com/memcached/memcached/wiki/BinaryProtocolRevamped
+const (
+	magicRequest  = 0x80
+	magicResponse = 0x81
+
+	binaryHeaderLength = 24
+)
+
+type opcode byte
+
+const (
+	opGet        opcode = 0x00
+	opSet        opcode = 0x01
+	opAdd        opcode = 0x02
+	opReplace    opcode = 0x03
+	opDelete     opcode = 0x04
+	opIncrement  opcode = 0x05
+	opDecrement  opcode = 0x06
+	opQuit       opcode = 0x07
+	opFlush      opcode = 0x08
+	opGetQ       opcode = 0x09
+	opNoop       opcode = 0x0a
+	opVersion    opcode = 0x0b
+	opGetK       opcode = 0x0c
+	opGetKQ      opcode = 0x0d
+	opAppend     opcode = 0x0e
+	opPrepend    opcode = 0x0f
+	opStat       opcode = 0x10
+	opSetQ       opcode = 0x11
+	opAddQ       opcode = 0x12
+	opReplaceQ   opcode = 0x13
+	opDeleteQ    opcode = 0x14
+	opIncrementQ opcode = 0x15
+	opDecrementQ opcode = 0x16
+	opQuitQ      opcode = 0x17
+	opFlushQ     opcode = 0x18
+	opAppendQ    opcode = 0x19
+	opPrependQ   opcode = 0x1a
+	opVerbosity  opcode = 0x1b
+	opTouch      opcode = 0x1c
+	opGAT        opcode = 0x1d
+	opGATQ       opcode = 0x1e
+	opSASLList   opcode = 0x20
+	opSASLAuth   opcode = 0x21
+	opSASLStep   opcode = 0x22
+	opGATK       opcode = 0x23
+	opGATKQ      opcode = 0x24
+)
+
+var opcodeNames = map[opcode]string{
+	opGet:        "get",
+	opSet:        "set",
+	opAdd:        "add",
+	opReplace:    "replace",
+	opDelete:     "delete",
+	opIncrement:  "incr",
+	opDecrement:  "decr",
+	opQuit:       "quit",
+	opFlush:      "flush",
+	opGetQ:       "getq",
+	opNoop:       "noop",
+	opVersion:    "version",
+	opGetK:       "getk",
+	opGetKQ:      "getkq",
+	opAppend:     "append",
+	opPrepend:    "prepend",
+	opStat:       "stat",
+	opSetQ:       "setq",
+	opAddQ:       "addq",
+	opReplaceQ:   "replaceq",
+	opDeleteQ:    "deleteq",
+	opIncrementQ: "incrq",
+	opDecrementQ: "decrq",
+	opQuitQ:      "quitq",
+	opFlushQ:     "flushq",
+	opAppendQ:    "appendq",
+	opPrependQ:   "prependq",
+	opVerbosity:  "verbosity",
+	opTouch:      "touch",
+	opGAT:        "gat",
+	opGATQ:       "gatq",
+	opSASLList:   "sasl_list_mechs",
+	opSASLAuth:   "sasl_auth",
+	opSASLStep:   "sasl_step",
+	opGATK:       "gatk",
+	opGATKQ:      "gatkq",
+}
+
+func (o opcode) String() string {
+	if name, ok := opcodeNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (o opcode) retrieval() bool {
+	switch o {
+	case opGet, opGetQ, opGetK, opGetKQ, opGAT, opGATQ, opGATK, opGATKQ:
+		return true
+	}
+	return false
+}
+
+var statusNames = map[uint16]string{
+	0x0000: "no_error",
+	0x0001: "key_not_found",
+	0x0002: "key_exists",
+	0x0003: "value_too_large",
+	0x0004: "invalid_arguments",
+	0x0005: "item_not_stored",
+	0x0006: "non_numeric_value",
+	0x0007: "vbucket_belongs_to_another_server",
+	0x0008: "authentication_error",
+	0x0009: "authentication_continue",
+	0x0081: "unknown_command",
+	0x0082: "out_of_memory",
+	0x0083: "not_supported",
+	0x0084: "internal_error",
+	0x0085: "busy",
+	0x0086: "temporary_failure",
+}
+
+const (
+	statusNoError     = 0x0000
+	statusKeyNotFound = 0x0001
+)
+
+// https://github.com/memcached/memcached/blob/master/doc/protocol.txt
+var (
+	retrievalCommands = map[string]bool{
+		"get":  true,
+		"gets": true,
+		"gat":  true,
+		"gats": true,
+	}
+	storageCommands = map[string]bool{
+		"set":     true,
+		"add":     true,
+		"replace": true,
+		"append":  true,
+		"prepend": true,
+		"cas":     true,
+	}
+	keyCommands = map[string]bool{
+		"delete": true,
+		"incr":   true,
+		"decr":   true,
+		"touch":  true,
+		"mg":     true,
+		"md":     true,
+		"ma":     true,
+		"me":     true,
+	}
+)
+
+const (
+	// max length of a line of text protocol ( key is up to 250 bytes )
+	maxLineLength = 64 * 1024
+
+	// max number of requests waiting for response
+	maxPendingRequests = 1000
+)
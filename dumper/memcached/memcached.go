@@ -0,0 +1,531 @@
+package memcached
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name      string
+	logger    *zap.Logger
+	dumpValue bool
+}
+
+// record is a request or a response of memcached
+type record struct {
+	protocol  string
+	command   string
+	keys      []string
+	valueSize int // -1: no value
+	value     []byte
+	noreply   bool
+	retrieval bool
+	response  string
+	message   string
+	hits      int
+	misses    int
+	opaque    uint32
+	binary    bool
+}
+
+// stream is state of text/binary protocol stream per direction
+type stream struct {
+	buffer  []byte  // partial line or binary header
+	skip    int     // remaining bytes of data block
+	pending *record // record waiting for the end of data block
+	current *record // response of retrieval command waiting for END
+}
+
+// request is request waiting for response ( text protocol )
+type request struct {
+	command   string
+	keys      int
+	retrieval bool
+}
+
+type connMetadataInternal struct {
+	client   *stream
+	server   *stream
+	requests []request
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:      "memcached",
+		logger:    logger.NewQueryLogger(),
+		dumpValue: viper.GetBool("memcached.dumpValue"),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (m *Dumper) Name() string {
+	return m.name
+}
+
+// Dump command of memcached
+func (m *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := m.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		m.Log(values)
+	}
+	return nil
+}
+
+// Read return the first command of byte to analyzed string ( use ReadFrames to read all commands )
+func (m *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := m.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return commands and responses of byte to analyzed string
+func (m *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	values := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return values, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.client == nil {
+		internal.client = &stream{}
+	}
+	if internal.server == nil {
+		internal.server = &stream{}
+	}
+
+	var records []*record
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		records = m.readStream(in, internal.server, true)
+		for _, r := range records {
+			if r.binary {
+				continue
+			}
+			// text protocol responses are in order of requests
+			if len(internal.requests) == 0 {
+				continue
+			}
+			req := internal.requests[0]
+			internal.requests = internal.requests[1:]
+			r.command = req.command
+			if req.retrieval {
+				r.retrieval = true
+				if r.response == "HD" {
+					// mg without v flag
+					r.hits = req.keys
+				}
+				r.misses = req.keys - r.hits
+				if r.misses < 0 {
+					r.misses = 0
+				}
+			}
+		}
+	} else {
+		records = m.readStream(in, internal.client, false)
+		for _, r := range records {
+			if r.binary || r.noreply {
+				continue
+			}
+			if len(internal.requests) >= maxPendingRequests {
+				// responses were not captured
+				internal.requests = nil
+			}
+			internal.requests = append(internal.requests, request{
+				command:   r.command,
+				keys:      len(r.keys),
+				retrieval: r.retrieval,
+			})
+		}
+	}
+	connMetadata.Internal = internal
+
+	for _, r := range records {
+		values = append(values, r.values(m.dumpValue))
+	}
+	return values, nil
+}
+
+// Log values
+func (m *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	m.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (m *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			client: &stream{},
+			server: &stream{},
+		},
+	}
+}
+
+// readStream read text/binary protocol and returns completed records
+func (m *Dumper) readStream(in []byte, s *stream, fromServer bool) []*record {
+	records := []*record{}
+	data := in
+	if len(s.buffer) > 0 {
+		data = append(s.buffer, in...)
+		s.buffer = nil
+	}
+	for len(data) > 0 {
+		if s.skip > 0 {
+			n := s.skip
+			if len(data) < n {
+				n = len(data)
+			}
+			if m.dumpValue && s.pending != nil && s.pending.valueSize > len(s.pending.value) {
+				c := s.pending.valueSize - len(s.pending.value)
+				if c > n {
+					c = n
+				}
+				s.pending.value = append(s.pending.value, data[:c]...)
+			}
+			data = data[n:]
+			s.skip -= n
+			if s.skip == 0 && s.pending != nil {
+				records = append(records, s.pending)
+				s.pending = nil
+			}
+			continue
+		}
+		if data[0] == magicRequest || data[0] == magicResponse {
+			r, n, skip, ok := readBinary(data)
+			if !ok {
+				if n < 0 {
+					// broken stream
+					return records
+				}
+				s.buffer = append([]byte{}, data...)
+				return records
+			}
+			data = data[n:]
+			if skip > 0 {
+				s.pending = r
+				s.skip = skip
+				continue
+			}
+			records = append(records, r)
+			continue
+		}
+		i := bytes.Index(data, []byte("\r\n"))
+		if i < 0 {
+			if len(data) <= maxLineLength {
+				s.buffer = append([]byte{}, data...)
+			}
+			return records
+		}
+		line := string(data[:i])
+		data = data[i+2:]
+		if fromServer {
+			if r := s.readResponseLine(line); r != nil {
+				records = append(records, r)
+			}
+			continue
+		}
+		r := readRequestLine(line)
+		if r == nil {
+			continue
+		}
+		if r.valueSize >= 0 {
+			// data block and "\r\n"
+			s.pending = r
+			s.skip = r.valueSize + 2
+			continue
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// readRequestLine parse command line of text protocol
+func readRequestLine(line string) *record {
+	tokens := strings.Fields(line)
+	if len(tokens) == 0 {
+		return nil
+	}
+	command := strings.ToLower(tokens[0])
+	args := tokens[1:]
+	r := &record{
+		protocol:  "text",
+		command:   command,
+		valueSize: -1,
+	}
+	switch {
+	case retrievalCommands[command]:
+		r.retrieval = true
+		if command == "gat" || command == "gats" {
+			// gat <exptime> <key>*
+			if len(args) > 0 {
+				args = args[1:]
+			}
+		}
+		r.keys = args
+	case storageCommands[command]:
+		// <command name> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]
+		if len(args) < 4 {
+			return r
+		}
+		r.keys = args[:1]
+		size, err := strconv.Atoi(args[3])
+		if err == nil && size >= 0 {
+			r.valueSize = size
+		}
+		r.noreply = args[len(args)-1] == "noreply"
+	case command == "ms":
+		// ms <key> <datalen> <flags>*
+		r.protocol = "meta"
+		if len(args) < 2 {
+			return r
+		}
+		r.keys = args[:1]
+		size, err := strconv.Atoi(args[1])
+		if err == nil && size >= 0 {
+			r.valueSize = size
+		}
+		r.noreply = metaQuiet(args[2:])
+	case keyCommands[command]:
+		if len(command) == 2 {
+			r.protocol = "meta"
+			r.noreply = metaQuiet(args)
+		} else if len(args) > 0 {
+			r.noreply = args[len(args)-1] == "noreply"
+		}
+		if len(args) > 0 {
+			r.keys = args[:1]
+		}
+		if command == "mg" {
+			r.retrieval = true
+		}
+	case command == "mn":
+		r.protocol = "meta"
+	default:
+		if len(args) > 0 {
+			r.noreply = args[len(args)-1] == "noreply"
+		}
+	}
+	return r
+}
+
+// metaQuiet returns whether meta command has q flag ( noreply semantics )
+func metaQuiet(flags []string) bool {
+	for _, f := range flags {
+		if f == "q" {
+			return true
+		}
+	}
+	return false
+}
+
+// readResponseLine parse response line of text protocol
+func (s *stream) readResponseLine(line string) *record {
+	tokens := strings.Fields(line)
+	if len(tokens) == 0 {
+		return nil
+	}
+	if s.current == nil {
+		s.current = &record{
+			protocol:  "text",
+			valueSize: -1,
+		}
+	}
+	r := s.current
+	switch tokens[0] {
+	case "VALUE":
+		// VALUE <key> <flags> <bytes> [<cas unique>]
+		if len(tokens) < 4 {
+			return nil
+		}
+		size, err := strconv.Atoi(tokens[3])
+		if err != nil || size < 0 {
+			return nil
+		}
+		r.hits++
+		r.keys = append(r.keys, tokens[1])
+		if r.valueSize < 0 {
+			r.valueSize = 0
+		}
+		r.valueSize += size
+		s.skip = size + 2
+		return nil
+	case "STAT":
+		return nil
+	case "VA":
+		// VA <size> <flags>*
+		r.protocol = "meta"
+		r.response = tokens[0]
+		r.hits++
+		if len(tokens) > 1 {
+			if size, err := strconv.Atoi(tokens[1]); err == nil && size >= 0 {
+				r.valueSize = size
+				s.current = nil
+				s.pending = r
+				s.skip = size + 2
+				return nil
+			}
+		}
+	case "HD", "EN", "NS", "EX", "NF", "MN":
+		r.protocol = "meta"
+		r.response = tokens[0]
+		if tokens[0] == "EN" {
+			r.misses++
+		}
+	case "CLIENT_ERROR", "SERVER_ERROR":
+		r.response = tokens[0]
+		r.message = strings.TrimSpace(strings.TrimPrefix(line, tokens[0]))
+	default:
+		r.response = tokens[0]
+	}
+	s.current = nil
+	return r
+}
+
+// readBinary parse a binary protocol packet and returns the record, length of header, extras and key, and length of value
+func readBinary(in []byte) (*record, int, int, bool) {
+	if len(in) < binaryHeaderLength {
+		return nil, 0, 0, false
+	}
+	keyLength := int(binary.BigEndian.Uint16(in[2:4]))
+	extrasLength := int(in[4])
+	bodyLength := int(binary.BigEndian.Uint32(in[8:12]))
+	if bodyLength < keyLength+extrasLength {
+		return nil, -1, 0, false
+	}
+	n := binaryHeaderLength + extrasLength + keyLength
+	if len(in) < n {
+		return nil, 0, 0, false
+	}
+	op := opcode(in[1])
+	valueLength := bodyLength - keyLength - extrasLength
+	r := &record{
+		protocol:  "binary",
+		binary:    true,
+		command:   op.String(),
+		retrieval: op.retrieval(),
+		valueSize: valueLength,
+		opaque:    binary.BigEndian.Uint32(in[12:16]),
+	}
+	if keyLength > 0 {
+		r.keys = []string{string(in[binaryHeaderLength+extrasLength : n])}
+	}
+	if in[0] == magicRequest {
+		if valueLength == 0 {
+			r.valueSize = -1
+		}
+		return r, n, valueLength, true
+	}
+	status := binary.BigEndian.Uint16(in[6:8])
+	if name, ok := statusNames[status]; ok {
+		r.response = name
+	} else {
+		r.response = strconv.Itoa(int(status))
+	}
+	if status != statusNoError {
+		// value is error message
+		r.valueSize = -1
+		if len(in) >= n+valueLength {
+			r.message = string(in[n : n+valueLength])
+		}
+	}
+	if r.retrieval {
+		switch status {
+		case statusNoError:
+			r.hits++
+		case statusKeyNotFound:
+			r.misses++
+		}
+	} else if valueLength == 0 {
+		r.valueSize = -1
+	}
+	return r, n, valueLength, true
+}
+
+// values returns DumpValues of record
+func (r *record) values(dumpValue bool) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "protocol",
+			Value: r.protocol,
+		},
+	}
+	if r.command != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "command",
+			Value: r.command,
+		})
+	}
+	if len(r.keys) > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "keys",
+			Value: r.keys,
+		})
+	}
+	if r.valueSize >= 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "value_size",
+			Value: r.valueSize,
+		})
+	}
+	if dumpValue && r.value != nil {
+		values = append(values, dumper.DumpValue{
+			Key:   "value",
+			Value: string(r.value),
+		})
+	}
+	if r.noreply {
+		values = append(values, dumper.DumpValue{
+			Key:   "noreply",
+			Value: true,
+		})
+	}
+	if r.binary {
+		values = append(values, dumper.DumpValue{
+			Key:   "opaque",
+			Value: r.opaque,
+		})
+	}
+	if r.response != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "response",
+			Value: r.response,
+		})
+	}
+	if r.message != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "error_message",
+			Value: r.message,
+		})
+	}
+	if r.response != "" && r.retrieval {
+		values = append(values, dumper.DumpValue{
+			Key:   "hits",
+			Value: r.hits,
+		})
+		values = append(values, dumper.DumpValue{
+			Key:   "misses",
+			Value: r.misses,
+		})
+	}
+	return values
+}
@@ -0,0 +1,667 @@
+package memcached
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type memcachedStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  []dumper.DumpValue
+}
+
+var memcachedReadTests = []struct {
+	description string
+	dumpValue   bool
+	steps       []memcachedStep
+}{
+	{
+		"Text get with multiple keys",
+		false,
+		[]memcachedStep{
+			{
+				[]byte("get foo bar baz\r\n"),
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "get",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo", "bar", "baz"},
+					},
+				},
+			},
+			{
+				[]byte("VALUE foo 0 5\r\nhello\r\nVALUE baz 0 3\r\n"),
+				dumper.DstToSrc,
+				[]dumper.DumpValue{},
+			},
+			{
+				[]byte("abc\r\nEND\r\n"),
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "get",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo", "baz"},
+					},
+					dumper.DumpValue{
+						Key:   "value_size",
+						Value: 8,
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "END",
+					},
+					dumper.DumpValue{
+						Key:   "hits",
+						Value: 2,
+					},
+					dumper.DumpValue{
+						Key:   "misses",
+						Value: 1,
+					},
+				},
+			},
+		},
+	},
+	{
+		"Text set with data block split into packets",
+		true,
+		[]memcachedStep{
+			{
+				[]byte("set foo 0 3600 11\r\nhello"),
+				dumper.ClientToRemote,
+				[]dumper.DumpValue{},
+			},
+			{
+				[]byte(" world\r\n"),
+				dumper.ClientToRemote,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "set",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+					dumper.DumpValue{
+						Key:   "value_size",
+						Value: 11,
+					},
+					dumper.DumpValue{
+						Key:   "value",
+						Value: "hello world",
+					},
+				},
+			},
+			{
+				[]byte("STORED\r\n"),
+				dumper.RemoteToClient,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "set",
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "STORED",
+					},
+				},
+			},
+		},
+	},
+	{
+		"Text set does not log value by default",
+		false,
+		[]memcachedStep{
+			{
+				[]byte("set foo 0 0 5 noreply\r\nhello\r\ndelete bar\r\n"),
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "set",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+					dumper.DumpValue{
+						Key:   "value_size",
+						Value: 5,
+					},
+					dumper.DumpValue{
+						Key:   "noreply",
+						Value: true,
+					},
+				},
+			},
+			{
+				[]byte("NOT_FOUND\r\n"),
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "delete",
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "NOT_FOUND",
+					},
+				},
+			},
+		},
+	},
+	{
+		"Text error response",
+		false,
+		[]memcachedStep{
+			{
+				[]byte("incr foo abc\r\n"),
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "incr",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+				},
+			},
+			{
+				[]byte("CLIENT_ERROR invalid numeric delta argument\r\n"),
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "text",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "incr",
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "CLIENT_ERROR",
+					},
+					dumper.DumpValue{
+						Key:   "error_message",
+						Value: "invalid numeric delta argument",
+					},
+				},
+			},
+		},
+	},
+	{
+		"Meta commands",
+		false,
+		[]memcachedStep{
+			{
+				[]byte("mg foo v t\r\n"),
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "meta",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "mg",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+				},
+			},
+			{
+				[]byte("VA 5 t-1\r\nhello\r\n"),
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "meta",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "mg",
+					},
+					dumper.DumpValue{
+						Key:   "value_size",
+						Value: 5,
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "VA",
+					},
+					dumper.DumpValue{
+						Key:   "hits",
+						Value: 1,
+					},
+					dumper.DumpValue{
+						Key:   "misses",
+						Value: 0,
+					},
+				},
+			},
+			{
+				[]byte("mg bar v\r\n"),
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "meta",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "mg",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"bar"},
+					},
+				},
+			},
+			{
+				[]byte("EN\r\n"),
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "meta",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "mg",
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "EN",
+					},
+					dumper.DumpValue{
+						Key:   "hits",
+						Value: 0,
+					},
+					dumper.DumpValue{
+						Key:   "misses",
+						Value: 1,
+					},
+				},
+			},
+			{
+				[]byte("ms foo 2 T60\r\nhi\r\n"),
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "meta",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "ms",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+					dumper.DumpValue{
+						Key:   "value_size",
+						Value: 2,
+					},
+				},
+			},
+			{
+				[]byte("HD\r\n"),
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "meta",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "ms",
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "HD",
+					},
+				},
+			},
+		},
+	},
+	{
+		"Binary getkq pipeline",
+		false,
+		[]memcachedStep{
+			{
+				[]byte{
+					0x80, 0x0d, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x66, 0x6f, 0x6f, 0x80, 0x0d, 0x00, 0x03, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x62, 0x61, 0x72, 0x80, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "binary",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "getkq",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+					dumper.DumpValue{
+						Key:   "opaque",
+						Value: uint32(1),
+					},
+				},
+			},
+			{
+				[]byte{
+					0x81, 0x0d, 0x00, 0x03, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x01,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x66, 0x6f, 0x6f, 0x68,
+					0x65, 0x6c, 0x6c, 0x6f, 0x81, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "binary",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "getkq",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+					dumper.DumpValue{
+						Key:   "value_size",
+						Value: 5,
+					},
+					dumper.DumpValue{
+						Key:   "opaque",
+						Value: uint32(1),
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "no_error",
+					},
+					dumper.DumpValue{
+						Key:   "hits",
+						Value: 1,
+					},
+					dumper.DumpValue{
+						Key:   "misses",
+						Value: 0,
+					},
+				},
+			},
+		},
+	},
+	{
+		"Binary set split into packets",
+		true,
+		[]memcachedStep{
+			{
+				[]byte{
+					0x80, 0x01, 0x00, 0x03, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x16, 0x00, 0x00, 0x00, 0x04,
+					0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0e, 0x10, 0x66, 0x6f, 0x6f, 0x68,
+					0x65, 0x6c,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "binary",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "set",
+					},
+					dumper.DumpValue{
+						Key:   "keys",
+						Value: []string{"foo"},
+					},
+					dumper.DumpValue{
+						Key:   "value_size",
+						Value: 11,
+					},
+					dumper.DumpValue{
+						Key:   "value",
+						Value: "hello world",
+					},
+					dumper.DumpValue{
+						Key:   "opaque",
+						Value: uint32(4),
+					},
+				},
+			},
+		},
+	},
+	{
+		"Binary get miss",
+		false,
+		[]memcachedStep{
+			{
+				[]byte{
+					0x81, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x05,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4e, 0x6f, 0x74, 0x20, 0x66, 0x6f, 0x75, 0x6e,
+					0x64,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "protocol",
+						Value: "binary",
+					},
+					dumper.DumpValue{
+						Key:   "command",
+						Value: "get",
+					},
+					dumper.DumpValue{
+						Key:   "opaque",
+						Value: uint32(5),
+					},
+					dumper.DumpValue{
+						Key:   "response",
+						Value: "key_not_found",
+					},
+					dumper.DumpValue{
+						Key:   "error_message",
+						Value: "Not found",
+					},
+					dumper.DumpValue{
+						Key:   "hits",
+						Value: 0,
+					},
+					dumper.DumpValue{
+						Key:   "misses",
+						Value: 1,
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestMemcachedRead(t *testing.T) {
+	for _, tt := range memcachedReadTests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger:    newTestLogger(out),
+				dumpValue: tt.dumpValue,
+			}
+			connMetadata := d.NewConnMetadata()
+			for i, s := range tt.steps {
+				actual, err := d.Read(s.in, s.direction, connMetadata)
+				if err != nil {
+					t.Errorf("%v", err)
+				}
+				if !reflect.DeepEqual(actual, s.expected) {
+					t.Errorf("step %d\nactual %#v\nwant %#v", i, actual, s.expected)
+				}
+			}
+		})
+	}
+}
+
+var memcachedReadFramesTests = []struct {
+	description string
+	in          []byte
+	direction   dumper.Direction
+	expected    []string // command of records
+}{
+	{
+		"Text set / delete / get pipeline",
+		[]byte("set foo 0 0 5\r\nhello\r\ndelete bar\r\nget foo baz\r\n"),
+		dumper.SrcToDst,
+		[]string{"set", "delete", "get"},
+	},
+	{
+		"Responses of text pipeline",
+		[]byte("STORED\r\nNOT_FOUND\r\nVALUE foo 0 5\r\nhello\r\nEND\r\n"),
+		dumper.DstToSrc,
+		[]string{"set", "delete", "get"},
+	},
+	{
+		"Binary getkq pipeline",
+		[]byte{
+			0x80, 0x0d, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x66, 0x6f, 0x6f, 0x80, 0x0d, 0x00, 0x03, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x62, 0x61, 0x72, 0x80, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		dumper.SrcToDst,
+		[]string{"getkq", "getkq", "noop"},
+	},
+}
+
+func TestMemcachedReadFrames(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+	lines := 0
+	for _, tt := range memcachedReadFramesTests {
+		records, err := d.ReadFrames(tt.in, tt.direction, connMetadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := []string{}
+		for _, r := range records {
+			for _, v := range r {
+				if v.Key == "command" {
+					actual = append(actual, v.Value.(string))
+				}
+			}
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%s\nactual %v\nwant %v", tt.description, actual, tt.expected)
+		}
+		lines += len(tt.expected)
+	}
+
+	connMetadata = d.NewConnMetadata()
+	for _, tt := range memcachedReadFramesTests {
+		if err := d.Dump(tt.in, tt.direction, connMetadata, []dumper.DumpValue{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if actual := bytes.Count(out.Bytes(), []byte("\n")); actual != lines {
+		t.Errorf("actual %d lines\nwant %d", actual, lines)
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
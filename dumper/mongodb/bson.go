@@ -0,0 +1,344 @@
+package mongodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// https://bsonspec.org/spec.html
+const (
+	bsonDouble              = 0x01
+	bsonString              = 0x02
+	bsonDocument            = 0x03
+	bsonArray               = 0x04
+	bsonBinary              = 0x05
+	bsonUndefined           = 0x06
+	bsonObjectID            = 0x07
+	bsonBoolean             = 0x08
+	bsonDateTime            = 0x09
+	bsonNull                = 0x0a
+	bsonRegex               = 0x0b
+	bsonDBPointer           = 0x0c
+	bsonJavaScript          = 0x0d
+	bsonSymbol              = 0x0e
+	bsonJavaScriptWithScope = 0x0f
+	bsonInt32               = 0x10
+	bsonTimestamp           = 0x11
+	bsonInt64               = 0x12
+	bsonDecimal128          = 0x13
+	bsonMinKey              = 0xff
+	bsonMaxKey              = 0x7f
+)
+
+// element is key/value of BSON document
+type element struct {
+	key   string
+	value interface{}
+}
+
+// document is BSON document that keeps order of keys
+type document []element
+
+// lookup returns value of key
+func (d document) lookup(key string) (interface{}, bool) {
+	for _, e := range d {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+// firstKey returns first key of document ( command name )
+func (d document) firstKey() string {
+	if len(d) == 0 {
+		return ""
+	}
+	return d[0].key
+}
+
+// MarshalJSON encode document to MongoDB Extended JSON ( relaxed )
+func (d document) MarshalJSON() ([]byte, error) {
+	buff := new(bytes.Buffer)
+	buff.WriteByte('{')
+	for i, e := range d {
+		if i > 0 {
+			buff.WriteByte(',')
+		}
+		k, err := json.Marshal(e.key)
+		if err != nil {
+			return nil, err
+		}
+		buff.Write(k)
+		buff.WriteByte(':')
+		v, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buff.Write(v)
+	}
+	buff.WriteByte('}')
+	return buff.Bytes(), nil
+}
+
+// String returns Extended JSON
+func (d document) String() string {
+	b, err := d.MarshalJSON()
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// readDocument decode BSON document
+func readDocument(in []byte) (document, int, error) {
+	if len(in) < 5 {
+		return nil, 0, errors.New("invalid BSON document")
+	}
+	l := int(int32(binary.LittleEndian.Uint32(in[0:4])))
+	if l < 5 || l > len(in) || in[l-1] != 0x00 {
+		return nil, 0, errors.New("invalid BSON document length")
+	}
+	d := document{}
+	buff := in[4 : l-1]
+	for len(buff) > 0 {
+		t := buff[0]
+		key, n, err := readCString(buff[1:])
+		if err != nil {
+			return nil, 0, err
+		}
+		buff = buff[1+n:]
+		v, n, err := readValue(t, buff)
+		if err != nil {
+			return nil, 0, err
+		}
+		buff = buff[n:]
+		d = append(d, element{key: key, value: v})
+	}
+	return d, l, nil
+}
+
+func readValue(t byte, in []byte) (interface{}, int, error) {
+	switch t {
+	case bsonDouble:
+		if len(in) < 8 {
+			return nil, 0, errors.New("invalid BSON double")
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(in[0:8]))
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return map[string]string{"$numberDouble": strconv.FormatFloat(f, 'g', -1, 64)}, 8, nil
+		}
+		return f, 8, nil
+	case bsonString, bsonJavaScript, bsonSymbol:
+		s, n, err := readString(in)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch t {
+		case bsonJavaScript:
+			return map[string]string{"$code": s}, n, nil
+		case bsonSymbol:
+			return map[string]string{"$symbol": s}, n, nil
+		}
+		return s, n, nil
+	case bsonDocument:
+		return readDocument(in)
+	case bsonArray:
+		d, n, err := readDocument(in)
+		if err != nil {
+			return nil, 0, err
+		}
+		a := []interface{}{}
+		for _, e := range d {
+			a = append(a, e.value)
+		}
+		return a, n, nil
+	case bsonBinary:
+		if len(in) < 5 {
+			return nil, 0, errors.New("invalid BSON binary")
+		}
+		l := int(int32(binary.LittleEndian.Uint32(in[0:4])))
+		if l < 0 || len(in) < 5+l {
+			return nil, 0, errors.New("invalid BSON binary length")
+		}
+		return map[string]interface{}{
+			"$binary": map[string]string{
+				"base64":  base64.StdEncoding.EncodeToString(in[5 : 5+l]),
+				"subType": fmt.Sprintf("%02x", in[4]),
+			},
+		}, 5 + l, nil
+	case bsonUndefined:
+		return map[string]bool{"$undefined": true}, 0, nil
+	case bsonObjectID:
+		if len(in) < 12 {
+			return nil, 0, errors.New("invalid BSON ObjectId")
+		}
+		return map[string]string{"$oid": hex.EncodeToString(in[0:12])}, 12, nil
+	case bsonBoolean:
+		if len(in) < 1 {
+			return nil, 0, errors.New("invalid BSON boolean")
+		}
+		return in[0] == 0x01, 1, nil
+	case bsonDateTime:
+		if len(in) < 8 {
+			return nil, 0, errors.New("invalid BSON datetime")
+		}
+		ms := int64(binary.LittleEndian.Uint64(in[0:8]))
+		return map[string]string{"$date": time.Unix(0, ms*int64(time.Millisecond)).UTC().Format("2006-01-02T15:04:05.999Z07:00")}, 8, nil
+	case bsonNull:
+		return nil, 0, nil
+	case bsonRegex:
+		pattern, n1, err := readCString(in)
+		if err != nil {
+			return nil, 0, err
+		}
+		options, n2, err := readCString(in[n1:])
+		if err != nil {
+			return nil, 0, err
+		}
+		return map[string]interface{}{
+			"$regularExpression": map[string]string{
+				"pattern": pattern,
+				"options": options,
+			},
+		}, n1 + n2, nil
+	case bsonDBPointer:
+		s, n, err := readString(in)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(in) < n+12 {
+			return nil, 0, errors.New("invalid BSON DBPointer")
+		}
+		return map[string]interface{}{
+			"$dbPointer": map[string]interface{}{
+				"$ref": s,
+				"$id":  map[string]string{"$oid": hex.EncodeToString(in[n : n+12])},
+			},
+		}, n + 12, nil
+	case bsonJavaScriptWithScope:
+		if len(in) < 4 {
+			return nil, 0, errors.New("invalid BSON JavaScript code with scope")
+		}
+		l := int(int32(binary.LittleEndian.Uint32(in[0:4])))
+		if l < 4 || l > len(in) {
+			return nil, 0, errors.New("invalid BSON JavaScript code with scope length")
+		}
+		s, n, err := readString(in[4:l])
+		if err != nil {
+			return nil, 0, err
+		}
+		scope, _, err := readDocument(in[4+n : l])
+		if err != nil {
+			return nil, 0, err
+		}
+		return map[string]interface{}{
+			"$code":  s,
+			"$scope": scope,
+		}, l, nil
+	case bsonInt32:
+		if len(in) < 4 {
+			return nil, 0, errors.New("invalid BSON int32")
+		}
+		return int32(binary.LittleEndian.Uint32(in[0:4])), 4, nil
+	case bsonTimestamp:
+		if len(in) < 8 {
+			return nil, 0, errors.New("invalid BSON timestamp")
+		}
+		return map[string]interface{}{
+			"$timestamp": map[string]uint32{
+				"t": binary.LittleEndian.Uint32(in[4:8]),
+				"i": binary.LittleEndian.Uint32(in[0:4]),
+			},
+		}, 8, nil
+	case bsonInt64:
+		if len(in) < 8 {
+			return nil, 0, errors.New("invalid BSON int64")
+		}
+		return int64(binary.LittleEndian.Uint64(in[0:8])), 8, nil
+	case bsonDecimal128:
+		if len(in) < 16 {
+			return nil, 0, errors.New("invalid BSON decimal128")
+		}
+		return map[string]string{"$numberDecimal": decimal128String(binary.LittleEndian.Uint64(in[8:16]), binary.LittleEndian.Uint64(in[0:8]))}, 16, nil
+	case bsonMinKey:
+		return map[string]int{"$minKey": 1}, 0, nil
+	case bsonMaxKey:
+		return map[string]int{"$maxKey": 1}, 0, nil
+	}
+	return nil, 0, errors.Errorf("unknown BSON type: 0x%02x", t)
+}
+
+func readCString(in []byte) (string, int, error) {
+	i := bytes.IndexByte(in, 0x00)
+	if i < 0 {
+		return "", 0, errors.New("invalid BSON cstring")
+	}
+	return string(in[:i]), i + 1, nil
+}
+
+func readString(in []byte) (string, int, error) {
+	if len(in) < 4 {
+		return "", 0, errors.New("invalid BSON string")
+	}
+	l := int(int32(binary.LittleEndian.Uint32(in[0:4])))
+	if l < 1 || len(in) < 4+l {
+		return "", 0, errors.New("invalid BSON string length")
+	}
+	return strings.TrimRight(string(in[4:4+l]), "\x00"), 4 + l, nil
+}
+
+// decimal128String format IEEE 754-2008 decimal128 ( BID )
+func decimal128String(high, low uint64) string {
+	sign := ""
+	if high>>63 == 1 {
+		sign = "-"
+	}
+	var exp int
+	var coefficientHigh uint64
+	switch {
+	case (high>>58)&0x1f == 0x1f:
+		return "NaN"
+	case (high>>58)&0x1f == 0x1e:
+		return sign + "Infinity"
+	case (high>>61)&0x03 == 0x03:
+		// coefficient is larger than maximum ( non canonical )
+		exp = int((high>>47)&0x3fff) - 6176
+		coefficientHigh = 0
+		low = 0
+	default:
+		exp = int((high>>49)&0x3fff) - 6176
+		coefficientHigh = high & 0x1ffffffffffff
+	}
+	coefficient := new(big.Int).SetUint64(coefficientHigh)
+	coefficient.Lsh(coefficient, 64)
+	coefficient.Or(coefficient, new(big.Int).SetUint64(low))
+	digits := coefficient.String()
+	if exp == 0 {
+		return sign + digits
+	}
+	if exp < 0 && len(digits)+exp > 0 {
+		// plain notation
+		return sign + digits[:len(digits)+exp] + "." + digits[len(digits)+exp:]
+	}
+	if exp < 0 && len(digits)+exp > -6 {
+		return sign + "0." + strings.Repeat("0", -(len(digits)+exp)) + digits
+	}
+	adjusted := exp + len(digits) - 1
+	s := digits[:1]
+	if len(digits) > 1 {
+		s = s + "." + digits[1:]
+	}
+	return fmt.Sprintf("%s%sE%+d", sign, s, adjusted)
+}
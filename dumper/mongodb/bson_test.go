@@ -0,0 +1,85 @@
+package mongodb
+
+import (
+	"testing"
+)
+
+var readDocumentTests = []struct {
+	description string
+	in          []byte
+	expected    string
+}{
+	{
+		"Decode BSON types to Extended JSON",
+		[]byte{
+			0xd9, 0x00, 0x00, 0x00, 0x01, 0x64, 0x6f, 0x75, 0x62, 0x6c, 0x65, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0xf8, 0x3f, 0x02, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x00, 0x04, 0x00, 0x00, 0x00,
+			0x61, 0x62, 0x63, 0x00, 0x04, 0x61, 0x72, 0x72, 0x61, 0x79, 0x00, 0x15, 0x00, 0x00, 0x00, 0x10,
+			0x30, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x31, 0x00, 0x02, 0x00, 0x00, 0x00, 0x78, 0x00, 0x00,
+			0x05, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03,
+			0x07, 0x6f, 0x69, 0x64, 0x00, 0x5f, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e, 0x6f, 0x70, 0x81, 0x92, 0x03,
+			0x04, 0x08, 0x62, 0x6f, 0x6f, 0x6c, 0x00, 0x01, 0x09, 0x64, 0x61, 0x74, 0x65, 0x00, 0x00, 0xe8,
+			0x66, 0x5e, 0x6f, 0x01, 0x00, 0x00, 0x0a, 0x6e, 0x75, 0x6c, 0x6c, 0x00, 0x0b, 0x72, 0x65, 0x67,
+			0x65, 0x78, 0x00, 0x5e, 0x61, 0x2e, 0x2a, 0x00, 0x69, 0x00, 0x10, 0x69, 0x6e, 0x74, 0x33, 0x32,
+			0x00, 0xf9, 0xff, 0xff, 0xff, 0x11, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x00,
+			0x01, 0x00, 0x00, 0x00, 0x00, 0xe1, 0x0b, 0x5e, 0x12, 0x69, 0x6e, 0x74, 0x36, 0x34, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x13, 0x64, 0x65, 0x63, 0x69, 0x6d, 0x61, 0x6c, 0x00,
+			0x39, 0x30, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3c, 0x30,
+			0xff, 0x6d, 0x69, 0x6e, 0x4b, 0x65, 0x79, 0x00, 0x00,
+		},
+		`{"double":1.5,"string":"abc","array":[1,"x"],"binary":{"$binary":{"base64":"AQID","subType":"00"}},"oid":{"$oid":"5f1a2b3c4d5e6f7081920304"},"bool":true,"date":{"$date":"2020-01-01T00:00:00Z"},"null":null,"regex":{"$regularExpression":{"options":"i","pattern":"^a.*"}},"int32":-7,"timestamp":{"$timestamp":{"i":1,"t":1577836800}},"int64":1099511627776,"decimal":{"$numberDecimal":"123.45"},"minKey":{"$minKey":1}}`,
+	},
+	{
+		"Empty document",
+		[]byte{0x05, 0x00, 0x00, 0x00, 0x00},
+		`{}`,
+	},
+}
+
+func TestReadDocument(t *testing.T) {
+	for _, tt := range readDocumentTests {
+		t.Run(tt.description, func(t *testing.T) {
+			d, n, err := readDocument(tt.in)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if n != len(tt.in) {
+				t.Errorf("actual %d\nwant %d", n, len(tt.in))
+			}
+			actual := d.String()
+			if actual != tt.expected {
+				t.Errorf("actual %s\nwant %s", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadDocumentInvalid(t *testing.T) {
+	in := []byte{0x10, 0x00, 0x00, 0x00, 0x02, 0x61, 0x00, 0xff, 0x00, 0x00, 0x00, 0x61, 0x00}
+	if _, _, err := readDocument(in); err == nil {
+		t.Errorf("want error")
+	}
+}
+
+var decimal128StringTests = []struct {
+	high     uint64
+	low      uint64
+	expected string
+}{
+	{0x3040000000000000, 1, "1"},
+	{0x303c000000000000, 12345, "123.45"},
+	{0xb040000000000000, 5, "-5"},
+	{0x3034000000000000, 1, "0.000001"},
+	{0x3046000000000000, 12, "1.2E+4"},
+	{0x7c00000000000000, 0, "NaN"},
+	{0x7800000000000000, 0, "Infinity"},
+}
+
+func TestDecimal128String(t *testing.T) {
+	for _, tt := range decimal128StringTests {
+		actual := decimal128String(tt.high, tt.low)
+		if actual != tt.expected {
+			t.Errorf("actual %s\nwant %s", actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package mongodb
+
+// https://www.mongodb.com/docs/manual/reference/mongodb-wire-protocol/#opcodes
+type opCode int32
+
+const (
+	opReply       opCode = 1
+	opUpdate      opCode = 2001
+	opInsert      opCode = 2002
+	opQuery       opCode = 2004
+	opGetMore     opCode = 2005
+	opDelete      opCode = 2006
+	opKillCursors opCode = 2007
+	opCompressed  opCode = 2012
+	opMsg         opCode = 2013
+)
+
+func (o opCode) String() string {
+	switch o {
+	case opReply:
+		return "OP_REPLY"
+	case opUpdate:
+		return "OP_UPDATE"
+	case opInsert:
+		return "OP_INSERT"
+	case opQuery:
+		return "OP_QUERY"
+	case opGetMore:
+		return "OP_GET_MORE"
+	case opDelete:
+		return "OP_DELETE"
+	case opKillCursors:
+		return "OP_KILL_CURSORS"
+	case opCompressed:
+		return "OP_COMPRESSED"
+	case opMsg:
+		return "OP_MSG"
+	}
+	return "UNKNOWN"
+}
+
+// https://github.com/mongodb/specifications/blob/master/source/compression/OP_COMPRESSED.md
+type compressorID uint8
+
+const (
+	compressorNoop   compressorID = 0
+	compressorSnappy compressorID = 1
+	compressorZlib   compressorID = 2
+	compressorZstd   compressorID = 3
+)
+
+func (c compressorID) String() string {
+	switch c {
+	case compressorNoop:
+		return "noop"
+	case compressorSnappy:
+		return "snappy"
+	case compressorZlib:
+		return "zlib"
+	case compressorZstd:
+		return "zstd"
+	}
+	return "unknown"
+}
+
+const (
+	headerLength = 16
+
+	// https://www.mongodb.com/docs/manual/reference/command/hello/#mongodb-data-hello.maxMessageSizeBytes
+	maxMessageSize = 48000000
+
+	// OP_MSG flagBits
+	msgFlagChecksumPresent = 1 << 0
+
+	// OP_MSG section kind
+	sectionBody             = 0
+	sectionDocumentSequence = 1
+
+	maxRequests = 1000
+)
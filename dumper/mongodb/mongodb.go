@@ -0,0 +1,526 @@
+package mongodb
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderOnce sync.Once
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	clientCache []byte
+	serverCache []byte
+	requests    map[int32]string // requestID:command
+}
+
+// message is MongoDB wire protocol message
+type message struct {
+	requestID  int32
+	responseTo int32
+	opCode     opCode
+	compressor string
+	collection string // fullCollectionName of legacy opcodes
+	body       document
+	sequences  map[string][]document
+	flags      uint32
+	numberRet  int32
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "mongodb",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (m *Dumper) Name() string {
+	return m.name
+}
+
+// Dump query of MongoDB
+func (m *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := m.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		m.Log(values)
+	}
+	return nil
+}
+
+// Read return the first message of byte to analyzed string ( use ReadFrames to read all messages )
+func (m *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := m.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return messages of byte to analyzed string
+func (m *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	fromServer := direction == dumper.RemoteToClient || direction == dumper.DstToSrc
+
+	var buff []byte
+	if fromServer {
+		buff = append(internal.serverCache, in...)
+		internal.serverCache = nil
+	} else {
+		buff = append(internal.clientCache, in...)
+		internal.clientCache = nil
+	}
+
+	// split messages and cache the last partial message
+	msgs := [][]byte{}
+	msg, rest, ok := splitMessage(buff)
+	for ok {
+		msgs = append(msgs, msg)
+		msg, rest, ok = splitMessage(rest)
+	}
+	if len(rest) > 0 {
+		cache := append([]byte{}, rest...)
+		if fromServer {
+			internal.serverCache = cache
+		} else {
+			internal.clientCache = cache
+		}
+	}
+	connMetadata.Internal = internal
+
+	var err error
+	for _, msg := range msgs {
+		parsed, e := readMessage(msg)
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		var v []dumper.DumpValue
+		if fromServer {
+			v = m.readResponse(parsed, connMetadata)
+		} else {
+			v = m.readRequest(parsed, connMetadata)
+		}
+		if len(v) > 0 {
+			records = append(records, v)
+		}
+	}
+	return records, err
+}
+
+// Log values
+func (m *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	m.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (m *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			requests: map[int32]string{},
+		},
+	}
+}
+
+// splitMessage returns a complete message and rest bytes
+// when in does not have a complete message, returns nil message and in as rest ( to cache )
+func splitMessage(in []byte) ([]byte, []byte, bool) {
+	if len(in) < headerLength {
+		return nil, in, false
+	}
+	l := int(int32(binary.LittleEndian.Uint32(in[0:4])))
+	if l < headerLength || l > maxMessageSize {
+		// not message boundary
+		return nil, nil, false
+	}
+	if len(in) < l {
+		return nil, in, false
+	}
+	return in[:l], in[l:], true
+}
+
+func readMessage(in []byte) (message, error) {
+	msg := message{
+		requestID:  int32(binary.LittleEndian.Uint32(in[4:8])),
+		responseTo: int32(binary.LittleEndian.Uint32(in[8:12])),
+		opCode:     opCode(int32(binary.LittleEndian.Uint32(in[12:16]))),
+	}
+	body := in[headerLength:]
+
+	if msg.opCode == opCompressed {
+		if len(body) < 9 {
+			return msg, errors.New("invalid OP_COMPRESSED")
+		}
+		msg.opCode = opCode(int32(binary.LittleEndian.Uint32(body[0:4])))
+		uncompressedSize := int(int32(binary.LittleEndian.Uint32(body[4:8])))
+		c := compressorID(body[8])
+		msg.compressor = c.String()
+		if uncompressedSize < 0 || uncompressedSize > maxMessageSize {
+			return msg, errors.New("invalid OP_COMPRESSED uncompressedSize")
+		}
+		decompressed, err := decompress(c, body[9:], uncompressedSize)
+		if err != nil {
+			return msg, errors.Wrapf(err, "failed to decompress OP_COMPRESSED (%s)", msg.compressor)
+		}
+		body = decompressed
+	}
+
+	var err error
+	switch msg.opCode {
+	case opMsg:
+		err = msg.readOpMsg(body)
+	case opQuery:
+		err = msg.readOpQuery(body)
+	case opReply:
+		err = msg.readOpReply(body)
+	case opUpdate, opInsert, opGetMore, opDelete:
+		// int32 ZERO or flags, cstring fullCollectionName
+		if len(body) > 4 {
+			msg.collection, _, err = readCString(body[4:])
+		}
+	}
+	return msg, err
+}
+
+// https://www.mongodb.com/docs/manual/reference/mongodb-wire-protocol/#op_msg
+func (msg *message) readOpMsg(in []byte) error {
+	if len(in) < 4 {
+		return errors.New("invalid OP_MSG")
+	}
+	msg.flags = binary.LittleEndian.Uint32(in[0:4])
+	in = in[4:]
+	if msg.flags&msgFlagChecksumPresent > 0 && len(in) >= 4 {
+		in = in[:len(in)-4]
+	}
+	for len(in) > 0 {
+		kind := in[0]
+		in = in[1:]
+		switch kind {
+		case sectionBody:
+			d, n, err := readDocument(in)
+			if err != nil {
+				return err
+			}
+			msg.body = d
+			in = in[n:]
+		case sectionDocumentSequence:
+			if len(in) < 4 {
+				return errors.New("invalid OP_MSG document sequence")
+			}
+			size := int(int32(binary.LittleEndian.Uint32(in[0:4])))
+			if size < 4 || size > len(in) {
+				return errors.New("invalid OP_MSG document sequence size")
+			}
+			identifier, n, err := readCString(in[4:size])
+			if err != nil {
+				return err
+			}
+			docs := in[4+n : size]
+			for len(docs) > 0 {
+				d, n, err := readDocument(docs)
+				if err != nil {
+					return err
+				}
+				if msg.sequences == nil {
+					msg.sequences = map[string][]document{}
+				}
+				msg.sequences[identifier] = append(msg.sequences[identifier], d)
+				docs = docs[n:]
+			}
+			in = in[size:]
+		default:
+			return errors.Errorf("unknown OP_MSG section kind: %d", kind)
+		}
+	}
+	return nil
+}
+
+// https://www.mongodb.com/docs/manual/legacy-opcodes/#op_query
+func (msg *message) readOpQuery(in []byte) error {
+	if len(in) < 4 {
+		return errors.New("invalid OP_QUERY")
+	}
+	msg.flags = binary.LittleEndian.Uint32(in[0:4])
+	collection, n, err := readCString(in[4:])
+	if err != nil {
+		return err
+	}
+	msg.collection = collection
+	in = in[4+n:]
+	if len(in) < 8 {
+		return errors.New("invalid OP_QUERY")
+	}
+	d, _, err := readDocument(in[8:])
+	if err != nil {
+		return err
+	}
+	msg.body = d
+	return nil
+}
+
+// https://www.mongodb.com/docs/manual/legacy-opcodes/#op_reply
+func (msg *message) readOpReply(in []byte) error {
+	if len(in) < 20 {
+		return errors.New("invalid OP_REPLY")
+	}
+	msg.flags = binary.LittleEndian.Uint32(in[0:4])
+	msg.numberRet = int32(binary.LittleEndian.Uint32(in[16:20]))
+	if msg.numberRet > 0 && len(in) > 20 {
+		d, _, err := readDocument(in[20:])
+		if err != nil {
+			return err
+		}
+		msg.body = d
+	}
+	return nil
+}
+
+func decompress(c compressorID, in []byte, size int) ([]byte, error) {
+	switch c {
+	case compressorNoop:
+		return in, nil
+	case compressorSnappy:
+		return snappy.Decode(nil, in)
+	case compressorZlib:
+		r, err := zlib.NewReader(bytes.NewReader(in))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out := make([]byte, 0, size)
+		buff := bytes.NewBuffer(out)
+		if _, err := io.Copy(buff, io.LimitReader(r, int64(size))); err != nil {
+			return nil, err
+		}
+		return buff.Bytes(), nil
+	case compressorZstd:
+		zstdDecoderOnce.Do(func() {
+			zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		})
+		return zstdDecoder.DecodeAll(in, make([]byte, 0, size))
+	}
+	return nil, errors.Errorf("unknown compressor id: %d", c)
+}
+
+func (m *Dumper) readRequest(msg message, connMetadata *dumper.ConnMetadata) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "op_code",
+			Value: msg.opCode.String(),
+		},
+		dumper.DumpValue{
+			Key:   "request_id",
+			Value: msg.requestID,
+		},
+	}
+	if msg.compressor != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "compressor",
+			Value: msg.compressor,
+		})
+	}
+
+	var command, database, collection string
+	body := msg.body
+	switch msg.opCode {
+	case opMsg:
+		command = body.firstKey()
+		if db, ok := body.lookup("$db"); ok {
+			database, _ = db.(string)
+		}
+		collection = commandCollection(body)
+	case opQuery:
+		database, collection = splitNamespace(msg.collection)
+		if q, ok := body.lookup("$query"); ok {
+			if d, ok := q.(document); ok {
+				body = d
+			}
+		}
+		if collection == "$cmd" {
+			command = body.firstKey()
+			collection = commandCollection(body)
+		} else {
+			command = "find"
+			body = document{
+				element{key: "find", value: collection},
+				element{key: "filter", value: body},
+			}
+		}
+	default:
+		database, collection = splitNamespace(msg.collection)
+		command = strings.ToLower(strings.TrimPrefix(msg.opCode.String(), "OP_"))
+	}
+
+	if command != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "command",
+			Value: command,
+		})
+		internal := connMetadata.Internal.(connMetadataInternal)
+		if internal.requests == nil {
+			internal.requests = map[int32]string{}
+		}
+		if len(internal.requests) >= maxRequests {
+			// responses were not captured
+			internal.requests = map[int32]string{}
+		}
+		internal.requests[msg.requestID] = command
+		connMetadata.Internal = internal
+	}
+	if database != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "database",
+			Value: database,
+		})
+	}
+	if collection != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "collection",
+			Value: collection,
+		})
+	}
+	for _, key := range []string{"filter", "query", "q"} {
+		if f, ok := body.lookup(key); ok {
+			if d, ok := f.(document); ok {
+				values = append(values, dumper.DumpValue{
+					Key:   "filter",
+					Value: d.String(),
+				})
+				break
+			}
+		}
+	}
+	if len(body) > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "document",
+			Value: body.String(),
+		})
+	}
+	if len(msg.sequences) > 0 {
+		sequences := map[string]int{}
+		for identifier, docs := range msg.sequences {
+			sequences[identifier] = len(docs)
+		}
+		values = append(values, dumper.DumpValue{
+			Key:   "document_sequences",
+			Value: sequences,
+		})
+	}
+	return values
+}
+
+func (m *Dumper) readResponse(msg message, connMetadata *dumper.ConnMetadata) []dumper.DumpValue {
+	if msg.opCode != opMsg && msg.opCode != opReply {
+		return []dumper.DumpValue{}
+	}
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "op_code",
+			Value: msg.opCode.String(),
+		},
+		dumper.DumpValue{
+			Key:   "request_id",
+			Value: msg.requestID,
+		},
+		dumper.DumpValue{
+			Key:   "response_to",
+			Value: msg.responseTo,
+		},
+	}
+	if msg.compressor != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "compressor",
+			Value: msg.compressor,
+		})
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if command, ok := internal.requests[msg.responseTo]; ok {
+		delete(internal.requests, msg.responseTo)
+		connMetadata.Internal = internal
+		values = append(values, dumper.DumpValue{
+			Key:   "command",
+			Value: command,
+		})
+	}
+	if msg.opCode == opReply {
+		values = append(values, dumper.DumpValue{
+			Key:   "number_returned",
+			Value: msg.numberRet,
+		})
+	}
+	for _, key := range []string{"ok", "errmsg", "code", "codeName", "n", "nModified"} {
+		v, ok := msg.body.lookup(key)
+		if !ok {
+			continue
+		}
+		values = append(values, dumper.DumpValue{
+			Key:   key,
+			Value: v,
+		})
+	}
+	return values
+}
+
+// commandCollection returns collection name from command document ( e.g. {"find": "users"} )
+func commandCollection(body document) string {
+	command := body.firstKey()
+	if command == "getMore" {
+		if c, ok := body.lookup("collection"); ok {
+			s, _ := c.(string)
+			return s
+		}
+		return ""
+	}
+	if len(body) == 0 {
+		return ""
+	}
+	s, _ := body[0].value.(string)
+	return s
+}
+
+// splitNamespace split fullCollectionName to database and collection
+func splitNamespace(ns string) (string, string) {
+	i := strings.Index(ns, ".")
+	if i < 0 {
+		return ns, ""
+	}
+	return ns[:i], ns[i+1:]
+}
@@ -0,0 +1,582 @@
+package mongodb
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var findRequest = []byte{
+	0x63, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x4e, 0x00, 0x00, 0x00, 0x02, 0x66, 0x69, 0x6e, 0x64, 0x00, 0x06,
+	0x00, 0x00, 0x00, 0x75, 0x73, 0x65, 0x72, 0x73, 0x00, 0x03, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x00, 0x18, 0x00, 0x00, 0x00, 0x03, 0x61, 0x67, 0x65, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x10, 0x24,
+	0x67, 0x74, 0x00, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x00,
+	0x01, 0x00, 0x00, 0x00, 0x02, 0x24, 0x64, 0x62, 0x00, 0x05, 0x00, 0x00, 0x00, 0x74, 0x65, 0x73,
+	0x74, 0x00, 0x00,
+}
+
+var mongodbValueTests = []struct {
+	description string
+	in          []byte
+	direction   dumper.Direction
+	internal    connMetadataInternal
+	expected    []dumper.DumpValue
+}{
+	{
+		"Parse find command from OP_MSG",
+		[]byte{
+			0x63, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x4e, 0x00, 0x00, 0x00, 0x02, 0x66, 0x69, 0x6e, 0x64, 0x00, 0x06,
+			0x00, 0x00, 0x00, 0x75, 0x73, 0x65, 0x72, 0x73, 0x00, 0x03, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72,
+			0x00, 0x18, 0x00, 0x00, 0x00, 0x03, 0x61, 0x67, 0x65, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x10, 0x24,
+			0x67, 0x74, 0x00, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x00,
+			0x01, 0x00, 0x00, 0x00, 0x02, 0x24, 0x64, 0x62, 0x00, 0x05, 0x00, 0x00, 0x00, 0x74, 0x65, 0x73,
+			0x74, 0x00, 0x00,
+		},
+		dumper.SrcToDst,
+		connMetadataInternal{
+			requests: map[int32]string{},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_MSG",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(1),
+			},
+			dumper.DumpValue{
+				Key:   "command",
+				Value: "find",
+			},
+			dumper.DumpValue{
+				Key:   "database",
+				Value: "test",
+			},
+			dumper.DumpValue{
+				Key:   "collection",
+				Value: "users",
+			},
+			dumper.DumpValue{
+				Key:   "filter",
+				Value: `{"age":{"$gt":20}}`,
+			},
+			dumper.DumpValue{
+				Key:   "document",
+				Value: `{"find":"users","filter":{"age":{"$gt":20}},"limit":1,"$db":"test"}`,
+			},
+		},
+	},
+	{
+		"Parse response of find command from OP_MSG",
+		[]byte{
+			0x8c, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x77, 0x00, 0x00, 0x00, 0x03, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72,
+			0x00, 0x5e, 0x00, 0x00, 0x00, 0x04, 0x66, 0x69, 0x72, 0x73, 0x74, 0x42, 0x61, 0x74, 0x63, 0x68,
+			0x00, 0x2e, 0x00, 0x00, 0x00, 0x03, 0x30, 0x00, 0x26, 0x00, 0x00, 0x00, 0x07, 0x5f, 0x69, 0x64,
+			0x00, 0x5f, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e, 0x6f, 0x70, 0x81, 0x92, 0x03, 0x04, 0x02, 0x6e, 0x61,
+			0x6d, 0x65, 0x00, 0x06, 0x00, 0x00, 0x00, 0x61, 0x6c, 0x69, 0x63, 0x65, 0x00, 0x00, 0x00, 0x12,
+			0x69, 0x64, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x6e, 0x73, 0x00, 0x0b,
+			0x00, 0x00, 0x00, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x73, 0x00, 0x00, 0x01,
+			0x6f, 0x6b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, 0x00,
+		},
+		dumper.DstToSrc,
+		connMetadataInternal{
+			requests: map[int32]string{1: "find"},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_MSG",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(100),
+			},
+			dumper.DumpValue{
+				Key:   "response_to",
+				Value: int32(1),
+			},
+			dumper.DumpValue{
+				Key:   "command",
+				Value: "find",
+			},
+			dumper.DumpValue{
+				Key:   "ok",
+				Value: float64(1),
+			},
+		},
+	},
+	{
+		"Parse insert command with document sequence from OP_MSG",
+		[]byte{
+			0x7b, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x2f, 0x00, 0x00, 0x00, 0x02, 0x69, 0x6e, 0x73, 0x65, 0x72, 0x74,
+			0x00, 0x06, 0x00, 0x00, 0x00, 0x75, 0x73, 0x65, 0x72, 0x73, 0x00, 0x08, 0x6f, 0x72, 0x64, 0x65,
+			0x72, 0x65, 0x64, 0x00, 0x01, 0x02, 0x24, 0x64, 0x62, 0x00, 0x05, 0x00, 0x00, 0x00, 0x74, 0x65,
+			0x73, 0x74, 0x00, 0x00, 0x01, 0x36, 0x00, 0x00, 0x00, 0x64, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e,
+			0x74, 0x73, 0x00, 0x13, 0x00, 0x00, 0x00, 0x02, 0x6e, 0x61, 0x6d, 0x65, 0x00, 0x04, 0x00, 0x00,
+			0x00, 0x62, 0x6f, 0x62, 0x00, 0x00, 0x15, 0x00, 0x00, 0x00, 0x02, 0x6e, 0x61, 0x6d, 0x65, 0x00,
+			0x06, 0x00, 0x00, 0x00, 0x63, 0x61, 0x72, 0x6f, 0x6c, 0x00, 0x00,
+		},
+		dumper.ClientToRemote,
+		connMetadataInternal{
+			requests: map[int32]string{},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_MSG",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(2),
+			},
+			dumper.DumpValue{
+				Key:   "command",
+				Value: "insert",
+			},
+			dumper.DumpValue{
+				Key:   "database",
+				Value: "test",
+			},
+			dumper.DumpValue{
+				Key:   "collection",
+				Value: "users",
+			},
+			dumper.DumpValue{
+				Key:   "document",
+				Value: `{"insert":"users","ordered":true,"$db":"test"}`,
+			},
+			dumper.DumpValue{
+				Key:   "document_sequences",
+				Value: map[string]int{"documents": 2},
+			},
+		},
+	},
+	{
+		"Parse response of insert command from OP_MSG",
+		[]byte{
+			0x2d, 0x00, 0x00, 0x00, 0x65, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x10, 0x6e, 0x00, 0x02, 0x00, 0x00, 0x00,
+			0x01, 0x6f, 0x6b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, 0x00,
+		},
+		dumper.RemoteToClient,
+		connMetadataInternal{
+			requests: map[int32]string{2: "insert"},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_MSG",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(101),
+			},
+			dumper.DumpValue{
+				Key:   "response_to",
+				Value: int32(2),
+			},
+			dumper.DumpValue{
+				Key:   "command",
+				Value: "insert",
+			},
+			dumper.DumpValue{
+				Key:   "ok",
+				Value: float64(1),
+			},
+			dumper.DumpValue{
+				Key:   "n",
+				Value: int32(2),
+			},
+		},
+	},
+	{
+		"Parse error response from OP_MSG",
+		[]byte{
+			0x81, 0x00, 0x00, 0x00, 0x66, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x6c, 0x00, 0x00, 0x00, 0x01, 0x6f, 0x6b, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x65, 0x72, 0x72, 0x6d, 0x73, 0x67, 0x00, 0x2a, 0x00, 0x00,
+			0x00, 0x6e, 0x6f, 0x74, 0x20, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x64, 0x20,
+			0x6f, 0x6e, 0x20, 0x74, 0x65, 0x73, 0x74, 0x20, 0x74, 0x6f, 0x20, 0x65, 0x78, 0x65, 0x63, 0x75,
+			0x74, 0x65, 0x20, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x00, 0x10, 0x63, 0x6f, 0x64, 0x65,
+			0x00, 0x0d, 0x00, 0x00, 0x00, 0x02, 0x63, 0x6f, 0x64, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x00, 0x0d,
+			0x00, 0x00, 0x00, 0x55, 0x6e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x64, 0x00,
+			0x00,
+		},
+		dumper.DstToSrc,
+		connMetadataInternal{
+			requests: map[int32]string{},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_MSG",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(102),
+			},
+			dumper.DumpValue{
+				Key:   "response_to",
+				Value: int32(5),
+			},
+			dumper.DumpValue{
+				Key:   "ok",
+				Value: float64(0),
+			},
+			dumper.DumpValue{
+				Key:   "errmsg",
+				Value: "not authorized on test to execute command",
+			},
+			dumper.DumpValue{
+				Key:   "code",
+				Value: int32(13),
+			},
+			dumper.DumpValue{
+				Key:   "codeName",
+				Value: "Unauthorized",
+			},
+		},
+	},
+	{
+		"Parse command from OP_QUERY",
+		[]byte{
+			0x67, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xd4, 0x07, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x24, 0x63, 0x6d, 0x64, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x40, 0x00, 0x00, 0x00, 0x10, 0x69, 0x73, 0x4d, 0x61,
+			0x73, 0x74, 0x65, 0x72, 0x00, 0x01, 0x00, 0x00, 0x00, 0x03, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74,
+			0x00, 0x25, 0x00, 0x00, 0x00, 0x03, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f,
+			0x6e, 0x00, 0x13, 0x00, 0x00, 0x00, 0x02, 0x6e, 0x61, 0x6d, 0x65, 0x00, 0x04, 0x00, 0x00, 0x00,
+			0x61, 0x70, 0x70, 0x00, 0x00, 0x00, 0x00,
+		},
+		dumper.SrcToDst,
+		connMetadataInternal{
+			requests: map[int32]string{},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_QUERY",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(3),
+			},
+			dumper.DumpValue{
+				Key:   "command",
+				Value: "isMaster",
+			},
+			dumper.DumpValue{
+				Key:   "database",
+				Value: "admin",
+			},
+			dumper.DumpValue{
+				Key:   "document",
+				Value: `{"isMaster":1,"client":{"application":{"name":"app"}}}`,
+			},
+		},
+	},
+	{
+		"Parse legacy query from OP_QUERY",
+		[]byte{
+			0x3c, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xd4, 0x07, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x73, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x15, 0x00, 0x00, 0x00, 0x02, 0x6e, 0x61, 0x6d, 0x65,
+			0x00, 0x06, 0x00, 0x00, 0x00, 0x61, 0x6c, 0x69, 0x63, 0x65, 0x00, 0x00,
+		},
+		dumper.SrcToDst,
+		connMetadataInternal{
+			requests: map[int32]string{},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_QUERY",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(4),
+			},
+			dumper.DumpValue{
+				Key:   "command",
+				Value: "find",
+			},
+			dumper.DumpValue{
+				Key:   "database",
+				Value: "test",
+			},
+			dumper.DumpValue{
+				Key:   "collection",
+				Value: "users",
+			},
+			dumper.DumpValue{
+				Key:   "filter",
+				Value: `{"name":"alice"}`,
+			},
+			dumper.DumpValue{
+				Key:   "document",
+				Value: `{"find":"users","filter":{"name":"alice"}}`,
+			},
+		},
+	},
+	{
+		"Parse OP_REPLY",
+		[]byte{
+			0x40, 0x00, 0x00, 0x00, 0x67, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+			0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x00, 0x00, 0x00, 0x1c, 0x00, 0x00, 0x00, 0x08, 0x69, 0x73, 0x6d, 0x61, 0x73, 0x74, 0x65,
+			0x72, 0x00, 0x01, 0x01, 0x6f, 0x6b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, 0x00,
+		},
+		dumper.DstToSrc,
+		connMetadataInternal{
+			requests: map[int32]string{3: "isMaster"},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "op_code",
+				Value: "OP_REPLY",
+			},
+			dumper.DumpValue{
+				Key:   "request_id",
+				Value: int32(103),
+			},
+			dumper.DumpValue{
+				Key:   "response_to",
+				Value: int32(3),
+			},
+			dumper.DumpValue{
+				Key:   "command",
+				Value: "isMaster",
+			},
+			dumper.DumpValue{
+				Key:   "number_returned",
+				Value: int32(1),
+			},
+			dumper.DumpValue{
+				Key:   "ok",
+				Value: float64(1),
+			},
+		},
+	},
+}
+
+func TestMongodbRead(t *testing.T) {
+	for _, tt := range mongodbValueTests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := &dumper.ConnMetadata{
+				DumpValues: []dumper.DumpValue{},
+				Internal:   tt.internal,
+			}
+
+			actual, err := d.Read(tt.in, tt.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			if len(actual) != len(tt.expected) {
+				t.Errorf("actual %v\nwant %v", actual, tt.expected)
+			}
+			for i := 0; i < len(actual) && i < len(tt.expected); i++ {
+				if !reflect.DeepEqual(actual[i], tt.expected[i]) {
+					t.Errorf("actual %#v\nwant %#v", actual[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMongodbReadSplitPackets(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+
+	actual, err := d.Read(findRequest[:10], dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(actual) != 0 {
+		t.Errorf("actual %#v\nwant %#v", actual, []dumper.DumpValue{})
+	}
+	actual, err = d.Read(findRequest[10:50], dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(actual) != 0 {
+		t.Errorf("actual %#v\nwant %#v", actual, []dumper.DumpValue{})
+	}
+	actual, err = d.Read(findRequest[50:], dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(actual) != len(mongodbValueTests[0].expected) {
+		t.Errorf("actual %#v\nwant %#v", actual, mongodbValueTests[0].expected)
+	}
+}
+
+func TestMongodbReadFrames(t *testing.T) {
+	second := append([]byte{}, findRequest...)
+	binary.LittleEndian.PutUint32(second[4:8], 2)
+	third := append([]byte{}, findRequest...)
+	binary.LittleEndian.PutUint32(third[4:8], 3)
+	requests := append(append(append([]byte{}, findRequest...), second...), third[:10]...)
+	// replies arrive back to back ( e.g. exhaust cursor )
+	response := mongodbValueTests[1].in
+	responses := append(append([]byte{}, response...), response...)
+
+	tests := []struct {
+		in        []byte
+		direction dumper.Direction
+		expected  []int32 // request_id of records
+	}{
+		{requests, dumper.SrcToDst, []int32{1, 2}},
+		{third[10:], dumper.SrcToDst, []int32{3}},
+		{responses, dumper.DstToSrc, []int32{100, 100}},
+	}
+
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+	for _, tt := range tests {
+		records, err := d.ReadFrames(tt.in, tt.direction, connMetadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := []int32{}
+		for _, r := range records {
+			for _, v := range r {
+				if v.Key == "request_id" {
+					actual = append(actual, v.Value.(int32))
+				}
+			}
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("actual %v\nwant %v", actual, tt.expected)
+		}
+	}
+
+	connMetadata = d.NewConnMetadata()
+	for _, tt := range tests {
+		if err := d.Dump(tt.in, tt.direction, connMetadata, []dumper.DumpValue{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if actual := bytes.Count(out.Bytes(), []byte("\n")); actual != 5 {
+		t.Errorf("actual %d lines\nwant %d", actual, 5)
+	}
+}
+
+var mongodbCompressedTests = []struct {
+	compressor compressorID
+	expected   string
+}{
+	{compressorNoop, "noop"},
+	{compressorSnappy, "snappy"},
+	{compressorZlib, "zlib"},
+	{compressorZstd, "zstd"},
+}
+
+func TestMongodbReadCompressed(t *testing.T) {
+	for _, tt := range mongodbCompressedTests {
+		t.Run(tt.expected, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := d.NewConnMetadata()
+
+			in := newCompressedMessage(t, findRequest, tt.compressor)
+			actual, err := d.Read(in, dumper.SrcToDst, connMetadata)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			expected := []dumper.DumpValue{}
+			expected = append(expected, mongodbValueTests[0].expected[:2]...)
+			expected = append(expected, dumper.DumpValue{
+				Key:   "compressor",
+				Value: tt.expected,
+			})
+			expected = append(expected, mongodbValueTests[0].expected[2:]...)
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("actual %#v\nwant %#v", actual, expected)
+			}
+		})
+	}
+}
+
+// newCompressedMessage returns OP_COMPRESSED message
+func newCompressedMessage(t *testing.T, in []byte, c compressorID) []byte {
+	original := in[headerLength:]
+	var compressed []byte
+	switch c {
+	case compressorNoop:
+		compressed = original
+	case compressorSnappy:
+		compressed = snappy.Encode(nil, original)
+	case compressorZlib:
+		buff := new(bytes.Buffer)
+		w := zlib.NewWriter(buff)
+		if _, err := io.Copy(w, bytes.NewReader(original)); err != nil {
+			t.Fatal(err)
+		}
+		_ = w.Close()
+		compressed = buff.Bytes()
+	case compressorZstd:
+		e, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressed = e.EncodeAll(original, nil)
+	}
+	out := make([]byte, headerLength+9)
+	binary.LittleEndian.PutUint32(out[0:4], uint32(headerLength+9+len(compressed)))
+	copy(out[4:12], in[4:12])
+	binary.LittleEndian.PutUint32(out[12:16], uint32(opCompressed))
+	copy(out[16:20], in[12:16])
+	binary.LittleEndian.PutUint32(out[20:24], uint32(len(original)))
+	out[24] = byte(c)
+	return append(out, compressed...)
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
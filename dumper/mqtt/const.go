@@ -0,0 +1,186 @@
+package mqtt
+
+// maxPacketSize is max size of buffered packet. The rest of larger packet ( payload of PUBLISH ) is skipped
+const maxPacketSize = 64 * 1024
+
+// maxRemainingLengthSize is max size of Variable Byte Integer
+const maxRemainingLengthSize = 4
+
+// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901022
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	packetAuth        = 15
+)
+
+var packetTypeNames = map[uint8]string{
+	packetConnect:     "CONNECT",
+	packetConnack:     "CONNACK",
+	packetPublish:     "PUBLISH",
+	packetPuback:      "PUBACK",
+	packetPubrec:      "PUBREC",
+	packetPubrel:      "PUBREL",
+	packetPubcomp:     "PUBCOMP",
+	packetSubscribe:   "SUBSCRIBE",
+	packetSuback:      "SUBACK",
+	packetUnsubscribe: "UNSUBSCRIBE",
+	packetUnsuback:    "UNSUBACK",
+	packetPingreq:     "PINGREQ",
+	packetPingresp:    "PINGRESP",
+	packetDisconnect:  "DISCONNECT",
+	packetAuth:        "AUTH",
+}
+
+// protocol level of CONNECT
+const (
+	version31  = 3
+	version311 = 4
+	version5   = 5
+)
+
+// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901027
+const (
+	propPayloadFormatIndicator          = 0x01
+	propMessageExpiryInterval           = 0x02
+	propContentType                     = 0x03
+	propResponseTopic                   = 0x08
+	propCorrelationData                 = 0x09
+	propSubscriptionIdentifier          = 0x0b
+	propSessionExpiryInterval           = 0x11
+	propAssignedClientIdentifier        = 0x12
+	propServerKeepAlive                 = 0x13
+	propAuthenticationMethod            = 0x15
+	propAuthenticationData              = 0x16
+	propRequestProblemInformation       = 0x17
+	propWillDelayInterval               = 0x18
+	propRequestResponseInformation      = 0x19
+	propResponseInformation             = 0x1a
+	propServerReference                 = 0x1c
+	propReasonString                    = 0x1f
+	propReceiveMaximum                  = 0x21
+	propTopicAliasMaximum               = 0x22
+	propTopicAlias                      = 0x23
+	propMaximumQoS                      = 0x24
+	propRetainAvailable                 = 0x25
+	propUserProperty                    = 0x26
+	propMaximumPacketSize               = 0x27
+	propWildcardSubscriptionAvailable   = 0x28
+	propSubscriptionIdentifierAvailable = 0x29
+	propSharedSubscriptionAvailable     = 0x2a
+)
+
+// property types
+const (
+	typeByte = iota
+	typeTwoByteInteger
+	typeFourByteInteger
+	typeVariableByteInteger
+	typeUTF8String
+	typeBinaryData
+	typeUTF8StringPair
+)
+
+// property is name and type of property
+type property struct {
+	name     string
+	dataType int
+}
+
+var properties = map[uint64]property{
+	propPayloadFormatIndicator:          {"payload_format_indicator", typeByte},
+	propMessageExpiryInterval:           {"message_expiry_interval", typeFourByteInteger},
+	propContentType:                     {"content_type", typeUTF8String},
+	propResponseTopic:                   {"response_topic", typeUTF8String},
+	propCorrelationData:                 {"correlation_data", typeBinaryData},
+	propSubscriptionIdentifier:          {"subscription_identifier", typeVariableByteInteger},
+	propSessionExpiryInterval:           {"session_expiry_interval", typeFourByteInteger},
+	propAssignedClientIdentifier:        {"assigned_client_identifier", typeUTF8String},
+	propServerKeepAlive:                 {"server_keep_alive", typeTwoByteInteger},
+	propAuthenticationMethod:            {"authentication_method", typeUTF8String},
+	propAuthenticationData:              {"authentication_data", typeBinaryData},
+	propRequestProblemInformation:       {"request_problem_information", typeByte},
+	propWillDelayInterval:               {"will_delay_interval", typeFourByteInteger},
+	propRequestResponseInformation:      {"request_response_information", typeByte},
+	propResponseInformation:             {"response_information", typeUTF8String},
+	propServerReference:                 {"server_reference", typeUTF8String},
+	propReasonString:                    {"reason_string", typeUTF8String},
+	propReceiveMaximum:                  {"receive_maximum", typeTwoByteInteger},
+	propTopicAliasMaximum:               {"topic_alias_maximum", typeTwoByteInteger},
+	propTopicAlias:                      {"topic_alias", typeTwoByteInteger},
+	propMaximumQoS:                      {"maximum_qos", typeByte},
+	propRetainAvailable:                 {"retain_available", typeByte},
+	propUserProperty:                    {"user_properties", typeUTF8StringPair},
+	propMaximumPacketSize:               {"maximum_packet_size", typeFourByteInteger},
+	propWildcardSubscriptionAvailable:   {"wildcard_subscription_available", typeByte},
+	propSubscriptionIdentifierAvailable: {"subscription_identifier_available", typeByte},
+	propSharedSubscriptionAvailable:     {"shared_subscription_available", typeByte},
+}
+
+// https://docs.oasis-open.org/mqtt/mqtt/v3.1.1/os/mqtt-v3.1.1-os.html#_Toc398718035
+var connectReturnCodeNames = map[uint8]string{
+	0x00: "Connection Accepted",
+	0x01: "Connection Refused, unacceptable protocol version",
+	0x02: "Connection Refused, identifier rejected",
+	0x03: "Connection Refused, Server unavailable",
+	0x04: "Connection Refused, bad user name or password",
+	0x05: "Connection Refused, not authorized",
+}
+
+// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901031
+var reasonCodeNames = map[uint8]string{
+	0x00: "Success",
+	0x01: "Granted QoS 1",
+	0x02: "Granted QoS 2",
+	0x04: "Disconnect with Will Message",
+	0x10: "No matching subscribers",
+	0x11: "No subscription existed",
+	0x18: "Continue authentication",
+	0x19: "Re-authenticate",
+	0x80: "Unspecified error",
+	0x81: "Malformed Packet",
+	0x82: "Protocol Error",
+	0x83: "Implementation specific error",
+	0x84: "Unsupported Protocol Version",
+	0x85: "Client Identifier not valid",
+	0x86: "Bad User Name or Password",
+	0x87: "Not authorized",
+	0x88: "Server unavailable",
+	0x89: "Server busy",
+	0x8a: "Banned",
+	0x8b: "Server shutting down",
+	0x8c: "Bad authentication method",
+	0x8d: "Keep Alive timeout",
+	0x8e: "Session taken over",
+	0x8f: "Topic Filter invalid",
+	0x90: "Topic Name invalid",
+	0x91: "Packet Identifier in use",
+	0x92: "Packet Identifier not found",
+	0x93: "Receive Maximum exceeded",
+	0x94: "Topic Alias invalid",
+	0x95: "Packet too large",
+	0x96: "Message rate too high",
+	0x97: "Quota exceeded",
+	0x98: "Administrative action",
+	0x99: "Payload format invalid",
+	0x9a: "Retain not supported",
+	0x9b: "QoS not supported",
+	0x9c: "Use another server",
+	0x9d: "Server moved",
+	0x9e: "Shared Subscriptions not supported",
+	0x9f: "Connection rate exceeded",
+	0xa0: "Maximum connect time",
+	0xa1: "Subscription Identifiers not supported",
+	0xa2: "Wildcard Subscriptions not supported",
+}
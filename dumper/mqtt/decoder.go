@@ -0,0 +1,150 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidPacket = errors.New("invalid MQTT packet")
+
+// decoder read data representations of MQTT
+// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901006
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func newDecoder(in []byte) *decoder {
+	return &decoder{
+		buf: in,
+	}
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errInvalidPacket
+		d.buf = nil
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) byte() uint8 {
+	b := d.read(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	b := d.read(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.read(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// varint read Variable Byte Integer
+func (d *decoder) varint() uint64 {
+	v, n, ok := readVarint(d.buf)
+	if d.err != nil || !ok || n == 0 {
+		d.err = errInvalidPacket
+		d.buf = nil
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	return string(d.binary())
+}
+
+func (d *decoder) binary() []byte {
+	return d.read(int(d.uint16()))
+}
+
+// properties read properties of MQTT 5.0
+// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901027
+func (d *decoder) properties() map[string]interface{} {
+	props := map[string]interface{}{}
+	l := d.varint()
+	if uint64(len(d.buf)) < l {
+		d.err = errInvalidPacket
+		d.buf = nil
+		return props
+	}
+	pd := newDecoder(d.read(int(l)))
+	for len(pd.buf) > 0 && pd.err == nil {
+		id := pd.varint()
+		p, ok := properties[id]
+		if !ok {
+			pd.err = errInvalidPacket
+			break
+		}
+		var v interface{}
+		switch p.dataType {
+		case typeByte:
+			v = pd.byte()
+		case typeTwoByteInteger:
+			v = pd.uint16()
+		case typeFourByteInteger:
+			v = pd.uint32()
+		case typeVariableByteInteger:
+			v = uint32(pd.varint())
+		case typeUTF8String:
+			v = pd.string()
+		case typeBinaryData:
+			b := pd.binary()
+			if id == propAuthenticationData {
+				// credentials are not dumped
+				continue
+			}
+			v = hex.EncodeToString(b)
+		case typeUTF8StringPair:
+			pair := []string{pd.string(), pd.string()}
+			pairs, _ := props[p.name].([][]string)
+			v = append(pairs, pair)
+		}
+		if _, exist := props[p.name]; exist && p.dataType != typeUTF8StringPair {
+			// keep the first one ( Subscription Identifier can be repeated )
+			continue
+		}
+		props[p.name] = v
+	}
+	if pd.err != nil {
+		d.err = pd.err
+	}
+	return props
+}
+
+// readVarint read Variable Byte Integer. n is 0 when more bytes are required
+func readVarint(b []byte) (uint64, int, bool) {
+	var v uint64
+	for i := 0; i < maxRemainingLengthSize; i++ {
+		if i >= len(b) {
+			return 0, 0, true
+		}
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i]&0x80 == 0 {
+			return v, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,207 @@
+package mqtt
+
+import (
+	"strconv"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	client  *stream
+	server  *stream
+	version uint8 // protocol level of CONNECT
+}
+
+// stream is MQTT control packets of a direction
+type stream struct {
+	buffer  []byte            // partial packet
+	skip    int               // rest bytes of large packet
+	broken  bool              // packet boundary is lost
+	aliases map[uint16]string // Topic Alias:Topic Name
+}
+
+// packet is MQTT control packet ( without fixed header )
+type packet struct {
+	packetType      uint8
+	flags           uint8
+	remainingLength int
+	data            []byte // only the head when the packet is larger than maxPacketSize
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "mqtt",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (m *Dumper) Name() string {
+	return m.name
+}
+
+// Dump MQTT control packets
+func (m *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := m.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		m.Log(values)
+	}
+	return nil
+}
+
+// Read return the first control packet of byte to analyzed string ( use ReadFrames to read all control packets )
+func (m *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := m.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return control packets of byte to analyzed string
+func (m *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	s := internal.client
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		s = internal.server
+	}
+	for _, p := range s.readPackets(in) {
+		if p.packetType == packetConnect {
+			internal.version = readProtocolVersion(p)
+		}
+		records = append(records, readPacket(p, internal.version, s))
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (m *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	m.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (m *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			client:  newStream(),
+			server:  newStream(),
+			version: version311,
+		},
+	}
+}
+
+func newStream() *stream {
+	return &stream{
+		aliases: map[uint16]string{},
+	}
+}
+
+// readPackets returns complete packets and cache the partial packet
+// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901020
+func (s *stream) readPackets(in []byte) []packet {
+	packets := []packet{}
+	if s.broken {
+		return packets
+	}
+	if s.skip > 0 {
+		if s.skip >= len(in) {
+			s.skip -= len(in)
+			return packets
+		}
+		in = in[s.skip:]
+		s.skip = 0
+	}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) > 1 {
+		packetType := buff[0] >> 4
+		l, n, ok := readVarint(buff[1:])
+		if !ok || !validFlags(packetType, buff[0]&0x0f) {
+			s.broken = true
+			return packets
+		}
+		if n == 0 {
+			break
+		}
+		headerLength := 1 + n
+		remainingLength := int(l)
+		size := remainingLength
+		if size > maxPacketSize {
+			size = maxPacketSize
+		}
+		if len(buff) < headerLength+size {
+			break
+		}
+		packets = append(packets, packet{
+			packetType:      packetType,
+			flags:           buff[0] & 0x0f,
+			remainingLength: remainingLength,
+			data:            buff[headerLength : headerLength+size],
+		})
+		if rest := headerLength + remainingLength - len(buff); rest > 0 {
+			// skip the rest of large packet
+			s.skip = rest
+			return packets
+		}
+		buff = buff[headerLength+remainingLength:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return packets
+}
+
+// validFlags returns true when flags of fixed header are valid
+// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901023
+func validFlags(packetType, flags uint8) bool {
+	switch packetType {
+	case 0:
+		return false
+	case packetPublish:
+		return (flags>>1)&0x03 != 0x03
+	case packetPubrel, packetSubscribe, packetUnsubscribe:
+		return flags == 0x02
+	default:
+		return flags == 0
+	}
+}
+
+func packetTypeName(t uint8) string {
+	if n, ok := packetTypeNames[t]; ok {
+		return n
+	}
+	return strconv.Itoa(int(t))
+}
+
+func reasonCodeName(code uint8) string {
+	if n, ok := reasonCodeNames[code]; ok {
+		return n
+	}
+	return strconv.Itoa(int(code))
+}
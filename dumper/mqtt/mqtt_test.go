@@ -0,0 +1,666 @@
+package mqtt
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type mqttStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  [][]dumper.DumpValue
+}
+
+var mqttReadFramesTests = []struct {
+	description string
+	steps       []mqttStep
+}{
+	{
+		"MQTT 3.1.1",
+		[]mqttStep{
+			{
+				[]byte{
+					0x10, 0x3e, 0x00, 0x04, 0x4d, 0x51, 0x54, 0x54, 0x04, 0xce, 0x00, 0x3c, 0x00, 0x0c, 0x74, 0x63,
+					0x70, 0x64, 0x70, 0x2d, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x00, 0x0c, 0x73, 0x74, 0x61, 0x74,
+					0x75, 0x73, 0x2f, 0x74, 0x63, 0x70, 0x64, 0x70, 0x00, 0x07, 0x6f, 0x66, 0x66, 0x6c, 0x69, 0x6e,
+					0x65, 0x00, 0x05, 0x61, 0x6c, 0x69, 0x63, 0x65, 0x00, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "CONNECT",
+						},
+						{
+							Key:   "protocol_name",
+							Value: "MQTT",
+						},
+						{
+							Key:   "protocol_version",
+							Value: uint8(4),
+						},
+						{
+							Key:   "clean_session",
+							Value: true,
+						},
+						{
+							Key:   "keep_alive",
+							Value: uint16(60),
+						},
+						{
+							Key:   "client_id",
+							Value: "tcpdp-client",
+						},
+						{
+							Key:   "will_topic",
+							Value: "status/tcpdp",
+						},
+						{
+							Key:   "will_qos",
+							Value: uint8(1),
+						},
+						{
+							Key:   "will_retain",
+							Value: false,
+						},
+						{
+							Key:   "username",
+							Value: "alice",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x20, 0x02, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "CONNACK",
+						},
+						{
+							Key:   "session_present",
+							Value: false,
+						},
+						{
+							Key:   "reason_code",
+							Value: uint8(0),
+						},
+						{
+							Key:   "reason_name",
+							Value: "Connection Accepted",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x82, 0x1e, 0x00, 0x01, 0x00, 0x0e, 0x73, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x73, 0x2f, 0x2b, 0x2f,
+					0x74, 0x65, 0x6d, 0x70, 0x01, 0x00, 0x08, 0x61, 0x6c, 0x65, 0x72, 0x74, 0x73, 0x2f, 0x23, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "SUBSCRIBE",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(1),
+						},
+						{
+							Key:   "topic_filters",
+							Value: []string{"sensors/+/temp", "alerts/#"},
+						},
+						{
+							Key:   "requested_qos",
+							Value: []uint8{0x1, 0x0},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x90, 0x04, 0x00, 0x01, 0x01, 0x80,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "SUBACK",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(1),
+						},
+						{
+							Key:   "reason_codes",
+							Value: []uint8{0x1, 0x80},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x33, 0x16, 0x00, 0x0e, 0x73, 0x65, 0x6e, 0x73, 0x6f, 0x72,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x73, 0x2f, 0x61, 0x2f, 0x74, 0x65, 0x6d, 0x70, 0x00, 0x0a, 0x32, 0x31, 0x2e, 0x35, 0xc0, 0x00,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "PUBLISH",
+						},
+						{
+							Key:   "topic",
+							Value: "sensors/a/temp",
+						},
+						{
+							Key:   "qos",
+							Value: uint8(1),
+						},
+						{
+							Key:   "retain",
+							Value: true,
+						},
+						{
+							Key:   "dup",
+							Value: false,
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(10),
+						},
+						{
+							Key:   "payload_size",
+							Value: 4,
+						},
+					},
+					{
+						{
+							Key:   "packet_type",
+							Value: "PINGREQ",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x40, 0x02, 0x00, 0x0a, 0xd0, 0x00,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "PUBACK",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(10),
+						},
+					},
+					{
+						{
+							Key:   "packet_type",
+							Value: "PINGRESP",
+						},
+					},
+				},
+			},
+			{
+				append([]byte{0x30, 0xf0, 0xa2, 0x04, 0x00, 0x03, 0x62, 0x69, 0x67}, make([]byte, 65531)...),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "PUBLISH",
+						},
+						{
+							Key:   "topic",
+							Value: "big",
+						},
+						{
+							Key:   "qos",
+							Value: uint8(0),
+						},
+						{
+							Key:   "retain",
+							Value: false,
+						},
+						{
+							Key:   "dup",
+							Value: false,
+						},
+						{
+							Key:   "payload_size",
+							Value: 69995,
+						},
+					},
+				},
+			},
+			{
+				append(make([]byte, 4464), 0xe0, 0x00),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "DISCONNECT",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"MQTT 5.0",
+		[]mqttStep{
+			{
+				[]byte{
+					0x10, 0x2b, 0x00, 0x04, 0x4d, 0x51, 0x54, 0x54, 0x05, 0x02, 0x00, 0x1e, 0x15, 0x11, 0x00, 0x00,
+					0x0e, 0x10, 0x21, 0x00, 0x14, 0x26, 0x00, 0x03, 0x61, 0x70, 0x70, 0x00, 0x05, 0x74, 0x63, 0x70,
+					0x64, 0x70, 0x00, 0x09, 0x76, 0x35, 0x2d, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "CONNECT",
+						},
+						{
+							Key:   "protocol_name",
+							Value: "MQTT",
+						},
+						{
+							Key:   "protocol_version",
+							Value: uint8(5),
+						},
+						{
+							Key:   "clean_session",
+							Value: true,
+						},
+						{
+							Key:   "keep_alive",
+							Value: uint16(30),
+						},
+						{
+							Key:   "properties",
+							Value: map[string]interface{}{"session_expiry_interval": uint32(3600), "receive_maximum": uint16(20), "user_properties": [][]string{{"app", "tcpdp"}}},
+						},
+						{
+							Key:   "client_id",
+							Value: "v5-client",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x20, 0x06, 0x00, 0x00, 0x03, 0x22, 0x00, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "CONNACK",
+						},
+						{
+							Key:   "session_present",
+							Value: false,
+						},
+						{
+							Key:   "reason_code",
+							Value: uint8(0),
+						},
+						{
+							Key:   "reason_name",
+							Value: "Success",
+						},
+						{
+							Key:   "properties",
+							Value: map[string]interface{}{"topic_alias_maximum": uint16(10)},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x30, 0x2f, 0x00, 0x0e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x2f, 0x63, 0x72, 0x65, 0x61, 0x74,
+					0x65, 0x64, 0x16, 0x23, 0x00, 0x01, 0x03, 0x00, 0x10, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x63, 0x61,
+					0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x6a, 0x73, 0x6f, 0x6e, 0x7b, 0x22, 0x69, 0x64, 0x22, 0x3a, 0x31,
+					0x7d, 0x30, 0x08, 0x00, 0x00, 0x03, 0x23, 0x00, 0x01, 0x7b, 0x7d,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "PUBLISH",
+						},
+						{
+							Key:   "topic",
+							Value: "orders/created",
+						},
+						{
+							Key:   "qos",
+							Value: uint8(0),
+						},
+						{
+							Key:   "retain",
+							Value: false,
+						},
+						{
+							Key:   "dup",
+							Value: false,
+						},
+						{
+							Key:   "properties",
+							Value: map[string]interface{}{"topic_alias": uint16(1), "content_type": "application/json"},
+						},
+						{
+							Key:   "payload_size",
+							Value: 8,
+						},
+					},
+					{
+						{
+							Key:   "packet_type",
+							Value: "PUBLISH",
+						},
+						{
+							Key:   "topic",
+							Value: "orders/created",
+						},
+						{
+							Key:   "qos",
+							Value: uint8(0),
+						},
+						{
+							Key:   "retain",
+							Value: false,
+						},
+						{
+							Key:   "dup",
+							Value: false,
+						},
+						{
+							Key:   "properties",
+							Value: map[string]interface{}{"topic_alias": uint16(1)},
+						},
+						{
+							Key:   "payload_size",
+							Value: 2,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x34, 0x07, 0x00, 0x01, 0x74, 0x00, 0x05, 0x00, 0x78,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "PUBLISH",
+						},
+						{
+							Key:   "topic",
+							Value: "t",
+						},
+						{
+							Key:   "qos",
+							Value: uint8(2),
+						},
+						{
+							Key:   "retain",
+							Value: false,
+						},
+						{
+							Key:   "dup",
+							Value: false,
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(5),
+						},
+						{
+							Key:   "payload_size",
+							Value: 1,
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x50, 0x03, 0x00, 0x05, 0x10,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "PUBREC",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(5),
+						},
+						{
+							Key:   "reason_code",
+							Value: uint8(16),
+						},
+						{
+							Key:   "reason_name",
+							Value: "No matching subscribers",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x82, 0x0b, 0x00, 0x02, 0x02, 0x0b, 0x07, 0x00, 0x03, 0x61, 0x2f, 0x23, 0x06,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "SUBSCRIBE",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(2),
+						},
+						{
+							Key:   "properties",
+							Value: map[string]interface{}{"subscription_identifier": uint32(7)},
+						},
+						{
+							Key:   "topic_filters",
+							Value: []string{"a/#"},
+						},
+						{
+							Key:   "requested_qos",
+							Value: []uint8{0x2},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x90, 0x04, 0x00, 0x02, 0x00, 0x02,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "SUBACK",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(2),
+						},
+						{
+							Key:   "reason_codes",
+							Value: []uint8{0x2},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0xa2, 0x08, 0x00, 0x03, 0x00, 0x00, 0x03, 0x61, 0x2f, 0x23,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "UNSUBSCRIBE",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(3),
+						},
+						{
+							Key:   "topic_filters",
+							Value: []string{"a/#"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0xb0, 0x04, 0x00, 0x03, 0x00, 0x11,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "UNSUBACK",
+						},
+						{
+							Key:   "packet_id",
+							Value: uint16(3),
+						},
+						{
+							Key:   "reason_codes",
+							Value: []uint8{0x11},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0xe0, 0x17, 0x8e, 0x15, 0x1f, 0x00, 0x12, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x20, 0x74,
+					0x61, 0x6b, 0x65, 0x6e, 0x20, 0x6f, 0x76, 0x65, 0x72,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "packet_type",
+							Value: "DISCONNECT",
+						},
+						{
+							Key:   "reason_code",
+							Value: uint8(142),
+						},
+						{
+							Key:   "reason_name",
+							Value: "Session taken over",
+						},
+						{
+							Key:   "properties",
+							Value: map[string]interface{}{"reason_string": "session taken over"},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Not MQTT",
+		[]mqttStep{
+			{
+				[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte("HTTP/1.1 400 Bad Request\r\n\r\n"),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+}
+
+func TestMQTTReadFrames(t *testing.T) {
+	for _, tt := range mqttReadFramesTests {
+		out := new(bytes.Buffer)
+		d := NewDumper()
+		d.logger = newTestLogger(out)
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
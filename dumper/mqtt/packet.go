@@ -0,0 +1,158 @@
+package mqtt
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// readProtocolVersion returns protocol level of CONNECT
+func readProtocolVersion(p packet) uint8 {
+	d := newDecoder(p.data)
+	_ = d.string() // protocol name
+	v := d.byte()
+	if d.err != nil {
+		return version311
+	}
+	return v
+}
+
+// readPacket returns values of control packet
+func readPacket(p packet, version uint8, s *stream) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		{
+			Key:   "packet_type",
+			Value: packetTypeName(p.packetType),
+		},
+	}
+	d := newDecoder(p.data)
+	v5 := version >= version5
+	args := []dumper.DumpValue{}
+	add := func(key string, value interface{}) {
+		args = append(args, dumper.DumpValue{
+			Key:   key,
+			Value: value,
+		})
+	}
+	addProperties := func() {
+		if !v5 {
+			return
+		}
+		if props := d.properties(); len(props) > 0 {
+			add("properties", props)
+		}
+	}
+	switch p.packetType {
+	case packetConnect:
+		// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901033
+		add("protocol_name", d.string())
+		add("protocol_version", d.byte())
+		flags := d.byte()
+		add("clean_session", flags&0x02 > 0)
+		add("keep_alive", d.uint16())
+		addProperties()
+		add("client_id", d.string())
+		if flags&0x04 > 0 {
+			if v5 {
+				// will properties
+				_ = d.properties()
+			}
+			add("will_topic", d.string())
+			_ = d.binary() // will payload
+			add("will_qos", (flags>>3)&0x03)
+			add("will_retain", flags&0x20 > 0)
+		}
+		if flags&0x80 > 0 {
+			add("username", d.string())
+		}
+		// password is not dumped
+	case packetConnack:
+		add("session_present", d.byte()&0x01 > 0)
+		code := d.byte()
+		add("reason_code", code)
+		if v5 {
+			add("reason_name", reasonCodeName(code))
+		} else {
+			add("reason_name", connectReturnCodeNames[code])
+		}
+		addProperties()
+	case packetPublish:
+		// https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901100
+		qos := (p.flags >> 1) & 0x03
+		topic := d.string()
+		var packetID uint16
+		if qos > 0 {
+			packetID = d.uint16()
+		}
+		props := map[string]interface{}{}
+		if v5 {
+			props = d.properties()
+			if alias, ok := props["topic_alias"].(uint16); ok {
+				if topic == "" {
+					topic = s.aliases[alias]
+				} else {
+					s.aliases[alias] = topic
+				}
+			}
+		}
+		add("topic", topic)
+		add("qos", qos)
+		add("retain", p.flags&0x01 > 0)
+		add("dup", p.flags&0x08 > 0)
+		if qos > 0 {
+			add("packet_id", packetID)
+		}
+		if len(props) > 0 {
+			add("properties", props)
+		}
+		add("payload_size", p.remainingLength-(len(p.data)-len(d.buf)))
+	case packetPuback, packetPubrec, packetPubrel, packetPubcomp:
+		add("packet_id", d.uint16())
+		if v5 && len(d.buf) > 0 {
+			code := d.byte()
+			add("reason_code", code)
+			add("reason_name", reasonCodeName(code))
+			if len(d.buf) > 0 {
+				addProperties()
+			}
+		}
+	case packetSubscribe, packetUnsubscribe:
+		add("packet_id", d.uint16())
+		addProperties()
+		filters := []string{}
+		qos := []uint8{}
+		for len(d.buf) > 0 && d.err == nil {
+			filters = append(filters, d.string())
+			if p.packetType == packetSubscribe {
+				// subscription options ( Requested QoS is the lower 2 bits )
+				qos = append(qos, d.byte()&0x03)
+			}
+		}
+		add("topic_filters", filters)
+		if p.packetType == packetSubscribe {
+			add("requested_qos", qos)
+		}
+	case packetSuback, packetUnsuback:
+		add("packet_id", d.uint16())
+		if p.packetType == packetUnsuback && !v5 {
+			break
+		}
+		addProperties()
+		codes := []uint8{}
+		for len(d.buf) > 0 && d.err == nil {
+			codes = append(codes, d.byte())
+		}
+		add("reason_codes", codes)
+	case packetDisconnect, packetAuth:
+		if v5 && len(d.buf) > 0 {
+			code := d.byte()
+			add("reason_code", code)
+			add("reason_name", reasonCodeName(code))
+			if len(d.buf) > 0 {
+				addProperties()
+			}
+		}
+	}
+	if d.err != nil {
+		return values
+	}
+	return append(values, args...)
+}
@@ -1,11 +1,31 @@
 package mysql
 
+import "strings"
+
 const (
+	comInitDB      = 0x02
 	comQuery       = 0x03
+	comChangeUser  = 0x11
 	comStmtPrepare = 0x16
 	comStmtExecute = 0x17
 
+	// https://dev.mysql.com/doc/internals/en/text-protocol.html
+	maxCommandID = 0x1f // COM_RESET_CONNECTION
+
+	comStmtSendLongData = 0x18
+
 	comStmtPrepareOK = 0x00
+
+	handshakeV10            = 0x0a
+	packetOK                = 0x00
+	packetERR               = 0xff
+	packetAuthSwitchRequest = 0xfe
+
+	authResultOK  = "ok"
+	authResultERR = "error"
+
+	// https://dev.mysql.com/doc/internals/en/sending-more-than-16mbyte.html
+	maxPayloadLength = 0xFFFFFF
 )
 
 type dataType byte
@@ -205,3 +225,111 @@ func (c charSet) String() string {
 		return ""
 	}
 }
+
+// collations is character set of each collation ID that is not default collation of the character set
+// https://dev.mysql.com/doc/refman/8.0/en/information-schema-collations-table.html
+var collations = map[uint32]charSet{
+	2:   charSetLatin2,   // latin2_czech_cs
+	5:   charSetLatin1,   // latin1_german1_ci
+	14:  charSetCp1251,   // cp1251_bulgarian_ci
+	15:  charSetLatin1,   // latin1_danish_ci
+	20:  charSetLatin7,   // latin7_estonian_cs
+	21:  charSetLatin2,   // latin2_hungarian_ci
+	23:  charSetCp1251,   // cp1251_ukrainian_ci
+	27:  charSetLatin2,   // latin2_croatian_ci
+	29:  charSetCp1257,   // cp1257_lithuanian_ci
+	31:  charSetLatin1,   // latin1_german2_ci
+	34:  charSetCp1250,   // cp1250_czech_cs
+	42:  charSetLatin7,   // latin7_general_cs
+	43:  charSetMacce,    // macce_bin
+	44:  charSetCp1250,   // cp1250_croatian_ci
+	45:  charSetUtf8mb4,  // utf8mb4_general_ci
+	46:  charSetUtf8mb4,  // utf8mb4_bin
+	47:  charSetLatin1,   // latin1_bin
+	48:  charSetLatin1,   // latin1_general_ci
+	49:  charSetLatin1,   // latin1_general_cs
+	50:  charSetCp1251,   // cp1251_bin
+	52:  charSetCp1251,   // cp1251_general_cs
+	53:  charSetMacroman, // macroman_bin
+	55:  charSetUtf16,    // utf16_bin
+	58:  charSetCp1257,   // cp1257_bin
+	61:  charSetUtf32,    // utf32_bin
+	62:  charSetUtf16le,  // utf16le_bin
+	64:  charSetArmscii8, // armscii8_bin
+	65:  charSetASCII,    // ascii_bin
+	66:  charSetCp1250,   // cp1250_bin
+	67:  charSetCp1256,   // cp1256_bin
+	68:  charSetCp866,    // cp866_bin
+	69:  charSetDec8,     // dec8_bin
+	70:  charSetGreek,    // greek_bin
+	71:  charSetHebrew,   // hebrew_bin
+	72:  charSetHp8,      // hp8_bin
+	73:  charSetKeybcs2,  // keybcs2_bin
+	74:  charSetKoi8r,    // koi8r_bin
+	75:  charSetKoi8u,    // koi8u_bin
+	76:  charSetUtf8,     // utf8_tolower_ci
+	77:  charSetLatin2,   // latin2_bin
+	78:  charSetLatin5,   // latin5_bin
+	79:  charSetLatin7,   // latin7_bin
+	80:  charSetCp850,    // cp850_bin
+	81:  charSetCp852,    // cp852_bin
+	82:  charSetSwe7,     // swe7_bin
+	83:  charSetUtf8,     // utf8_bin
+	84:  charSetBig5,     // big5_bin
+	85:  charSetEuckr,    // euckr_bin
+	86:  charSetGb2312,   // gb2312_bin
+	87:  charSetGbk,      // gbk_bin
+	88:  charSetSjis,     // sjis_bin
+	89:  charSetTis620,   // tis620_bin
+	90:  charSetUcs2,     // ucs2_bin
+	91:  charSetUjis,     // ujis_bin
+	93:  charSetGeostd8,  // geostd8_bin
+	94:  charSetLatin1,   // latin1_spanish_ci
+	96:  charSetCp932,    // cp932_bin
+	98:  charSetEucjpms,  // eucjpms_bin
+	99:  charSetCp1250,   // cp1250_polish_ci
+	159: charSetUcs2,     // ucs2_general_mysql500_ci
+	223: charSetUtf8,     // utf8_general_mysql500_ci
+	249: charSetGb18030,  // gb18030_bin
+	250: charSetGb18030,  // gb18030_unicode_520_ci
+}
+
+// charSetByCollationID returns charSet by collation ID sent in Protocol::HandshakeResponse41 and COM_CHANGE_USER
+func charSetByCollationID(id uint32) charSet {
+	if c, ok := collations[id]; ok {
+		return c
+	}
+	switch {
+	case id >= 101 && id <= 124: // utf16_unicode_ci ...
+		return charSetUtf16
+	case id >= 128 && id <= 151: // ucs2_unicode_ci ...
+		return charSetUcs2
+	case id >= 160 && id <= 183: // utf32_unicode_ci ...
+		return charSetUtf32
+	case id >= 192 && id <= 215: // utf8_unicode_ci ...
+		return charSetUtf8
+	case id >= 224 && id <= 247: // utf8mb4_unicode_ci ...
+		return charSetUtf8mb4
+	case id >= 255 && id <= 323: // utf8mb4_0900_ai_ci ...
+		return charSetUtf8mb4
+	}
+	if c := charSet(id); c.String() != "" {
+		// default collation of the character set
+		return c
+	}
+	return charSetUnknown
+}
+
+// charSetByName returns charSet by character set name
+func charSetByName(name string) charSet {
+	name = strings.ToLower(name)
+	if name == "utf8mb3" {
+		name = "utf8"
+	}
+	for c := charSetUnknown + 1; c <= charSetUtf8mb4; c++ {
+		if c.String() == name {
+			return c
+		}
+	}
+	return charSetUnknown
+}
@@ -1,31 +1,83 @@
 package mysql
 
 import (
-	"bytes"
-	"io/ioutil"
+	"regexp"
 	"strings"
 
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
 )
 
+var (
+	setNamesRegexp         = regexp.MustCompile("(?i)^\\s*SET\\s+(?:NAMES|CHARACTER\\s+SET|CHARSET)\\s+['\"`]?([0-9a-z_]+)")
+	setCharSetClientRegexp = regexp.MustCompile("(?i)^\\s*SET\\s+.*character_set_client\\s*:?=\\s*['\"`]?([0-9a-z_]+)")
+)
+
+// https://dev.mysql.com/doc/refman/8.0/en/charset-charsets.html
+var encodings = map[charSet]encoding.Encoding{
+	charSetBig5:     traditionalchinese.Big5,
+	charSetCp850:    charmap.CodePage850,
+	charSetKoi8r:    charmap.KOI8R,
+	charSetLatin1:   charmap.Windows1252, // MySQL latin1 is cp1252
+	charSetLatin2:   charmap.ISO8859_2,
+	charSetUjis:     japanese.EUCJP,
+	charSetSjis:     japanese.ShiftJIS,
+	charSetHebrew:   charmap.ISO8859_8,
+	charSetTis620:   charmap.Windows874,
+	charSetEuckr:    korean.EUCKR,
+	charSetKoi8u:    charmap.KOI8U,
+	charSetGb2312:   simplifiedchinese.GBK,
+	charSetGreek:    charmap.ISO8859_7,
+	charSetCp1250:   charmap.Windows1250,
+	charSetGbk:      simplifiedchinese.GBK,
+	charSetLatin5:   charmap.ISO8859_9,
+	charSetUcs2:     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	charSetCp866:    charmap.CodePage866,
+	charSetMacroman: charmap.Macintosh,
+	charSetCp852:    charmap.CodePage852,
+	charSetLatin7:   charmap.ISO8859_13,
+	charSetCp1251:   charmap.Windows1251,
+	charSetUtf16:    unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	charSetUtf16le:  unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	charSetCp1256:   charmap.Windows1256,
+	charSetCp1257:   charmap.Windows1257,
+	charSetUtf32:    utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM),
+	charSetCp932:    japanese.ShiftJIS,
+	charSetEucjpms:  japanese.EUCJP,
+	charSetGb18030:  simplifiedchinese.GB18030,
+}
+
 func readString(src []byte, srcCharSet charSet) string {
-	switch srcCharSet {
-	case charSetUjis, charSetEucjpms:
-		buff := bytes.NewBuffer(src)
-		dst, err := ioutil.ReadAll(transform.NewReader(buff, japanese.EUCJP.NewDecoder()))
-		if err != nil {
-			return strings.TrimRight(string(src), "\x00")
-		}
-		return strings.TrimRight(string(dst), "\x00")
-	case charSetSjis, charSetCp932:
-		buff := bytes.NewBuffer(src)
-		dst, err := ioutil.ReadAll(transform.NewReader(buff, japanese.ShiftJIS.NewDecoder()))
-		if err != nil {
-			return strings.TrimRight(string(src), "\x00")
-		}
-		return strings.TrimRight(string(dst), "\x00")
-	default:
+	enc, ok := encodings[srcCharSet]
+	if !ok {
+		return strings.TrimRight(string(src), "\x00")
+	}
+	dst, err := enc.NewDecoder().Bytes(src)
+	if err != nil {
 		return strings.TrimRight(string(src), "\x00")
 	}
+	return strings.TrimRight(string(dst), "\x00")
+}
+
+// parseSetCharSet returns character set of client changed by `SET NAMES`, `SET CHARACTER SET` or `SET character_set_client`
+// https://dev.mysql.com/doc/refman/8.0/en/charset-connection.html
+func parseSetCharSet(query string) (charSet, bool) {
+	m := setNamesRegexp.FindStringSubmatch(query)
+	if m == nil {
+		m = setCharSetClientRegexp.FindStringSubmatch(query)
+	}
+	if m == nil {
+		return charSetUnknown, false
+	}
+	c := charSetByName(m[1])
+	if c == charSetUnknown {
+		return charSetUnknown, false
+	}
+	return c, true
 }
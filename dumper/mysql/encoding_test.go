@@ -33,6 +33,70 @@ var readStringTests = []struct {
 		charSetUnknown,
 		"select * from posts",
 	},
+	{
+		[]byte{
+			0x0e, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0x63, 0x61, 0x66,
+			0xe9, 0x27,
+		},
+		charSetLatin1,
+		"SELECT 'café'",
+	},
+	{
+		[]byte{
+			0x10, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0xef, 0xf0, 0xe8,
+			0xe2, 0xe5, 0xf2, 0x27,
+		},
+		charSetCp1251,
+		"SELECT 'привет'",
+	},
+	{
+		[]byte{
+			0x0d, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0xcd, 0xc9, 0xd2,
+			0x27,
+		},
+		charSetKoi8r,
+		"SELECT 'мир'",
+	},
+	{
+		[]byte{
+			0x0e, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0xc4, 0xe3, 0xba,
+			0xc3, 0x27,
+		},
+		charSetGbk,
+		"SELECT '你好'",
+	},
+	{
+		[]byte{
+			0x0e, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0xc4, 0xe3, 0xba,
+			0xc3, 0x27,
+		},
+		charSetGb18030,
+		"SELECT '你好'",
+	},
+	{
+		[]byte{
+			0x0e, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0xa7, 0x41, 0xa6,
+			0x6e, 0x27,
+		},
+		charSetBig5,
+		"SELECT '你好'",
+	},
+	{
+		[]byte{
+			0x0e, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0xbe, 0xc8, 0xb3,
+			0xe7, 0x27,
+		},
+		charSetEuckr,
+		"SELECT '안녕'",
+	},
+	{
+		[]byte{
+			0x11, 0x00, 0x00, 0x00, 0x03, 0x53, 0x00, 0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x43, 0x00, 0x54,
+			0x00, 0x20, 0x00, 0x31, 0x00,
+		},
+		charSetUtf16le,
+		"SELECT 1",
+	},
 }
 
 func TestReadString(t *testing.T) {
@@ -43,3 +107,82 @@ func TestReadString(t *testing.T) {
 		}
 	}
 }
+
+var parseSetCharSetTests = []struct {
+	query    string
+	expected charSet
+	wantOK   bool
+}{
+	{"SET NAMES utf8mb4", charSetUtf8mb4, true},
+	{"set names 'sjis' COLLATE 'sjis_bin'", charSetSjis, true},
+	{"SET CHARACTER SET cp1251", charSetCp1251, true},
+	{"SET character_set_client = latin1", charSetLatin1, true},
+	{"SET autocommit=1, @@session.character_set_client='gbk'", charSetGbk, true},
+	{"SET NAMES utf8mb3", charSetUtf8, true},
+	{"SET NAMES DEFAULT", charSetUnknown, false},
+	{"SELECT 'SET NAMES sjis'", charSetUnknown, false},
+}
+
+func TestParseSetCharSet(t *testing.T) {
+	for _, tt := range parseSetCharSetTests {
+		actual, ok := parseSetCharSet(tt.query)
+		if ok != tt.wantOK {
+			t.Errorf("%s: actual %#v\nwant %#v", tt.query, ok, tt.wantOK)
+		}
+		if actual != tt.expected {
+			t.Errorf("%s: actual %#v\nwant %#v", tt.query, actual, tt.expected)
+		}
+	}
+}
+
+var charSetByCollationIDTests = []struct {
+	id       uint32
+	expected charSet
+}{
+	{8, charSetLatin1},    // latin1_swedish_ci
+	{33, charSetUtf8},     // utf8_general_ci
+	{45, charSetUtf8mb4},  // utf8mb4_general_ci
+	{46, charSetUtf8mb4},  // utf8mb4_bin
+	{255, charSetUtf8mb4}, // utf8mb4_0900_ai_ci
+	{309, charSetUtf8mb4}, // utf8mb4_0900_bin
+	{48, charSetLatin1},   // latin1_general_ci
+	{84, charSetBig5},     // big5_bin
+	{87, charSetGbk},      // gbk_bin
+	{91, charSetUjis},     // ujis_bin
+	{192, charSetUtf8},    // utf8_unicode_ci
+	{0, charSetUnknown},
+	{17, charSetUnknown},
+	{1000, charSetUnknown},
+}
+
+func TestCharSetByCollationID(t *testing.T) {
+	for _, tt := range charSetByCollationIDTests {
+		actual := charSetByCollationID(tt.id)
+		if actual != tt.expected {
+			t.Errorf("%d: actual %#v\nwant %#v", tt.id, actual, tt.expected)
+		}
+	}
+}
+
+var readStringByCollationIDTests = []struct {
+	in       []byte
+	id       uint32
+	expected string
+}{
+	{[]byte{0x63, 0x61, 0x66, 0xe9}, 48, "café"},            // latin1_general_ci
+	{[]byte{0xa7, 0x41, 0xa6, 0x6e}, 84, "你好"},              // big5_bin
+	{[]byte{0xc4, 0xe3, 0xba, 0xc3}, 87, "你好"},              // gbk_bin
+	{[]byte{0xa4, 0xa2, 0xa4, 0xa4}, 91, "あい"},              // ujis_bin
+	{[]byte{0xe3, 0x81, 0x82, 0xe3, 0x81, 0x84}, 45, "あい"},  // utf8mb4_general_ci
+	{[]byte{0xe3, 0x81, 0x82, 0xe3, 0x81, 0x84}, 46, "あい"},  // utf8mb4_bin
+	{[]byte{0xe3, 0x81, 0x82, 0xe3, 0x81, 0x84}, 255, "あい"}, // utf8mb4_0900_ai_ci
+}
+
+func TestReadStringByCollationID(t *testing.T) {
+	for _, tt := range readStringByCollationIDTests {
+		actual := readString(tt.in, charSetByCollationID(tt.id))
+		if actual != tt.expected {
+			t.Errorf("%d: actual %#v\nwant %#v", tt.id, actual, tt.expected)
+		}
+	}
+}
@@ -7,38 +7,54 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/k1LoW/tcpdp/dumper"
 	"github.com/k1LoW/tcpdp/logger"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 // Dumper struct
 type Dumper struct {
-	name   string
-	logger *zap.Logger
+	name         string
+	logger       *zap.Logger
+	maxQuerySize int // 0: unlimited
 }
 
 type clientCapabilities map[clientCapability]bool
 
 type stmtNumParams map[int]int // statement_id:num_params
 
+type authState int
+
+const (
+	authStateUnknown   authState = iota // Protocol::Handshake not captured
+	authStateHandshake                  // Protocol::Handshake received
+	authStateResponse                   // Protocol::HandshakeResponse sent, wait for OK/ERR
+	authStateDone
+)
+
 type connMetadataInternal struct {
 	clientCapabilities clientCapabilities
 	stmtNumParams      stmtNumParams
 	charSet            charSet
-	payloadLength      uint32
-	longPacketCache    []byte
+	longPacket         *longPacket // client packet not completed yet
+	authState          authState
+	resynced           bool // a command boundary is found in the connection seen mid-stream
 }
 
 // NewDumper returns a Dumper
 func NewDumper() *Dumper {
 	dumper := &Dumper{
-		name:   "mysql",
-		logger: logger.NewQueryLogger(),
+		name:         "mysql",
+		logger:       logger.NewQueryLogger(),
+		maxQuerySize: viper.GetInt("mysql.maxQuerySize"),
 	}
 	return dumper
 }
@@ -66,20 +82,45 @@ func (m *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumpe
 
 // Read return byte to analyzed string
 func (m *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	if connMetadata.MidStream && !resync(in, direction, connMetadata) {
+		return []dumper.DumpValue{}, nil
+	}
+
 	values, handshakeErr := m.readHandshakeResponse(in, direction, connMetadata)
 
 	connMetadata.DumpValues = append(connMetadata.DumpValues, values...)
 	cSet := connMetadata.Internal.(connMetadataInternal).charSet
 
-	if len(connMetadata.Internal.(connMetadataInternal).longPacketCache) > 0 {
+	if handshakeErr != nil {
+		return values, handshakeErr
+	}
+
+	joined := false
+	if p := connMetadata.Internal.(connMetadataInternal).longPacket; p != nil && (direction == dumper.ClientToRemote || direction == dumper.SrcToDst) {
+		if !p.write(in) {
+			return []dumper.DumpValue{}, nil
+		}
 		internal := connMetadata.Internal.(connMetadataInternal)
-		in = append(internal.longPacketCache, in...)
-		internal.longPacketCache = nil
+		internal.longPacket = nil
 		connMetadata.Internal = internal
+		in = p.packet
+		joined = p.headerLength == packetHeaderLength
 	}
 
-	if handshakeErr != nil {
-		return values, handshakeErr
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		authValues, ok := m.readServerAuthPacket(in, connMetadata)
+		if ok {
+			return authValues, nil
+		}
+	} else if connMetadata.Internal.(connMetadataInternal).authState == authStateResponse {
+		if len(in) < 4 || in[3] != 0x00 {
+			// HandshakeResponse, AuthSwitchResponse or other authentication data
+			return []dumper.DumpValue{}, nil
+		}
+		// sequence_id is reset to 0 in command phase, so OK packet of authentication is not captured
+		internal := connMetadata.Internal.(connMetadataInternal)
+		internal.authState = authStateDone
+		connMetadata.Internal = internal
 	}
 
 	// Client Compress
@@ -90,6 +131,15 @@ func (m *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumpe
 		lenCompressed := int(bytesToUint64(readBytes(buff, 3))) // 3:length of compressed payload
 		_ = readBytes(buff, 1)                                  // 1:compressed sequence id
 		lenUncompressed := bytesToUint64(readBytes(buff, 3))    // 3:length of payload before compression
+		if buff.Len() < lenCompressed && (direction == dumper.ClientToRemote || direction == dumper.SrcToDst) {
+			p := newLongPacket(compressedPacketHeaderLength, 0)
+			if !p.write(in) {
+				internal := connMetadata.Internal.(connMetadataInternal)
+				internal.longPacket = p
+				connMetadata.Internal = internal
+				return []dumper.DumpValue{}, nil
+			}
+		}
 		if buff.Len() == lenCompressed {
 			if lenUncompressed > 0 {
 				// https://dev.mysql.com/doc/internals/en/compressed-payload.html
@@ -128,45 +178,84 @@ func (m *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumpe
 		return []dumper.DumpValue{}, nil
 	}
 
-	var payloadLength uint32
-	internal := connMetadata.Internal.(connMetadataInternal)
-	if internal.payloadLength > 0 {
-		payloadLength = internal.payloadLength
-	} else {
-		pl := make([]byte, 3)
-		copy(pl, in[0:3])
-		payloadLength = bytesToUint32(pl) // 3:payload_length
-	}
-	if uint32(len(in[4:])) < payloadLength {
-		internal.payloadLength = payloadLength
-		internal.longPacketCache = append(internal.longPacketCache, in...)
-		connMetadata.Internal = internal
-		return []dumper.DumpValue{}, nil
+	payloadLength := int(bytesToUint32(in[0:3])) // 3:payload_length
+	if !joined && (len(in[4:]) < payloadLength || payloadLength == maxPayloadLength) {
+		p := newLongPacket(packetHeaderLength, m.maxQuerySize)
+		if !p.write(in) {
+			internal := connMetadata.Internal.(connMetadataInternal)
+			internal.longPacket = p
+			connMetadata.Internal = internal
+			return []dumper.DumpValue{}, nil
+		}
+		in = p.packet
 	}
-	internal.payloadLength = uint32(0)
-	connMetadata.Internal = internal
 
 	seqNum := int64(in[3])
 	commandID := in[4]
 
 	var dumps = []dumper.DumpValue{}
 	switch commandID {
+	case comInitDB:
+		// https://dev.mysql.com/doc/internals/en/com-init-db.html
+		setDumpValue(connMetadata, "database", readString(in[5:], cSet), true)
+		return []dumper.DumpValue{}, nil
+	case comChangeUser:
+		readChangeUser(in[5:], connMetadata)
+		return []dumper.DumpValue{}, nil
 	case comQuery:
 		query := readString(in[5:], cSet)
+		if c, ok := parseSetCharSet(query); ok {
+			internal := connMetadata.Internal.(connMetadataInternal)
+			internal.charSet = c
+			connMetadata.Internal = internal
+			setDumpValue(connMetadata, "character_set", c.String(), true)
+		}
+		query, truncated := m.truncateQuery(query)
 		dumps = []dumper.DumpValue{
 			dumper.DumpValue{
 				Key:   "query",
 				Value: query,
 			},
 		}
+		if truncated {
+			dumps = append(dumps, dumper.DumpValue{
+				Key:   "query_truncated",
+				Value: true,
+			})
+		}
 	case comStmtPrepare:
-		stmtPrepare := readString(in[5:], cSet)
+		stmtPrepare, truncated := m.truncateQuery(readString(in[5:], cSet))
 		dumps = []dumper.DumpValue{
 			dumper.DumpValue{
 				Key:   "stmt_prepare_query",
 				Value: stmtPrepare,
 			},
 		}
+		if truncated {
+			dumps = append(dumps, dumper.DumpValue{
+				Key:   "query_truncated",
+				Value: true,
+			})
+		}
+	case comStmtSendLongData:
+		// https://dev.mysql.com/doc/internals/en/com-stmt-send-long-data.html
+		buff := bytes.NewBuffer(in[5:])
+		stmtID := readBytes(buff, 4)  // 4:statement-id
+		paramID := readBytes(buff, 2) // 2:param-id
+		dumps = []dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "stmt_id",
+				Value: int(bytesToUint64(stmtID)),
+			},
+			dumper.DumpValue{
+				Key:   "stmt_param_id",
+				Value: int(bytesToUint64(paramID)),
+			},
+			dumper.DumpValue{
+				Key:   "stmt_long_data_size",
+				Value: buff.Len(),
+			},
+		}
 	case comStmtExecute:
 		// https://dev.mysql.com/doc/internals/en/com-stmt-execute.html
 		buff := bytes.NewBuffer(in[5:])
@@ -271,7 +360,6 @@ func (m *Dumper) NewConnMetadata() *dumper.ConnMetadata {
 			stmtNumParams:      stmtNumParams{},
 			clientCapabilities: clientCapabilities{},
 			charSet:            charSetUnknown,
-			payloadLength:      uint32(0),
 		},
 	}
 }
@@ -296,7 +384,7 @@ func (m *Dumper) readHandshakeResponse(in []byte, direction dumper.Direction, co
 	if len(in) > 35 && clientCapabilities&uint32(clientProtocol41) > 0 && bytes.Compare(in[13:36], []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}) == 0 {
 		internal := connMetadata.Internal.(connMetadataInternal)
 
-		cSet := charSet(uint32(in[12]))
+		cSet := charSetByCollationID(uint32(in[12]))
 		values = append(values, dumper.DumpValue{
 			Key:   "character_set",
 			Value: cSet.String(),
@@ -348,7 +436,28 @@ func (m *Dumper) readHandshakeResponse(in []byte, direction dumper.Direction, co
 				Value: database,
 			})
 		}
+		if clientCapabilities&uint32(clientPluginAuth) > 0 {
+			connMetadata.Internal.(connMetadataInternal).clientCapabilities[clientPluginAuth] = true
+			readed, _ := buff.ReadBytes(0x00)
+			authPlugin := strings.TrimRight(string(readed), "\x00")
+			if authPlugin != "" && !setDumpValue(connMetadata, "auth_plugin", authPlugin, false) {
+				values = append(values, dumper.DumpValue{
+					Key:   "auth_plugin",
+					Value: authPlugin,
+				})
+			}
+		}
+		if clientCapabilities&uint32(clientConnectAttrs) > 0 && buff.Len() > 0 {
+			connMetadata.Internal.(connMetadataInternal).clientCapabilities[clientConnectAttrs] = true
+			values = append(values, dumper.DumpValue{
+				Key:   "connect_attrs",
+				Value: readConnectAttrs(buff, cSet),
+			})
+		}
 		connMetadata.Internal.(connMetadataInternal).clientCapabilities[clientCompress] = (clientCapabilities&uint32(clientCompress) > 0)
+		internal = connMetadata.Internal.(connMetadataInternal)
+		internal.authState = authStateResponse
+		connMetadata.Internal = internal
 		return values, nil
 	}
 
@@ -385,12 +494,279 @@ func (m *Dumper) readHandshakeResponse(in []byte, direction dumper.Direction, co
 		}
 		if buff.Len() == 0 {
 			values = append(values, v...)
+			internal = connMetadata.Internal.(connMetadataInternal)
+			internal.authState = authStateResponse
+			connMetadata.Internal = internal
 		}
 	}
 
 	return values, nil
 }
 
+// readServerAuthPacket parse packets from server in connection phase
+// https://dev.mysql.com/doc/internals/en/connection-phase.html
+func (m *Dumper) readServerAuthPacket(in []byte, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, bool) {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if len(in) < 5 {
+		return []dumper.DumpValue{}, internal.authState == authStateHandshake || internal.authState == authStateResponse
+	}
+	seqNum := in[3]
+	switch internal.authState {
+	case authStateUnknown:
+		if seqNum != 0x00 || len(internal.clientCapabilities) > 0 {
+			return nil, false
+		}
+		switch in[4] {
+		case handshakeV10:
+			connMetadata.DumpValues = append(connMetadata.DumpValues, readHandshakeV10(in[5:])...)
+			internal.authState = authStateHandshake
+			connMetadata.Internal = internal
+			return []dumper.DumpValue{}, true
+		case packetERR:
+			// ex. Too many connections
+			internal.authState = authStateDone
+			connMetadata.Internal = internal
+			return readAuthERR(in[5:]), true
+		}
+	case authStateHandshake:
+		// Protocol::HandshakeResponse not captured
+		return []dumper.DumpValue{}, true
+	case authStateResponse:
+		switch in[4] {
+		case packetOK:
+			internal.authState = authStateDone
+			connMetadata.Internal = internal
+			return []dumper.DumpValue{
+				dumper.DumpValue{
+					Key:   "auth_result",
+					Value: authResultOK,
+				},
+			}, true
+		case packetERR:
+			internal.authState = authStateDone
+			connMetadata.Internal = internal
+			return readAuthERR(in[5:]), true
+		case packetAuthSwitchRequest:
+			// https://dev.mysql.com/doc/internals/en/connection-phase-packets.html#packet-Protocol::AuthSwitchRequest
+			buff := bytes.NewBuffer(in[5:])
+			readed, _ := buff.ReadBytes(0x00)
+			authPlugin := strings.TrimRight(string(readed), "\x00")
+			if authPlugin != "" {
+				setDumpValue(connMetadata, "auth_plugin", authPlugin, true)
+			}
+			return []dumper.DumpValue{}, true
+		default:
+			// Protocol::AuthMoreData
+			return []dumper.DumpValue{}, true
+		}
+	}
+	return nil, false
+}
+
+// resync waits for a command boundary of the connection seen mid-stream ( Protocol::Handshake is not captured )
+// Compressed connection is not resynced
+func resync(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) bool {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.resynced {
+		return true
+	}
+	if direction != dumper.ClientToRemote && direction != dumper.SrcToDst {
+		return false
+	}
+	if !isCommandBoundary(in) {
+		return false
+	}
+	internal.resynced = true
+	internal.authState = authStateDone
+	internal.clientCapabilities[clientProtocol41] = true
+	internal.clientCapabilities[clientSecureConnection] = true
+	connMetadata.Internal = internal
+	return true
+}
+
+// isCommandBoundary returns true if in starts with a command packet and ends at the end of a packet
+func isCommandBoundary(in []byte) bool {
+	if len(in) < 5 || in[3] != 0x00 || in[4] == 0x00 || in[4] > maxCommandID {
+		return false
+	}
+	for len(in) > 0 {
+		if len(in) < 4 {
+			return false
+		}
+		l := int(bytesToUint32(in[0:3])) // 3:payload_length
+		if len(in) < 4+l {
+			return false
+		}
+		in = in[4+l:]
+	}
+	return true
+}
+
+// readChangeUser parse COM_CHANGE_USER and replaces username, database and character_set
+// https://dev.mysql.com/doc/internals/en/com-change-user.html
+func readChangeUser(in []byte, connMetadata *dumper.ConnMetadata) {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	cSet := internal.charSet
+	buff := bytes.NewBuffer(in)
+	readed, _ := buff.ReadBytes(0x00)
+	setDumpValue(connMetadata, "username", readString(readed, cSet), true)
+	if internal.clientCapabilities[clientSecureConnection] || internal.clientCapabilities[clientPluginAuthLenEncClientData] {
+		l, _ := buff.ReadByte()
+		_ = readBytes(buff, int(l))
+	} else {
+		_, _ = buff.ReadBytes(0x00)
+	}
+	readed, _ = buff.ReadBytes(0x00)
+	setDumpValue(connMetadata, "database", readString(readed, cSet), true)
+	if buff.Len() >= 2 {
+		c := charSetByCollationID(bytesToUint32(readBytes(buff, 2))) // 2:character set
+		internal.charSet = c
+		setDumpValue(connMetadata, "character_set", c.String(), true)
+	}
+	// authentication of the new user ( AuthSwitchRequest, OK or ERR )
+	internal.authState = authStateResponse
+	connMetadata.Internal = internal
+}
+
+// https://dev.mysql.com/doc/internals/en/connection-phase-packets.html#packet-Protocol::HandshakeV10
+func readHandshakeV10(in []byte) []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	buff := bytes.NewBuffer(in)
+	readed, _ := buff.ReadBytes(0x00)
+	values = append(values, dumper.DumpValue{
+		Key:   "server_version",
+		Value: strings.TrimRight(string(readed), "\x00"),
+	})
+	if buff.Len() < 4 {
+		return values
+	}
+	connectionID := binary.LittleEndian.Uint32(readBytes(buff, 4)) // 4:connection id
+	values = append(values, dumper.DumpValue{
+		Key:   "mysql_connection_id",
+		Value: connectionID,
+	})
+	_ = readBytes(buff, 8)                            // 8:auth-plugin-data-part-1
+	_ = readBytes(buff, 1)                            // 1:filler
+	capabilities := bytesToUint32(readBytes(buff, 2)) // 2:capability flags (lower 2 bytes)
+	if buff.Len() == 0 {
+		return values
+	}
+	_ = readBytes(buff, 1)                                              // 1:character set
+	_ = readBytes(buff, 2)                                              // 2:status flags
+	capabilities = capabilities | bytesToUint32(readBytes(buff, 2))<<16 // 2:capability flags (upper 2 bytes)
+	authPluginDataLen, _ := buff.ReadByte()
+	_ = readBytes(buff, 10) // 10:reserved
+	if capabilities&uint32(clientSecureConnection) > 0 {
+		l := int(authPluginDataLen) - 8
+		if l < 13 {
+			l = 13
+		}
+		_ = readBytes(buff, l) // auth-plugin-data-part-2
+	}
+	if capabilities&uint32(clientPluginAuth) > 0 {
+		readed, _ := buff.ReadBytes(0x00)
+		authPlugin := strings.TrimRight(string(readed), "\x00")
+		if authPlugin != "" {
+			values = append(values, dumper.DumpValue{
+				Key:   "auth_plugin",
+				Value: authPlugin,
+			})
+		}
+	}
+	return values
+}
+
+// ReadErrorResponse return error code of ERR_Packet in packets from server
+func (m *Dumper) ReadErrorResponse(in []byte) (string, bool) {
+	for len(in) >= 5 {
+		payloadLength := int(bytesToUint32(in[0:3]))
+		if len(in) < 4+payloadLength {
+			return "", false
+		}
+		if in[4] == packetERR && payloadLength >= 3 {
+			// https://dev.mysql.com/doc/internals/en/packet-ERR_Packet.html
+			return strconv.Itoa(int(binary.LittleEndian.Uint16(in[5:7]))), true
+		}
+		in = in[4+payloadLength:]
+	}
+	return "", false
+}
+
+// https://dev.mysql.com/doc/internals/en/packet-ERR_Packet.html
+func readAuthERR(in []byte) []dumper.DumpValue {
+	buff := bytes.NewBuffer(in)
+	errorCode := int(bytesToUint64(readBytes(buff, 2))) // 2:error_code
+	if buff.Len() > 0 && buff.Bytes()[0] == '#' {
+		_ = readBytes(buff, 6) // 1:sql_state_marker 5:sql_state
+	}
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "auth_result",
+			Value: authResultERR,
+		},
+		dumper.DumpValue{
+			Key:   "auth_error_code",
+			Value: errorCode,
+		},
+		dumper.DumpValue{
+			Key:   "auth_error_message",
+			Value: buff.String(),
+		},
+	}
+}
+
+// https://dev.mysql.com/doc/internals/en/connection-phase-packets.html#packet-Protocol::HandshakeResponse41
+func readConnectAttrs(buff *bytes.Buffer, cSet charSet) map[string]string {
+	attrs := map[string]string{}
+	l := int(readLengthEncodedInteger(buff))
+	if l > buff.Len() {
+		l = buff.Len()
+	}
+	attrsBuff := bytes.NewBuffer(readBytes(buff, l))
+	for attrsBuff.Len() > 0 {
+		kl := readLengthEncodedInteger(attrsBuff)
+		k := readString(readBytes(attrsBuff, int(kl)), cSet)
+		vl := readLengthEncodedInteger(attrsBuff)
+		v := readString(readBytes(attrsBuff, int(vl)), cSet)
+		attrs[k] = v
+	}
+	return attrs
+}
+
+// setDumpValue replaces value of connMetadata.DumpValues with key. If key does not exist and appendIfNotExist is true, append it
+func setDumpValue(connMetadata *dumper.ConnMetadata, key string, value interface{}, appendIfNotExist bool) bool {
+	for i, kv := range connMetadata.DumpValues {
+		if kv.Key == key {
+			connMetadata.DumpValues[i].Value = value
+			return true
+		}
+	}
+	if !appendIfNotExist {
+		return false
+	}
+	connMetadata.DumpValues = append(connMetadata.DumpValues, dumper.DumpValue{
+		Key:   key,
+		Value: value,
+	})
+	return true
+}
+
+// truncateQuery truncates query to maxQuerySize bytes without breaking a multibyte character
+func (m *Dumper) truncateQuery(query string) (string, bool) {
+	if m.maxQuerySize <= 0 || len(query) <= m.maxQuerySize {
+		return query, false
+	}
+	truncated := query[:m.maxQuerySize]
+	for i := 0; i < utf8.UTFMax-1 && len(truncated) > 0; i++ {
+		r, size := utf8.DecodeLastRuneInString(truncated)
+		if r != utf8.RuneError || size > 1 {
+			break
+		}
+		truncated = truncated[:len(truncated)-1]
+	}
+	return truncated, true
+}
+
 func readMysqlType(buff *bytes.Buffer) dataType {
 	b, _ := buff.ReadByte()
 	return dataType(b)
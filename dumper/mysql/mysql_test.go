@@ -3,6 +3,7 @@ package mysql
 import (
 	"bytes"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -57,6 +58,10 @@ func TestMysqlReadHandshakeResponse(t *testing.T) {
 							t.Errorf("actual %#v\nwant %#v", v.([]interface{})[j], ev.([]interface{})[j])
 						}
 					}
+				case map[string]string:
+					if !reflect.DeepEqual(v, ev) {
+						t.Errorf("actual %#v\nwant %#v", v, ev)
+					}
 				default:
 					if actual[i] != expected[i] {
 						t.Errorf("actual %#v\nwant %#v", actual[i], expected[i])
@@ -102,6 +107,10 @@ func TestMysqlRead(t *testing.T) {
 							t.Errorf("actual %#v\nwant %#v", v.([]interface{})[j], ev.([]interface{})[j])
 						}
 					}
+				case map[string]string:
+					if !reflect.DeepEqual(v, ev) {
+						t.Errorf("actual %#v\nwant %#v", v, ev)
+					}
 				default:
 					if actual[i] != expected[i] {
 						t.Errorf("actual %#v\nwant %#v", actual[i], expected[i])
@@ -148,6 +157,10 @@ func TestMysqlDump(t *testing.T) {
 							t.Errorf("actual %#v\nwant %#v", v.([]interface{})[j], ev.([]interface{})[j])
 						}
 					}
+				case map[string]string:
+					if !reflect.DeepEqual(v, ev) {
+						t.Errorf("actual %#v\nwant %#v", v, ev)
+					}
 				default:
 					if actual[i] != expected[i] {
 						t.Errorf("actual %#v\nwant %#v", actual[i], expected[i])
@@ -318,6 +331,574 @@ func TestReadBytes(t *testing.T) {
 	}
 }
 
+func TestMysqlReadMultiPackets(t *testing.T) {
+	// COM_QUERY with payload length > 0xFFFFFF ( https://dev.mysql.com/doc/internals/en/sending-more-than-16mbyte.html )
+	query := "SELECT '" + strings.Repeat("a", maxPayloadLength) + "'"
+	payload := append([]byte{comQuery}, []byte(query)...)
+	first := append([]byte{0xff, 0xff, 0xff, 0x00}, payload[:maxPayloadLength]...)
+	rest := payload[maxPayloadLength:]
+	second := append([]byte{byte(len(rest)), 0x00, 0x00, 0x01}, rest...)
+	stream := append(first, second...)
+
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+	connMetadata.Internal.(connMetadataInternal).clientCapabilities[clientProtocol41] = true
+
+	chunkSize := 0xFFFF
+	var actual []dumper.DumpValue
+	for i := 0; i < len(stream); i += chunkSize {
+		end := i + chunkSize
+		if end > len(stream) {
+			end = len(stream)
+		}
+		read, err := d.Read(stream[i:end], dumper.SrcToDst, connMetadata)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if end < len(stream) && len(read) > 0 {
+			t.Fatalf("got values before all packets are read: %v", read[len(read)-2:])
+		}
+		actual = read
+	}
+	if len(actual) != 3 {
+		t.Fatalf("got %d values\nwant %d", len(actual), 3)
+	}
+	if actual[0].Key != "query" || actual[0].Value != query {
+		t.Errorf("got %s (len %d)\nwant query (len %d)", actual[0].Key, len(actual[0].Value.(string)), len(query))
+	}
+	if actual[1].Value != int64(0) {
+		t.Errorf("got %#v\nwant %#v", actual[1].Value, int64(0))
+	}
+}
+
+func TestMysqlReadMultiPacketsMaxQuerySize(t *testing.T) {
+	query := "SELECT '" + strings.Repeat("a", maxPayloadLength) + "'"
+	payload := append([]byte{comQuery}, []byte(query)...)
+	first := append([]byte{0xff, 0xff, 0xff, 0x00}, payload[:maxPayloadLength]...)
+	rest := payload[maxPayloadLength:]
+	second := append([]byte{byte(len(rest)), 0x00, 0x00, 0x01}, rest...)
+	stream := append(first, second...)
+
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger:       newTestLogger(out),
+		maxQuerySize: 100,
+	}
+	connMetadata := d.NewConnMetadata()
+	connMetadata.Internal.(connMetadataInternal).clientCapabilities[clientProtocol41] = true
+
+	chunkSize := 0xFFFF
+	var actual []dumper.DumpValue
+	for i := 0; i < len(stream); i += chunkSize {
+		end := i + chunkSize
+		if end > len(stream) {
+			end = len(stream)
+		}
+		read, err := d.Read(stream[i:end], dumper.SrcToDst, connMetadata)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if p := connMetadata.Internal.(connMetadataInternal).longPacket; p != nil && len(p.packet) > 4+1+100+4 {
+			t.Fatalf("got %d bytes buffered", len(p.packet))
+		}
+		actual = read
+	}
+	want := []dumper.DumpValue{
+		dumper.DumpValue{Key: "query", Value: query[:100]},
+		dumper.DumpValue{Key: "query_truncated", Value: true},
+		dumper.DumpValue{Key: "seq_num", Value: int64(0)},
+		dumper.DumpValue{Key: "command_id", Value: byte(comQuery)},
+	}
+	if !reflect.DeepEqual(actual, want) {
+		t.Errorf("got %#v\nwant %#v", actual, want)
+	}
+}
+
+func TestMysqlReadSplitCompressedPacket(t *testing.T) {
+	// https://dev.mysql.com/doc/internals/en/example-one-mysql-packet.html
+	in := []byte{
+		0x22, 0x00, 0x00, 0x00, 0x32, 0x00, 0x00, 0x78, 0x9c, 0xd3, 0x63, 0x60, 0x60, 0x60, 0x2e, 0x4e,
+		0xcd, 0x49, 0x4d, 0x2e, 0x51, 0x50, 0x32, 0x30, 0x34, 0x32, 0x36, 0x31, 0x35, 0x33, 0xb7, 0xb0,
+		0xc4, 0xcd, 0x52, 0x02, 0x00, 0x0c, 0xd1, 0x0a, 0x6c,
+	}
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+	connMetadata.Internal.(connMetadataInternal).clientCapabilities[clientCompress] = true
+
+	var actual []dumper.DumpValue
+	for _, chunk := range [][]byte{in[:5], in[5:20], in[20:]} {
+		read, err := d.Read(chunk, dumper.ClientToRemote, connMetadata)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		actual = read
+	}
+	if len(actual) == 0 || actual[0].Value != "select \"012345678901234567890123456789012345\"" {
+		t.Errorf("got %#v", actual)
+	}
+}
+
+func TestMysqlReadSetNames(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+	connMetadata.Internal.(connMetadataInternal).clientCapabilities[clientProtocol41] = true
+	connMetadata.DumpValues = []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "character_set",
+			Value: "utf8",
+		},
+	}
+
+	// SET NAMES sjis
+	in := []byte{
+		0x0e, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x54, 0x20, 0x4e, 0x41, 0x4d, 0x45, 0x53, 0x20, 0x73,
+		0x6a, 0x69, 0x73,
+	}
+	if _, err := d.Read(in, dumper.SrcToDst, connMetadata); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if connMetadata.DumpValues[0].Value != "sjis" {
+		t.Errorf("actual %#v\nwant %#v", connMetadata.DumpValues[0].Value, "sjis")
+	}
+
+	// SELECT 'あいうえお' (sjis)
+	in = []byte{
+		0x14, 0x00, 0x00, 0x00, 0x03, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x27, 0x82, 0xa0, 0x82,
+		0xa2, 0x82, 0xa4, 0x82, 0xa6, 0x82, 0xa8, 0x27,
+	}
+	actual, err := d.Read(in, dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if actual[0].Value != "SELECT 'あいうえお'" {
+		t.Errorf("actual %#v\nwant %#v", actual[0].Value, "SELECT 'あいうえお'")
+	}
+}
+
+func TestMysqlReadHandshakeResponseCollation(t *testing.T) {
+	tests := []struct {
+		collationID byte
+		expected    string
+	}{
+		{0x08, "latin1"},  // latin1_swedish_ci
+		{0x2d, "utf8mb4"}, // utf8mb4_general_ci
+		{0x2e, "utf8mb4"}, // utf8mb4_bin
+		{0xff, "utf8mb4"}, // utf8mb4_0900_ai_ci
+		{0x30, "latin1"},  // latin1_general_ci
+		{0x54, "big5"},    // big5_bin
+		{0x57, "gbk"},     // gbk_bin
+		{0x5b, "ujis"},    // ujis_bin
+	}
+	for _, tt := range tests {
+		out := new(bytes.Buffer)
+		d := &Dumper{
+			logger: newTestLogger(out),
+		}
+		in := make([]byte, len(newMysqlReadTests()[0].in))
+		copy(in, newMysqlReadTests()[0].in)
+		in[12] = tt.collationID
+		connMetadata := d.NewConnMetadata()
+
+		actual, err := d.readHandshakeResponse(in, dumper.SrcToDst, connMetadata)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if actual[0].Key != "character_set" || actual[0].Value != tt.expected {
+			t.Errorf("%d: actual %#v\nwant %#v", tt.collationID, actual[0].Value, tt.expected)
+		}
+	}
+}
+
+var mysqlAuthFlowTests = []struct {
+	description       string
+	packets           [][]byte
+	directions        []dumper.Direction
+	expected          []dumper.DumpValue
+	expectedDumpValue map[string]interface{}
+}{
+	{
+		"HandshakeV10 -> HandshakeResponse41 -> AuthSwitchRequest -> AuthSwitchResponse -> OK",
+		[][]byte{
+			[]byte{
+				0x4a, 0x00, 0x00, 0x00, 0x0a, 0x38, 0x2e, 0x30, 0x2e, 0x33, 0x36, 0x00, 0x2a, 0x00, 0x00, 0x00,
+				0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x00, 0xff, 0xff, 0xff, 0x02, 0x00, 0xff, 0xdf,
+				0x15, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x69, 0x6a, 0x6b, 0x6c, 0x6d,
+				0x6e, 0x6f, 0x70, 0x71, 0x72, 0x73, 0x74, 0x00, 0x63, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x67, 0x5f,
+				0x73, 0x68, 0x61, 0x32, 0x5f, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x00,
+			},
+			[]byte{
+				0x54, 0x00, 0x00, 0x01, 0x8d, 0xa6, 0x0f, 0x00, 0x00, 0x00, 0x00, 0x01, 0x08, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x70, 0x61, 0x6d, 0x00, 0x14, 0xab, 0x09, 0xee, 0xf6, 0xbc, 0xb1, 0x32,
+				0x3e, 0x61, 0x14, 0x38, 0x65, 0xc0, 0x99, 0x1d, 0x95, 0x7d, 0x75, 0xd4, 0x47, 0x74, 0x65, 0x73,
+				0x74, 0x00, 0x6d, 0x79, 0x73, 0x71, 0x6c, 0x5f, 0x6e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x70,
+				0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x00,
+			},
+			[]byte{
+				0x2c, 0x00, 0x00, 0x02, 0xfe, 0x6d, 0x79, 0x73, 0x71, 0x6c, 0x5f, 0x6e, 0x61, 0x74, 0x69, 0x76,
+				0x65, 0x5f, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x00, 0x61, 0x62, 0x63, 0x64, 0x65,
+				0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70, 0x71, 0x72, 0x73, 0x74, 0x00,
+			},
+			[]byte{
+				0x14, 0x00, 0x00, 0x03, 0x03, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+				0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78, 0x78,
+			},
+			[]byte{
+				0x07, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+			},
+		},
+		[]dumper.Direction{dumper.DstToSrc, dumper.SrcToDst, dumper.DstToSrc, dumper.SrcToDst, dumper.DstToSrc},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "auth_result",
+				Value: "ok",
+			},
+		},
+		map[string]interface{}{
+			"server_version":      "8.0.36",
+			"mysql_connection_id": uint32(42),
+			"auth_plugin":         "mysql_native_password",
+			"username":            "pam",
+			"database":            "test",
+		},
+	},
+	{
+		"HandshakeResponse41 -> ERR",
+		[][]byte{
+			[]byte{
+				0x54, 0x00, 0x00, 0x01, 0x8d, 0xa6, 0x0f, 0x00, 0x00, 0x00, 0x00, 0x01, 0x08, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x70, 0x61, 0x6d, 0x00, 0x14, 0xab, 0x09, 0xee, 0xf6, 0xbc, 0xb1, 0x32,
+				0x3e, 0x61, 0x14, 0x38, 0x65, 0xc0, 0x99, 0x1d, 0x95, 0x7d, 0x75, 0xd4, 0x47, 0x74, 0x65, 0x73,
+				0x74, 0x00, 0x6d, 0x79, 0x73, 0x71, 0x6c, 0x5f, 0x6e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x70,
+				0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x00,
+			},
+			[]byte{
+				0x48, 0x00, 0x00, 0x02, 0xff, 0x15, 0x04, 0x23, 0x32, 0x38, 0x30, 0x30, 0x30, 0x41, 0x63, 0x63,
+				0x65, 0x73, 0x73, 0x20, 0x64, 0x65, 0x6e, 0x69, 0x65, 0x64, 0x20, 0x66, 0x6f, 0x72, 0x20, 0x75,
+				0x73, 0x65, 0x72, 0x20, 0x27, 0x72, 0x6f, 0x6f, 0x74, 0x27, 0x40, 0x27, 0x6c, 0x6f, 0x63, 0x61,
+				0x6c, 0x68, 0x6f, 0x73, 0x74, 0x27, 0x20, 0x28, 0x75, 0x73, 0x69, 0x6e, 0x67, 0x20, 0x70, 0x61,
+				0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x3a, 0x20, 0x59, 0x45, 0x53, 0x29,
+			},
+		},
+		[]dumper.Direction{dumper.ClientToRemote, dumper.RemoteToClient},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "auth_result",
+				Value: "error",
+			},
+			dumper.DumpValue{
+				Key:   "auth_error_code",
+				Value: 1045,
+			},
+			dumper.DumpValue{
+				Key:   "auth_error_message",
+				Value: "Access denied for user 'root'@'localhost' (using password: YES)",
+			},
+		},
+		map[string]interface{}{
+			"auth_plugin": "mysql_native_password",
+			"username":    "pam",
+		},
+	},
+}
+
+func TestMysqlReadAuthFlow(t *testing.T) {
+	for _, tt := range mysqlAuthFlowTests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := d.NewConnMetadata()
+			var actual []dumper.DumpValue
+			for i, in := range tt.packets {
+				read, err := d.Read(in, tt.directions[i], connMetadata)
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				if i < len(tt.packets)-1 && len(read) > 0 {
+					t.Errorf("packet %d: got %v\nwant no values", i, read)
+				}
+				actual = read
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("actual %#v\nwant %#v", actual, tt.expected)
+			}
+			for k, ev := range tt.expectedDumpValue {
+				found := false
+				for _, kv := range connMetadata.DumpValues {
+					if kv.Key == k {
+						found = true
+						if kv.Value != ev {
+							t.Errorf("%s: actual %#v\nwant %#v", k, kv.Value, ev)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("%s not found in %v", k, connMetadata.DumpValues)
+				}
+			}
+		})
+	}
+}
+
+var mysqlMidStreamTests = []struct {
+	description       string
+	midStream         bool
+	packets           [][]byte
+	directions        []dumper.Direction
+	expected          []dumper.DumpValue
+	expectedDumpValue map[string]interface{}
+}{
+	{
+		"Resync on a command boundary",
+		true,
+		[][]byte{
+			// the rest of a long COM_QUERY
+			[]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa'"),
+			// OK
+			mysqlPacket(0x01, []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}),
+			// COM_QUERY and the first half of the next COM_QUERY
+			append(mysqlPacket(0x00, append([]byte{comQuery}, []byte("SELECT 1")...)), 0x20, 0x00, 0x00, 0x00, comQuery),
+			mysqlPacket(0x00, append([]byte{comQuery}, []byte("SELECT 2")...)),
+		},
+		[]dumper.Direction{dumper.SrcToDst, dumper.DstToSrc, dumper.SrcToDst, dumper.SrcToDst},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "query",
+				Value: "SELECT 2",
+			},
+			dumper.DumpValue{
+				Key:   "seq_num",
+				Value: int64(0),
+			},
+			dumper.DumpValue{
+				Key:   "command_id",
+				Value: byte(comQuery),
+			},
+		},
+		map[string]interface{}{},
+	},
+	{
+		"COM_INIT_DB",
+		true,
+		[][]byte{
+			mysqlPacket(0x00, append([]byte{comInitDB}, []byte("app")...)),
+			mysqlPacket(0x01, []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}),
+			mysqlPacket(0x00, append([]byte{comQuery}, []byte("SELECT 1")...)),
+		},
+		[]dumper.Direction{dumper.ClientToRemote, dumper.RemoteToClient, dumper.ClientToRemote},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "query",
+				Value: "SELECT 1",
+			},
+			dumper.DumpValue{
+				Key:   "seq_num",
+				Value: int64(0),
+			},
+			dumper.DumpValue{
+				Key:   "command_id",
+				Value: byte(comQuery),
+			},
+		},
+		map[string]interface{}{
+			"database": "app",
+		},
+	},
+	{
+		"COM_CHANGE_USER -> AuthSwitchRequest -> AuthSwitchResponse -> OK",
+		false,
+		[][]byte{
+			mysqlPacket(0x00, append(append(append([]byte{comChangeUser}, []byte("alice\x00")...), append([]byte{0x14}, []byte(strings.Repeat("x", 20))...)...), append([]byte("shop\x00"), 0x21, 0x00)...)),
+			mysqlPacket(0x01, append([]byte{packetAuthSwitchRequest}, []byte("mysql_native_password\x00abcdefghijklmnopqrst\x00")...)),
+			mysqlPacket(0x02, []byte(strings.Repeat("x", 20))),
+			mysqlPacket(0x03, []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}),
+		},
+		[]dumper.Direction{dumper.SrcToDst, dumper.DstToSrc, dumper.SrcToDst, dumper.DstToSrc},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "auth_result",
+				Value: "ok",
+			},
+		},
+		map[string]interface{}{
+			"username":      "alice",
+			"database":      "shop",
+			"character_set": "utf8",
+			"auth_plugin":   "mysql_native_password",
+		},
+	},
+}
+
+func TestMysqlReadMidStream(t *testing.T) {
+	for _, tt := range mysqlMidStreamTests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := d.NewConnMetadata()
+			connMetadata.MidStream = tt.midStream
+			if !tt.midStream {
+				internal := connMetadata.Internal.(connMetadataInternal)
+				internal.authState = authStateDone
+				internal.clientCapabilities[clientProtocol41] = true
+				internal.clientCapabilities[clientSecureConnection] = true
+				connMetadata.Internal = internal
+				connMetadata.DumpValues = []dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "username",
+						Value: "pam",
+					},
+					dumper.DumpValue{
+						Key:   "auth_plugin",
+						Value: "caching_sha2_password",
+					},
+				}
+			}
+			var actual []dumper.DumpValue
+			for i, in := range tt.packets {
+				read, err := d.Read(in, tt.directions[i], connMetadata)
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				if i < len(tt.packets)-1 && len(read) > 0 {
+					t.Errorf("packet %d: got %v\nwant no values", i, read)
+				}
+				actual = read
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("actual %#v\nwant %#v", actual, tt.expected)
+			}
+			for k, ev := range tt.expectedDumpValue {
+				found := false
+				for _, kv := range connMetadata.DumpValues {
+					if kv.Key == k {
+						found = true
+						if kv.Value != ev {
+							t.Errorf("%s: actual %#v\nwant %#v", k, kv.Value, ev)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("%s not found in %v", k, connMetadata.DumpValues)
+				}
+			}
+		})
+	}
+}
+
+func mysqlPacket(seqNum byte, payload []byte) []byte {
+	l := len(payload)
+	return append([]byte{byte(l), byte(l >> 8), byte(l >> 16), seqNum}, payload...)
+}
+
+var truncateQueryTests = []struct {
+	maxQuerySize  int
+	query         string
+	expected      string
+	wantTruncated bool
+}{
+	{
+		0,
+		"SELECT * FROM posts",
+		"SELECT * FROM posts",
+		false,
+	},
+	{
+		19,
+		"SELECT * FROM posts",
+		"SELECT * FROM posts",
+		false,
+	},
+	{
+		8,
+		"SELECT * FROM posts",
+		"SELECT *",
+		true,
+	},
+	{
+		10,
+		"SELECT 'あいうえお'",
+		"SELECT '",
+		true,
+	},
+	{
+		11,
+		"SELECT 'あいうえお'",
+		"SELECT 'あ",
+		true,
+	},
+}
+
+func TestTruncateQuery(t *testing.T) {
+	for _, tt := range truncateQueryTests {
+		d := &Dumper{
+			maxQuerySize: tt.maxQuerySize,
+		}
+		actual, truncated := d.truncateQuery(tt.query)
+		if actual != tt.expected {
+			t.Errorf("actual %#v\nwant %#v", actual, tt.expected)
+		}
+		if truncated != tt.wantTruncated {
+			t.Errorf("actual %#v\nwant %#v", truncated, tt.wantTruncated)
+		}
+	}
+}
+
+var readErrorResponseTests = []struct {
+	description string
+	in          []byte
+	wantCode    string
+	wantOK      bool
+}{
+	{
+		"ERR_Packet",
+		mysqlPacket(1, append([]byte{0xff, 0xbd, 0x04, '#', '4', '0', '0', '0', '1'}, []byte("Deadlock found when trying to get lock")...)),
+		"1213",
+		true,
+	},
+	{
+		"ERR_Packet after result set",
+		append(append(mysqlPacket(1, []byte{0x01}), mysqlPacket(2, []byte{0x03, 'd', 'e', 'f'})...), mysqlPacket(3, []byte{0xff, 0x50, 0x0c, 'Q', 'u', 'e', 'r', 'y'})...),
+		"3152",
+		true,
+	},
+	{
+		"OK_Packet",
+		mysqlPacket(1, []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}),
+		"",
+		false,
+	},
+	{
+		"Truncated packet",
+		[]byte{0x20, 0x00, 0x00, 0x01, 0xff, 0xbd, 0x04},
+		"",
+		false,
+	},
+}
+
+func TestReadErrorResponse(t *testing.T) {
+	d := &Dumper{}
+	for _, tt := range readErrorResponseTests {
+		code, ok := d.ReadErrorResponse(tt.in)
+		if code != tt.wantCode || ok != tt.wantOK {
+			t.Errorf("%s\nactual %#v %#v\nwant %#v %#v", tt.description, code, ok, tt.wantCode, tt.wantOK)
+		}
+	}
+}
+
 // newTestLogger return zap.Logger for test
 func newTestLogger(out io.Writer) *zap.Logger {
 	encoderConfig := zapcore.EncoderConfig{
@@ -378,6 +959,10 @@ func newMysqlReadTests() []mysqlReadTest {
 					Key:   "database",
 					Value: "test",
 				},
+				dumper.DumpValue{
+					Key:   "auth_plugin",
+					Value: "mysql_native_password",
+				},
 			},
 			[]dumper.DumpValue{},
 			"",
@@ -422,6 +1007,21 @@ func newMysqlReadTests() []mysqlReadTest {
 					Key:   "database",
 					Value: "testdb",
 				},
+				dumper.DumpValue{
+					Key:   "auth_plugin",
+					Value: "mysql_native_password",
+				},
+				dumper.DumpValue{
+					Key: "connect_attrs",
+					Value: map[string]string{
+						"_os":             "osx10.13",
+						"_client_name":    "libmysql",
+						"_pid":            "16703",
+						"_client_version": "5.7.23",
+						"_platform":       "x86_64",
+						"program_name":    "mysql",
+					},
+				},
 			},
 			[]dumper.DumpValue{},
 			"",
@@ -899,6 +1499,46 @@ func newMysqlReadTests() []mysqlReadTest {
 			},
 			"\"stmt_execute_values\":[1,23.4,0]",
 		},
+		{
+			"Parse stmt_id/param_id from COM_STMT_SEND_LONG_DATA packet",
+			false,
+			[]byte{
+				0x0b, 0x00, 0x00, 0x00, 0x18, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x61, 0x62, 0x63, 0x64,
+			},
+			dumper.ClientToRemote,
+			dumper.ConnMetadata{
+				DumpValues: []dumper.DumpValue{},
+				Internal: connMetadataInternal{
+					stmtNumParams:      stmtNumParams{1: 2},
+					clientCapabilities: clientCapabilities{clientProtocol41: true},
+					charSet:            charSetUnknown,
+				},
+			},
+			[]dumper.DumpValue{},
+			[]dumper.DumpValue{
+				dumper.DumpValue{
+					Key:   "stmt_id",
+					Value: 1,
+				},
+				dumper.DumpValue{
+					Key:   "stmt_param_id",
+					Value: 1,
+				},
+				dumper.DumpValue{
+					Key:   "stmt_long_data_size",
+					Value: 4,
+				},
+				dumper.DumpValue{
+					Key:   "seq_num",
+					Value: int64(0),
+				},
+				dumper.DumpValue{
+					Key:   "command_id",
+					Value: byte(24),
+				},
+			},
+			"\"stmt_long_data_size\":4",
+		},
 		{
 			"tcpdp mysql dumper not support SSL connection (https://dev.mysql.com/doc/internals/en/ssl.html)",
 			true,
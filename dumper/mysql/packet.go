@@ -0,0 +1,93 @@
+package mysql
+
+import "unicode/utf8"
+
+const (
+	packetHeaderLength           = 4 // 3:payload_length 1:sequence_id
+	compressedPacketHeaderLength = 7 // 3:length of compressed payload 1:compressed sequence id 3:length of payload before compression
+)
+
+// longPacket joins a packet split into multiple TCP segments, and a payload split into multiple packets ( payload_length = 0xFFFFFF )
+// https://dev.mysql.com/doc/internals/en/sending-more-than-16mbyte.html
+type longPacket struct {
+	headerLength int
+	maxQuerySize int    // 0: unlimited
+	packet       []byte // header of the first packet and joined payload
+	header       []byte // header of the current packet being read
+	remaining    int    // length of payload of the current packet not read yet
+	last         bool   // the current packet is the last packet of the payload
+}
+
+func newLongPacket(headerLength, maxQuerySize int) *longPacket {
+	return &longPacket{
+		headerLength: headerLength,
+		maxQuerySize: maxQuerySize,
+		header:       make([]byte, 0, headerLength),
+	}
+}
+
+// write appends in to the packet and returns true when the payload is completed
+// Bytes after the completed payload are ignored
+func (p *longPacket) write(in []byte) bool {
+	for {
+		if p.remaining == 0 && p.last {
+			return true
+		}
+		if len(in) == 0 {
+			return false
+		}
+		if p.remaining == 0 {
+			n := p.headerLength - len(p.header)
+			if n > len(in) {
+				n = len(in)
+			}
+			p.header = append(p.header, in[:n]...)
+			in = in[n:]
+			if len(p.header) < p.headerLength {
+				return false
+			}
+			if p.packet == nil {
+				p.packet = append([]byte{}, p.header...)
+			}
+			p.remaining = int(bytesToUint32(p.header[0:3])) // 3:payload_length
+			p.last = p.remaining < maxPayloadLength
+			p.header = p.header[:0]
+			continue
+		}
+		n := p.remaining
+		if n > len(in) {
+			n = len(in)
+		}
+		p.keep(in[:n])
+		p.remaining -= n
+		in = in[n:]
+	}
+}
+
+// keep appends payload up to the length needed to dump the packet
+func (p *longPacket) keep(payload []byte) {
+	if len(p.packet) == p.headerLength && len(payload) > 0 {
+		p.packet = append(p.packet, payload[0]) // 1:command
+		payload = payload[1:]
+	}
+	if limit := p.limit(); limit > 0 && len(p.packet)+len(payload) > limit {
+		if len(p.packet) >= limit {
+			return
+		}
+		payload = payload[:limit-len(p.packet)]
+	}
+	p.packet = append(p.packet, payload...)
+}
+
+// limit return max length of packet to keep. 0 is unlimited
+// A query is truncated to maxQuerySize after converted to UTF-8, so it keeps extra bytes of a multibyte character
+func (p *longPacket) limit() int {
+	if p.maxQuerySize <= 0 || p.headerLength != packetHeaderLength || len(p.packet) <= p.headerLength {
+		return 0
+	}
+	switch p.packet[p.headerLength] {
+	case comQuery, comStmtPrepare:
+		return p.headerLength + 1 + p.maxQuerySize + utf8.UTFMax
+	}
+	return 0
+}
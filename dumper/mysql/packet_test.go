@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var longPacketTests = []struct {
+	description  string
+	headerLength int
+	maxQuerySize int
+	in           [][]byte
+	want         []byte
+}{
+	{
+		"Packet split into TCP segments",
+		packetHeaderLength,
+		0,
+		[][]byte{
+			[]byte{0x05, 0x00},
+			[]byte{0x00, 0x00, 0x03, 0x61},
+			[]byte{0x62, 0x63, 0x64},
+		},
+		[]byte{0x05, 0x00, 0x00, 0x00, 0x03, 0x61, 0x62, 0x63, 0x64},
+	},
+	{
+		"Bytes after the packet are ignored",
+		packetHeaderLength,
+		0,
+		[][]byte{
+			[]byte{0x02, 0x00, 0x00, 0x00, 0x03, 0x61, 0x01, 0x00, 0x00, 0x00, 0x0e},
+		},
+		[]byte{0x02, 0x00, 0x00, 0x00, 0x03, 0x61},
+	},
+	{
+		"Compressed packet",
+		compressedPacketHeaderLength,
+		1,
+		[][]byte{
+			[]byte{0x03, 0x00, 0x00, 0x00, 0x00},
+			[]byte{0x00, 0x00, 0x03, 0x61, 0x62},
+		},
+		[]byte{0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x61, 0x62},
+	},
+}
+
+func TestLongPacket(t *testing.T) {
+	for _, tt := range longPacketTests {
+		p := newLongPacket(tt.headerLength, tt.maxQuerySize)
+		for i, in := range tt.in {
+			completed := p.write(in)
+			if want := i == len(tt.in)-1; completed != want {
+				t.Fatalf("%s: %d: got %v\nwant %v", tt.description, i, completed, want)
+			}
+		}
+		if !bytes.Equal(p.packet, tt.want) {
+			t.Errorf("%s\ngot %#v\nwant %#v", tt.description, p.packet, tt.want)
+		}
+	}
+}
+
+func TestLongPacketMultiPackets(t *testing.T) {
+	// payload length is a multiple of 0xFFFFFF, so an empty packet follows
+	payload := append([]byte{comStmtSendLongData}, []byte(strings.Repeat("a", maxPayloadLength-1))...)
+	stream := append([]byte{0xff, 0xff, 0xff, 0x00}, payload...)
+	stream = append(stream, 0x00, 0x00, 0x00, 0x01)
+
+	p := newLongPacket(packetHeaderLength, 10)
+	if p.write(stream[:len(stream)-2]) {
+		t.Fatal("completed before the empty packet is read")
+	}
+	if !p.write(stream[len(stream)-2:]) {
+		t.Fatal("not completed")
+	}
+	if got, want := len(p.packet), 4+maxPayloadLength; got != want {
+		t.Errorf("got %d\nwant %d", got, want)
+	}
+}
+
+func TestLongPacketLimit(t *testing.T) {
+	tests := []struct {
+		commandID    byte
+		maxQuerySize int
+		want         int
+	}{
+		{comQuery, 100, 4 + 1 + 100 + 4},
+		{comStmtPrepare, 100, 4 + 1 + 100 + 4},
+		{comQuery, 0, 4 + maxPayloadLength + 10},
+		{comStmtExecute, 100, 4 + maxPayloadLength + 10},
+	}
+	for _, tt := range tests {
+		first := append([]byte{0xff, 0xff, 0xff, 0x00, tt.commandID}, bytes.Repeat([]byte{0x61}, maxPayloadLength-1)...)
+		second := append([]byte{0x0a, 0x00, 0x00, 0x01}, bytes.Repeat([]byte{0x62}, 10)...)
+		p := newLongPacket(packetHeaderLength, tt.maxQuerySize)
+		for i := 0; i < len(first); i += 0xFFFF {
+			end := i + 0xFFFF
+			if end > len(first) {
+				end = len(first)
+			}
+			_ = p.write(first[i:end])
+		}
+		if !p.write(second) {
+			t.Fatalf("%#x %d: not completed", tt.commandID, tt.maxQuerySize)
+		}
+		if got := len(p.packet); got != tt.want {
+			t.Errorf("%#x %d: got %d\nwant %d", tt.commandID, tt.maxQuerySize, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package pg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"regexp"
+	"strings"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+var copyToStdoutRegexp = regexp.MustCompile(`(?is)\bCOPY\b.*\bTO\s+STDOUT\b`)
+
+// https://www.postgresql.org/docs/current/sql-copy.html#id-1.9.3.55.9.4
+var copyBinarySignature = []byte{'P', 'G', 'C', 'O', 'P', 'Y', '\n', 0xff, '\r', '\n', 0x00}
+
+// copyStream is state of COPY sub-protocol per direction
+// https://www.postgresql.org/docs/current/protocol-flow.html#PROTOCOL-COPY
+type copyStream struct {
+	active      bool
+	header      []byte // partial message header
+	messageType byte   // message type currently being read ( 0: waiting for header )
+	remaining   int    // remaining length of the current message body
+	body        []byte // body of CopyFail / CopyInResponse / CopyOutResponse
+	started     bool
+	binary      bool
+	bytes       int
+	rows        int
+}
+
+// read walks messages in COPY sub-protocol and returns the message type that finished COPY and the rest bytes after it
+func (s *copyStream) read(in []byte) (byte, []byte, bool) {
+	for len(in) > 0 {
+		if s.messageType == 0 {
+			n := 5 - len(s.header)
+			if len(in) < n {
+				s.header = append(s.header, in...)
+				return 0, nil, false
+			}
+			s.header = append(s.header, in[:n]...)
+			in = in[n:]
+			l := int(binary.BigEndian.Uint32(s.header[1:5]))
+			if l < 4 {
+				// broken stream
+				return s.header[0], nil, true
+			}
+			s.messageType = s.header[0]
+			s.remaining = l - 4
+			s.header = nil
+		}
+		n := s.remaining
+		if len(in) < n {
+			n = len(in)
+		}
+		body := in[:n]
+		in = in[n:]
+		s.remaining -= n
+		switch s.messageType {
+		case messageCopyData:
+			if !s.started {
+				s.started = true
+				if bytes.HasPrefix(body, copyBinarySignature) {
+					s.binary = true
+				}
+			}
+			s.bytes += n
+			if !s.binary {
+				s.rows += bytes.Count(body, []byte{'\n'})
+			}
+		case messageCopyFail, messageCopyInResponse, messageCopyOutResponse:
+			s.body = append(s.body, body...)
+		}
+		if s.remaining > 0 {
+			return 0, nil, false
+		}
+		messageType := s.messageType
+		s.messageType = 0
+		switch messageType {
+		case messageCopyDone, messageCopyFail, messageErrorResponse, messageReadyForQuery:
+			return messageType, in, true
+		case messageCopyInResponse, messageCopyOutResponse:
+			// Int8: 0 indicates the overall COPY format is textual, 1 indicates binary
+			if len(s.body) > 0 && s.body[0] == 1 {
+				s.binary = true
+			}
+			s.body = nil
+		}
+	}
+	return 0, nil, false
+}
+
+// values returns byte/row counts of COPY
+func (s *copyStream) values() []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "copy_bytes",
+			Value: s.bytes,
+		},
+	}
+	if !s.binary {
+		values = append(values, dumper.DumpValue{
+			Key:   "copy_rows",
+			Value: s.rows,
+		})
+	}
+	return values
+}
+
+func isCopyInMessage(messageType byte) bool {
+	return messageType == messageCopyData || messageType == messageCopyDone || messageType == messageCopyFail
+}
+
+// readCopyIn read CopyData / CopyDone / CopyFail from client ( COPY ... FROM STDIN ) and returns the rest bytes after COPY
+func (p *Dumper) readCopyIn(in []byte, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, []byte, bool) {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	s := internal.copyIn
+	if s.messageType == 0 && len(s.header) == 0 && !isCopyInMessage(in[0]) {
+		if s.active {
+			internal.copyIn = copyStream{}
+			connMetadata.Internal = internal
+		}
+		return nil, in, false
+	}
+	s.active = true
+	messageType, rest, done := s.read(in)
+	if !done {
+		internal.copyIn = s
+		connMetadata.Internal = internal
+		return []dumper.DumpValue{}, nil, true
+	}
+	internal.copyIn = copyStream{}
+	connMetadata.Internal = internal
+
+	values := s.values()
+	switch messageType {
+	case messageCopyDone:
+	case messageCopyFail:
+		values = append(values, dumper.DumpValue{
+			Key:   "copy_fail_message",
+			Value: strings.TrimRight(string(s.body), "\x00"),
+		})
+	default:
+		return []dumper.DumpValue{}, rest, true
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "message_type",
+		Value: string(messageType),
+	}), rest, true
+}
+
+// expectCopyOut mark connection to read CopyOutResponse / CopyData / CopyDone from server ( COPY ... TO STDOUT )
+func (p *Dumper) expectCopyOut(query string, connMetadata *dumper.ConnMetadata) {
+	if !copyToStdoutRegexp.MatchString(query) {
+		return
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	internal.copyOut = copyStream{active: true}
+	connMetadata.Internal = internal
+}
+
+// readCopyOut read CopyData / CopyDone from server
+func (p *Dumper) readCopyOut(in []byte, connMetadata *dumper.ConnMetadata) []dumper.DumpValue {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	s := internal.copyOut
+	if !s.active {
+		return []dumper.DumpValue{}
+	}
+	messageType, _, done := s.read(in)
+	if !done {
+		internal.copyOut = s
+		connMetadata.Internal = internal
+		return []dumper.DumpValue{}
+	}
+	internal.copyOut = copyStream{}
+	connMetadata.Internal = internal
+	if messageType != messageCopyDone {
+		return []dumper.DumpValue{}
+	}
+	return append(s.values(), dumper.DumpValue{
+		Key:   "message_type",
+		Value: string(messageType),
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"strings"
+	"sync"
 
 	"github.com/k1LoW/tcpdp/dumper"
 	"github.com/k1LoW/tcpdp/logger"
@@ -12,13 +13,46 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// frontend messages
 const (
-	messageQuery   = 'Q'
-	messageParse   = 'P'
-	messageBind    = 'B'
-	messageExecute = 'E'
+	messageQuery        = 'Q'
+	messageParse        = 'P'
+	messageBind         = 'B'
+	messageExecute      = 'E'
+	messageDescribe     = 'D'
+	messageClose        = 'C'
+	messageSync         = 'S'
+	messageFlush        = 'H'
+	messageFunctionCall = 'F'
+	messageCopyFail     = 'f'
+	messageTerminate    = 'X'
 )
 
+// frontend and backend messages
+const (
+	messageCopyData = 'd'
+	messageCopyDone = 'c'
+)
+
+// backend messages
+const (
+	messageBackendKeyData  = 'K'
+	messageReadyForQuery   = 'Z'
+	messageErrorResponse   = 'E'
+	messageCopyInResponse  = 'G'
+	messageCopyOutResponse = 'H'
+)
+
+// https://www.postgresql.org/docs/current/protocol-message-formats.html
+const (
+	protocolVersion3  = 196608 // 3.0
+	cancelRequestCode = 80877102
+	sslRequestCode    = 80877103
+	gssencRequestCode = 80877104
+)
+
+const maxBackendKeys = 10000
+
 type dataType int16
 
 const (
@@ -28,20 +62,74 @@ const (
 
 // Dumper struct
 type Dumper struct {
-	name   string
-	logger *zap.Logger
+	name        string
+	logger      *zap.Logger
+	backendKeys *backendKeyMap
 }
 
 type connMetadataInternal struct {
-	messageLength   uint32
-	longPacketCache []byte
+	longPacketCache   []byte // partial message
+	encryptionRequest uint32 // SSLRequest or GSSENCRequest code waiting for response
+	encrypted         bool
+	readyForQuery     bool
+	copyIn            copyStream
+	copyOut           copyStream
+	replication       replicationState
+	resynced          bool // a message boundary is found in the connection seen mid-stream
+}
+
+type backendKey struct {
+	processID uint32
+	secretKey uint32
+}
+
+// backendKeyMap is map of BackendKeyData to conn_id for CancelRequest
+type backendKeyMap struct {
+	connIDs map[backendKey]string
+	keys    []backendKey
+	mutex   *sync.Mutex
+}
+
+func newBackendKeyMap() *backendKeyMap {
+	return &backendKeyMap{
+		connIDs: map[backendKey]string{},
+		keys:    []backendKey{},
+		mutex:   new(sync.Mutex),
+	}
+}
+
+func (m *backendKeyMap) set(key backendKey, connID string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.connIDs[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.connIDs[key] = connID
+	if len(m.keys) > maxBackendKeys {
+		delete(m.connIDs, m.keys[0])
+		m.keys = m.keys[1:]
+	}
+}
+
+func (m *backendKeyMap) get(key backendKey) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	connID, ok := m.connIDs[key]
+	return connID, ok
 }
 
 // NewDumper returns a Dumper
 func NewDumper() *Dumper {
 	dumper := &Dumper{
-		name:   "pg",
-		logger: logger.NewQueryLogger(),
+		name:        "pg",
+		logger:      logger.NewQueryLogger(),
+		backendKeys: newBackendKeyMap(),
 	}
 	return dumper
 }
@@ -53,31 +141,70 @@ func (p *Dumper) Name() string {
 
 // Dump query of PostgreSQL
 func (p *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
-	read, _ := p.Read(in, direction, connMetadata)
-	if len(read) == 0 {
-		return nil
-	}
+	records, _ := p.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
 
-	values := []dumper.DumpValue{}
-	values = append(values, read...)
-	values = append(values, connMetadata.DumpValues...)
-	values = append(values, additional...)
-
-	p.Log(values)
+		p.Log(values)
+	}
 	return nil
 }
 
-// Read return byte to analyzed string
+// Read return the first message of byte to analyzed string ( use ReadFrames to read all messages )
 func (p *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := p.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return messages of byte to analyzed string
+func (p *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if connMetadata.Internal.(connMetadataInternal).encrypted {
+		return records, nil
+	}
+
+	if cancelValues, ok := p.readCancelRequest(in, direction); ok {
+		if len(cancelValues) > 0 {
+			records = append(records, cancelValues)
+		}
+		return records, nil
+	}
+
+	if connMetadata.MidStream && !resync(in, direction, connMetadata) {
+		return records, nil
+	}
+
 	values, handshakeErr := p.readHandshake(in, direction, connMetadata)
 	connMetadata.DumpValues = append(connMetadata.DumpValues, values...)
 
 	if handshakeErr != nil {
-		return values, handshakeErr
+		if len(values) > 0 {
+			records = append(records, values)
+		}
+		return records, handshakeErr
+	}
+
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		var read []dumper.DumpValue
+		if connMetadata.Internal.(connMetadataInternal).replication.streaming {
+			read = p.readReplicationStream(in, connMetadata)
+		} else {
+			read = p.readCopyOut(in, connMetadata)
+		}
+		if len(read) > 0 {
+			records = append(records, read)
+		}
+		return records, nil
 	}
 
-	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc || direction == dumper.Unknown {
-		return []dumper.DumpValue{}, nil
+	if direction == dumper.Unknown {
+		return records, nil
 	}
 
 	if len(connMetadata.Internal.(connMetadataInternal).longPacketCache) > 0 {
@@ -87,38 +214,60 @@ func (p *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumpe
 		connMetadata.Internal = internal
 	}
 
-	if len(in) == 0 {
-		return []dumper.DumpValue{}, nil
-	}
+	for len(in) > 0 {
+		if connMetadata.Internal.(connMetadataInternal).replication.streaming && (in[0] == messageCopyData || in[0] == messageCopyDone) {
+			// Standby status update, Hot standby feedback message and CopyDone of replication stream
+			return records, nil
+		}
 
-	messageType := in[0]
+		if copyValues, rest, ok := p.readCopyIn(in, connMetadata); ok {
+			if len(copyValues) > 0 {
+				records = append(records, copyValues)
+			}
+			in = rest
+			continue
+		}
 
-	switch messageType {
-	case messageQuery, messageParse, messageBind, messageExecute:
-		var messageLength uint32
-		internal := connMetadata.Internal.(connMetadataInternal)
-		if internal.messageLength > 0 {
-			messageLength = internal.messageLength
-		} else {
-			ml := make([]byte, 4)
-			copy(ml, in[1:5])
-			messageLength = binary.BigEndian.Uint32(ml)
+		switch in[0] {
+		case messageQuery, messageParse, messageBind, messageExecute, messageDescribe, messageClose, messageSync, messageFlush, messageFunctionCall:
+		default:
+			return records, nil
+		}
+		if len(in) < 5 {
+			// message length is split into the next packet
+			p.cacheMessage(in, connMetadata)
+			return records, nil
+		}
+		messageLength := binary.BigEndian.Uint32(in[1:5])
+		if messageLength < 4 {
+			return records, nil
 		}
 		if uint32(len(in[1:])) < messageLength {
-			internal.messageLength = messageLength
-			internal.longPacketCache = append(internal.longPacketCache, in...)
-			connMetadata.Internal = internal
-			return []dumper.DumpValue{}, nil
+			p.cacheMessage(in, connMetadata)
+			return records, nil
 		}
-		internal.messageLength = uint32(0)
-		connMetadata.Internal = internal
+		records = append(records, p.readMessage(in[:1+messageLength], connMetadata))
+		in = in[1+messageLength:]
 	}
+	return records, nil
+}
+
+// cacheMessage cache the partial message until the rest is read
+func (p *Dumper) cacheMessage(in []byte, connMetadata *dumper.ConnMetadata) {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	internal.longPacketCache = append([]byte{}, in...)
+	connMetadata.Internal = internal
+}
 
+// readMessage read a frontend message
+// https://www.postgresql.org/docs/10/static/protocol-message-formats.html
+func (p *Dumper) readMessage(in []byte, connMetadata *dumper.ConnMetadata) []dumper.DumpValue {
+	messageType := in[0]
 	var dumps = []dumper.DumpValue{}
-	// https://www.postgresql.org/docs/10/static/protocol-message-formats.html
 	switch messageType {
 	case messageQuery:
 		query := strings.TrimRight(string(in[5:]), "\x00")
+		p.expectCopyOut(query, connMetadata)
 
 		dumps = []dumper.DumpValue{
 			dumper.DumpValue{
@@ -126,12 +275,22 @@ func (p *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumpe
 				Value: query,
 			},
 		}
+		if statements := splitStatements(query); len(statements) > 1 {
+			dumps = append(dumps, dumper.DumpValue{
+				Key:   "queries",
+				Value: statements,
+			})
+		}
+		if connMetadata.Internal.(connMetadataInternal).replication.logical {
+			dumps = append(dumps, p.readReplicationCommand(query, connMetadata)...)
+		}
 	case messageParse:
 		buff := bytes.NewBuffer(in[5:])
 		b, _ := buff.ReadString(0x00)
 		stmtName := strings.TrimRight(b, "\x00")
 		b, _ = buff.ReadString(0x00)
 		query := strings.TrimRight(b, "\x00")
+		p.expectCopyOut(query, connMetadata)
 		numParams := int(binary.BigEndian.Uint16(readBytes(buff, 2)))
 		for i := 0; i < numParams; i++ {
 			// TODO
@@ -154,31 +313,7 @@ func (p *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumpe
 		portalName := strings.TrimRight(b, "\x00")
 		b, _ = buff.ReadString(0x00)
 		stmtName := strings.TrimRight(b, "\x00")
-		c := int(binary.BigEndian.Uint16(readBytes(buff, 2)))
-		dataTypes := []dataType{}
-		for i := 0; i < c; i++ {
-			t := dataType(binary.BigEndian.Uint16(readBytes(buff, 2)))
-			dataTypes = append(dataTypes, t)
-		}
-		numParams := int(binary.BigEndian.Uint16(readBytes(buff, 2)))
-		if c == 0 {
-			for i := 0; i < numParams; i++ {
-				dataTypes = append(dataTypes, typeString)
-			}
-		}
-		values := []interface{}{}
-		for i := 0; i < numParams; i++ {
-			n := int32(binary.BigEndian.Uint32(readBytes(buff, 4)))
-			if n == -1 {
-				continue
-			}
-			v := readBytes(buff, int(n))
-			if dataTypes[i] == typeString {
-				values = append(values, string(v))
-			} else {
-				values = append(values, v)
-			}
-		}
+		values := readParameterValues(buff)
 
 		dumps = []dumper.DumpValue{
 			dumper.DumpValue{
@@ -209,13 +344,43 @@ func (p *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumpe
 				Value: "",
 			},
 		}
-	default:
-		return []dumper.DumpValue{}, nil
+	case messageDescribe, messageClose:
+		buff := bytes.NewBuffer(in[5:])
+		target, _ := buff.ReadByte()
+		b, _ := buff.ReadString(0x00)
+		name := strings.TrimRight(b, "\x00")
+		key := "stmt_name"
+		if target == 'P' {
+			key = "portal_name"
+		}
+
+		dumps = []dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   key,
+				Value: name,
+			},
+		}
+	case messageSync, messageFlush:
+	case messageFunctionCall:
+		buff := bytes.NewBuffer(in[5:])
+		oid := binary.BigEndian.Uint32(readBytes(buff, 4))
+		values := readParameterValues(buff)
+
+		dumps = []dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "function_oid",
+				Value: oid,
+			},
+			dumper.DumpValue{
+				Key:   "function_args",
+				Value: values,
+			},
+		}
 	}
 	return append(dumps, dumper.DumpValue{
 		Key:   "message_type",
 		Value: string(messageType),
-	}), nil
+	})
 }
 
 // Log values
@@ -231,62 +396,274 @@ func (p *Dumper) Log(values []dumper.DumpValue) {
 func (p *Dumper) NewConnMetadata() *dumper.ConnMetadata {
 	return &dumper.ConnMetadata{
 		DumpValues: []dumper.DumpValue{},
-		Internal: connMetadataInternal{
-			messageLength: uint32(0),
-		},
+		Internal:   connMetadataInternal{},
 	}
 }
 
 func (p *Dumper) readHandshake(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
 	values := []dumper.DumpValue{}
 	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
-		return values, nil
+		return p.readBackendHandshake(in, connMetadata)
 	}
 	if len(in) < 8 {
 		return values, nil
 	}
-	b := make([]byte, 2)
-	copy(b, in[4:6])
-	pNo := binary.BigEndian.Uint16(b)
-	if pNo == 1234 {
-		// SSLRequest
-		b := make([]byte, 2)
-		copy(b, in[6:8])
-		uNo := binary.BigEndian.Uint16(b)
-		if uNo == 5679 {
-			// tcpdp pg dumper not support SSL connection.
-			err := errors.New("client is trying to connect using SSL. tcpdp pg dumper not support SSL connection")
-			return values, err
-		}
+	code := binary.BigEndian.Uint32(in[4:8])
+	switch code {
+	case sslRequestCode, gssencRequestCode:
+		// wait for the response of server ( 'S' or 'G': accept, 'N': reject )
+		internal := connMetadata.Internal.(connMetadataInternal)
+		internal.encryptionRequest = code
+		connMetadata.Internal = internal
+		return values, nil
 	}
-	// parse StartupMessage to get username, database
-	if pNo != 3 {
+	// parse StartupMessage to get username, database and other parameters
+	if code != protocolVersion3 {
 		return values, nil
 	}
+	params := map[string]string{}
 	splited := bytes.Split(in[8:], []byte{0x00})
-	if len(splited) > 0 {
-		for i, keyOrValue := range splited {
-			if i%2 != 0 {
+	for i := 0; i+1 < len(splited); i += 2 {
+		key := string(splited[i])
+		if key == "" {
+			break
+		}
+		value := string(splited[i+1])
+		switch key {
+		case "user":
+			values = append(values, dumper.DumpValue{
+				Key:   "username",
+				Value: value,
+			})
+		case "database":
+			values = append(values, dumper.DumpValue{
+				Key:   "database",
+				Value: value,
+			})
+		case "replication":
+			params[key] = value
+			if value == "database" {
+				// logical replication walsender
+				internal := connMetadata.Internal.(connMetadataInternal)
+				internal.replication.logical = true
+				connMetadata.Internal = internal
+			}
+		default:
+			params[key] = value
+		}
+	}
+	if len(params) > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "startup_parameters",
+			Value: params,
+		})
+	}
+	return values, nil
+}
+
+// readBackendHandshake parse the response of SSLRequest/GSSENCRequest and BackendKeyData
+func (p *Dumper) readBackendHandshake(in []byte, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	values := []dumper.DumpValue{}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.readyForQuery || len(in) == 0 {
+		return values, nil
+	}
+	if internal.encryptionRequest > 0 {
+		code := internal.encryptionRequest
+		internal.encryptionRequest = 0
+		connMetadata.Internal = internal
+		switch {
+		case in[0] == 'S' && code == sslRequestCode:
+			// tcpdp pg dumper not support SSL connection.
+			internal.encrypted = true
+			connMetadata.Internal = internal
+			return values, errors.New("server accepted SSL connection. tcpdp pg dumper not support SSL connection")
+		case in[0] == 'G' && code == gssencRequestCode:
+			// tcpdp pg dumper not support GSSAPI encrypted connection.
+			internal.encrypted = true
+			connMetadata.Internal = internal
+			return values, errors.New("server accepted GSSAPI encryption. tcpdp pg dumper not support GSSAPI encrypted connection")
+		}
+		// 'N': client continues with StartupMessage in plaintext
+		return values, nil
+	}
+
+	buff := bytes.NewBuffer(in)
+	for buff.Len() >= 5 {
+		messageType, _ := buff.ReadByte()
+		l := int(binary.BigEndian.Uint32(readBytes(buff, 4)))
+		if l < 4 || buff.Len() < l-4 {
+			break
+		}
+		body := readBytes(buff, l-4)
+		switch messageType {
+		case messageBackendKeyData:
+			if len(body) < 8 {
 				continue
 			}
-			if string(keyOrValue) == "user" {
-				values = append(values, dumper.DumpValue{
-					Key:   "username",
-					Value: string(splited[i+1]),
-				})
+			key := backendKey{
+				processID: binary.BigEndian.Uint32(body[0:4]),
+				secretKey: binary.BigEndian.Uint32(body[4:8]),
 			}
-			if string(keyOrValue) == "database" {
-				values = append(values, dumper.DumpValue{
-					Key:   "database",
-					Value: string(splited[i+1]),
-				})
+			if connID := connIDFromConnMetadata(connMetadata); connID != "" {
+				p.backendKeys.set(key, connID)
 			}
+			values = append(values, dumper.DumpValue{
+				Key:   "backend_pid",
+				Value: key.processID,
+			})
+		case messageReadyForQuery:
+			internal.readyForQuery = true
+			connMetadata.Internal = internal
 		}
 	}
 	return values, nil
 }
 
+// ReadErrorResponse return SQLSTATE of ErrorResponse in messages from backend
+func (p *Dumper) ReadErrorResponse(in []byte) (string, bool) {
+	for len(in) >= 5 {
+		l := int(binary.BigEndian.Uint32(in[1:5]))
+		if l < 4 || len(in) < 1+l {
+			return "", false
+		}
+		if in[0] == messageErrorResponse {
+			// https://www.postgresql.org/docs/current/protocol-error-fields.html
+			fields := bytes.Split(in[5:1+l], []byte{0x00})
+			for _, f := range fields {
+				if len(f) > 1 && f[0] == 'C' {
+					return string(f[1:]), true
+				}
+			}
+		}
+		in = in[1+l:]
+	}
+	return "", false
+}
+
+// resync waits for a message boundary of the connection seen mid-stream ( StartupMessage is not captured )
+func resync(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) bool {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.resynced {
+		return true
+	}
+	if direction != dumper.ClientToRemote && direction != dumper.SrcToDst {
+		return false
+	}
+	if !isMessageBoundary(in) {
+		return false
+	}
+	internal.resynced = true
+	internal.readyForQuery = true
+	connMetadata.Internal = internal
+	return true
+}
+
+// isMessageBoundary returns true if in starts with a frontend message and ends at the end of a message
+func isMessageBoundary(in []byte) bool {
+	if len(in) == 0 {
+		return false
+	}
+	for len(in) > 0 {
+		if len(in) < 5 {
+			return false
+		}
+		switch in[0] {
+		case messageQuery, messageParse, messageBind, messageExecute, messageDescribe, messageClose, messageSync, messageFlush, messageFunctionCall, messageTerminate:
+		default:
+			return false
+		}
+		l := int(binary.BigEndian.Uint32(in[1:5]))
+		if l < 4 || len(in) < 1+l {
+			return false
+		}
+		in = in[1+l:]
+	}
+	return true
+}
+
+// readCancelRequest parse CancelRequest
+func (p *Dumper) readCancelRequest(in []byte, direction dumper.Direction) ([]dumper.DumpValue, bool) {
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		return nil, false
+	}
+	if len(in) != 16 || binary.BigEndian.Uint32(in[0:4]) != 16 || binary.BigEndian.Uint32(in[4:8]) != cancelRequestCode {
+		return nil, false
+	}
+	key := backendKey{
+		processID: binary.BigEndian.Uint32(in[8:12]),
+		secretKey: binary.BigEndian.Uint32(in[12:16]),
+	}
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "cancel_backend_pid",
+			Value: key.processID,
+		},
+		dumper.DumpValue{
+			Key:   "cancel_secret_key",
+			Value: key.secretKey,
+		},
+	}
+	if connID, ok := p.backendKeys.get(key); ok {
+		values = append(values, dumper.DumpValue{
+			Key:   "cancel_conn_id",
+			Value: connID,
+		})
+	}
+	return values, true
+}
+
+func connIDFromConnMetadata(connMetadata *dumper.ConnMetadata) string {
+	for _, kv := range connMetadata.DumpValues {
+		if kv.Key == "conn_id" {
+			if connID, ok := kv.Value.(string); ok {
+				return connID
+			}
+		}
+	}
+	return ""
+}
+
+// readParameterValues read parameter format codes and parameter values of Bind / FunctionCall
+func readParameterValues(buff *bytes.Buffer) []interface{} {
+	c := int(binary.BigEndian.Uint16(readBytes(buff, 2)))
+	dataTypes := []dataType{}
+	for i := 0; i < c; i++ {
+		t := dataType(binary.BigEndian.Uint16(readBytes(buff, 2)))
+		dataTypes = append(dataTypes, t)
+	}
+	numParams := int(binary.BigEndian.Uint16(readBytes(buff, 2)))
+	values := []interface{}{}
+	for i := 0; i < numParams; i++ {
+		n := int32(binary.BigEndian.Uint32(readBytes(buff, 4)))
+		if n == -1 {
+			continue
+		}
+		if n < 0 || int(n) > buff.Len() {
+			break
+		}
+		v := readBytes(buff, int(n))
+		t := typeString
+		switch {
+		case c == 1:
+			// the format code is applied to all parameters
+			t = dataTypes[0]
+		case i < c:
+			t = dataTypes[i]
+		}
+		if t == typeString {
+			values = append(values, string(v))
+		} else {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func readBytes(buff *bytes.Buffer, len int) []byte {
+	if len < 0 {
+		len = 0
+	}
 	b := make([]byte, len)
 	_, _ = buff.Read(b)
 	return b
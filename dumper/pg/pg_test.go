@@ -3,6 +3,7 @@ package pg
 import (
 	"bytes"
 	"io"
+	"reflect"
 	"testing"
 
 	"github.com/k1LoW/tcpdp/dumper"
@@ -32,9 +33,7 @@ var pgValueTests = []struct {
 		dumper.SrcToDst,
 		dumper.ConnMetadata{
 			DumpValues: []dumper.DumpValue{},
-			Internal: connMetadataInternal{
-				messageLength: uint32(0),
-			},
+			Internal:   connMetadataInternal{},
 		},
 		[]dumper.DumpValue{
 			dumper.DumpValue{
@@ -45,6 +44,14 @@ var pgValueTests = []struct {
 				Key:   "database",
 				Value: "testdb",
 			},
+			dumper.DumpValue{
+				Key: "startup_parameters",
+				Value: map[string]string{
+					"extra_float_digits": "2",
+					"client_encoding":    "UTF8",
+					"datestyle":          "ISO, MDY",
+				},
+			},
 		},
 		[]dumper.DumpValue{},
 	},
@@ -57,9 +64,7 @@ var pgValueTests = []struct {
 		dumper.SrcToDst,
 		dumper.ConnMetadata{
 			DumpValues: []dumper.DumpValue{},
-			Internal: connMetadataInternal{
-				messageLength: uint32(0),
-			},
+			Internal:   connMetadataInternal{},
 		},
 		[]dumper.DumpValue{},
 		[]dumper.DumpValue{
@@ -85,9 +90,7 @@ var pgValueTests = []struct {
 		dumper.SrcToDst,
 		dumper.ConnMetadata{
 			DumpValues: []dumper.DumpValue{},
-			Internal: connMetadataInternal{
-				messageLength: uint32(0),
-			},
+			Internal:   connMetadataInternal{},
 		},
 		[]dumper.DumpValue{},
 		[]dumper.DumpValue{
@@ -96,7 +99,7 @@ var pgValueTests = []struct {
 				Value: "",
 			},
 			dumper.DumpValue{
-				Key:   "parse",
+				Key:   "parse_query",
 				Value: "SELECT CONCAT($1::text, $2::text, $3::text);",
 			},
 			dumper.DumpValue{
@@ -117,9 +120,7 @@ var pgValueTests = []struct {
 		dumper.SrcToDst,
 		dumper.ConnMetadata{
 			DumpValues: []dumper.DumpValue{},
-			Internal: connMetadataInternal{
-				messageLength: uint32(0),
-			},
+			Internal:   connMetadataInternal{},
 		},
 		[]dumper.DumpValue{},
 		[]dumper.DumpValue{
@@ -133,7 +134,7 @@ var pgValueTests = []struct {
 			},
 			dumper.DumpValue{
 				Key:   "bind_values",
-				Value: []string{"012345679", "あいうえおかきくけこ", ""},
+				Value: []interface{}{"012345679", "あいうえおかきくけこ", ""},
 			},
 			dumper.DumpValue{
 				Key:   "message_type",
@@ -141,6 +142,141 @@ var pgValueTests = []struct {
 			},
 		},
 	},
+	{
+		"Parse multi-statement query from MessageQuery packet",
+		[]byte{
+			0x51, 0x00, 0x00, 0x00, 0x1f, 0x42, 0x45, 0x47, 0x49, 0x4e, 0x3b, 0x20, 0x53, 0x45, 0x4c, 0x45,
+			0x43, 0x54, 0x20, 0x27, 0x3b, 0x27, 0x3b, 0x20, 0x43, 0x4f, 0x4d, 0x4d, 0x49, 0x54, 0x3b, 0x00,
+		},
+		dumper.SrcToDst,
+		dumper.ConnMetadata{
+			DumpValues: []dumper.DumpValue{},
+			Internal:   connMetadataInternal{},
+		},
+		[]dumper.DumpValue{},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "query",
+				Value: "BEGIN; SELECT ';'; COMMIT;",
+			},
+			dumper.DumpValue{
+				Key:   "queries",
+				Value: []string{"BEGIN", "SELECT ';'", "COMMIT"},
+			},
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "Q",
+			},
+		},
+	},
+	{
+		"Parse MessageDescribe packet",
+		[]byte{
+			0x44, 0x00, 0x00, 0x00, 0x0b, 0x53, 0x73, 0x74, 0x6d, 0x74, 0x31, 0x00, 0x53, 0x00, 0x00, 0x00,
+			0x04,
+		},
+		dumper.SrcToDst,
+		dumper.ConnMetadata{
+			DumpValues: []dumper.DumpValue{},
+			Internal:   connMetadataInternal{},
+		},
+		[]dumper.DumpValue{},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "stmt_name",
+				Value: "stmt1",
+			},
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "D",
+			},
+		},
+	},
+	{
+		"Parse MessageClose packet",
+		[]byte{
+			0x43, 0x00, 0x00, 0x00, 0x0d, 0x50, 0x70, 0x6f, 0x72, 0x74, 0x61, 0x6c, 0x31, 0x00,
+		},
+		dumper.SrcToDst,
+		dumper.ConnMetadata{
+			DumpValues: []dumper.DumpValue{},
+			Internal:   connMetadataInternal{},
+		},
+		[]dumper.DumpValue{},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "portal_name",
+				Value: "portal1",
+			},
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "C",
+			},
+		},
+	},
+	{
+		"Parse MessageSync packet",
+		[]byte{
+			0x53, 0x00, 0x00, 0x00, 0x04,
+		},
+		dumper.SrcToDst,
+		dumper.ConnMetadata{
+			DumpValues: []dumper.DumpValue{},
+			Internal:   connMetadataInternal{},
+		},
+		[]dumper.DumpValue{},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "S",
+			},
+		},
+	},
+	{
+		"Parse MessageFlush packet",
+		[]byte{
+			0x48, 0x00, 0x00, 0x00, 0x04,
+		},
+		dumper.SrcToDst,
+		dumper.ConnMetadata{
+			DumpValues: []dumper.DumpValue{},
+			Internal:   connMetadataInternal{},
+		},
+		[]dumper.DumpValue{},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "H",
+			},
+		},
+	},
+	{
+		"Parse MessageFunctionCall packet",
+		[]byte{
+			0x46, 0x00, 0x00, 0x00, 0x1c, 0x00, 0x00, 0x06, 0x3e, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00,
+			0x00, 0x00, 0x03, 0x61, 0x62, 0x63, 0x00, 0x00, 0x00, 0x01, 0x31, 0x00, 0x00,
+		},
+		dumper.SrcToDst,
+		dumper.ConnMetadata{
+			DumpValues: []dumper.DumpValue{},
+			Internal:   connMetadataInternal{},
+		},
+		[]dumper.DumpValue{},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "function_oid",
+				Value: uint32(1598),
+			},
+			dumper.DumpValue{
+				Key:   "function_args",
+				Value: []interface{}{"abc", "1"},
+			},
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "F",
+			},
+		},
+	},
 	{
 		"When direction = dumper.RemoteToClient do not parse query",
 		[]byte{
@@ -150,9 +286,7 @@ var pgValueTests = []struct {
 		dumper.RemoteToClient,
 		dumper.ConnMetadata{
 			DumpValues: []dumper.DumpValue{},
-			Internal: connMetadataInternal{
-				messageLength: uint32(0),
-			},
+			Internal:   connMetadataInternal{},
 		},
 		[]dumper.DumpValue{},
 		[]dumper.DumpValue{},
@@ -179,12 +313,9 @@ func TestPgReadHandshakeStartupMessage(t *testing.T) {
 			if len(actual) != len(expected) {
 				t.Errorf("actual %v\nwant %v", actual, expected)
 			}
-			if len(actual) == 2 {
-				if actual[0] != expected[0] {
-					t.Errorf("actual %v\nwant %v", actual, expected)
-				}
-				if actual[1] != expected[1] {
-					t.Errorf("actual %v\nwant %v", actual, expected)
+			for i := 0; i < len(actual) && i < len(expected); i++ {
+				if !reflect.DeepEqual(actual[i], expected[i]) {
+					t.Errorf("actual %#v\nwant %#v", actual[i], expected[i])
 				}
 			}
 		})
@@ -211,18 +342,755 @@ func TestPgRead(t *testing.T) {
 			if len(actual) != len(expected) {
 				t.Errorf("actual %v\nwant %v", actual, expected)
 			}
-			if len(actual) == 2 {
-				if actual[0] != expected[0] {
-					t.Errorf("actual %#v\nwant %#v", actual[0], expected[0])
+			for i := 0; i < len(actual) && i < len(expected); i++ {
+				if !reflect.DeepEqual(actual[i], expected[i]) {
+					t.Errorf("actual %#v\nwant %#v", actual[i], expected[i])
+				}
+			}
+		})
+	}
+}
+
+var pgEncryptionRequestTests = []struct {
+	description string
+	request     []byte
+	response    []byte
+	expectErr   bool
+}{
+	{
+		"SSLRequest rejected by server",
+		[]byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f},
+		[]byte{0x4e},
+		false,
+	},
+	{
+		"SSLRequest accepted by server",
+		[]byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f},
+		[]byte{0x53},
+		true,
+	},
+	{
+		"GSSENCRequest rejected by server",
+		[]byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x30},
+		[]byte{0x4e},
+		false,
+	},
+	{
+		"GSSENCRequest accepted by server",
+		[]byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x30},
+		[]byte{0x47},
+		true,
+	},
+}
+
+func TestPgReadEncryptionRequest(t *testing.T) {
+	for _, tt := range pgEncryptionRequestTests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := d.NewConnMetadata()
+
+			actual, err := d.Read(tt.request, dumper.SrcToDst, connMetadata)
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+			if len(actual) != 0 {
+				t.Errorf("actual %#v\nwant %#v", actual, []dumper.DumpValue{})
+			}
+			_, err = d.Read(tt.response, dumper.DstToSrc, connMetadata)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("actual %v\nwant error: %v", err, tt.expectErr)
+			}
+			if connMetadata.Internal.(connMetadataInternal).encrypted != tt.expectErr {
+				t.Errorf("actual %v\nwant %v", connMetadata.Internal.(connMetadataInternal).encrypted, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestPgReadCancelRequest(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger:      newTestLogger(out),
+		backendKeys: newBackendKeyMap(),
+	}
+
+	// AuthenticationOk, BackendKeyData (pid: 12345, key: 0x01020304), ReadyForQuery
+	connMetadata := d.NewConnMetadata()
+	connMetadata.DumpValues = append(connMetadata.DumpValues, dumper.DumpValue{
+		Key:   "conn_id",
+		Value: "bfmgiphkqkf5p4kse5o0",
+	})
+	in := []byte{
+		0x52, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x4b, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00,
+		0x30, 0x39, 0x01, 0x02, 0x03, 0x04, 0x5a, 0x00, 0x00, 0x00, 0x05, 0x49,
+	}
+	actual, err := d.Read(in, dumper.DstToSrc, connMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(actual) != 0 {
+		t.Errorf("actual %#v\nwant %#v", actual, []dumper.DumpValue{})
+	}
+	expectedDumpValues := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "conn_id",
+			Value: "bfmgiphkqkf5p4kse5o0",
+		},
+		dumper.DumpValue{
+			Key:   "backend_pid",
+			Value: uint32(12345),
+		},
+	}
+	if !reflect.DeepEqual(connMetadata.DumpValues, expectedDumpValues) {
+		t.Errorf("actual %#v\nwant %#v", connMetadata.DumpValues, expectedDumpValues)
+	}
+	if !connMetadata.Internal.(connMetadataInternal).readyForQuery {
+		t.Errorf("actual %v\nwant %v", false, true)
+	}
+
+	// CancelRequest on another connection
+	cancelConnMetadata := d.NewConnMetadata()
+	in = []byte{
+		0x00, 0x00, 0x00, 0x10, 0x04, 0xd2, 0x16, 0x2e, 0x00, 0x00, 0x30, 0x39, 0x01, 0x02, 0x03, 0x04,
+	}
+	actual, err = d.Read(in, dumper.SrcToDst, cancelConnMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	expected := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "cancel_backend_pid",
+			Value: uint32(12345),
+		},
+		dumper.DumpValue{
+			Key:   "cancel_secret_key",
+			Value: uint32(0x01020304),
+		},
+		dumper.DumpValue{
+			Key:   "cancel_conn_id",
+			Value: "bfmgiphkqkf5p4kse5o0",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %#v\nwant %#v", actual, expected)
+	}
+}
+
+func TestPgReadMidStream(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger:      newTestLogger(out),
+		backendKeys: newBackendKeyMap(),
+	}
+	connMetadata := d.NewConnMetadata()
+	connMetadata.MidStream = true
+
+	query := append([]byte("SELECT 1"), 0x00)
+	packets := []struct {
+		in        []byte
+		direction dumper.Direction
+	}{
+		// the rest of a long Query
+		{[]byte("aaaaaaaaaaaaaaaaaaaaaaaa'\x00"), dumper.SrcToDst},
+		// CommandComplete, ReadyForQuery
+		{[]byte{0x43, 0x00, 0x00, 0x00, 0x0d, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x20, 0x31, 0x00, 0x5a, 0x00, 0x00, 0x00, 0x05, 0x49}, dumper.DstToSrc},
+		// Query and the first half of the next message
+		{append(append([]byte{'Q', 0x00, 0x00, 0x00, byte(4 + len(query))}, query...), 'P', 0x00), dumper.SrcToDst},
+	}
+	for i, p := range packets {
+		actual, err := d.Read(p.in, p.direction, connMetadata)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(actual) > 0 {
+			t.Errorf("packet %d: got %v\nwant no values", i, actual)
+		}
+	}
+
+	in := append([]byte{'Q', 0x00, 0x00, 0x00, byte(4 + len(query))}, query...)
+	actual, err := d.Read(in, dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "query",
+			Value: "SELECT 1",
+		},
+		dumper.DumpValue{
+			Key:   "message_type",
+			Value: "Q",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %#v\nwant %#v", actual, expected)
+	}
+	if !connMetadata.Internal.(connMetadataInternal).readyForQuery {
+		t.Errorf("actual %v\nwant %v", false, true)
+	}
+}
+
+var pgTruncatedMessageTests = []struct {
+	description string
+	in          []byte
+}{
+	{"Close without length", []byte{'C'}},
+	{"Describe without length", []byte{'D'}},
+	{"Query without length", []byte{'Q', 0x00}},
+	{"Parse without length", []byte{'P', 0x00, 0x00}},
+	{"Bind without length", []byte{'B', 0x00, 0x00, 0x00}},
+	{"Execute without length", []byte{'E'}},
+	{"FunctionCall without length", []byte{'F'}},
+	{"Describe with invalid length", []byte{'D', 0x00, 0x00, 0x00, 0x00}},
+	{"Bind with negative parameter length", []byte{'B', 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xff, 0xff, 0xff, 0xfe}},
+	{"FunctionCall with too long parameter length", []byte{'F', 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x61, 0x62}},
+}
+
+func TestPgReadTruncatedMessage(t *testing.T) {
+	for _, tt := range pgTruncatedMessageTests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := d.NewConnMetadata()
+			if _, err := d.Read(tt.in, dumper.SrcToDst, connMetadata); err != nil {
+				t.Errorf("%v", err)
+			}
+		})
+	}
+}
+
+func TestPgReadSplitMessageLength(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+
+	query := append([]byte("SELECT 1"), 0x00)
+	in := append([]byte{'Q', 0x00, 0x00, 0x00, byte(4 + len(query))}, query...)
+	actual, err := d.Read(in[:3], dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(actual) > 0 {
+		t.Errorf("got %v\nwant no values", actual)
+	}
+	actual, err = d.Read(in[3:], dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "query",
+			Value: "SELECT 1",
+		},
+		dumper.DumpValue{
+			Key:   "message_type",
+			Value: "Q",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %#v\nwant %#v", actual, expected)
+	}
+}
+
+func TestPgReadFrames(t *testing.T) {
+	// libpq sends Parse / Bind / Describe / Execute / Sync in a segment
+	extended := []byte{}
+	extended = append(extended, pgMessage('P', []byte("\x00SELECT $1::int\x00\x00\x00"))...)
+	extended = append(extended, pgMessage('B', []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, '1', 0x00, 0x00})...)
+	extended = append(extended, pgMessage('D', []byte("P\x00"))...)
+	extended = append(extended, pgMessage('E', []byte{0x00, 0x00, 0x00, 0x00, 0x00})...)
+	extended = append(extended, pgMessage('S', []byte{})...)
+	// Sync follows CopyDone of COPY FROM STDIN
+	copyIn := []byte{}
+	copyIn = append(copyIn, pgMessage('d', []byte("1\tfoo\n"))...)
+	copyIn = append(copyIn, pgMessage('c', []byte{})...)
+	copyIn = append(copyIn, pgMessage('Q', []byte("SELECT 1\x00"))...)
+
+	tests := []struct {
+		description string
+		in          [][]byte
+		expected    []string // message_type of records
+	}{
+		{
+			"Extended query in a segment",
+			[][]byte{extended},
+			[]string{"P", "B", "D", "E", "S"},
+		},
+		{
+			"Extended query split in the middle of Bind",
+			[][]byte{extended[:30], extended[30:]},
+			[]string{"P", "B", "D", "E", "S"},
+		},
+		{
+			"Query after CopyDone",
+			[][]byte{copyIn},
+			[]string{"c", "Q"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := d.NewConnMetadata()
+			connMetadata.Internal = connMetadataInternal{
+				readyForQuery: true,
+			}
+			actual := []string{}
+			for _, in := range tt.in {
+				records, err := d.ReadFrames(in, dumper.SrcToDst, connMetadata)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, r := range records {
+					for _, v := range r {
+						if v.Key == "message_type" {
+							actual = append(actual, v.Value.(string))
+						}
+					}
+				}
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("actual %v\nwant %v", actual, tt.expected)
+			}
+
+			out.Reset()
+			connMetadata = d.NewConnMetadata()
+			connMetadata.Internal = connMetadataInternal{
+				readyForQuery: true,
+			}
+			for _, in := range tt.in {
+				if err := d.Dump(in, dumper.SrcToDst, connMetadata, []dumper.DumpValue{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if actual := bytes.Count(out.Bytes(), []byte("\n")); actual != len(tt.expected) {
+				t.Errorf("actual %d lines\nwant %d", actual, len(tt.expected))
+			}
+		})
+	}
+}
+
+var pgCopyTests = []struct {
+	description string
+	packets     []struct {
+		in        []byte
+		direction dumper.Direction
+	}
+	expected []dumper.DumpValue
+}{
+	{
+		"COPY FROM STDIN with CopyData split into packets",
+		[]struct {
+			in        []byte
+			direction dumper.Direction
+		}{
+			{
+				[]byte{
+					0x64, 0x00, 0x00, 0x00, 0x10, 0x31, 0x09, 0x66, 0x6f, 0x6f, 0x0a, 0x32, 0x09, 0x62, 0x61, 0x72,
+					0x0a, 0x64, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+			},
+			{
+				[]byte{
+					0x00, 0x0a, 0x33, 0x09, 0x62, 0x61, 0x7a, 0x0a, 0x63, 0x00, 0x00, 0x00, 0x04,
+				},
+				dumper.SrcToDst,
+			},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "copy_bytes",
+				Value: 18,
+			},
+			dumper.DumpValue{
+				Key:   "copy_rows",
+				Value: 3,
+			},
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "c",
+			},
+		},
+	},
+	{
+		"COPY FROM STDIN with CopyFail",
+		[]struct {
+			in        []byte
+			direction dumper.Direction
+		}{
+			{
+				[]byte{
+					0x64, 0x00, 0x00, 0x00, 0x0a, 0x31, 0x09, 0x66, 0x6f, 0x6f, 0x0a, 0x66, 0x00, 0x00, 0x00, 0x14,
+					0x61, 0x62, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x20, 0x62, 0x79, 0x20, 0x75, 0x73, 0x65, 0x72, 0x00,
+				},
+				dumper.SrcToDst,
+			},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "copy_bytes",
+				Value: 6,
+			},
+			dumper.DumpValue{
+				Key:   "copy_rows",
+				Value: 1,
+			},
+			dumper.DumpValue{
+				Key:   "copy_fail_message",
+				Value: "aborted by user",
+			},
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "f",
+			},
+		},
+	},
+	{
+		"COPY TO STDOUT",
+		[]struct {
+			in        []byte
+			direction dumper.Direction
+		}{
+			{
+				[]byte{
+					0x51, 0x00, 0x00, 0x00, 0x19, 0x43, 0x4f, 0x50, 0x59, 0x20, 0x75, 0x73, 0x65, 0x72, 0x73, 0x20,
+					0x54, 0x4f, 0x20, 0x53, 0x54, 0x44, 0x4f, 0x55, 0x54, 0x00,
+				},
+				dumper.SrcToDst,
+			},
+			{
+				[]byte{
+					0x48, 0x00, 0x00, 0x00, 0x0b, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00,
+					0x0a, 0x31, 0x09, 0x66, 0x6f, 0x6f, 0x0a, 0x64, 0x00, 0x00, 0x00, 0x0a, 0x32, 0x09, 0x62, 0x61,
+					0x72, 0x0a,
+				},
+				dumper.DstToSrc,
+			},
+			{
+				[]byte{
+					0x63, 0x00, 0x00, 0x00, 0x04, 0x43, 0x00, 0x00, 0x00, 0x0b, 0x43, 0x4f, 0x50, 0x59, 0x20, 0x32,
+					0x00, 0x5a, 0x00, 0x00, 0x00, 0x05, 0x49,
+				},
+				dumper.DstToSrc,
+			},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "copy_bytes",
+				Value: 12,
+			},
+			dumper.DumpValue{
+				Key:   "copy_rows",
+				Value: 2,
+			},
+			dumper.DumpValue{
+				Key:   "message_type",
+				Value: "c",
+			},
+		},
+	},
+}
+
+func TestPgReadCopy(t *testing.T) {
+	for _, tt := range pgCopyTests {
+		t.Run(tt.description, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			d := &Dumper{
+				logger: newTestLogger(out),
+			}
+			connMetadata := d.NewConnMetadata()
+			connMetadata.Internal = connMetadataInternal{
+				readyForQuery: true,
+			}
+
+			var actual []dumper.DumpValue
+			for _, p := range tt.packets {
+				read, err := d.Read(p.in, p.direction, connMetadata)
+				if err != nil {
+					t.Errorf("%v", err)
 				}
-				if actual[1] != expected[1] {
-					t.Errorf("actual %#v\nwant %#v", actual[1], expected[1])
+				if len(read) > 0 {
+					actual = read
 				}
 			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("actual %#v\nwant %#v", actual, tt.expected)
+			}
+			internal := connMetadata.Internal.(connMetadataInternal)
+			if internal.copyIn.active || internal.copyOut.active {
+				t.Errorf("COPY state should be reset: %#v", internal)
+			}
 		})
 	}
 }
 
+var splitStatementsTests = []struct {
+	in       string
+	expected []string
+}{
+	{
+		"SELECT 1;",
+		[]string{"SELECT 1"},
+	},
+	{
+		"BEGIN; UPDATE users SET name = 'a;b' WHERE id = 1; COMMIT",
+		[]string{"BEGIN", "UPDATE users SET name = 'a;b' WHERE id = 1", "COMMIT"},
+	},
+	{
+		"SELECT \"a;b\" FROM t; -- comment; here\nSELECT 2 /* ; */;",
+		[]string{"SELECT \"a;b\" FROM t", "-- comment; here\nSELECT 2 /* ; */"},
+	},
+	{
+		"DO $body$ BEGIN PERFORM 1; END $body$; SELECT $1; SELECT E'\\';'",
+		[]string{"DO $body$ BEGIN PERFORM 1; END $body$", "SELECT $1", "SELECT E'\\';'"},
+	},
+	{
+		" ; ",
+		[]string{},
+	},
+}
+
+func TestSplitStatements(t *testing.T) {
+	for _, tt := range splitStatementsTests {
+		actual := splitStatements(tt.in)
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("actual %#v\nwant %#v", actual, tt.expected)
+		}
+	}
+}
+
+var readErrorResponseTests = []struct {
+	description string
+	in          []byte
+	wantCode    string
+	wantOK      bool
+}{
+	{
+		"ErrorResponse",
+		pgMessage('E', []byte("SERROR\x00VERROR\x00C40P01\x00Mdeadlock detected\x00\x00")),
+		"40P01",
+		true,
+	},
+	{
+		"ErrorResponse after DataRow",
+		append(pgMessage('D', []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x01, '1'}), pgMessage('E', []byte("SERROR\x00C22012\x00Mdivision by zero\x00\x00"))...),
+		"22012",
+		true,
+	},
+	{
+		"CommandComplete",
+		pgMessage('C', []byte("SELECT 1\x00")),
+		"",
+		false,
+	},
+	{
+		"SSLRequest response",
+		[]byte{'N'},
+		"",
+		false,
+	},
+}
+
+func TestReadErrorResponse(t *testing.T) {
+	d := &Dumper{}
+	for _, tt := range readErrorResponseTests {
+		code, ok := d.ReadErrorResponse(tt.in)
+		if code != tt.wantCode || ok != tt.wantOK {
+			t.Errorf("%s\nactual %#v %#v\nwant %#v %#v", tt.description, code, ok, tt.wantCode, tt.wantOK)
+		}
+	}
+}
+
+func pgMessage(messageType byte, body []byte) []byte {
+	l := len(body) + 4
+	return append([]byte{messageType, byte(l >> 24), byte(l >> 16), byte(l >> 8), byte(l)}, body...)
+}
+
+func TestPgReadLogicalReplication(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+
+	// StartupMessage with replication=database
+	in := []byte{
+		0x00, 0x00, 0x00, 0x38, 0x00, 0x03, 0x00, 0x00, 0x75, 0x73, 0x65, 0x72, 0x00, 0x72, 0x65, 0x70,
+		0x6c, 0x00, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x00, 0x74, 0x65, 0x73, 0x74, 0x64,
+		0x62, 0x00, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x00, 0x64, 0x61,
+		0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x00, 0x00,
+	}
+	if _, err := d.Read(in, dumper.SrcToDst, connMetadata); err != nil {
+		t.Errorf("%v", err)
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	internal.readyForQuery = true
+	connMetadata.Internal = internal
+
+	// START_REPLICATION
+	in = []byte{
+		0x51, 0x00, 0x00, 0x00, 0x62, 0x53, 0x54, 0x41, 0x52, 0x54, 0x5f, 0x52, 0x45, 0x50, 0x4c, 0x49,
+		0x43, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x20, 0x53, 0x4c, 0x4f, 0x54, 0x20, 0x22, 0x73, 0x75, 0x62,
+		0x22, 0x20, 0x4c, 0x4f, 0x47, 0x49, 0x43, 0x41, 0x4c, 0x20, 0x30, 0x2f, 0x31, 0x36, 0x42, 0x33,
+		0x37, 0x34, 0x38, 0x20, 0x28, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69,
+		0x6f, 0x6e, 0x20, 0x27, 0x31, 0x27, 0x2c, 0x20, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x61, 0x74,
+		0x69, 0x6f, 0x6e, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x20, 0x27, 0x22, 0x70, 0x75, 0x62, 0x22,
+		0x27, 0x29, 0x00,
+	}
+	actual, err := d.Read(in, dumper.SrcToDst, connMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	expected := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "query",
+			Value: "START_REPLICATION SLOT \"sub\" LOGICAL 0/16B3748 (proto_version '1', publication_names '\"pub\"')",
+		},
+		dumper.DumpValue{
+			Key:   "replication_command",
+			Value: "START_REPLICATION",
+		},
+		dumper.DumpValue{
+			Key:   "replication_slot",
+			Value: "sub",
+		},
+		dumper.DumpValue{
+			Key:   "replication_start_lsn",
+			Value: "0/16B3748",
+		},
+		dumper.DumpValue{
+			Key: "replication_options",
+			Value: map[string]string{
+				"proto_version":     "1",
+				"publication_names": "\"pub\"",
+			},
+		},
+		dumper.DumpValue{
+			Key:   "message_type",
+			Value: "Q",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %#v\nwant %#v", actual, expected)
+	}
+
+	// CopyBothResponse, Begin, Relation and a part of Insert
+	in = []byte{
+		0x57, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00, 0x32, 0x77, 0x00, 0x00,
+		0x00, 0x00, 0x01, 0x6b, 0x37, 0x48, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x37, 0x48, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x42, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x38, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xe4, 0x64, 0x00, 0x00, 0x00, 0x4c,
+		0x77, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x37, 0x48, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x37,
+		0x48, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x52, 0x00, 0x00, 0x40, 0x01, 0x70, 0x75,
+		0x62, 0x6c, 0x69, 0x63, 0x00, 0x75, 0x73, 0x65, 0x72, 0x73, 0x00, 0x64, 0x00, 0x02, 0x01, 0x69,
+		0x64, 0x00, 0x00, 0x00, 0x00, 0x17, 0xff, 0xff, 0xff, 0xff, 0x00, 0x6e, 0x61, 0x6d, 0x65, 0x00,
+		0x00, 0x00, 0x00, 0x19, 0xff, 0xff, 0xff, 0xff, 0x64, 0x00, 0x00, 0x00, 0x2c, 0x77, 0x00, 0x00,
+		0x00, 0x00,
+	}
+	actual, err = d.Read(in, dumper.DstToSrc, connMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	expected = []dumper.DumpValue{
+		dumper.DumpValue{
+			Key: "replication_messages",
+			Value: []map[string]interface{}{
+				map[string]interface{}{
+					"type":        "Begin",
+					"wal_start":   "0/16B3748",
+					"final_lsn":   "0/16B3800",
+					"commit_time": postgresEpoch,
+					"xid":         uint32(740),
+				},
+				map[string]interface{}{
+					"type":      "Relation",
+					"wal_start": "0/16B3748",
+					"relation":  "public.users",
+					"columns":   []string{"id", "name"},
+				},
+			},
+		},
+		dumper.DumpValue{
+			Key:   "message_type",
+			Value: "d",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %#v\nwant %#v", actual, expected)
+	}
+
+	// rest of Insert, Update and Commit
+	in = []byte{
+		0x01, 0x6b, 0x37, 0x80, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x37, 0x80, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x49, 0x00, 0x00, 0x40, 0x01, 0x4e, 0x00, 0x02, 0x74, 0x00, 0x00, 0x00,
+		0x01, 0x31, 0x6e, 0x64, 0x00, 0x00, 0x00, 0x3f, 0x77, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x37,
+		0x90, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x37, 0x90, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x55, 0x00, 0x00, 0x40, 0x01, 0x4b, 0x00, 0x02, 0x74, 0x00, 0x00, 0x00, 0x01, 0x31, 0x6e,
+		0x4e, 0x00, 0x02, 0x74, 0x00, 0x00, 0x00, 0x01, 0x32, 0x74, 0x00, 0x00, 0x00, 0x05, 0x61, 0x6c,
+		0x69, 0x63, 0x65, 0x64, 0x00, 0x00, 0x00, 0x37, 0x77, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x38,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x38, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x43, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x6b, 0x38, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+		0x6b, 0x38, 0x30, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	actual, err = d.Read(in, dumper.DstToSrc, connMetadata)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	expected = []dumper.DumpValue{
+		dumper.DumpValue{
+			Key: "replication_messages",
+			Value: []map[string]interface{}{
+				map[string]interface{}{
+					"type":      "Insert",
+					"wal_start": "0/16B3780",
+					"relation":  "public.users",
+					"new_tuple": map[string]interface{}{
+						"id":   "1",
+						"name": nil,
+					},
+				},
+				map[string]interface{}{
+					"type":      "Update",
+					"wal_start": "0/16B3790",
+					"relation":  "public.users",
+					"old_tuple": map[string]interface{}{
+						"id":   "1",
+						"name": nil,
+					},
+					"new_tuple": map[string]interface{}{
+						"id":   "2",
+						"name": "alice",
+					},
+				},
+				map[string]interface{}{
+					"type":        "Commit",
+					"wal_start":   "0/16B3800",
+					"commit_lsn":  "0/16B3800",
+					"end_lsn":     "0/16B3830",
+					"commit_time": postgresEpoch,
+				},
+			},
+		},
+		dumper.DumpValue{
+			Key:   "message_type",
+			Value: "d",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual %#v\nwant %#v", actual, expected)
+	}
+}
+
 var readBytesTests = []struct {
 	in       []byte
 	len      int
@@ -238,6 +1106,11 @@ var readBytesTests = []struct {
 		0,
 		[]byte{},
 	},
+	{
+		[]byte{0x12, 0x34, 0x56, 0x78},
+		-2,
+		[]byte{},
+	},
 }
 
 func TestReadBytes(t *testing.T) {
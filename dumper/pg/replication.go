@@ -0,0 +1,402 @@
+package pg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// https://www.postgresql.org/docs/current/protocol-replication.html
+var (
+	replicationCommandRegexp     = regexp.MustCompile(`(?i)^\s*(IDENTIFY_SYSTEM|CREATE_REPLICATION_SLOT|DROP_REPLICATION_SLOT|ALTER_REPLICATION_SLOT|READ_REPLICATION_SLOT|START_REPLICATION|TIMELINE_HISTORY|BASE_BACKUP|UPLOAD_MANIFEST|SHOW)\b`)
+	createReplicationSlotRegexp  = regexp.MustCompile(`(?i)^\s*CREATE_REPLICATION_SLOT\s+"?([^\s"]+)"?\s+(?:TEMPORARY\s+)?(PHYSICAL|LOGICAL)(?:\s+"?([^\s"()]+)"?)?`)
+	dropReplicationSlotRegexp    = regexp.MustCompile(`(?i)^\s*DROP_REPLICATION_SLOT\s+"?([^\s";]+)"?`)
+	startReplicationRegexp       = regexp.MustCompile(`(?i)^\s*START_REPLICATION\s+(?:SLOT\s+"?([^\s"]+)"?\s+)?(PHYSICAL|LOGICAL)?\s*([0-9A-F]+/[0-9A-F]+)(?:\s+TIMELINE\s+\d+)?\s*(?:\((.*)\))?`)
+	startReplicationOptionRegexp = regexp.MustCompile(`"?([A-Za-z_][A-Za-z0-9_]*)"?(?:\s+'((?:[^']|'')*)')?`)
+)
+
+var postgresEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const maxReplicationBufferLength = 64 * 1024 * 1024
+
+// replication stream messages
+const (
+	messageCopyBothResponse = 'W'
+	messageCommandComplete  = 'C'
+
+	replicationXLogData = 'w'
+)
+
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+const (
+	pgoutputBegin        = 'B'
+	pgoutputCommit       = 'C'
+	pgoutputOrigin       = 'O'
+	pgoutputRelation     = 'R'
+	pgoutputType         = 'Y'
+	pgoutputInsert       = 'I'
+	pgoutputUpdate       = 'U'
+	pgoutputDelete       = 'D'
+	pgoutputTruncate     = 'T'
+	pgoutputMessage      = 'M'
+	pgoutputStreamStart  = 'S'
+	pgoutputStreamStop   = 'E'
+	pgoutputStreamCommit = 'c'
+	pgoutputStreamAbort  = 'A'
+)
+
+// relation is RelationMessage of pgoutput
+type relation struct {
+	namespace string
+	name      string
+	columns   []string
+}
+
+func (r relation) String() string {
+	if r.namespace == "" {
+		return r.name
+	}
+	return fmt.Sprintf("%s.%s", r.namespace, r.name)
+}
+
+// replicationState is state of logical replication connection ( replication=database )
+type replicationState struct {
+	logical   bool
+	streaming bool   // START_REPLICATION ... LOGICAL is running
+	inStream  bool   // between Stream Start and Stream Stop ( protocol version 2 )
+	buffer    []byte // partial message
+	relations map[uint32]relation
+}
+
+func formatLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
+
+func pgTime(microseconds int64) time.Time {
+	return postgresEpoch.Add(time.Duration(microseconds) * time.Microsecond)
+}
+
+// readReplicationCommand parse replication command in simple Query
+func (p *Dumper) readReplicationCommand(query string, connMetadata *dumper.ConnMetadata) []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	m := replicationCommandRegexp.FindStringSubmatch(query)
+	if m == nil {
+		return values
+	}
+	command := strings.ToUpper(m[1])
+	values = append(values, dumper.DumpValue{
+		Key:   "replication_command",
+		Value: command,
+	})
+	switch command {
+	case "CREATE_REPLICATION_SLOT":
+		if m := createReplicationSlotRegexp.FindStringSubmatch(query); m != nil {
+			values = append(values, dumper.DumpValue{
+				Key:   "replication_slot",
+				Value: m[1],
+			})
+			if m[3] != "" {
+				values = append(values, dumper.DumpValue{
+					Key:   "replication_plugin",
+					Value: m[3],
+				})
+			}
+		}
+	case "DROP_REPLICATION_SLOT":
+		if m := dropReplicationSlotRegexp.FindStringSubmatch(query); m != nil {
+			values = append(values, dumper.DumpValue{
+				Key:   "replication_slot",
+				Value: m[1],
+			})
+		}
+	case "START_REPLICATION":
+		m := startReplicationRegexp.FindStringSubmatch(query)
+		if m == nil {
+			break
+		}
+		if m[1] != "" {
+			values = append(values, dumper.DumpValue{
+				Key:   "replication_slot",
+				Value: m[1],
+			})
+		}
+		values = append(values, dumper.DumpValue{
+			Key:   "replication_start_lsn",
+			Value: m[3],
+		})
+		options := map[string]string{}
+		for _, o := range startReplicationOptionRegexp.FindAllStringSubmatch(m[4], -1) {
+			options[o[1]] = strings.Replace(o[2], "''", "'", -1)
+		}
+		if len(options) > 0 {
+			values = append(values, dumper.DumpValue{
+				Key:   "replication_options",
+				Value: options,
+			})
+		}
+		internal := connMetadata.Internal.(connMetadataInternal)
+		if internal.replication.logical && strings.ToUpper(m[2]) == "LOGICAL" {
+			// wait for CopyBothResponse and CopyData of pgoutput
+			internal.replication.streaming = true
+			internal.replication.buffer = nil
+			if internal.replication.relations == nil {
+				internal.replication.relations = map[uint32]relation{}
+			}
+			connMetadata.Internal = internal
+		}
+	}
+	return values
+}
+
+// readReplicationStream read CopyData ( XLogData ) of logical replication stream from server
+func (p *Dumper) readReplicationStream(in []byte, connMetadata *dumper.ConnMetadata) []dumper.DumpValue {
+	internal := connMetadata.Internal.(connMetadataInternal)
+	state := internal.replication
+	buff := append(state.buffer, in...)
+	messages := []map[string]interface{}{}
+	for len(buff) >= 5 {
+		messageType := buff[0]
+		l := int(binary.BigEndian.Uint32(buff[1:5]))
+		if l < 4 {
+			// broken stream
+			state.streaming = false
+			buff = nil
+			break
+		}
+		if len(buff) < l+1 {
+			break
+		}
+		body := buff[5 : l+1]
+		buff = buff[l+1:]
+		switch messageType {
+		case messageCopyData:
+			if m := state.readCopyData(body); m != nil {
+				messages = append(messages, m)
+			}
+		case messageCopyBothResponse:
+		case messageCopyDone, messageErrorResponse, messageCommandComplete, messageReadyForQuery:
+			state.streaming = false
+		}
+		if !state.streaming {
+			buff = nil
+			break
+		}
+	}
+	if len(buff) > maxReplicationBufferLength {
+		buff = nil
+	}
+	state.buffer = append([]byte{}, buff...)
+	internal.replication = state
+	connMetadata.Internal = internal
+
+	if len(messages) == 0 {
+		return []dumper.DumpValue{}
+	}
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "replication_messages",
+			Value: messages,
+		},
+		dumper.DumpValue{
+			Key:   "message_type",
+			Value: string(messageCopyData),
+		},
+	}
+}
+
+// readCopyData decode XLogData and pgoutput message
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+func (s *replicationState) readCopyData(in []byte) map[string]interface{} {
+	if len(in) < 25 || in[0] != replicationXLogData {
+		// Primary keepalive message and others
+		return nil
+	}
+	walStart := binary.BigEndian.Uint64(in[1:9])
+	buff := bytes.NewBuffer(in[25:])
+	t, err := buff.ReadByte()
+	if err != nil {
+		return nil
+	}
+	m := map[string]interface{}{
+		"wal_start": formatLSN(walStart),
+	}
+	switch t {
+	case pgoutputBegin:
+		m["type"] = "Begin"
+		m["final_lsn"] = formatLSN(readUint64(buff))
+		m["commit_time"] = pgTime(int64(readUint64(buff)))
+		m["xid"] = readUint32(buff)
+	case pgoutputCommit:
+		m["type"] = "Commit"
+		_, _ = buff.ReadByte() // flags
+		m["commit_lsn"] = formatLSN(readUint64(buff))
+		m["end_lsn"] = formatLSN(readUint64(buff))
+		m["commit_time"] = pgTime(int64(readUint64(buff)))
+	case pgoutputOrigin:
+		m["type"] = "Origin"
+		m["origin_lsn"] = formatLSN(readUint64(buff))
+		m["origin_name"] = readCString(buff)
+	case pgoutputRelation:
+		m["type"] = "Relation"
+		s.readStreamXID(buff, m)
+		oid := readUint32(buff)
+		r := relation{
+			namespace: readCString(buff),
+			name:      readCString(buff),
+		}
+		_, _ = buff.ReadByte() // replica identity
+		c := int(readUint16(buff))
+		for i := 0; i < c && buff.Len() > 0; i++ {
+			_, _ = buff.ReadByte() // flags
+			r.columns = append(r.columns, readCString(buff))
+			_ = readUint32(buff) // type oid
+			_ = readUint32(buff) // type modifier
+		}
+		s.relations[oid] = r
+		m["relation"] = r.String()
+		m["columns"] = r.columns
+	case pgoutputType:
+		m["type"] = "Type"
+		s.readStreamXID(buff, m)
+		_ = readUint32(buff) // type oid
+		namespace := readCString(buff)
+		m["type_name"] = fmt.Sprintf("%s.%s", namespace, readCString(buff))
+	case pgoutputInsert:
+		m["type"] = "Insert"
+		s.readStreamXID(buff, m)
+		r := s.relation(readUint32(buff))
+		m["relation"] = r.String()
+		if b, _ := buff.ReadByte(); b == 'N' {
+			m["new_tuple"] = readTupleData(buff, r)
+		}
+	case pgoutputUpdate:
+		m["type"] = "Update"
+		s.readStreamXID(buff, m)
+		r := s.relation(readUint32(buff))
+		m["relation"] = r.String()
+		b, _ := buff.ReadByte()
+		if b == 'K' || b == 'O' {
+			m["old_tuple"] = readTupleData(buff, r)
+			b, _ = buff.ReadByte()
+		}
+		if b == 'N' {
+			m["new_tuple"] = readTupleData(buff, r)
+		}
+	case pgoutputDelete:
+		m["type"] = "Delete"
+		s.readStreamXID(buff, m)
+		r := s.relation(readUint32(buff))
+		m["relation"] = r.String()
+		if b, _ := buff.ReadByte(); b == 'K' || b == 'O' {
+			m["old_tuple"] = readTupleData(buff, r)
+		}
+	case pgoutputTruncate:
+		m["type"] = "Truncate"
+		s.readStreamXID(buff, m)
+		c := int(readUint32(buff))
+		_, _ = buff.ReadByte() // option bits
+		relations := []string{}
+		for i := 0; i < c && buff.Len() >= 4; i++ {
+			relations = append(relations, s.relation(readUint32(buff)).String())
+		}
+		m["relations"] = relations
+	case pgoutputMessage:
+		m["type"] = "Message"
+		s.readStreamXID(buff, m)
+		_, _ = buff.ReadByte() // flags
+		m["message_lsn"] = formatLSN(readUint64(buff))
+		m["prefix"] = readCString(buff)
+		m["content_size"] = readUint32(buff)
+	case pgoutputStreamStart:
+		m["type"] = "StreamStart"
+		s.inStream = true
+		m["xid"] = readUint32(buff)
+	case pgoutputStreamStop:
+		m["type"] = "StreamStop"
+		s.inStream = false
+	case pgoutputStreamCommit:
+		m["type"] = "StreamCommit"
+		m["xid"] = readUint32(buff)
+		_, _ = buff.ReadByte() // flags
+		m["commit_lsn"] = formatLSN(readUint64(buff))
+		m["end_lsn"] = formatLSN(readUint64(buff))
+		m["commit_time"] = pgTime(int64(readUint64(buff)))
+	case pgoutputStreamAbort:
+		m["type"] = "StreamAbort"
+		m["xid"] = readUint32(buff)
+		m["sub_xid"] = readUint32(buff)
+	default:
+		m["type"] = string(t)
+	}
+	return m
+}
+
+// readStreamXID read Xid of the transaction in messages sent while streaming in-progress transaction
+func (s *replicationState) readStreamXID(buff *bytes.Buffer, m map[string]interface{}) {
+	if !s.inStream {
+		return
+	}
+	m["xid"] = readUint32(buff)
+}
+
+func (s *replicationState) relation(oid uint32) relation {
+	r, ok := s.relations[oid]
+	if !ok {
+		return relation{name: fmt.Sprintf("%d", oid)}
+	}
+	return r
+}
+
+// readTupleData read TupleData to map of column name to value
+func readTupleData(buff *bytes.Buffer, r relation) map[string]interface{} {
+	tuple := map[string]interface{}{}
+	c := int(readUint16(buff))
+	for i := 0; i < c && buff.Len() > 0; i++ {
+		column := fmt.Sprintf("%d", i+1)
+		if i < len(r.columns) {
+			column = r.columns[i]
+		}
+		t, _ := buff.ReadByte()
+		switch t {
+		case 'n':
+			tuple[column] = nil
+		case 'u':
+			tuple[column] = "(unchanged toast)"
+		case 't':
+			tuple[column] = string(readValueBytes(buff))
+		case 'b':
+			tuple[column] = readValueBytes(buff)
+		}
+	}
+	return tuple
+}
+
+func readValueBytes(buff *bytes.Buffer) []byte {
+	n := int(readUint32(buff))
+	if n > buff.Len() {
+		n = buff.Len()
+	}
+	return readBytes(buff, n)
+}
+
+func readCString(buff *bytes.Buffer) string {
+	b, _ := buff.ReadString(0x00)
+	return strings.TrimRight(b, "\x00")
+}
+
+func readUint16(buff *bytes.Buffer) uint16 {
+	return binary.BigEndian.Uint16(readBytes(buff, 2))
+}
+
+func readUint32(buff *bytes.Buffer) uint32 {
+	return binary.BigEndian.Uint32(readBytes(buff, 4))
+}
+
+func readUint64(buff *bytes.Buffer) uint64 {
+	return binary.BigEndian.Uint64(readBytes(buff, 8))
+}
@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"regexp"
+	"strings"
+)
+
+var dollarQuoteRegexp = regexp.MustCompile(`^\$(?:[A-Za-z_\x80-\xff][A-Za-z0-9_\x80-\xff]*)?\$`)
+
+// splitStatements split simple Query into statements by `;` outside of quotes, dollar quotes and comments
+func splitStatements(query string) []string {
+	statements := []string{}
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			escape := c == '\'' && i > 0 && (query[i-1] == 'E' || query[i-1] == 'e')
+			j := i + 1
+			for ; j < len(query); j++ {
+				if escape && query[j] == '\\' {
+					j++
+					continue
+				}
+				if query[j] == c {
+					break
+				}
+			}
+			i = j
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				i = len(query)
+				continue
+			}
+			i += j
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				i = len(query)
+				continue
+			}
+			i += j + 3
+		case c == '$':
+			tag := dollarQuoteRegexp.FindString(query[i:])
+			if tag == "" {
+				continue
+			}
+			j := strings.Index(query[i+len(tag):], tag)
+			if j < 0 {
+				i = len(query)
+				continue
+			}
+			i += len(tag) + j + len(tag) - 1
+		case c == ';':
+			if s := strings.TrimSpace(query[start:i]); s != "" {
+				statements = append(statements, s)
+			}
+			start = i + 1
+		}
+	}
+	if start < len(query) {
+		if s := strings.TrimSpace(query[start:]); s != "" {
+			statements = append(statements, s)
+		}
+	}
+	return statements
+}
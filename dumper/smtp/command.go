@@ -0,0 +1,181 @@
+package smtp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// readCommand parse command line
+// Unknown command is returned as "UNKNOWN" without arguments not to dump credentials
+func readCommand(line string) (*command, bool) {
+	line = strings.TrimLeft(line, " ")
+	if line == "" {
+		return nil, true
+	}
+	verb := line
+	arg := ""
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		verb = line[:i]
+		arg = strings.TrimSpace(line[i+1:])
+	}
+	if !isAlpha(verb) {
+		return nil, false
+	}
+	verb = strings.ToUpper(verb)
+	if _, ok := commands[verb]; !ok {
+		return &command{
+			verb: verb,
+			values: []dumper.DumpValue{
+				{
+					Key:   "command",
+					Value: "UNKNOWN",
+				},
+			},
+		}, true
+	}
+	c := &command{
+		verb: verb,
+		values: []dumper.DumpValue{
+			{
+				Key:   "command",
+				Value: verb,
+			},
+		},
+	}
+	switch verb {
+	case "HELO", "EHLO", "LHLO":
+		c.add("domain", arg)
+	case "MAIL":
+		path, params := readPath(arg, "FROM:")
+		c.add("mail_from", path)
+		for _, p := range params {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) != 2 || strings.ToUpper(kv[0]) != "SIZE" {
+				continue
+			}
+			if size, err := strconv.ParseInt(kv[1], 10, 64); err == nil {
+				c.add("size", size)
+			}
+		}
+	case "RCPT":
+		path, _ := readPath(arg, "TO:")
+		c.add("rcpt_to", path)
+	case "AUTH":
+		// initial response is not dumped
+		mechanism := strings.Fields(arg)
+		if len(mechanism) > 0 {
+			c.add("auth_mechanism", strings.ToUpper(mechanism[0]))
+		}
+	case "BDAT":
+		args := strings.Fields(arg)
+		if len(args) > 0 {
+			if size, err := strconv.Atoi(args[0]); err == nil && size >= 0 {
+				c.add("message_size", size)
+			}
+		}
+		c.add("last", len(args) > 1 && strings.ToUpper(args[1]) == "LAST")
+	}
+	return c, true
+}
+
+func (c *command) add(key string, value interface{}) {
+	c.values = append(c.values, dumper.DumpValue{
+		Key:   key,
+		Value: value,
+	})
+}
+
+// bdatSize returns chunk size of BDAT
+func bdatSize(c *command) int {
+	for _, v := range c.values {
+		if v.Key == "message_size" {
+			return v.Value.(int)
+		}
+	}
+	return 0
+}
+
+// readPath parse "FROM:<reverse-path> params" or "TO:<forward-path> params"
+func readPath(arg, prefix string) (string, []string) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil
+	}
+	arg = strings.TrimLeft(arg[len(prefix):], " ")
+	if strings.HasPrefix(arg, "<") {
+		i := strings.IndexByte(arg, '>')
+		if i < 0 {
+			return arg[1:], nil
+		}
+		return arg[1:i], strings.Fields(arg[i+1:])
+	}
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// readReplyLine parse "Reply-code [ SP / - ] textstring"
+func readReplyLine(line string) (int, bool, string, bool) {
+	if len(line) < 3 {
+		return 0, false, "", false
+	}
+	for _, c := range line[:3] {
+		if c < '0' || c > '9' {
+			return 0, false, "", false
+		}
+	}
+	code, _ := strconv.Atoi(line[:3])
+	if code < 200 || code > 599 {
+		return 0, false, "", false
+	}
+	if len(line) == 3 {
+		return code, true, "", true
+	}
+	switch line[3] {
+	case ' ':
+		return code, true, line[4:], true
+	case '-':
+		return code, false, line[4:], true
+	}
+	return 0, false, "", false
+}
+
+// values returns values of the reply to the command
+func (r *reply) values(verb string) []dumper.DumpValue {
+	text := strings.Join(r.lines, "\n")
+	if (verb == "EHLO" || verb == "LHLO") && len(r.lines) > 1 {
+		text = r.lines[0]
+	}
+	values := []dumper.DumpValue{
+		{
+			Key:   "response_code",
+			Value: r.code,
+		},
+		{
+			Key:   "response_text",
+			Value: text,
+		},
+	}
+	if (verb == "EHLO" || verb == "LHLO") && r.code/100 == 2 {
+		values = append(values, dumper.DumpValue{
+			Key:   "extensions",
+			Value: append([]string{}, r.lines[1:]...),
+		})
+	}
+	return values
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package smtp
+
+// maxLineLength is max length of buffered command/reply line. The partial line longer than it is discarded
+const maxLineLength = 4096
+
+// maxPendingCommands is max number of commands waiting for reply ( PIPELINING )
+const maxPendingCommands = 100
+
+// reply codes that do not complete the command
+// https://tools.ietf.org/html/rfc5321#section-4.2
+const (
+	replyServiceReady = 220
+	replyAuthContinue = 334
+	replyStartData    = 354
+)
+
+// commands https://tools.ietf.org/html/rfc5321#section-4.1
+var commands = map[string]struct{}{
+	"HELO":     {},
+	"EHLO":     {},
+	"LHLO":     {}, // LMTP https://tools.ietf.org/html/rfc2033
+	"MAIL":     {},
+	"RCPT":     {},
+	"DATA":     {},
+	"BDAT":     {}, // CHUNKING https://tools.ietf.org/html/rfc3030
+	"RSET":     {},
+	"VRFY":     {},
+	"EXPN":     {},
+	"HELP":     {},
+	"NOOP":     {},
+	"QUIT":     {},
+	"AUTH":     {}, // https://tools.ietf.org/html/rfc4954
+	"STARTTLS": {}, // https://tools.ietf.org/html/rfc3207
+	"ETRN":     {},
+	"TURN":     {},
+	"ATRN":     {},
+	"XCLIENT":  {},
+	"XFORWARD": {},
+}
@@ -0,0 +1,319 @@
+package smtp
+
+import (
+	"bytes"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	client  *clientStream
+	server  *serverStream
+	pending []*command // commands waiting for reply
+	tls     bool       // TLS is started by STARTTLS
+}
+
+// clientStream is state of commands and message data
+type clientStream struct {
+	buffer []byte   // partial line
+	skip   int      // rest bytes of BDAT chunk
+	data   *command // DATA command waiting for the end of message data
+	auth   bool     // next line is SASL response
+	dot    int      // 1: "." at the beginning of line, 2: ".\r" at the beginning of line
+	bol    bool     // beginning of line in message data
+	broken bool     // not SMTP
+}
+
+// serverStream is state of replies
+type serverStream struct {
+	buffer []byte // partial line
+	reply  *reply // multiline reply waiting for the last line
+	broken bool   // not SMTP
+}
+
+// command is a command line
+type command struct {
+	verb   string
+	values []dumper.DumpValue
+}
+
+// reply is a ( multiline ) reply
+type reply struct {
+	code  int
+	lines []string
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "smtp",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (s *Dumper) Name() string {
+	return s.name
+}
+
+// Dump SMTP commands
+func (s *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := s.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		s.Log(values)
+	}
+	return nil
+}
+
+// Read return the first command of byte to analyzed string ( use ReadFrames to read all commands )
+func (s *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := s.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return commands of byte to analyzed string
+// A command is returned with its reply when the ( last ) reply arrives
+func (s *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.tls {
+		return records, nil
+	}
+	if direction == dumper.RemoteToClient || direction == dumper.DstToSrc {
+		for _, r := range internal.server.readReplies(in) {
+			if values := internal.handleReply(r); len(values) > 0 {
+				records = append(records, values)
+			}
+			if internal.tls {
+				// the rest is TLS
+				break
+			}
+		}
+	} else {
+		for _, c := range internal.client.readCommands(in) {
+			if len(internal.pending) >= maxPendingCommands {
+				// replies were not captured
+				internal.pending = nil
+			}
+			internal.pending = append(internal.pending, c)
+		}
+	}
+	connMetadata.Internal = internal
+	return records, nil
+}
+
+// Log values
+func (s *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	s.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (s *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal: connMetadataInternal{
+			client: &clientStream{},
+			server: &serverStream{},
+		},
+	}
+}
+
+// handleReply match the reply with the pending command
+func (i *connMetadataInternal) handleReply(r *reply) []dumper.DumpValue {
+	if len(i.pending) == 0 {
+		// greeting or unsolicited reply ( ex. 421 )
+		return r.values("")
+	}
+	c := i.pending[0]
+	switch {
+	case r.code == replyStartData && c.verb == "DATA":
+		i.client.data = c
+		i.client.bol = true
+		i.client.dot = 0
+		return nil
+	case r.code == replyAuthContinue && c.verb == "AUTH":
+		i.client.auth = true
+		return nil
+	}
+	i.pending = i.pending[1:]
+	if r.code == replyServiceReady && c.verb == "STARTTLS" {
+		i.tls = true
+	}
+	values := []dumper.DumpValue{}
+	values = append(values, c.values...)
+	return append(values, r.values(c.verb)...)
+}
+
+// readCommands returns completed command lines
+// message data of DATA/BDAT and SASL responses are not returned
+func (s *clientStream) readCommands(in []byte) []*command {
+	commands := []*command{}
+	if s.broken {
+		return commands
+	}
+	data := in
+	if len(s.buffer) > 0 {
+		data = append(s.buffer, in...)
+		s.buffer = nil
+	}
+	for len(data) > 0 {
+		if s.skip > 0 {
+			n := s.skip
+			if len(data) < n {
+				n = len(data)
+			}
+			data = data[n:]
+			s.skip -= n
+			continue
+		}
+		if s.data != nil {
+			data = data[s.readData(data):]
+			continue
+		}
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if len(data) <= maxLineLength {
+				s.buffer = append([]byte{}, data...)
+			}
+			break
+		}
+		line := string(bytes.TrimSuffix(data[:i], []byte("\r")))
+		data = data[i+1:]
+		if s.auth {
+			// SASL response ( credentials )
+			s.auth = false
+			continue
+		}
+		c, ok := readCommand(line)
+		if !ok {
+			s.broken = true
+			break
+		}
+		if c == nil {
+			continue
+		}
+		if c.verb == "BDAT" {
+			s.skip = bdatSize(c)
+		}
+		commands = append(commands, c)
+	}
+	return commands
+}
+
+// readData count message data until "\r\n.\r\n" and returns read length
+func (s *clientStream) readData(in []byte) int {
+	size := 0
+	for i, b := range in {
+		switch s.dot {
+		case 1:
+			if b == '\r' {
+				s.dot = 2
+				continue
+			}
+			if b == '\n' {
+				s.endData(size)
+				return i + 1
+			}
+			size++
+			s.dot = 0
+		case 2:
+			if b == '\n' {
+				s.endData(size)
+				return i + 1
+			}
+			size += 2
+			s.dot = 0
+		}
+		if s.bol && b == '.' {
+			s.dot = 1
+			s.bol = false
+			continue
+		}
+		size++
+		s.bol = b == '\n'
+	}
+	s.addDataSize(size)
+	return len(in)
+}
+
+func (s *clientStream) addDataSize(size int) {
+	for i, v := range s.data.values {
+		if v.Key == "message_size" {
+			s.data.values[i].Value = v.Value.(int) + size
+			return
+		}
+	}
+	s.data.values = append(s.data.values, dumper.DumpValue{
+		Key:   "message_size",
+		Value: size,
+	})
+}
+
+func (s *clientStream) endData(size int) {
+	s.addDataSize(size)
+	s.data = nil
+	s.dot = 0
+}
+
+// readReplies returns completed ( multiline ) replies
+func (s *serverStream) readReplies(in []byte) []*reply {
+	replies := []*reply{}
+	if s.broken {
+		return replies
+	}
+	data := in
+	if len(s.buffer) > 0 {
+		data = append(s.buffer, in...)
+		s.buffer = nil
+	}
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if len(data) <= maxLineLength {
+				s.buffer = append([]byte{}, data...)
+			}
+			break
+		}
+		line := string(bytes.TrimSuffix(data[:i], []byte("\r")))
+		data = data[i+1:]
+		code, last, text, ok := readReplyLine(line)
+		if !ok {
+			s.broken = true
+			break
+		}
+		if s.reply == nil {
+			s.reply = &reply{code: code}
+		}
+		s.reply.lines = append(s.reply.lines, text)
+		if last {
+			replies = append(replies, s.reply)
+			s.reply = nil
+		}
+	}
+	return replies
+}
@@ -0,0 +1,564 @@
+package smtp
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type smtpStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  [][]dumper.DumpValue
+}
+
+var smtpReadFramesTests = []struct {
+	description string
+	steps       []smtpStep
+}{
+	{
+		"Mail transaction with PIPELINING",
+		[]smtpStep{
+			{
+				[]byte{
+					0x32, 0x32, 0x30, 0x20, 0x6d, 0x78, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63,
+					0x6f, 0x6d, 0x20, 0x45, 0x53, 0x4d, 0x54, 0x50, 0x20, 0x50, 0x6f, 0x73, 0x74, 0x66, 0x69, 0x78,
+					0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "response_code",
+							Value: 220,
+						},
+						{
+							Key:   "response_text",
+							Value: "mx.example.com ESMTP Postfix",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x45, 0x48, 0x4c, 0x4f, 0x20, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x65, 0x78, 0x61, 0x6d,
+					0x70, 0x6c, 0x65, 0x2e, 0x6f, 0x72, 0x67, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x32, 0x35, 0x30, 0x2d, 0x6d, 0x78, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63,
+					0x6f, 0x6d, 0x0d, 0x0a, 0x32, 0x35, 0x30, 0x2d, 0x50, 0x49, 0x50, 0x45, 0x4c, 0x49, 0x4e, 0x49,
+					0x4e, 0x47, 0x0d, 0x0a, 0x32, 0x35, 0x30, 0x2d, 0x53, 0x49, 0x5a, 0x45, 0x20, 0x31, 0x30, 0x32,
+					0x34, 0x30, 0x30, 0x30, 0x30, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x32, 0x35, 0x30, 0x2d, 0x53, 0x54, 0x41, 0x52, 0x54, 0x54, 0x4c, 0x53, 0x0d, 0x0a, 0x32, 0x35,
+					0x30, 0x20, 0x38, 0x42, 0x49, 0x54, 0x4d, 0x49, 0x4d, 0x45, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "command",
+							Value: "EHLO",
+						},
+						{
+							Key:   "domain",
+							Value: "client.example.org",
+						},
+						{
+							Key:   "response_code",
+							Value: 250,
+						},
+						{
+							Key:   "response_text",
+							Value: "mx.example.com",
+						},
+						{
+							Key:   "extensions",
+							Value: []string{"PIPELINING", "SIZE 10240000", "STARTTLS", "8BITMIME"},
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x4d, 0x41, 0x49, 0x4c, 0x20, 0x46, 0x52, 0x4f, 0x4d, 0x3a, 0x3c, 0x61, 0x6c, 0x69, 0x63, 0x65,
+					0x40, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x6f, 0x72, 0x67, 0x3e, 0x20, 0x53, 0x49,
+					0x5a, 0x45, 0x3d, 0x32, 0x37, 0x20, 0x42, 0x4f, 0x44, 0x59, 0x3d, 0x38, 0x42, 0x49, 0x54, 0x4d,
+					0x49, 0x4d, 0x45, 0x0d, 0x0a, 0x52, 0x43, 0x50, 0x54, 0x20, 0x54, 0x4f, 0x3a, 0x3c, 0x62, 0x6f,
+					0x62, 0x40, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x3e, 0x0d, 0x0a,
+					0x52, 0x43, 0x50, 0x54, 0x20, 0x54, 0x4f, 0x3a, 0x3c, 0x6e, 0x6f, 0x62, 0x6f, 0x64, 0x79, 0x40,
+					0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x3e, 0x0d, 0x0a, 0x44, 0x41,
+					0x54, 0x41, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x32, 0x35, 0x30, 0x20, 0x32, 0x2e, 0x31, 0x2e, 0x30, 0x20, 0x4f, 0x6b, 0x0d, 0x0a, 0x32, 0x35,
+					0x30, 0x20, 0x32, 0x2e, 0x31, 0x2e, 0x35, 0x20, 0x4f, 0x6b, 0x0d, 0x0a, 0x35, 0x35, 0x30, 0x20,
+					0x35, 0x2e, 0x31, 0x2e, 0x31, 0x20, 0x3c, 0x6e, 0x6f, 0x62, 0x6f, 0x64, 0x79, 0x40, 0x65, 0x78,
+					0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x3e, 0x3a, 0x20, 0x52, 0x65, 0x63, 0x69,
+					0x70, 0x69, 0x65, 0x6e, 0x74, 0x20, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x20, 0x72, 0x65,
+					0x6a, 0x65, 0x63, 0x74, 0x65, 0x64, 0x0d, 0x0a, 0x33, 0x35, 0x34, 0x20, 0x45, 0x6e, 0x64, 0x20,
+					0x64, 0x61, 0x74, 0x61, 0x20, 0x77, 0x69, 0x74, 0x68, 0x20, 0x3c, 0x43, 0x52, 0x3e, 0x3c, 0x4c,
+					0x46, 0x3e, 0x2e, 0x3c, 0x43, 0x52, 0x3e, 0x3c, 0x4c, 0x46, 0x3e, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "command",
+							Value: "MAIL",
+						},
+						{
+							Key:   "mail_from",
+							Value: "alice@example.org",
+						},
+						{
+							Key:   "size",
+							Value: int64(27),
+						},
+						{
+							Key:   "response_code",
+							Value: 250,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.1.0 Ok",
+						},
+					},
+					{
+						{
+							Key:   "command",
+							Value: "RCPT",
+						},
+						{
+							Key:   "rcpt_to",
+							Value: "bob@example.com",
+						},
+						{
+							Key:   "response_code",
+							Value: 250,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.1.5 Ok",
+						},
+					},
+					{
+						{
+							Key:   "command",
+							Value: "RCPT",
+						},
+						{
+							Key:   "rcpt_to",
+							Value: "nobody@example.com",
+						},
+						{
+							Key:   "response_code",
+							Value: 550,
+						},
+						{
+							Key:   "response_text",
+							Value: "5.1.1 <nobody@example.com>: Recipient address rejected",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x3a, 0x20, 0x68, 0x69, 0x0d, 0x0a, 0x0d, 0x0a, 0x2e,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x2e, 0x64, 0x6f, 0x74, 0x0d, 0x0a, 0x62, 0x79, 0x65, 0x0d, 0x0a, 0x2e, 0x0d, 0x0a, 0x51, 0x55,
+					0x49, 0x54, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x32, 0x35, 0x30, 0x20, 0x32, 0x2e, 0x30, 0x2e, 0x30, 0x20, 0x4f, 0x6b, 0x3a, 0x20, 0x71, 0x75,
+					0x65, 0x75, 0x65, 0x64, 0x20, 0x61, 0x73, 0x20, 0x34, 0x46, 0x32, 0x42, 0x31, 0x0d, 0x0a, 0x32,
+					0x32, 0x31, 0x20, 0x32, 0x2e, 0x30, 0x2e, 0x30, 0x20, 0x42, 0x79, 0x65, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "command",
+							Value: "DATA",
+						},
+						{
+							Key:   "message_size",
+							Value: 27,
+						},
+						{
+							Key:   "response_code",
+							Value: 250,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.0.0 Ok: queued as 4F2B1",
+						},
+					},
+					{
+						{
+							Key:   "command",
+							Value: "QUIT",
+						},
+						{
+							Key:   "response_code",
+							Value: 221,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.0.0 Bye",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"AUTH",
+		[]smtpStep{
+			{
+				[]byte{
+					0x41, 0x55, 0x54, 0x48, 0x20, 0x4c, 0x4f, 0x47, 0x49, 0x4e, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x33, 0x33, 0x34, 0x20, 0x56, 0x58, 0x4e, 0x6c, 0x63, 0x6d, 0x35, 0x68, 0x62, 0x57, 0x55, 0x36,
+					0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x59, 0x57, 0x78, 0x70, 0x59, 0x32, 0x55, 0x3d, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x33, 0x33, 0x34, 0x20, 0x55, 0x47, 0x46, 0x7a, 0x63, 0x33, 0x64, 0x76, 0x63, 0x6d, 0x51, 0x36,
+					0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x63, 0x32, 0x56, 0x6a, 0x63, 0x6d, 0x56, 0x30, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x35, 0x33, 0x35, 0x20, 0x35, 0x2e, 0x37, 0x2e, 0x38, 0x20, 0x41, 0x75, 0x74, 0x68, 0x65, 0x6e,
+					0x74, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x20, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74,
+					0x69, 0x61, 0x6c, 0x73, 0x20, 0x69, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "command",
+							Value: "AUTH",
+						},
+						{
+							Key:   "auth_mechanism",
+							Value: "LOGIN",
+						},
+						{
+							Key:   "response_code",
+							Value: 535,
+						},
+						{
+							Key:   "response_text",
+							Value: "5.7.8 Authentication credentials invalid",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x41, 0x55, 0x54, 0x48, 0x20, 0x70, 0x6c, 0x61, 0x69, 0x6e, 0x20, 0x41, 0x47, 0x46, 0x73, 0x61,
+					0x57, 0x4e, 0x6c, 0x41, 0x48, 0x4e, 0x6c, 0x59, 0x33, 0x4a, 0x6c, 0x64, 0x41, 0x3d, 0x3d, 0x0d,
+					0x0a, 0x58, 0x59, 0x5a, 0x5a, 0x59, 0x20, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x32, 0x33, 0x35, 0x20, 0x32, 0x2e, 0x37, 0x2e, 0x30, 0x20, 0x41, 0x75, 0x74, 0x68, 0x65, 0x6e,
+					0x74, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x20, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73,
+					0x66, 0x75, 0x6c, 0x0d, 0x0a, 0x35, 0x30, 0x30, 0x20, 0x35, 0x2e, 0x35, 0x2e, 0x32, 0x20, 0x45,
+					0x72, 0x72, 0x6f, 0x72, 0x3a, 0x20, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x20, 0x6e, 0x6f,
+					0x74, 0x20, 0x72, 0x65, 0x63, 0x6f, 0x67, 0x6e, 0x69, 0x7a, 0x65, 0x64, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "command",
+							Value: "AUTH",
+						},
+						{
+							Key:   "auth_mechanism",
+							Value: "PLAIN",
+						},
+						{
+							Key:   "response_code",
+							Value: 235,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.7.0 Authentication successful",
+						},
+					},
+					{
+						{
+							Key:   "command",
+							Value: "UNKNOWN",
+						},
+						{
+							Key:   "response_code",
+							Value: 500,
+						},
+						{
+							Key:   "response_text",
+							Value: "5.5.2 Error: command not recognized",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"BDAT",
+		[]smtpStep{
+			{
+				[]byte{
+					0x42, 0x44, 0x41, 0x54, 0x20, 0x31, 0x30, 0x0d, 0x0a, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36,
+					0x37, 0x38, 0x39, 0x42, 0x44, 0x41, 0x54, 0x20, 0x35, 0x20, 0x4c, 0x41, 0x53, 0x54, 0x0d, 0x0a,
+					0x61, 0x62,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x63, 0x64, 0x65, 0x52, 0x53, 0x45, 0x54, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x32, 0x35, 0x30, 0x20, 0x32, 0x2e, 0x30, 0x2e, 0x30, 0x20, 0x31, 0x30, 0x20, 0x6f, 0x63, 0x74,
+					0x65, 0x74, 0x73, 0x20, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x0d, 0x0a, 0x32, 0x35,
+					0x30, 0x20, 0x32, 0x2e, 0x30, 0x2e, 0x30, 0x20, 0x4f, 0x6b, 0x3a, 0x20, 0x71, 0x75, 0x65, 0x75,
+					0x65, 0x64, 0x0d, 0x0a, 0x32, 0x35, 0x30, 0x20, 0x32, 0x2e, 0x30, 0x2e, 0x30, 0x20, 0x4f, 0x6b,
+					0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "command",
+							Value: "BDAT",
+						},
+						{
+							Key:   "message_size",
+							Value: 10,
+						},
+						{
+							Key:   "last",
+							Value: false,
+						},
+						{
+							Key:   "response_code",
+							Value: 250,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.0.0 10 octets received",
+						},
+					},
+					{
+						{
+							Key:   "command",
+							Value: "BDAT",
+						},
+						{
+							Key:   "message_size",
+							Value: 5,
+						},
+						{
+							Key:   "last",
+							Value: true,
+						},
+						{
+							Key:   "response_code",
+							Value: 250,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.0.0 Ok: queued",
+						},
+					},
+					{
+						{
+							Key:   "command",
+							Value: "RSET",
+						},
+						{
+							Key:   "response_code",
+							Value: 250,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.0.0 Ok",
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"STARTTLS",
+		[]smtpStep{
+			{
+				[]byte{
+					0x53, 0x54, 0x41, 0x52, 0x54, 0x54, 0x4c, 0x53, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x32, 0x32, 0x30, 0x20, 0x32, 0x2e, 0x30, 0x2e, 0x30, 0x20, 0x52, 0x65, 0x61, 0x64, 0x79, 0x20,
+					0x74, 0x6f, 0x20, 0x73, 0x74, 0x61, 0x72, 0x74, 0x20, 0x54, 0x4c, 0x53, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{
+					{
+						{
+							Key:   "command",
+							Value: "STARTTLS",
+						},
+						{
+							Key:   "response_code",
+							Value: 220,
+						},
+						{
+							Key:   "response_text",
+							Value: "2.0.0 Ready to start TLS",
+						},
+					},
+				},
+			},
+			{
+				[]byte{
+					0x16, 0x03, 0x01, 0x00, 0xa5, 0x01, 0x00, 0x00, 0xa1, 0x03, 0x03, 0x45, 0x48, 0x4c, 0x4f, 0x20,
+					0x78, 0x0d, 0x0a,
+				},
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte{
+					0x16, 0x03, 0x03, 0x00, 0x7a, 0x02, 0x00, 0x00, 0x76, 0x03, 0x03, 0x32, 0x35, 0x30, 0x20, 0x6f,
+					0x6b, 0x0d, 0x0a,
+				},
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+	{
+		"Not SMTP",
+		[]smtpStep{
+			{
+				[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+				dumper.SrcToDst,
+				[][]dumper.DumpValue{},
+			},
+			{
+				[]byte("HTTP/1.1 400 Bad Request\r\n\r\n"),
+				dumper.DstToSrc,
+				[][]dumper.DumpValue{},
+			},
+		},
+	},
+}
+
+func TestSMTPReadFrames(t *testing.T) {
+	for _, tt := range smtpReadFramesTests {
+		out := new(bytes.Buffer)
+		d := NewDumper()
+		d.logger = newTestLogger(out)
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.ReadFrames(s.in, s.direction, connMetadata)
+			if err != nil {
+				t.Errorf("%s step %d: %v", tt.description, i, err)
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
@@ -0,0 +1,214 @@
+package tds
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/9b4a463c-2634-4a4b-ac35-bebfff2fb0f7
+type packetType byte
+
+const (
+	packetSQLBatch           packetType = 0x01
+	packetPreTDS7Login       packetType = 0x02
+	packetRPC                packetType = 0x03
+	packetTabularResult      packetType = 0x04
+	packetAttention          packetType = 0x06
+	packetBulkLoad           packetType = 0x07
+	packetFedAuthToken       packetType = 0x08
+	packetTransactionManager packetType = 0x0e
+	packetLogin7             packetType = 0x10
+	packetSSPI               packetType = 0x11
+	packetPrelogin           packetType = 0x12
+)
+
+var packetTypeNames = map[packetType]string{
+	packetSQLBatch:           "sql_batch",
+	packetPreTDS7Login:       "pre_tds7_login",
+	packetRPC:                "rpc",
+	packetTabularResult:      "tabular_result",
+	packetAttention:          "attention",
+	packetBulkLoad:           "bulk_load",
+	packetFedAuthToken:       "fedauth_token",
+	packetTransactionManager: "transaction_manager",
+	packetLogin7:             "login7",
+	packetSSPI:               "sspi",
+	packetPrelogin:           "prelogin",
+}
+
+func (t packetType) String() string {
+	if name, ok := packetTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+const (
+	authResultOK  = "ok"
+	authResultERR = "error"
+)
+
+const (
+	headerLength = 8
+
+	// packet status
+	statusEOM = 0x01
+
+	// max size of a request message to be buffered
+	maxMessageSize = 16 * 1024 * 1024
+
+	// TLS record ( TLS handshake in PRELOGIN or encrypted LOGIN7 )
+	tlsRecordHeaderLength = 5
+	tlsChangeCipherSpec   = 0x14
+	tlsApplicationData    = 0x17
+)
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/60f56408-0188-4cd5-8b90-25c6f2423868
+const (
+	preloginVersion    = 0x00
+	preloginEncryption = 0x01
+	preloginTerminator = 0xff
+
+	encryptOff    = 0x00
+	encryptOn     = 0x01
+	encryptNotSup = 0x02
+	encryptReq    = 0x03
+)
+
+var encryptionNames = map[byte]string{
+	encryptOff:    "off",
+	encryptOn:     "on",
+	encryptNotSup: "not_sup",
+	encryptReq:    "req",
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/773a62b6-ee89-4c02-9e5e-344882630aac
+const (
+	login7FixedLength = 94
+
+	tdsVersion70 = 0x70000000
+	tdsVersion72 = 0x72090002
+)
+
+// beforeTDS72 returns true when ALL_HEADERS is not present and DONE row count is 4 bytes
+func beforeTDS72(v uint32) bool {
+	return v >= tdsVersion70 && v < tdsVersion72
+}
+
+var tdsVersionNames = map[uint32]string{
+	0x70000000: "7.0",
+	0x71000000: "7.1",
+	0x71000001: "7.1",
+	0x72090002: "7.2",
+	0x730a0003: "7.3",
+	0x730b0003: "7.3",
+	0x74000004: "7.4",
+	0x08000000: "8.0",
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/619c43b6-9495-4a58-9e49-a4950db245b3
+var procNames = map[uint16]string{
+	1:  "sp_cursor",
+	2:  "sp_cursoropen",
+	3:  "sp_cursorprepare",
+	4:  "sp_cursorexecute",
+	5:  "sp_cursorprepexec",
+	6:  "sp_cursorunprepare",
+	7:  "sp_cursorfetch",
+	8:  "sp_cursoroption",
+	9:  "sp_cursorclose",
+	10: "sp_executesql",
+	11: "sp_prepare",
+	12: "sp_execute",
+	13: "sp_prepexec",
+	14: "sp_prepexecrpc",
+	15: "sp_unprepare",
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/d2ed21d6-527b-46ac-8035-94f6f68eb9a8
+var transactionRequestNames = map[uint16]string{
+	0: "get_dtc_address",
+	1: "propagate_xact",
+	5: "begin_xact",
+	6: "promote_xact",
+	7: "commit_xact",
+	8: "rollback_xact",
+	9: "save_xact",
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/7091f6f6-b83d-4ed2-afeb-ba5013dfb18f
+const (
+	tokenReturnStatus   = 0x79
+	tokenColMetadata    = 0x81
+	tokenTabName        = 0xa4
+	tokenColInfo        = 0xa5
+	tokenOrder          = 0xa9
+	tokenError          = 0xaa
+	tokenInfo           = 0xab
+	tokenLoginAck       = 0xad
+	tokenFeatureExtAck  = 0xae
+	tokenEnvChange      = 0xe3
+	tokenSessionState   = 0xe4
+	tokenSSPI           = 0xed
+	tokenFedAuthInfo    = 0xee
+	tokenDone           = 0xfd
+	tokenDoneProc       = 0xfe
+	tokenDoneInProc     = 0xff
+	featureExtTerminate = 0xff
+
+	// DONE token length ( TDS 7.2 or later )
+	doneTokenLength = 13
+
+	doneError = 0x0002
+	doneCount = 0x0010
+	doneAttn  = 0x0020
+
+	envChangeDatabase = 1
+)
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/ffb02215-af07-4b50-8545-1fd522106c68
+const (
+	typeNull           = 0x1f
+	typeInt1           = 0x30
+	typeBit            = 0x32
+	typeInt2           = 0x34
+	typeInt4           = 0x38
+	typeDateTim4       = 0x3a
+	typeFlt4           = 0x3b
+	typeMoney          = 0x3c
+	typeDateTime       = 0x3d
+	typeFlt8           = 0x3e
+	typeMoney4         = 0x7a
+	typeInt8           = 0x7f
+	typeGUID           = 0x24
+	typeIntN           = 0x26
+	typeDecimal        = 0x37
+	typeNumeric        = 0x3f
+	typeBitN           = 0x68
+	typeDecimalN       = 0x6a
+	typeNumericN       = 0x6c
+	typeFltN           = 0x6d
+	typeMoneyN         = 0x6e
+	typeDateTimN       = 0x6f
+	typeDateN          = 0x28
+	typeTimeN          = 0x29
+	typeDateTime2N     = 0x2a
+	typeDateTimeOffset = 0x2b
+	typeChar           = 0x2f
+	typeVarChar        = 0x27
+	typeBinary         = 0x2d
+	typeVarBinary      = 0x25
+	typeBigVarBinary   = 0xa5
+	typeBigVarChar     = 0xa7
+	typeBigBinary      = 0xad
+	typeBigChar        = 0xaf
+	typeNVarChar       = 0xe7
+	typeNChar          = 0xef
+	typeText           = 0x23
+	typeImage          = 0x22
+	typeNText          = 0x63
+
+	collationLength = 5
+
+	// USHORTLEN max length of (n)varchar(max) and varbinary(max)
+	plpMaxLength = 0xffff
+
+	plpNull     = 0xffffffffffffffff
+	textNull    = 0xffffffff
+	charBinNull = 0xffff
+)
@@ -0,0 +1,95 @@
+package tds
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// login7 offset/length of variable fields
+const (
+	login7HostName   = 36
+	login7UserName   = 40
+	login7AppName    = 48
+	login7ServerName = 52
+	login7Database   = 68
+)
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/60f56408-0188-4cd5-8b90-25c6f2423868
+func readPrelogin(in []byte) map[byte][]byte {
+	options := map[byte][]byte{}
+	for i := 0; i < len(in); i += 5 {
+		token := in[i]
+		if token == preloginTerminator || len(in) < i+5 {
+			break
+		}
+		offset := int(binary.BigEndian.Uint16(in[i+1 : i+3]))
+		l := int(binary.BigEndian.Uint16(in[i+3 : i+5]))
+		if len(in) < offset+l {
+			break
+		}
+		options[token] = in[offset : offset+l]
+	}
+	return options
+}
+
+// readVersion read VERSION of PRELOGIN ( major, minor, build )
+func readVersion(in []byte) string {
+	return fmt.Sprintf("%d.%d.%d", in[0], in[1], binary.BigEndian.Uint16(in[2:4]))
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/773a62b6-ee89-4c02-9e5e-344882630aac
+func readLogin7(in []byte) ([]dumper.DumpValue, uint32) {
+	if len(in) < login7FixedLength {
+		return []dumper.DumpValue{}, 0
+	}
+	tdsVersion := binary.LittleEndian.Uint32(in[4:8])
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "username",
+			Value: readLogin7Field(in, login7UserName),
+		},
+	}
+	database := readLogin7Field(in, login7Database)
+	if database != "" {
+		values = append(values, dumper.DumpValue{
+			Key:   "database",
+			Value: database,
+		})
+	}
+	for _, f := range []struct {
+		key    string
+		offset int
+	}{
+		{"app_name", login7AppName},
+		{"host_name", login7HostName},
+		{"server_name", login7ServerName},
+	} {
+		v := readLogin7Field(in, f.offset)
+		if v == "" {
+			continue
+		}
+		values = append(values, dumper.DumpValue{
+			Key:   f.key,
+			Value: v,
+		})
+	}
+	if v, ok := tdsVersionNames[tdsVersion]; ok {
+		values = append(values, dumper.DumpValue{
+			Key:   "tds_version",
+			Value: v,
+		})
+	}
+	return values, tdsVersion
+}
+
+// readLogin7Field read UTF-16LE string by ib ( offset ) and cch ( number of characters )
+func readLogin7Field(in []byte, pos int) string {
+	ib := int(binary.LittleEndian.Uint16(in[pos : pos+2]))
+	cch := int(binary.LittleEndian.Uint16(in[pos+2 : pos+4]))
+	if len(in) < ib+cch*2 {
+		return ""
+	}
+	return readUCS2(in[ib : ib+cch*2])
+}
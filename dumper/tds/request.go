@@ -0,0 +1,197 @@
+package tds
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+const (
+	procIDMarker = 0xffff
+
+	// RPC batch separators
+	rpcBatchFlag  = 0xff
+	rpcNoExecFlag = 0x80
+	rpcBatchFlag2 = 0xfe
+)
+
+// skipAllHeaders skip ALL_HEADERS of SQLBatch, RPC and TransactionManager request
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/e17e54ae-0fac-48b7-b8a8-c267be297923
+func skipAllHeaders(in []byte, tdsVersion uint32) []byte {
+	if beforeTDS72(tdsVersion) || len(in) < 4 {
+		return in
+	}
+	total := int(binary.LittleEndian.Uint32(in[0:4]))
+	if total < 4 || total > len(in) {
+		return in
+	}
+	// validate headers because TDS version is unknown when LOGIN7 is not captured
+	pos := 4
+	for pos < total {
+		if total < pos+4 {
+			return in
+		}
+		l := int(binary.LittleEndian.Uint32(in[pos : pos+4]))
+		if l < 6 || total < pos+l {
+			return in
+		}
+		pos += l
+	}
+	return in[total:]
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/f2026cd3-9a46-4a3f-9a08-f63140bcbbe3
+func readSQLBatch(in []byte, tdsVersion uint32) []dumper.DumpValue {
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "packet_type",
+			Value: packetSQLBatch.String(),
+		},
+		dumper.DumpValue{
+			Key:   "query",
+			Value: readUCS2(skipAllHeaders(in, tdsVersion)),
+		},
+	}
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/619c43b6-9495-4a58-9e49-a4950db245b3
+func readRPC(in []byte, tdsVersion uint32) []dumper.DumpValue {
+	buff := skipAllHeaders(in, tdsVersion)
+	if len(buff) < 2 {
+		return []dumper.DumpValue{}
+	}
+	var procName string
+	nameLen := int(binary.LittleEndian.Uint16(buff[0:2]))
+	buff = buff[2:]
+	if nameLen == procIDMarker {
+		if len(buff) < 2 {
+			return []dumper.DumpValue{}
+		}
+		procID := binary.LittleEndian.Uint16(buff[0:2])
+		procName = procNames[procID]
+		if procName == "" {
+			procName = strconv.Itoa(int(procID))
+		}
+		buff = buff[2:]
+	} else {
+		if len(buff) < nameLen*2 {
+			return []dumper.DumpValue{}
+		}
+		procName = readUCS2(buff[:nameLen*2])
+		buff = buff[nameLen*2:]
+	}
+	if len(buff) < 2 {
+		return []dumper.DumpValue{}
+	}
+	buff = buff[2:] // 2:OptionFlags
+
+	params := readRPCParams(buff)
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "packet_type",
+			Value: packetRPC.String(),
+		},
+		dumper.DumpValue{
+			Key:   "proc_name",
+			Value: procName,
+		},
+	}
+
+	switch procName {
+	case "sp_executesql":
+		// @stmt, @params, @param1, ...
+		values = appendParam(values, "query", params, 0)
+		values = appendParam(values, "param_definitions", params, 1)
+		values = appendParamValues(values, "stmt_execute_values", params, 2)
+	case "sp_prepexec":
+		// @handle OUTPUT, @params, @stmt, @param1, ...
+		values = appendParam(values, "query", params, 2)
+		values = appendParam(values, "param_definitions", params, 1)
+		values = appendParamValues(values, "stmt_execute_values", params, 3)
+	case "sp_prepare":
+		// @handle OUTPUT, @params, @stmt, @options
+		values = appendParam(values, "stmt_prepare_query", params, 2)
+		values = appendParam(values, "param_definitions", params, 1)
+	case "sp_execute":
+		// @handle, @param1, ...
+		values = appendParam(values, "stmt_id", params, 0)
+		values = appendParamValues(values, "stmt_execute_values", params, 1)
+	case "sp_unprepare":
+		values = appendParam(values, "stmt_id", params, 0)
+	default:
+		values = appendParamValues(values, "proc_params", params, 0)
+	}
+	return values
+}
+
+// readRPCParams read values of ParameterData of the first RPC in batch
+func readRPCParams(in []byte) []interface{} {
+	params := []interface{}{}
+	buff := in
+	for len(buff) > 0 {
+		if buff[0] == rpcBatchFlag || buff[0] == rpcBatchFlag2 || buff[0] == rpcNoExecFlag {
+			break
+		}
+		nameLen := int(buff[0]) * 2
+		if len(buff) < 1+nameLen+1 {
+			break
+		}
+		buff = buff[1+nameLen+1:] // 1:ParamName length, ParamName, 1:StatusFlags
+		ti, n, err := readTypeInfo(buff)
+		if err != nil {
+			break
+		}
+		buff = buff[n:]
+		v, n, err := readTypedValue(ti, buff)
+		if err != nil {
+			break
+		}
+		buff = buff[n:]
+		params = append(params, v)
+	}
+	return params
+}
+
+func appendParam(values []dumper.DumpValue, key string, params []interface{}, i int) []dumper.DumpValue {
+	if len(params) <= i {
+		return values
+	}
+	return append(values, dumper.DumpValue{
+		Key:   key,
+		Value: params[i],
+	})
+}
+
+func appendParamValues(values []dumper.DumpValue, key string, params []interface{}, from int) []dumper.DumpValue {
+	if len(params) <= from {
+		return values
+	}
+	return append(values, dumper.DumpValue{
+		Key:   key,
+		Value: params[from:],
+	})
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/d2ed21d6-527b-46ac-8035-94f6f68eb9a8
+func readTransactionManagerRequest(in []byte, tdsVersion uint32) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "packet_type",
+			Value: packetTransactionManager.String(),
+		},
+	}
+	buff := skipAllHeaders(in, tdsVersion)
+	if len(buff) < 2 {
+		return values
+	}
+	requestType := binary.LittleEndian.Uint16(buff[0:2])
+	name, ok := transactionRequestNames[requestType]
+	if !ok {
+		name = strconv.Itoa(int(requestType))
+	}
+	return append(values, dumper.DumpValue{
+		Key:   "transaction_request",
+		Value: name,
+	})
+}
@@ -0,0 +1,356 @@
+package tds
+
+import (
+	"encoding/binary"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/logger"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Dumper struct
+type Dumper struct {
+	name   string
+	logger *zap.Logger
+}
+
+type connMetadataInternal struct {
+	client       stream
+	server       stream
+	prelogin     bool // PRELOGIN is sent by client and waiting for response
+	preloginDone bool // PRELOGIN response is received ( encryption is negotiated )
+	loginSent    bool // LOGIN7 is sent by client and waiting for response
+	encrypted    bool
+	tdsVersion   uint32
+}
+
+// stream is TDS packets of a direction
+type stream struct {
+	buffer      []byte // partial packet
+	started     bool
+	messageType packetType
+	message     []byte // whole request message / first packet of response message
+	tail        []byte // last bytes of response message
+}
+
+// packet is TDS packet or TLS record
+type packet struct {
+	typ    packetType
+	status byte
+	data   []byte
+	tls    bool
+}
+
+// NewDumper returns a Dumper
+func NewDumper() *Dumper {
+	dumper := &Dumper{
+		name:   "tds",
+		logger: logger.NewQueryLogger(),
+	}
+	return dumper
+}
+
+// Name return dumper name
+func (t *Dumper) Name() string {
+	return t.name
+}
+
+// Dump query of TDS
+func (t *Dumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	records, _ := t.ReadFrames(in, direction, connMetadata)
+	for _, read := range records {
+		values := []dumper.DumpValue{}
+		values = append(values, read...)
+		values = append(values, connMetadata.DumpValues...)
+		values = append(values, additional...)
+
+		t.Log(values)
+	}
+	return nil
+}
+
+// Read return the first message of byte to analyzed string ( use ReadFrames to read all messages )
+func (t *Dumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	records, err := t.ReadFrames(in, direction, connMetadata)
+	if len(records) == 0 {
+		return []dumper.DumpValue{}, err
+	}
+	return records[0], err
+}
+
+// ReadFrames return messages of byte to analyzed string
+func (t *Dumper) ReadFrames(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	records := [][]dumper.DumpValue{}
+	if direction == dumper.Unknown {
+		return records, nil
+	}
+	internal := connMetadata.Internal.(connMetadataInternal)
+	if internal.encrypted {
+		return records, nil
+	}
+	fromServer := direction == dumper.RemoteToClient || direction == dumper.DstToSrc
+
+	s := &internal.client
+	if fromServer {
+		s = &internal.server
+	}
+	var err error
+	for _, p := range s.readPackets(in) {
+		var (
+			v []dumper.DumpValue
+			e error
+		)
+		if fromServer {
+			v, e = t.readServerPacket(p, &internal, connMetadata)
+		} else {
+			v, e = t.readClientPacket(p, &internal, connMetadata)
+		}
+		if e != nil && err == nil {
+			err = e
+		}
+		if len(v) > 0 {
+			records = append(records, v)
+		}
+		if internal.encrypted {
+			break
+		}
+	}
+	connMetadata.Internal = internal
+	return records, err
+}
+
+// Log values
+func (t *Dumper) Log(values []dumper.DumpValue) {
+	fields := []zapcore.Field{}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	t.logger.Info("-", fields...)
+}
+
+// NewConnMetadata return metadata per TCP connection
+func (t *Dumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+		Internal:   connMetadataInternal{},
+	}
+}
+
+func (t *Dumper) readClientPacket(p packet, internal *connMetadataInternal, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	if p.tls {
+		if !internal.preloginDone {
+			// TDS 8.0 starts TLS handshake before PRELOGIN
+			internal.encrypted = true
+			return nil, errors.New("client started TLS handshake. tcpdp tds dumper not support encrypted connection")
+		}
+		if p.typ == tlsApplicationData {
+			// LOGIN7 encrypted with TLS ( ENCRYPT_OFF )
+			internal.loginSent = true
+		}
+		return nil, nil
+	}
+
+	msg, typ, ok := internal.client.readMessage(p)
+	if !ok {
+		return nil, nil
+	}
+	switch typ {
+	case packetPrelogin:
+		if internal.preloginDone {
+			// TLS handshake in PRELOGIN packets
+			return nil, nil
+		}
+		internal.prelogin = true
+		return nil, nil
+	case packetLogin7:
+		values, tdsVersion := readLogin7(msg)
+		connMetadata.DumpValues = append(connMetadata.DumpValues, values...)
+		internal.tdsVersion = tdsVersion
+		internal.loginSent = true
+		return nil, nil
+	case packetSQLBatch:
+		return readSQLBatch(msg, internal.tdsVersion), nil
+	case packetRPC:
+		return readRPC(msg, internal.tdsVersion), nil
+	case packetTransactionManager:
+		return readTransactionManagerRequest(msg, internal.tdsVersion), nil
+	case packetAttention, packetBulkLoad:
+		return []dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "packet_type",
+				Value: typ.String(),
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+func (t *Dumper) readServerPacket(p packet, internal *connMetadataInternal, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	if p.tls {
+		return nil, nil
+	}
+	head, tail, typ, ok := internal.server.readResponse(p)
+	if !ok || typ != packetTabularResult {
+		return nil, nil
+	}
+
+	if internal.prelogin && !internal.preloginDone {
+		internal.prelogin = false
+		internal.preloginDone = true
+		options := readPrelogin(head)
+		if v, ok := options[preloginVersion]; ok && len(v) >= 4 {
+			setDumpValue(connMetadata, "server_version", readVersion(v), true)
+		}
+		if v, ok := options[preloginEncryption]; ok && len(v) >= 1 {
+			setDumpValue(connMetadata, "encryption", encryptionName(v[0]), true)
+			if v[0] == encryptOn || v[0] == encryptReq {
+				internal.encrypted = true
+				return nil, errors.New("server required encryption. tcpdp tds dumper not support encrypted connection")
+			}
+		}
+		return nil, nil
+	}
+
+	r := readTokens(head)
+	if r.database != "" {
+		setDumpValue(connMetadata, "database", r.database, true)
+	}
+	if r.serverVersion != "" {
+		setDumpValue(connMetadata, "server_version", r.serverVersion, true)
+	}
+	if internal.loginSent {
+		if r.authContinue && !r.loginAck && r.err == nil {
+			return nil, nil
+		}
+		internal.loginSent = false
+		return r.authValues(), nil
+	}
+	r.readDone(tail, internal.tdsVersion)
+	return r.values(), nil
+}
+
+// readPackets returns complete packets ( and TLS records ) and cache the partial packet
+func (s *stream) readPackets(in []byte) []packet {
+	packets := []packet{}
+	buff := append(s.buffer, in...)
+	s.buffer = nil
+	for len(buff) > 0 {
+		if isTLSRecord(buff) {
+			if len(buff) < tlsRecordHeaderLength {
+				break
+			}
+			l := tlsRecordHeaderLength + int(binary.BigEndian.Uint16(buff[3:5]))
+			if len(buff) < l {
+				break
+			}
+			packets = append(packets, packet{typ: packetType(buff[0]), tls: true})
+			buff = buff[l:]
+			continue
+		}
+		if _, ok := packetTypeNames[packetType(buff[0])]; !ok {
+			// not packet boundary
+			buff = nil
+			break
+		}
+		if len(buff) < headerLength {
+			break
+		}
+		l := int(binary.BigEndian.Uint16(buff[2:4]))
+		if l < headerLength {
+			buff = nil
+			break
+		}
+		if len(buff) < l {
+			break
+		}
+		packets = append(packets, packet{
+			typ:    packetType(buff[0]),
+			status: buff[1],
+			data:   buff[headerLength:l],
+		})
+		buff = buff[l:]
+	}
+	if len(buff) > 0 {
+		s.buffer = append([]byte{}, buff...)
+	}
+	return packets
+}
+
+// readMessage join packets and returns the message when the packet is end of message
+func (s *stream) readMessage(p packet) ([]byte, packetType, bool) {
+	if !s.started {
+		s.started = true
+		s.messageType = p.typ
+		s.message = []byte{}
+	}
+	if rest := maxMessageSize - len(s.message); rest > 0 {
+		if len(p.data) > rest {
+			s.message = append(s.message, p.data[:rest]...)
+		} else {
+			s.message = append(s.message, p.data...)
+		}
+	}
+	if p.status&statusEOM == 0 {
+		return nil, 0, false
+	}
+	msg, typ := s.message, s.messageType
+	s.started = false
+	s.message = nil
+	return msg, typ, true
+}
+
+// readResponse keeps only the first packet and the last bytes of the response message
+// because the response message ( result set ) may be huge
+func (s *stream) readResponse(p packet) ([]byte, []byte, packetType, bool) {
+	if !s.started {
+		s.started = true
+		s.messageType = p.typ
+		s.message = append([]byte{}, p.data...)
+		s.tail = []byte{}
+	}
+	s.tail = append(s.tail, p.data...)
+	if len(s.tail) > doneTokenLength {
+		s.tail = append([]byte{}, s.tail[len(s.tail)-doneTokenLength:]...)
+	}
+	if p.status&statusEOM == 0 {
+		return nil, nil, 0, false
+	}
+	head, tail, typ := s.message, s.tail, s.messageType
+	s.started = false
+	s.message = nil
+	s.tail = nil
+	return head, tail, typ, true
+}
+
+func isTLSRecord(in []byte) bool {
+	if in[0] < tlsChangeCipherSpec || in[0] > tlsApplicationData {
+		return false
+	}
+	return len(in) < 2 || in[1] == 0x03
+}
+
+func encryptionName(e byte) string {
+	if name, ok := encryptionNames[e]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func setDumpValue(connMetadata *dumper.ConnMetadata, key string, value interface{}, appendIfNotExist bool) bool {
+	for i, kv := range connMetadata.DumpValues {
+		if kv.Key == key {
+			connMetadata.DumpValues[i].Value = value
+			return true
+		}
+	}
+	if !appendIfNotExist {
+		return false
+	}
+	connMetadata.DumpValues = append(connMetadata.DumpValues, dumper.DumpValue{
+		Key:   key,
+		Value: value,
+	})
+	return true
+}
@@ -0,0 +1,640 @@
+package tds
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type tdsStep struct {
+	in        []byte
+	direction dumper.Direction
+	expected  []dumper.DumpValue
+	wantErr   bool
+}
+
+var tdsReadTests = []struct {
+	description        string
+	steps              []tdsStep
+	expectedConnValues []dumper.DumpValue
+}{
+	{
+		"PRELOGIN, LOGIN7 and LOGINACK",
+		[]tdsStep{
+			{
+				[]byte{
+					0x12, 0x01, 0x00, 0x1a, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0b, 0x00, 0x06, 0x01, 0x00, 0x11,
+					0x00, 0x01, 0xff, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{},
+				false,
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x1a, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0b, 0x00, 0x06, 0x01, 0x00, 0x11,
+					0x00, 0x01, 0xff, 0x10, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x02,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{},
+				false,
+			},
+			{
+				[]byte{
+					0x10, 0x01, 0x00, 0xc8, 0x00, 0x00, 0x01, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x74,
+					0x00, 0x10, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0xd2, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0xe0, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09, 0x04, 0x00, 0x00, 0x5e, 0x00, 0x05, 0x00,
+					0x68, 0x00, 0x02, 0x00, 0x6c, 0x00, 0x02, 0x00, 0x70, 0x00, 0x0a, 0x00, 0x84, 0x00, 0x04, 0x00,
+					0x8c, 0x00, 0x00, 0x00, 0x8c, 0x00, 0x0a, 0x00, 0xa0, 0x00, 0x0a, 0x00, 0xb4, 0x00, 0x06, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0xc0, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x77, 0x00, 0x65, 0x00, 0x62, 0x00, 0x30, 0x00, 0x31, 0x00,
+					0x73, 0x00, 0x61, 0x00, 0x70, 0x00, 0x77, 0x00, 0x74, 0x00, 0x63, 0x00, 0x70, 0x00, 0x64, 0x00,
+					0x70, 0x00, 0x2d, 0x00, 0x74, 0x00, 0x65, 0x00, 0x73, 0x00, 0x74, 0x00, 0x64, 0x00, 0x62, 0x00,
+					0x30, 0x00, 0x31, 0x00, 0x67, 0x00, 0x6f, 0x00, 0x2d, 0x00, 0x6d, 0x00, 0x73, 0x00, 0x73, 0x00,
+					0x71, 0x00, 0x6c, 0x00, 0x64, 0x00, 0x62, 0x00, 0x75, 0x00, 0x73, 0x00, 0x5f, 0x00, 0x65, 0x00,
+					0x6e, 0x00, 0x67, 0x00, 0x6c, 0x00, 0x69, 0x00, 0x73, 0x00, 0x68, 0x00, 0x6d, 0x00, 0x61, 0x00,
+					0x73, 0x00, 0x74, 0x00, 0x65, 0x00, 0x72, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{},
+				false,
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x68, 0x00, 0x00, 0x01, 0x00, 0xe3, 0x1b, 0x00, 0x01, 0x06, 0x6d, 0x00, 0x61,
+					0x00, 0x73, 0x00, 0x74, 0x00, 0x65, 0x00, 0x72, 0x00, 0x06, 0x6d, 0x00, 0x61, 0x00, 0x73, 0x00,
+					0x74, 0x00, 0x65, 0x00, 0x72, 0x00, 0xad, 0x32, 0x00, 0x01, 0x74, 0x00, 0x00, 0x04, 0x14, 0x4d,
+					0x00, 0x69, 0x00, 0x63, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x73, 0x00, 0x6f, 0x00, 0x66, 0x00, 0x74,
+					0x00, 0x20, 0x00, 0x53, 0x00, 0x51, 0x00, 0x4c, 0x00, 0x20, 0x00, 0x53, 0x00, 0x65, 0x00, 0x72,
+					0x00, 0x76, 0x00, 0x65, 0x00, 0x72, 0x00, 0x10, 0x00, 0x03, 0xe8, 0xfd, 0x00, 0x00, 0xc1, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "auth_result",
+						Value: "ok",
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "server_version",
+				Value: "16.0.1000",
+			},
+			dumper.DumpValue{
+				Key:   "encryption",
+				Value: "not_sup",
+			},
+			dumper.DumpValue{
+				Key:   "username",
+				Value: "sa",
+			},
+			dumper.DumpValue{
+				Key:   "database",
+				Value: "master",
+			},
+			dumper.DumpValue{
+				Key:   "app_name",
+				Value: "tcpdp-test",
+			},
+			dumper.DumpValue{
+				Key:   "host_name",
+				Value: "web01",
+			},
+			dumper.DumpValue{
+				Key:   "server_name",
+				Value: "db01",
+			},
+			dumper.DumpValue{
+				Key:   "tds_version",
+				Value: "7.4",
+			},
+		},
+	},
+	{
+		"Login failed",
+		[]tdsStep{
+			{
+				[]byte{
+					0x10, 0x01, 0x00, 0xc8, 0x00, 0x00, 0x01, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x74,
+					0x00, 0x10, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0xd2, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0xe0, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09, 0x04, 0x00, 0x00, 0x5e, 0x00, 0x05, 0x00,
+					0x68, 0x00, 0x02, 0x00, 0x6c, 0x00, 0x02, 0x00, 0x70, 0x00, 0x0a, 0x00, 0x84, 0x00, 0x04, 0x00,
+					0x8c, 0x00, 0x00, 0x00, 0x8c, 0x00, 0x0a, 0x00, 0xa0, 0x00, 0x0a, 0x00, 0xb4, 0x00, 0x06, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0xc0, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x77, 0x00, 0x65, 0x00, 0x62, 0x00, 0x30, 0x00, 0x31, 0x00,
+					0x73, 0x00, 0x61, 0x00, 0x70, 0x00, 0x77, 0x00, 0x74, 0x00, 0x63, 0x00, 0x70, 0x00, 0x64, 0x00,
+					0x70, 0x00, 0x2d, 0x00, 0x74, 0x00, 0x65, 0x00, 0x73, 0x00, 0x74, 0x00, 0x64, 0x00, 0x62, 0x00,
+					0x30, 0x00, 0x31, 0x00, 0x67, 0x00, 0x6f, 0x00, 0x2d, 0x00, 0x6d, 0x00, 0x73, 0x00, 0x73, 0x00,
+					0x71, 0x00, 0x6c, 0x00, 0x64, 0x00, 0x62, 0x00, 0x75, 0x00, 0x73, 0x00, 0x5f, 0x00, 0x65, 0x00,
+					0x6e, 0x00, 0x67, 0x00, 0x6c, 0x00, 0x69, 0x00, 0x73, 0x00, 0x68, 0x00, 0x6d, 0x00, 0x61, 0x00,
+					0x73, 0x00, 0x74, 0x00, 0x65, 0x00, 0x72, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{},
+				false,
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x68, 0x00, 0x00, 0x01, 0x00, 0xaa, 0x50, 0x00, 0x18, 0x48, 0x00, 0x00, 0x01,
+					0x0e, 0x1b, 0x00, 0x4c, 0x00, 0x6f, 0x00, 0x67, 0x00, 0x69, 0x00, 0x6e, 0x00, 0x20, 0x00, 0x66,
+					0x00, 0x61, 0x00, 0x69, 0x00, 0x6c, 0x00, 0x65, 0x00, 0x64, 0x00, 0x20, 0x00, 0x66, 0x00, 0x6f,
+					0x00, 0x72, 0x00, 0x20, 0x00, 0x75, 0x00, 0x73, 0x00, 0x65, 0x00, 0x72, 0x00, 0x20, 0x00, 0x27,
+					0x00, 0x73, 0x00, 0x61, 0x00, 0x27, 0x00, 0x2e, 0x00, 0x06, 0x73, 0x00, 0x71, 0x00, 0x6c, 0x00,
+					0x73, 0x00, 0x72, 0x00, 0x76, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0xfd, 0x02, 0x00, 0xc1, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "auth_result",
+						Value: "error",
+					},
+					dumper.DumpValue{
+						Key:   "auth_error_code",
+						Value: int32(18456),
+					},
+					dumper.DumpValue{
+						Key:   "auth_error_message",
+						Value: "Login failed for user 'sa'.",
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "username",
+				Value: "sa",
+			},
+			dumper.DumpValue{
+				Key:   "database",
+				Value: "master",
+			},
+			dumper.DumpValue{
+				Key:   "app_name",
+				Value: "tcpdp-test",
+			},
+			dumper.DumpValue{
+				Key:   "host_name",
+				Value: "web01",
+			},
+			dumper.DumpValue{
+				Key:   "server_name",
+				Value: "db01",
+			},
+			dumper.DumpValue{
+				Key:   "tds_version",
+				Value: "7.4",
+			},
+		},
+	},
+	{
+		"SQL Batch and DONE",
+		[]tdsStep{
+			{
+				[]byte{
+					0x01, 0x01, 0x00, 0x2e, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+					0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x53, 0x00,
+					0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x43, 0x00, 0x54, 0x00, 0x20, 0x00, 0x31, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "sql_batch",
+					},
+					dumper.DumpValue{
+						Key:   "query",
+						Value: "SELECT 1",
+					},
+				},
+				false,
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x2c, 0x00, 0x00, 0x01, 0x00, 0x81, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfd,
+					0x10, 0x00, 0xc1, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "tabular_result",
+					},
+					dumper.DumpValue{
+						Key:   "row_count",
+						Value: uint64(1),
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{},
+	},
+	{
+		"SQL Batch split into packets and segments",
+		[]tdsStep{
+			{
+				[]byte{
+					0x01, 0x00, 0x00, 0x30, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+					0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x53, 0x00,
+					0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x43, 0x00, 0x54, 0x00, 0x20, 0x00, 0x6e, 0x00, 0x61, 0x00,
+					0x01, 0x01, 0x00, 0x3c, 0x00,
+				},
+				dumper.ClientToRemote,
+				[]dumper.DumpValue{},
+				false,
+			},
+			{
+				[]byte{
+					0x00, 0x02, 0x00, 0x6d, 0x00, 0x65, 0x00, 0x20, 0x00, 0x46, 0x00, 0x52, 0x00, 0x4f, 0x00, 0x4d,
+					0x00, 0x20, 0x00, 0x75, 0x00, 0x73, 0x00, 0x65, 0x00, 0x72, 0x00, 0x73, 0x00, 0x20, 0x00, 0x57,
+					0x00, 0x48, 0x00, 0x45, 0x00, 0x52, 0x00, 0x45, 0x00, 0x20, 0x00, 0x69, 0x00, 0x64, 0x00, 0x20,
+					0x00, 0x3d, 0x00, 0x20, 0x00, 0x31, 0x00,
+				},
+				dumper.ClientToRemote,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "sql_batch",
+					},
+					dumper.DumpValue{
+						Key:   "query",
+						Value: "SELECT name FROM users WHERE id = 1",
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{},
+	},
+	{
+		"ENVCHANGE of database",
+		[]tdsStep{
+			{
+				[]byte{
+					0x01, 0x01, 0x00, 0x2e, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+					0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x55, 0x00,
+					0x53, 0x00, 0x45, 0x00, 0x20, 0x00, 0x74, 0x00, 0x65, 0x00, 0x73, 0x00, 0x74, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "sql_batch",
+					},
+					dumper.DumpValue{
+						Key:   "query",
+						Value: "USE test",
+					},
+				},
+				false,
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x2f, 0x00, 0x00, 0x01, 0x00, 0xe3, 0x17, 0x00, 0x01, 0x04, 0x74, 0x00, 0x65,
+					0x00, 0x73, 0x00, 0x74, 0x00, 0x06, 0x6d, 0x00, 0x61, 0x00, 0x73, 0x00, 0x74, 0x00, 0x65, 0x00,
+					0x72, 0x00, 0xfd, 0x00, 0x00, 0xc1, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "tabular_result",
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "database",
+				Value: "test",
+			},
+		},
+	},
+	{
+		"RPC sp_executesql and ERROR",
+		[]tdsStep{
+			{
+				[]byte{
+					0x03, 0x01, 0x00, 0xf9, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+					0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0xff, 0xff,
+					0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0xe7, 0x40, 0x1f, 0x09, 0x04, 0xd0, 0x00, 0x34, 0x62, 0x00,
+					0x53, 0x00, 0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x43, 0x00, 0x54, 0x00, 0x20, 0x00, 0x2a, 0x00,
+					0x20, 0x00, 0x46, 0x00, 0x52, 0x00, 0x4f, 0x00, 0x4d, 0x00, 0x20, 0x00, 0x75, 0x00, 0x73, 0x00,
+					0x65, 0x00, 0x72, 0x00, 0x73, 0x00, 0x20, 0x00, 0x57, 0x00, 0x48, 0x00, 0x45, 0x00, 0x52, 0x00,
+					0x45, 0x00, 0x20, 0x00, 0x69, 0x00, 0x64, 0x00, 0x20, 0x00, 0x3d, 0x00, 0x20, 0x00, 0x40, 0x00,
+					0x70, 0x00, 0x31, 0x00, 0x20, 0x00, 0x41, 0x00, 0x4e, 0x00, 0x44, 0x00, 0x20, 0x00, 0x6e, 0x00,
+					0x61, 0x00, 0x6d, 0x00, 0x65, 0x00, 0x20, 0x00, 0x3d, 0x00, 0x20, 0x00, 0x40, 0x00, 0x70, 0x00,
+					0x32, 0x00, 0x00, 0x00, 0xe7, 0x40, 0x1f, 0x09, 0x04, 0xd0, 0x00, 0x34, 0x30, 0x00, 0x40, 0x00,
+					0x70, 0x00, 0x31, 0x00, 0x20, 0x00, 0x69, 0x00, 0x6e, 0x00, 0x74, 0x00, 0x2c, 0x00, 0x40, 0x00,
+					0x70, 0x00, 0x32, 0x00, 0x20, 0x00, 0x6e, 0x00, 0x76, 0x00, 0x61, 0x00, 0x72, 0x00, 0x63, 0x00,
+					0x68, 0x00, 0x61, 0x00, 0x72, 0x00, 0x28, 0x00, 0x31, 0x00, 0x30, 0x00, 0x29, 0x00, 0x03, 0x40,
+					0x00, 0x70, 0x00, 0x31, 0x00, 0x00, 0x26, 0x04, 0x04, 0x2a, 0x00, 0x00, 0x00, 0x03, 0x40, 0x00,
+					0x70, 0x00, 0x32, 0x00, 0x00, 0xe7, 0x14, 0x00, 0x09, 0x04, 0xd0, 0x00, 0x34, 0x0a, 0x00, 0x61,
+					0x00, 0x6c, 0x00, 0x69, 0x00, 0x63, 0x00, 0x65, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "rpc",
+					},
+					dumper.DumpValue{
+						Key:   "proc_name",
+						Value: "sp_executesql",
+					},
+					dumper.DumpValue{
+						Key:   "query",
+						Value: "SELECT * FROM users WHERE id = @p1 AND name = @p2",
+					},
+					dumper.DumpValue{
+						Key:   "param_definitions",
+						Value: "@p1 int,@p2 nvarchar(10)",
+					},
+					dumper.DumpValue{
+						Key:   "stmt_execute_values",
+						Value: []interface{}{int64(42), "alice"},
+					},
+				},
+				false,
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x6a, 0x00, 0x00, 0x01, 0x00, 0xaa, 0x52, 0x00, 0xd0, 0x00, 0x00, 0x00, 0x01,
+					0x10, 0x1c, 0x00, 0x49, 0x00, 0x6e, 0x00, 0x76, 0x00, 0x61, 0x00, 0x6c, 0x00, 0x69, 0x00, 0x64,
+					0x00, 0x20, 0x00, 0x6f, 0x00, 0x62, 0x00, 0x6a, 0x00, 0x65, 0x00, 0x63, 0x00, 0x74, 0x00, 0x20,
+					0x00, 0x6e, 0x00, 0x61, 0x00, 0x6d, 0x00, 0x65, 0x00, 0x20, 0x00, 0x27, 0x00, 0x75, 0x00, 0x73,
+					0x00, 0x65, 0x00, 0x72, 0x00, 0x73, 0x00, 0x27, 0x00, 0x2e, 0x00, 0x06, 0x73, 0x00, 0x71, 0x00,
+					0x6c, 0x00, 0x73, 0x00, 0x72, 0x00, 0x76, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0xfd, 0x02, 0x00,
+					0xc1, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "tabular_result",
+					},
+					dumper.DumpValue{
+						Key:   "error_code",
+						Value: int32(208),
+					},
+					dumper.DumpValue{
+						Key:   "error_severity",
+						Value: byte(16),
+					},
+					dumper.DumpValue{
+						Key:   "error_state",
+						Value: byte(1),
+					},
+					dumper.DumpValue{
+						Key:   "error_message",
+						Value: "Invalid object name 'users'.",
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{},
+	},
+	{
+		"RPC sp_prepexec with NULL parameter",
+		[]tdsStep{
+			{
+				[]byte{
+					0x03, 0x01, 0x00, 0x9a, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+					0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0xff, 0xff,
+					0x0d, 0x00, 0x00, 0x00, 0x00, 0x01, 0x26, 0x04, 0x00, 0x00, 0x00, 0xe7, 0x40, 0x1f, 0x09, 0x04,
+					0xd0, 0x00, 0x34, 0x0e, 0x00, 0x40, 0x00, 0x70, 0x00, 0x31, 0x00, 0x20, 0x00, 0x69, 0x00, 0x6e,
+					0x00, 0x74, 0x00, 0x00, 0x00, 0xe7, 0x40, 0x1f, 0x09, 0x04, 0xd0, 0x00, 0x34, 0x40, 0x00, 0x44,
+					0x00, 0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x54, 0x00, 0x45, 0x00, 0x20, 0x00, 0x46, 0x00, 0x52,
+					0x00, 0x4f, 0x00, 0x4d, 0x00, 0x20, 0x00, 0x75, 0x00, 0x73, 0x00, 0x65, 0x00, 0x72, 0x00, 0x73,
+					0x00, 0x20, 0x00, 0x57, 0x00, 0x48, 0x00, 0x45, 0x00, 0x52, 0x00, 0x45, 0x00, 0x20, 0x00, 0x69,
+					0x00, 0x64, 0x00, 0x20, 0x00, 0x3d, 0x00, 0x20, 0x00, 0x40, 0x00, 0x70, 0x00, 0x31, 0x00, 0x03,
+					0x40, 0x00, 0x70, 0x00, 0x31, 0x00, 0x00, 0x26, 0x04, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "rpc",
+					},
+					dumper.DumpValue{
+						Key:   "proc_name",
+						Value: "sp_prepexec",
+					},
+					dumper.DumpValue{
+						Key:   "query",
+						Value: "DELETE FROM users WHERE id = @p1",
+					},
+					dumper.DumpValue{
+						Key:   "param_definitions",
+						Value: "@p1 int",
+					},
+					dumper.DumpValue{
+						Key:   "stmt_execute_values",
+						Value: []interface{}{nil},
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{},
+	},
+	{
+		"RPC by procedure name",
+		[]tdsStep{
+			{
+				[]byte{
+					0x03, 0x01, 0x00, 0x43, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+					0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x09, 0x00,
+					0x64, 0x00, 0x62, 0x00, 0x6f, 0x00, 0x2e, 0x00, 0x70, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x63, 0x00,
+					0x31, 0x00, 0x00, 0x00, 0x03, 0x40, 0x00, 0x69, 0x00, 0x64, 0x00, 0x00, 0x26, 0x04, 0x04, 0x07,
+					0x00, 0x00, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "packet_type",
+						Value: "rpc",
+					},
+					dumper.DumpValue{
+						Key:   "proc_name",
+						Value: "dbo.proc1",
+					},
+					dumper.DumpValue{
+						Key:   "proc_params",
+						Value: []interface{}{int64(7)},
+					},
+				},
+				false,
+			},
+		},
+		[]dumper.DumpValue{},
+	},
+	{
+		"Server required encryption",
+		[]tdsStep{
+			{
+				[]byte{
+					0x12, 0x01, 0x00, 0x1a, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0b, 0x00, 0x06, 0x01, 0x00, 0x11,
+					0x00, 0x01, 0xff, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{},
+				false,
+			},
+			{
+				[]byte{
+					0x04, 0x01, 0x00, 0x1a, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0b, 0x00, 0x06, 0x01, 0x00, 0x11,
+					0x00, 0x01, 0xff, 0x10, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x01,
+				},
+				dumper.DstToSrc,
+				[]dumper.DumpValue{},
+				true,
+			},
+			{
+				[]byte{
+					0x01, 0x01, 0x00, 0x2e, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+					0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x53, 0x00,
+					0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x43, 0x00, 0x54, 0x00, 0x20, 0x00, 0x31, 0x00,
+				},
+				dumper.SrcToDst,
+				[]dumper.DumpValue{},
+				false,
+			},
+		},
+		[]dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "server_version",
+				Value: "16.0.1000",
+			},
+			dumper.DumpValue{
+				Key:   "encryption",
+				Value: "on",
+			},
+		},
+	},
+}
+
+func TestTdsRead(t *testing.T) {
+	for _, tt := range tdsReadTests {
+		out := new(bytes.Buffer)
+		d := &Dumper{
+			logger: newTestLogger(out),
+		}
+		connMetadata := d.NewConnMetadata()
+		for i, s := range tt.steps {
+			actual, err := d.Read(s.in, s.direction, connMetadata)
+			if (err != nil) != s.wantErr {
+				t.Errorf("%s step %d: unexpected error %v", tt.description, i, err)
+			}
+			if len(actual) == 0 && len(s.expected) == 0 {
+				continue
+			}
+			if !reflect.DeepEqual(actual, s.expected) {
+				t.Errorf("%s step %d:\nactual %#v\nwant %#v", tt.description, i, actual, s.expected)
+			}
+		}
+		if !reflect.DeepEqual(connMetadata.DumpValues, tt.expectedConnValues) {
+			t.Errorf("%s:\nactual %#v\nwant %#v", tt.description, connMetadata.DumpValues, tt.expectedConnValues)
+		}
+	}
+}
+
+func TestTdsReadFrames(t *testing.T) {
+	// SQL Batch "SELECT 1" and "SELECT 2" in a segment, and their responses
+	requests := []byte{
+		0x01, 0x01, 0x00, 0x2e, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x53, 0x00,
+		0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x43, 0x00, 0x54, 0x00, 0x20, 0x00, 0x31, 0x00,
+		0x01, 0x01, 0x00, 0x2e, 0x00, 0x00, 0x01, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x53, 0x00,
+		0x45, 0x00, 0x4c, 0x00, 0x45, 0x00, 0x43, 0x00, 0x54, 0x00, 0x20, 0x00, 0x32, 0x00,
+	}
+	responses := []byte{
+		0x04, 0x01, 0x00, 0x2c, 0x00, 0x00, 0x01, 0x00, 0x81, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfd,
+		0x10, 0x00, 0xc1, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x04, 0x01, 0x00, 0x2c, 0x00, 0x00, 0x01, 0x00, 0x81, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfd,
+		0x10, 0x00, 0xc1, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	tests := []struct {
+		in        []byte
+		direction dumper.Direction
+		key       string
+		expected  []interface{}
+	}{
+		{requests, dumper.SrcToDst, "query", []interface{}{"SELECT 1", "SELECT 2"}},
+		{responses, dumper.DstToSrc, "row_count", []interface{}{uint64(1), uint64(2)}},
+	}
+
+	out := new(bytes.Buffer)
+	d := &Dumper{
+		logger: newTestLogger(out),
+	}
+	connMetadata := d.NewConnMetadata()
+	for _, tt := range tests {
+		records, err := d.ReadFrames(tt.in, tt.direction, connMetadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := []interface{}{}
+		for _, r := range records {
+			for _, v := range r {
+				if v.Key == tt.key {
+					actual = append(actual, v.Value)
+				}
+			}
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("actual %#v\nwant %#v", actual, tt.expected)
+		}
+	}
+
+	connMetadata = d.NewConnMetadata()
+	for _, tt := range tests {
+		if err := d.Dump(tt.in, tt.direction, connMetadata, []dumper.DumpValue{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if actual := bytes.Count(out.Bytes(), []byte("\n")); actual != 4 {
+		t.Errorf("actual %d lines\nwant %d", actual, 4)
+	}
+}
+
+// newTestLogger return zap.Logger for test
+func newTestLogger(out io.Writer) *zap.Logger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zapcore.DebugLevel,
+	))
+
+	return logger
+}
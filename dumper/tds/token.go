@@ -0,0 +1,219 @@
+package tds
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// response is tokens of tabular result
+type response struct {
+	loginAck      bool
+	authContinue  bool // SSPI or FEDAUTHINFO ( authentication is continued )
+	serverVersion string
+	database      string
+	err           *serverError
+	doneStatus    uint16
+	rowCount      uint64
+	done          bool
+}
+
+// serverError is ERROR token
+type serverError struct {
+	number  int32
+	state   byte
+	class   byte
+	message string
+}
+
+// readTokens read tokens before result set ( COLMETADATA ) in the first packet of response
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/67b6113f-fc70-4e7c-ab0d-e8c7ce92c4a7
+func readTokens(in []byte) *response {
+	r := &response{}
+	buff := in
+	for len(buff) > 0 {
+		token := buff[0]
+		buff = buff[1:]
+		var l int
+		switch token {
+		case tokenError, tokenInfo, tokenLoginAck, tokenEnvChange, tokenOrder, tokenTabName, tokenColInfo, tokenSSPI:
+			if len(buff) < 2 {
+				return r
+			}
+			l = int(binary.LittleEndian.Uint16(buff[0:2]))
+			buff = buff[2:]
+		case tokenSessionState, tokenFedAuthInfo:
+			if len(buff) < 4 {
+				return r
+			}
+			l = int(binary.LittleEndian.Uint32(buff[0:4]))
+			buff = buff[4:]
+		case tokenReturnStatus:
+			l = 4
+		case tokenDone, tokenDoneProc, tokenDoneInProc:
+			l = doneTokenLength - 1
+		case tokenFeatureExtAck:
+			for {
+				if len(buff) < 1 {
+					return r
+				}
+				if buff[0] == featureExtTerminate {
+					l = 1
+					break
+				}
+				if len(buff) < 5 {
+					return r
+				}
+				fl := int(binary.LittleEndian.Uint32(buff[1:5]))
+				if len(buff) < 5+fl {
+					return r
+				}
+				buff = buff[5+fl:]
+			}
+		default:
+			// COLMETADATA, ROW, RETURNVALUE and others
+			return r
+		}
+		if len(buff) < l {
+			return r
+		}
+		data := buff[:l]
+		buff = buff[l:]
+
+		switch token {
+		case tokenError:
+			if r.err == nil {
+				r.err = readError(data)
+			}
+		case tokenLoginAck:
+			r.loginAck = true
+			// 1:Interface 4:TDSVersion B_VARCHAR:ProgName 1:MajorVer 1:MinorVer 1:BuildNumHi 1:BuildNumLow
+			if len(data) >= 6 {
+				n := 6 + int(data[5])*2
+				if len(data) >= n+4 {
+					r.serverVersion = fmt.Sprintf("%d.%d.%d", data[n], data[n+1], binary.BigEndian.Uint16(data[n+2:n+4]))
+				}
+			}
+		case tokenSSPI, tokenFedAuthInfo:
+			r.authContinue = true
+		case tokenEnvChange:
+			if len(data) >= 2 && data[0] == envChangeDatabase && len(data) >= 2+int(data[1])*2 {
+				r.database = readUCS2(data[2 : 2+int(data[1])*2])
+			}
+		}
+	}
+	return r
+}
+
+// readDone read the last DONE token of response
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/3c06f110-98bd-4d5b-b836-b1ba66452cb7
+func (r *response) readDone(tail []byte, tdsVersion uint32) {
+	l := doneTokenLength
+	if beforeTDS72(tdsVersion) {
+		l = doneTokenLength - 4
+	}
+	if len(tail) < l {
+		return
+	}
+	d := tail[len(tail)-l:]
+	switch d[0] {
+	case tokenDone, tokenDoneProc, tokenDoneInProc:
+	default:
+		return
+	}
+	r.done = true
+	r.doneStatus = binary.LittleEndian.Uint16(d[1:3])
+	if l == doneTokenLength {
+		r.rowCount = binary.LittleEndian.Uint64(d[5:13])
+	} else {
+		r.rowCount = uint64(binary.LittleEndian.Uint32(d[5:9]))
+	}
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/9805e9fa-1f8b-4cf8-8f78-8d2602228635
+func readError(in []byte) *serverError {
+	// 4:Number 1:State 1:Class US_VARCHAR:MsgText B_VARCHAR:ServerName B_VARCHAR:ProcName 4:LineNumber
+	if len(in) < 8 {
+		return nil
+	}
+	e := &serverError{
+		number: int32(binary.LittleEndian.Uint32(in[0:4])),
+		state:  in[4],
+		class:  in[5],
+	}
+	l := int(binary.LittleEndian.Uint16(in[6:8])) * 2
+	if len(in) >= 8+l {
+		e.message = readUCS2(in[8 : 8+l])
+	}
+	return e
+}
+
+func (r *response) authValues() []dumper.DumpValue {
+	if r.loginAck {
+		return []dumper.DumpValue{
+			dumper.DumpValue{
+				Key:   "auth_result",
+				Value: authResultOK,
+			},
+		}
+	}
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "auth_result",
+			Value: authResultERR,
+		},
+	}
+	if r.err != nil {
+		values = append(values, dumper.DumpValue{
+			Key:   "auth_error_code",
+			Value: r.err.number,
+		}, dumper.DumpValue{
+			Key:   "auth_error_message",
+			Value: r.err.message,
+		})
+	}
+	return values
+}
+
+func (r *response) values() []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "packet_type",
+			Value: packetTabularResult.String(),
+		},
+	}
+	if r.done && r.doneStatus&doneCount > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "row_count",
+			Value: r.rowCount,
+		})
+	}
+	if r.done && r.doneStatus&doneAttn > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "attention_ack",
+			Value: true,
+		})
+	}
+	if r.err != nil {
+		values = append(values, dumper.DumpValue{
+			Key:   "error_code",
+			Value: r.err.number,
+		}, dumper.DumpValue{
+			Key:   "error_severity",
+			Value: r.err.class,
+		}, dumper.DumpValue{
+			Key:   "error_state",
+			Value: r.err.state,
+		}, dumper.DumpValue{
+			Key:   "error_message",
+			Value: r.err.message,
+		})
+	} else if r.done && r.doneStatus&doneError > 0 {
+		values = append(values, dumper.DumpValue{
+			Key:   "done_error",
+			Value: true,
+		})
+	}
+	return values
+}
@@ -0,0 +1,389 @@
+package tds
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	baseDate     = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	baseDateTime = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// typeInfo is TYPE_INFO of RPC parameter
+type typeInfo struct {
+	typ     byte
+	size    int // length of fixed-length type or max length of variable-length type
+	lenSize int // 0:fixed-length 1:BYTELEN 2:USHORTLEN 4:LONGLEN 8:PLP
+	prec    byte
+	scale   byte
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/cbe9c510-eae6-4b1f-9893-a098944d430a
+func readTypeInfo(in []byte) (typeInfo, int, error) {
+	if len(in) < 1 {
+		return typeInfo{}, 0, errors.New("invalid TYPE_INFO")
+	}
+	ti := typeInfo{typ: in[0]}
+	n := 1
+	switch ti.typ {
+	case typeNull:
+		ti.size = 0
+	case typeInt1, typeBit:
+		ti.size = 1
+	case typeInt2:
+		ti.size = 2
+	case typeInt4, typeDateTim4, typeFlt4, typeMoney4:
+		ti.size = 4
+	case typeMoney, typeDateTime, typeFlt8, typeInt8:
+		ti.size = 8
+	case typeGUID, typeIntN, typeBitN, typeFltN, typeMoneyN, typeDateTimN, typeChar, typeVarChar, typeBinary, typeVarBinary:
+		if len(in) < n+1 {
+			return typeInfo{}, 0, errors.New("invalid TYPE_INFO length")
+		}
+		ti.lenSize = 1
+		ti.size = int(in[n])
+		n++
+	case typeDecimal, typeNumeric, typeDecimalN, typeNumericN:
+		if len(in) < n+3 {
+			return typeInfo{}, 0, errors.New("invalid TYPE_INFO length")
+		}
+		ti.lenSize = 1
+		ti.size = int(in[n])
+		ti.prec = in[n+1]
+		ti.scale = in[n+2]
+		n += 3
+	case typeDateN:
+		ti.lenSize = 1
+		ti.size = 3
+	case typeTimeN, typeDateTime2N, typeDateTimeOffset:
+		if len(in) < n+1 {
+			return typeInfo{}, 0, errors.New("invalid TYPE_INFO length")
+		}
+		ti.lenSize = 1
+		ti.scale = in[n]
+		n++
+	case typeBigVarBinary, typeBigVarChar, typeBigBinary, typeBigChar, typeNVarChar, typeNChar:
+		if len(in) < n+2 {
+			return typeInfo{}, 0, errors.New("invalid TYPE_INFO length")
+		}
+		ti.lenSize = 2
+		ti.size = int(binary.LittleEndian.Uint16(in[n : n+2]))
+		n += 2
+		if ti.size == plpMaxLength {
+			ti.lenSize = 8
+		}
+		if isCharType(ti.typ) {
+			n += collationLength
+		}
+	case typeText, typeNText, typeImage:
+		if len(in) < n+4 {
+			return typeInfo{}, 0, errors.New("invalid TYPE_INFO length")
+		}
+		ti.lenSize = 4
+		ti.size = int(binary.LittleEndian.Uint32(in[n : n+4]))
+		n += 4
+		if isCharType(ti.typ) {
+			n += collationLength
+		}
+	default:
+		return typeInfo{}, 0, errors.Errorf("unsupported TDS data type: 0x%02x", ti.typ)
+	}
+	if len(in) < n {
+		return typeInfo{}, 0, errors.New("invalid TYPE_INFO length")
+	}
+	return ti, n, nil
+}
+
+// readTypedValue read TYPE_VARBYTE and decode it
+func readTypedValue(ti typeInfo, in []byte) (interface{}, int, error) {
+	var (
+		data []byte
+		n    int
+	)
+	switch ti.lenSize {
+	case 0:
+		if len(in) < ti.size {
+			return nil, 0, errors.New("invalid TDS value")
+		}
+		data = in[:ti.size]
+		n = ti.size
+	case 1:
+		if len(in) < 1 || len(in) < 1+int(in[0]) {
+			return nil, 0, errors.New("invalid TDS value length")
+		}
+		l := int(in[0])
+		if l == 0 && !isCharType(ti.typ) && !isBinaryType(ti.typ) {
+			return nil, 1, nil
+		}
+		data = in[1 : 1+l]
+		n = 1 + l
+	case 2:
+		if len(in) < 2 {
+			return nil, 0, errors.New("invalid TDS value length")
+		}
+		l := int(binary.LittleEndian.Uint16(in[0:2]))
+		if l == charBinNull {
+			return nil, 2, nil
+		}
+		if len(in) < 2+l {
+			return nil, 0, errors.New("invalid TDS value length")
+		}
+		data = in[2 : 2+l]
+		n = 2 + l
+	case 4:
+		if len(in) < 4 {
+			return nil, 0, errors.New("invalid TDS value length")
+		}
+		l := binary.LittleEndian.Uint32(in[0:4])
+		if l == textNull {
+			return nil, 4, nil
+		}
+		if uint64(len(in)) < 4+uint64(l) {
+			return nil, 0, errors.New("invalid TDS value length")
+		}
+		data = in[4 : 4+l]
+		n = 4 + int(l)
+	case 8:
+		var (
+			null bool
+			err  error
+		)
+		data, n, null, err = readPLP(in)
+		if err != nil {
+			return nil, 0, err
+		}
+		if null {
+			return nil, n, nil
+		}
+	}
+	v, err := decodeValue(ti, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, n, nil
+}
+
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/3f983fde-0509-485a-8c40-a9fa6679a828
+func readPLP(in []byte) ([]byte, int, bool, error) {
+	if len(in) < 8 {
+		return nil, 0, false, errors.New("invalid PLP length")
+	}
+	if binary.LittleEndian.Uint64(in[0:8]) == plpNull {
+		return nil, 8, true, nil
+	}
+	data := []byte{}
+	n := 8
+	for {
+		if len(in) < n+4 {
+			return nil, 0, false, errors.New("invalid PLP chunk length")
+		}
+		l := int(binary.LittleEndian.Uint32(in[n : n+4]))
+		n += 4
+		if l == 0 {
+			break
+		}
+		if len(in) < n+l {
+			return nil, 0, false, errors.New("invalid PLP chunk length")
+		}
+		data = append(data, in[n:n+l]...)
+		n += l
+	}
+	return data, n, false, nil
+}
+
+func decodeValue(ti typeInfo, data []byte) (interface{}, error) {
+	switch ti.typ {
+	case typeNull:
+		return nil, nil
+	case typeInt1, typeInt2, typeInt4, typeInt8, typeIntN:
+		switch len(data) {
+		case 1:
+			return int64(data[0]), nil
+		case 2:
+			return int64(int16(binary.LittleEndian.Uint16(data))), nil
+		case 4:
+			return int64(int32(binary.LittleEndian.Uint32(data))), nil
+		case 8:
+			return int64(binary.LittleEndian.Uint64(data)), nil
+		}
+	case typeBit, typeBitN:
+		if len(data) == 1 {
+			return data[0] != 0x00, nil
+		}
+	case typeFlt4, typeFlt8, typeFltN:
+		switch len(data) {
+		case 4:
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), nil
+		case 8:
+			return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+		}
+	case typeMoney, typeMoney4, typeMoneyN:
+		switch len(data) {
+		case 4:
+			return formatDecimal(big.NewInt(int64(int32(binary.LittleEndian.Uint32(data)))), 4), nil
+		case 8:
+			v := int64(binary.LittleEndian.Uint32(data[0:4]))<<32 | int64(binary.LittleEndian.Uint32(data[4:8]))
+			return formatDecimal(big.NewInt(v), 4), nil
+		}
+	case typeDateTime, typeDateTim4, typeDateTimN:
+		switch len(data) {
+		case 4:
+			days := binary.LittleEndian.Uint16(data[0:2])
+			minutes := binary.LittleEndian.Uint16(data[2:4])
+			t := baseDateTime.AddDate(0, 0, int(days)).Add(time.Duration(minutes) * time.Minute)
+			return t.Format("2006-01-02 15:04:05"), nil
+		case 8:
+			days := int32(binary.LittleEndian.Uint32(data[0:4]))
+			ticks := binary.LittleEndian.Uint32(data[4:8]) // 1/300 second
+			t := baseDateTime.AddDate(0, 0, int(days)).Add(time.Duration(ticks) * time.Second / 300)
+			return t.Format("2006-01-02 15:04:05.000"), nil
+		}
+	case typeGUID:
+		if len(data) == 16 {
+			return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+				binary.LittleEndian.Uint32(data[0:4]),
+				binary.LittleEndian.Uint16(data[4:6]),
+				binary.LittleEndian.Uint16(data[6:8]),
+				data[8:10],
+				data[10:16]), nil
+		}
+	case typeDecimal, typeNumeric, typeDecimalN, typeNumericN:
+		if len(data) > 1 {
+			magnitude := make([]byte, len(data)-1)
+			for i, b := range data[1:] {
+				magnitude[len(magnitude)-1-i] = b
+			}
+			v := new(big.Int).SetBytes(magnitude)
+			if data[0] == 0x00 {
+				v.Neg(v)
+			}
+			return formatDecimal(v, int(ti.scale)), nil
+		}
+	case typeDateN:
+		if len(data) == 3 {
+			return readDate(data).Format("2006-01-02"), nil
+		}
+	case typeTimeN:
+		if len(data) >= 3 && len(data) <= 5 {
+			return formatTime(data, ti.scale), nil
+		}
+	case typeDateTime2N:
+		if len(data) >= 6 && len(data) <= 8 {
+			l := len(data) - 3
+			return readDate(data[l:]).Format("2006-01-02") + " " + formatTime(data[:l], ti.scale), nil
+		}
+	case typeDateTimeOffset:
+		if len(data) >= 8 && len(data) <= 10 {
+			l := len(data) - 5
+			offset := int(int16(binary.LittleEndian.Uint16(data[l+3:])))
+			t := readDate(data[l : l+3]).Add(readTime(data[:l], ti.scale)).Add(time.Duration(offset) * time.Minute)
+			sign := '+'
+			if offset < 0 {
+				sign = '-'
+				offset = -offset
+			}
+			return fmt.Sprintf("%s %s %c%02d:%02d", t.Format("2006-01-02"), formatClock(t, ti.scale), sign, offset/60, offset%60), nil
+		}
+	case typeChar, typeVarChar, typeBigChar, typeBigVarChar, typeText:
+		return readVarChar(data), nil
+	case typeNChar, typeNVarChar, typeNText:
+		return readUCS2(data), nil
+	case typeBinary, typeVarBinary, typeBigBinary, typeBigVarBinary, typeImage:
+		return data, nil
+	}
+	return nil, errors.Errorf("invalid TDS value of data type: 0x%02x", ti.typ)
+}
+
+func isCharType(typ byte) bool {
+	switch typ {
+	case typeChar, typeVarChar, typeBigChar, typeBigVarChar, typeText, typeNChar, typeNVarChar, typeNText:
+		return true
+	}
+	return false
+}
+
+func isBinaryType(typ byte) bool {
+	switch typ {
+	case typeBinary, typeVarBinary, typeBigBinary, typeBigVarBinary, typeImage:
+		return true
+	}
+	return false
+}
+
+// readUCS2 decode UTF-16LE string
+func readUCS2(in []byte) string {
+	u := make([]uint16, len(in)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(in[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// readVarChar decode non-Unicode string ( treat as Latin-1 if not UTF-8 )
+func readVarChar(in []byte) string {
+	if utf8.Valid(in) {
+		return string(in)
+	}
+	r := make([]rune, len(in))
+	for i, b := range in {
+		r[i] = rune(b)
+	}
+	return string(r)
+}
+
+func formatDecimal(v *big.Int, scale int) string {
+	sign := ""
+	if v.Sign() < 0 {
+		sign = "-"
+		v = new(big.Int).Neg(v)
+	}
+	digits := v.String()
+	if scale <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// readDate read days since 0001-01-01
+func readDate(in []byte) time.Time {
+	days := uint32(in[0]) | uint32(in[1])<<8 | uint32(in[2])<<16
+	return baseDate.AddDate(0, 0, int(days))
+}
+
+// readTime read time in 10^-scale second units
+func readTime(in []byte, scale byte) time.Duration {
+	var v uint64
+	for i, b := range in {
+		v |= uint64(b) << (8 * uint(i))
+	}
+	for i := scale; i < 9; i++ {
+		v *= 10
+	}
+	return time.Duration(v)
+}
+
+func formatTime(in []byte, scale byte) string {
+	return formatClock(baseDate.Add(readTime(in, scale)), scale)
+}
+
+func formatClock(t time.Time, scale byte) string {
+	if scale == 0 {
+		return t.Format("15:04:05")
+	}
+	if scale > 7 {
+		scale = 7
+	}
+	return t.Format("15:04:05." + strings.Repeat("0", int(scale)))
+}
@@ -0,0 +1,164 @@
+package tds
+
+import (
+	"reflect"
+	"testing"
+)
+
+var decodeValueTests = []struct {
+	description string
+	ti          typeInfo
+	in          []byte
+	expected    interface{}
+}{
+	{
+		"decimal(10,2)",
+		typeInfo{typ: typeDecimalN, lenSize: 1, size: 5, prec: 10, scale: 2},
+		[]byte{0x00, 0x39, 0x30, 0x00, 0x00},
+		"-123.45",
+	},
+	{
+		"numeric(5,4) less than 1",
+		typeInfo{typ: typeNumericN, lenSize: 1, size: 5, prec: 5, scale: 4},
+		[]byte{0x01, 0x05, 0x00, 0x00, 0x00},
+		"0.0005",
+	},
+	{
+		"money",
+		typeInfo{typ: typeMoneyN, lenSize: 1, size: 8},
+		[]byte{0x00, 0x00, 0x00, 0x00, 0x4e, 0x61, 0xbc, 0x00},
+		"1234.5678",
+	},
+	{
+		"smallmoney",
+		typeInfo{typ: typeMoneyN, lenSize: 1, size: 4},
+		[]byte{0x68, 0xc5, 0xff, 0xff},
+		"-1.5000",
+	},
+	{
+		"datetime",
+		typeInfo{typ: typeDateTimN, lenSize: 1, size: 8},
+		[]byte{0x00, 0x00, 0x00, 0x00, 0x2c, 0x01, 0x00, 0x00},
+		"1900-01-01 00:00:01.000",
+	},
+	{
+		"date",
+		typeInfo{typ: typeDateN, lenSize: 1, size: 3},
+		[]byte{0x46, 0x46, 0x0b},
+		"2024-01-02",
+	},
+	{
+		"time(7)",
+		typeInfo{typ: typeTimeN, lenSize: 1, scale: 7},
+		[]byte{0x87, 0xee, 0x97, 0x76, 0x69},
+		"12:34:56.1234567",
+	},
+	{
+		"datetime2(3)",
+		typeInfo{typ: typeDateTime2N, lenSize: 1, scale: 3},
+		[]byte{0xfb, 0x29, 0xb3, 0x02, 0x46, 0x46, 0x0b},
+		"2024-01-02 12:34:56.123",
+	},
+	{
+		"datetimeoffset(0)",
+		typeInfo{typ: typeDateTimeOffset, lenSize: 1, scale: 0},
+		[]byte{0x15, 0xfe, 0x00, 0x45, 0x46, 0x0b, 0x1c, 0x02},
+		"2024-01-02 03:04:05 +09:00",
+	},
+	{
+		"uniqueidentifier",
+		typeInfo{typ: typeGUID, lenSize: 1, size: 16},
+		[]byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff},
+		"6F9619FF-8B86-D011-B42D-00C04FC964FF",
+	},
+	{
+		"varchar not UTF-8",
+		typeInfo{typ: typeBigVarChar, lenSize: 2, size: 10},
+		[]byte{0x63, 0x61, 0x66, 0xe9},
+		"café",
+	},
+	{
+		"nvarchar",
+		typeInfo{typ: typeNVarChar, lenSize: 2, size: 10},
+		[]byte{0x42, 0x30, 0x44, 0x30},
+		"あい",
+	},
+	{
+		"bit",
+		typeInfo{typ: typeBitN, lenSize: 1, size: 1},
+		[]byte{0x01},
+		true,
+	},
+}
+
+func TestDecodeValue(t *testing.T) {
+	for _, tt := range decodeValueTests {
+		actual, err := decodeValue(tt.ti, tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%s:\nactual %#v\nwant %#v", tt.description, actual, tt.expected)
+		}
+	}
+}
+
+var readTypedValueTests = []struct {
+	description string
+	in          []byte
+	expected    interface{}
+	expectedLen int
+}{
+	{
+		"int",
+		[]byte{0x38, 0xd6, 0xff, 0xff, 0xff},
+		int64(-42),
+		5,
+	},
+	{
+		"nvarchar(max) PLP",
+		[]byte{
+			0xe7, 0xff, 0xff, 0x09, 0x04, 0xd0, 0x00, 0x34,
+			0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x04, 0x00, 0x00, 0x00, 0x61, 0x00, 0x62, 0x00,
+			0x02, 0x00, 0x00, 0x00, 0x63, 0x00,
+			0x00, 0x00, 0x00, 0x00,
+		},
+		"abc",
+		34,
+	},
+	{
+		"varbinary(max) NULL",
+		[]byte{0xa5, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		nil,
+		11,
+	},
+	{
+		"nvarchar NULL",
+		[]byte{0xe7, 0x14, 0x00, 0x09, 0x04, 0xd0, 0x00, 0x34, 0xff, 0xff},
+		nil,
+		10,
+	},
+}
+
+func TestReadTypedValue(t *testing.T) {
+	for _, tt := range readTypedValueTests {
+		ti, n, err := readTypeInfo(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.description, err)
+			continue
+		}
+		actual, m, err := readTypedValue(ti, tt.in[n:])
+		if err != nil {
+			t.Errorf("%s: %v", tt.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%s:\nactual %#v\nwant %#v", tt.description, actual, tt.expected)
+		}
+		if n+m != tt.expectedLen {
+			t.Errorf("%s:\nactual %d\nwant %d", tt.description, n+m, tt.expectedLen)
+		}
+	}
+}
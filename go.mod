@@ -7,6 +7,7 @@ require (
 	github.com/bLamarche413/mysql v1.0.3
 	github.com/google/gopacket v1.1.17
 	github.com/hnakamur/zap-ltsv v0.0.0-20170731143423-10a3dd1d839c
+	github.com/klauspost/compress v1.17.11
 	github.com/lestrrat-go/file-rotatelogs v2.2.1-0.20180926095352-d72d6cf46fc8+incompatible
 	github.com/lestrrat-go/server-starter v0.0.0-20181210024821-8564cc80d990
 	github.com/pkg/errors v0.9.1
@@ -15,7 +16,9 @@ require (
 	github.com/spf13/viper v1.7.0
 	github.com/xo/dburl v0.0.0-20190203050942-98997a05b24f
 	go.uber.org/zap v1.13.0
+	golang.org/x/net v0.0.0-20220722155237-a158d28d115b
 	golang.org/x/text v0.3.8
+	google.golang.org/protobuf v1.34.2
 )
 
 require (
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"time"
 
 	ltsv "github.com/hnakamur/zap-ltsv"
@@ -28,6 +29,9 @@ const LogTypeLog = "log"
 // LogTypeDumpLog for dump.log
 const LogTypeDumpLog = "dumpLog"
 
+// LogTypePcapLog for dump.pcap
+const LogTypePcapLog = "pcapLog"
+
 // NewLogger returns logger
 func NewLogger() *zap.Logger {
 	encoderConfig := zapcore.EncoderConfig{
@@ -152,22 +156,44 @@ func newDumpLogger(encoderConfig zapcore.EncoderConfig) *zap.Logger {
 	return logger
 }
 
+// logWriters are writers per log file. Loggers of the same file ( e.g. dumpers of multiple targets ) share the writer, so that the file is rotated once
+var (
+	logWriters      = map[string]io.Writer{}
+	logWritersMutex sync.Mutex
+)
+
 func newLogWriter(logType string) io.Writer {
+	path := logPath(logType)
+	logWritersMutex.Lock()
+	defer logWritersMutex.Unlock()
+	if w, ok := logWriters[path]; ok {
+		return w
+	}
+	w := newRotateWriter(logType, rotatelogs.Local)
+	logWriters[path] = w
+	return w
+}
+
+func logPath(logType string) string {
 	dir := viper.GetString(fmt.Sprintf("%s.dir", logType))
-	rotateEnable := viper.GetBool(fmt.Sprintf("%s.rotateEnable", logType))
-	rotationTime := viper.GetString(fmt.Sprintf("%s.rotationTime", logType))
-	rotationCount := uint(viper.GetInt(fmt.Sprintf("%s.rotationCount", logType)))
-	rotationHook := viper.GetString(fmt.Sprintf("%s.rotationHook", logType))
 	fileName := viper.GetString(fmt.Sprintf("%s.fileName", logType))
-
 	path, err := filepath.Abs(filepath.Join(dir, fileName))
 	if err != nil {
 		log.Fatalf("Log setting error %v", err)
 	}
+	return path
+}
+
+func newRotateWriter(logType string, clock rotatelogs.Clock) io.Writer {
+	rotateEnable := viper.GetBool(fmt.Sprintf("%s.rotateEnable", logType))
+	rotationTime := viper.GetString(fmt.Sprintf("%s.rotationTime", logType))
+	rotationCount := uint(viper.GetInt(fmt.Sprintf("%s.rotationCount", logType)))
+	rotationHook := viper.GetString(fmt.Sprintf("%s.rotationHook", logType))
+	path := logPath(logType)
 
 	logSuffix := ""
 	options := []rotatelogs.Option{
-		rotatelogs.WithClock(rotatelogs.Local),
+		rotatelogs.WithClock(clock),
 		rotatelogs.WithMaxAge(-1),
 	}
 	if rotationCount > 0 {
@@ -179,6 +205,7 @@ func newLogWriter(logType string) io.Writer {
 	}
 	var w io.Writer
 	var t time.Duration
+	var err error
 	if rotateEnable {
 		switch rotationTime {
 		case "minutely":
@@ -203,8 +230,7 @@ func newLogWriter(logType string) io.Writer {
 			log.Fatalf("Log setting error %v", err)
 		}
 	} else {
-		// #nosec
-		w, err = os.Open(path)
+		w, err = openLogFile(path)
 		if err != nil {
 			log.Fatalf("Log setting error %v", err)
 		}
@@ -238,3 +264,9 @@ func (r *RotateHandler) Handle(e rotatelogs.Event) {
 		}
 	}
 }
+
+// openLogFile open log file not rotated for appending. The file is created if not exists
+func openLogFile(path string) (*os.File, error) {
+	// #nosec
+	return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+}
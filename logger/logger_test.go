@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/spf13/viper"
+)
+
+func TestNewRotateWriterNotRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.log")
+	if err := ioutil.WriteFile(path, []byte("existing\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set(fmt.Sprintf("%s.dir", LogTypeDumpLog), dir)
+	viper.Set(fmt.Sprintf("%s.fileName", LogTypeDumpLog), "dump.log")
+	viper.Set(fmt.Sprintf("%s.rotateEnable", LogTypeDumpLog), false)
+	defer viper.Reset()
+
+	for _, s := range []string{"first\n", "second\n"} {
+		w := newRotateWriter(LogTypeDumpLog, rotatelogs.Local)
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.(*os.File).Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "existing\nfirst\nsecond\n"; string(got) != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// PcapFormatPcap for format pcap
+const PcapFormatPcap = "pcap"
+
+// PcapFormatPcapng for format pcapng
+const PcapFormatPcapng = "pcapng"
+
+const pcapSnaplen = 0xFFFF
+
+// pcapClock is rotatelogs.Clock fixed while writing a packet, so that the file header and the packet are written to the same file
+type pcapClock struct {
+	now time.Time
+}
+
+func (c *pcapClock) Now() time.Time {
+	return c.now
+}
+
+// PcapLogger writes packets to pcap / pcapng file rotated by the same settings as logs
+type PcapLogger struct {
+	mutex   sync.Mutex
+	w       io.Writer
+	clock   *pcapClock
+	header  []byte
+	buf     *bytes.Buffer
+	pw      *pcapgo.Writer
+	ngw     *pcapgo.NgWriter
+	headers map[string]bool // files the file header is written to
+}
+
+// NewPcapLogger returns PcapLogger. Packets are raw IP ( LINKTYPE_RAW )
+func NewPcapLogger() (*PcapLogger, error) {
+	format := viper.GetString(fmt.Sprintf("%s.format", LogTypePcapLog))
+	clock := &pcapClock{now: time.Now()}
+	l := &PcapLogger{
+		w:       newRotateWriter(LogTypePcapLog, clock),
+		clock:   clock,
+		buf:     new(bytes.Buffer),
+		headers: map[string]bool{},
+	}
+	switch format {
+	case PcapFormatPcap:
+		l.pw = pcapgo.NewWriter(l.buf)
+		if err := l.pw.WriteFileHeader(pcapSnaplen, layers.LinkTypeRaw); err != nil {
+			return nil, err
+		}
+	case PcapFormatPcapng:
+		ngw, err := pcapgo.NewNgWriterInterface(l.buf, pcapgo.NgInterface{
+			Name:       "tcpdp",
+			LinkType:   layers.LinkTypeRaw,
+			SnapLength: pcapSnaplen,
+		}, pcapgo.DefaultNgWriterOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := ngw.Flush(); err != nil {
+			return nil, err
+		}
+		l.ngw = ngw
+	default:
+		return nil, errors.Errorf("invalid %s.format: %s", LogTypePcapLog, format)
+	}
+	l.header = append([]byte{}, l.buf.Bytes()...)
+	l.buf.Reset()
+	return l, nil
+}
+
+// WritePacket writes a packet. The file header is written to each rotated file
+func (l *PcapLogger) WritePacket(ts time.Time, data []byte) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.clock.now = ts
+	// rotate if needed
+	if _, err := l.w.Write([]byte{}); err != nil {
+		return err
+	}
+	if f := l.currentFileName(); !l.headers[f] {
+		// only the first file may be appended to the file written by the previous process
+		if len(l.headers) > 0 || !nonEmptyFile(f) {
+			if _, err := l.w.Write(l.header); err != nil {
+				return err
+			}
+		}
+		l.headers[f] = true
+	}
+
+	ci := gopacket.CaptureInfo{
+		Timestamp:     ts,
+		CaptureLength: len(data),
+		Length:        len(data),
+	}
+	if l.ngw != nil {
+		if err := l.ngw.WritePacket(ci, data); err != nil {
+			return err
+		}
+		if err := l.ngw.Flush(); err != nil {
+			return err
+		}
+	} else {
+		if err := l.pw.WritePacket(ci, data); err != nil {
+			return err
+		}
+	}
+	defer l.buf.Reset()
+	_, err := l.w.Write(l.buf.Bytes())
+	return err
+}
+
+func (l *PcapLogger) currentFileName() string {
+	switch w := l.w.(type) {
+	case *rotatelogs.RotateLogs:
+		return w.CurrentFileName()
+	case *os.File:
+		return w.Name()
+	}
+	return ""
+}
+
+func nonEmptyFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Size() > 0
+}
@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/spf13/viper"
+)
+
+var pcapLoggerTests = []struct {
+	format       string
+	rotateEnable bool
+	wantFiles    int
+}{
+	{PcapFormatPcap, true, 2},
+	{PcapFormatPcapng, true, 2},
+	{PcapFormatPcap, false, 1},
+	{PcapFormatPcapng, false, 1},
+}
+
+func TestPcapLogger(t *testing.T) {
+	for _, tt := range pcapLoggerTests {
+		dir := t.TempDir()
+		viper.Set(fmt.Sprintf("%s.dir", LogTypePcapLog), dir)
+		viper.Set(fmt.Sprintf("%s.fileName", LogTypePcapLog), "dump.pcap")
+		viper.Set(fmt.Sprintf("%s.format", LogTypePcapLog), tt.format)
+		viper.Set(fmt.Sprintf("%s.rotateEnable", LogTypePcapLog), tt.rotateEnable)
+		viper.Set(fmt.Sprintf("%s.rotationTime", LogTypePcapLog), "minutely")
+		viper.Set(fmt.Sprintf("%s.rotationCount", LogTypePcapLog), 0)
+
+		l, err := NewPcapLogger()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := []byte{0x45, 0x00, 0x00, 0x14}
+		for _, ts := range []time.Time{
+			time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local),
+			time.Date(2020, 1, 2, 3, 4, 6, 0, time.Local),
+			time.Date(2020, 1, 2, 3, 5, 5, 0, time.Local), // rotated
+		} {
+			if err := l.WritePacket(ts, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		files, err := filepath.Glob(filepath.Join(dir, "dump.pcap*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := 0
+		packets := 0
+		for _, f := range files {
+			if fi, err := os.Lstat(f); err != nil || fi.Mode()&os.ModeSymlink != 0 {
+				continue
+			}
+			got++
+			packets += countPcapPackets(t, f, tt.format)
+		}
+		if got != tt.wantFiles {
+			t.Errorf("%s: got %v\nwant %v", tt.format, got, tt.wantFiles)
+		}
+		if packets != 3 {
+			t.Errorf("%s: got %v\nwant %v", tt.format, packets, 3)
+		}
+	}
+}
+
+func TestPcapLoggerHeaderPerFile(t *testing.T) {
+	for _, format := range []string{PcapFormatPcap, PcapFormatPcapng} {
+		dir := t.TempDir()
+		viper.Set(fmt.Sprintf("%s.dir", LogTypePcapLog), dir)
+		viper.Set(fmt.Sprintf("%s.fileName", LogTypePcapLog), "dump.pcap")
+		viper.Set(fmt.Sprintf("%s.format", LogTypePcapLog), format)
+		viper.Set(fmt.Sprintf("%s.rotateEnable", LogTypePcapLog), true)
+		viper.Set(fmt.Sprintf("%s.rotationTime", LogTypePcapLog), "minutely")
+		viper.Set(fmt.Sprintf("%s.rotationCount", LogTypePcapLog), 0)
+
+		data := []byte{0x45, 0x00, 0x00, 0x14}
+		var header []byte
+		// each slice is written by a new PcapLogger ( restart of process )
+		for _, tss := range [][]time.Time{
+			[]time.Time{
+				time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local),
+				time.Date(2020, 1, 2, 3, 5, 5, 0, time.Local),  // rotated
+				time.Date(2020, 1, 2, 3, 4, 30, 0, time.Local), // back to the first file
+				time.Date(2020, 1, 2, 3, 5, 10, 0, time.Local),
+			},
+			[]time.Time{
+				time.Date(2020, 1, 2, 3, 5, 30, 0, time.Local), // appended to the file written by the previous process
+				time.Date(2020, 1, 2, 3, 6, 5, 0, time.Local),  // rotated
+			},
+		} {
+			l, err := NewPcapLogger()
+			if err != nil {
+				t.Fatal(err)
+			}
+			header = l.header
+			for _, ts := range tss {
+				if err := l.WritePacket(ts, data); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		for _, tt := range []struct {
+			suffix string
+			want   int
+		}{
+			{".202001020304", 2},
+			{".202001020305", 3},
+			{".202001020306", 1},
+		} {
+			path := filepath.Join(dir, "dump.pcap"+tt.suffix)
+			if got := countPcapPackets(t, path, format); got != tt.want {
+				t.Errorf("%s %s: got %v\nwant %v", format, tt.suffix, got, tt.want)
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bytes.Count(b, header); got != 1 {
+				t.Errorf("%s %s: got %v file headers\nwant %v", format, tt.suffix, got, 1)
+			}
+		}
+	}
+}
+
+func TestNewPcapLoggerInvalidFormat(t *testing.T) {
+	viper.Set(fmt.Sprintf("%s.dir", LogTypePcapLog), t.TempDir())
+	viper.Set(fmt.Sprintf("%s.format", LogTypePcapLog), "json")
+	if _, err := NewPcapLogger(); err == nil {
+		t.Errorf("want error")
+	}
+}
+
+// countPcapPackets return number of packets in file. Each file should have its own file header
+func countPcapPackets(t *testing.T, path, format string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	if format == PcapFormatPcapng {
+		r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for {
+			if _, _, err := r.ReadPacketData(); err != nil {
+				break
+			}
+			n++
+		}
+		return n
+	}
+	r, err := pcapgo.NewReader(f)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if r.LinkType() != layers.LinkTypeRaw {
+		t.Errorf("%s: got %v\nwant %v", path, r.LinkType(), layers.LinkTypeRaw)
+	}
+	for {
+		if _, _, err := r.ReadPacketData(); err != nil {
+			break
+		}
+		n++
+	}
+	return n
+}
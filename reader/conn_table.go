@@ -0,0 +1,136 @@
+package reader
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// eviction reasons
+const (
+	evictMaxConnections = "max_connections"
+	evictIdleTimeout    = "idle_timeout"
+)
+
+// connEntry is state of a TCP connection
+type connEntry struct {
+	key       string
+	metadata  *dumper.ConnMetadata
+	mss       int // 0: unknown
+	lastSeen  time.Time
+	requestTs time.Time // first packet of request waiting for response ( RingBuffer latency trigger )
+}
+
+// connTable is table of TCP connections with LRU eviction and idle timeout
+// Connections that missed FIN/RST ( mid-stream start, packet drop, asymmetric routing ) are evicted
+type connTable struct {
+	entries     map[string]*list.Element
+	lru         *list.List    // front is the most recently used
+	max         int           // 0: unlimited
+	idleTimeout time.Duration // 0: no timeout
+	onEvict     func(e *connEntry, reason string)
+	evicted     map[string]int // number of evicted connections per reason
+}
+
+func newConnTable(max int, idleTimeout time.Duration, onEvict func(e *connEntry, reason string)) *connTable {
+	return &connTable{
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+		max:         max,
+		idleTimeout: idleTimeout,
+		onEvict:     onEvict,
+		evicted:     map[string]int{},
+	}
+}
+
+// get return connection without updating LRU
+func (t *connTable) get(key string) (*connEntry, bool) {
+	el, ok := t.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return el.Value.(*connEntry), true
+}
+
+// touch update last seen of connection
+func (t *connTable) touch(key string, ts time.Time) {
+	el, ok := t.entries[key]
+	if !ok {
+		return
+	}
+	el.Value.(*connEntry).lastSeen = ts
+	t.lru.MoveToFront(el)
+}
+
+// put set metadata of connection and touch it. The least recently used connection is evicted when the table is full
+func (t *connTable) put(key string, metadata *dumper.ConnMetadata, ts time.Time) *connEntry {
+	if el, ok := t.entries[key]; ok {
+		e := el.Value.(*connEntry)
+		e.metadata = metadata
+		t.touch(key, ts)
+		return e
+	}
+	e := &connEntry{
+		key:      key,
+		metadata: metadata,
+		lastSeen: ts,
+	}
+	t.entries[key] = t.lru.PushFront(e)
+	for t.max > 0 && t.lru.Len() > t.max {
+		t.evict(t.lru.Back(), evictMaxConnections)
+	}
+	return e
+}
+
+func (t *connTable) delete(key string) {
+	el, ok := t.entries[key]
+	if !ok {
+		return
+	}
+	t.lru.Remove(el)
+	delete(t.entries, key)
+}
+
+// evictIdle evict connections idle longer than idleTimeout at now
+func (t *connTable) evictIdle(now time.Time) {
+	if t.idleTimeout <= 0 {
+		return
+	}
+	for {
+		el := t.lru.Back()
+		if el == nil || now.Sub(el.Value.(*connEntry).lastSeen) <= t.idleTimeout {
+			return
+		}
+		t.evict(el, evictIdleTimeout)
+	}
+}
+
+func (t *connTable) evict(el *list.Element, reason string) {
+	e := el.Value.(*connEntry)
+	t.lru.Remove(el)
+	delete(t.entries, e.key)
+	t.evicted[reason]++
+	if t.onEvict != nil {
+		t.onEvict(e, reason)
+	}
+}
+
+func (t *connTable) len() int {
+	return t.lru.Len()
+}
+
+// connID return conn_id of connection
+func (e *connEntry) connID() string {
+	if e.metadata == nil {
+		return ""
+	}
+	for _, v := range e.metadata.DumpValues {
+		if v.Key == "conn_id" {
+			if id, ok := v.Value.(string); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package reader
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+var connTableTests = []struct {
+	description string
+	max         int
+	idleTimeout time.Duration
+	ops         func(c *connTable, base time.Time)
+	wantKeys    []string
+	wantEvicted map[string][]string
+}{
+	{
+		"LRU eviction",
+		2,
+		0,
+		func(c *connTable, base time.Time) {
+			c.put("a", &dumper.ConnMetadata{}, base)
+			c.put("b", &dumper.ConnMetadata{}, base.Add(1*time.Second))
+			c.touch("a", base.Add(2*time.Second))
+			c.put("c", &dumper.ConnMetadata{}, base.Add(3*time.Second))
+		},
+		[]string{"a", "c"},
+		map[string][]string{
+			evictMaxConnections: []string{"b"},
+		},
+	},
+	{
+		"Unlimited",
+		0,
+		0,
+		func(c *connTable, base time.Time) {
+			c.put("a", &dumper.ConnMetadata{}, base)
+			c.put("b", &dumper.ConnMetadata{}, base)
+			c.put("c", &dumper.ConnMetadata{}, base)
+			c.evictIdle(base.Add(24 * time.Hour))
+		},
+		[]string{"a", "b", "c"},
+		map[string][]string{},
+	},
+	{
+		"Idle timeout",
+		0,
+		10 * time.Second,
+		func(c *connTable, base time.Time) {
+			c.put("a", &dumper.ConnMetadata{}, base)
+			c.put("b", &dumper.ConnMetadata{}, base.Add(5*time.Second))
+			c.put("c", &dumper.ConnMetadata{}, base.Add(6*time.Second))
+			c.put("a", &dumper.ConnMetadata{}, base.Add(7*time.Second))
+			c.evictIdle(base.Add(16 * time.Second))
+		},
+		[]string{"a", "c"},
+		map[string][]string{
+			evictIdleTimeout: []string{"b"},
+		},
+	},
+	{
+		"Delete",
+		2,
+		10 * time.Second,
+		func(c *connTable, base time.Time) {
+			c.put("a", &dumper.ConnMetadata{}, base)
+			c.put("b", &dumper.ConnMetadata{}, base)
+			c.delete("a")
+			c.delete("x")
+			c.put("c", &dumper.ConnMetadata{}, base)
+			c.evictIdle(base.Add(10 * time.Second))
+		},
+		[]string{"b", "c"},
+		map[string][]string{},
+	},
+}
+
+func TestConnTable(t *testing.T) {
+	base := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range connTableTests {
+		evicted := map[string][]string{}
+		c := newConnTable(tt.max, tt.idleTimeout, func(e *connEntry, reason string) {
+			evicted[reason] = append(evicted[reason], e.key)
+		})
+		tt.ops(c, base)
+
+		keys := []string{}
+		for k := range c.entries {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, tt.wantKeys) {
+			t.Errorf("%s: got %v\nwant %v", tt.description, keys, tt.wantKeys)
+		}
+		if c.len() != len(tt.wantKeys) {
+			t.Errorf("%s: got %v\nwant %v", tt.description, c.len(), len(tt.wantKeys))
+		}
+		if !reflect.DeepEqual(evicted, tt.wantEvicted) {
+			t.Errorf("%s: got %v\nwant %v", tt.description, evicted, tt.wantEvicted)
+		}
+		for reason, keys := range tt.wantEvicted {
+			if c.evicted[reason] != len(keys) {
+				t.Errorf("%s: got %v\nwant %v", tt.description, c.evicted[reason], len(keys))
+			}
+		}
+	}
+}
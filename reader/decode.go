@@ -0,0 +1,100 @@
+package reader
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// tcpPacket is the innermost TCP segment of a packet
+type tcpPacket struct {
+	srcIP  net.IP
+	dstIP  net.IP
+	tcp    *layers.TCP
+	values []dumper.DumpValue // outer metadata ( vlan_id, inner_vlan_id, vni, erspan_id )
+}
+
+// decodeTCPPacket extract the innermost TCP segment from a packet ( VLAN / QinQ / VXLAN / GRE / ERSPAN encapsulated )
+func decodeTCPPacket(packet gopacket.Packet) (*tcpPacket, bool) {
+	p := &tcpPacket{}
+	vlanIDs := []uint16{}
+	vni := -1
+	erspanID := -1
+L:
+	for _, l := range packetLayers(packet) {
+		switch l := l.(type) {
+		case *layers.Dot1Q:
+			vlanIDs = append(vlanIDs, l.VLANIdentifier)
+		case *ERSPAN:
+			if l.Version > 0 {
+				erspanID = int(l.SessionID)
+				if l.VLAN > 0 {
+					vlanIDs = append(vlanIDs, l.VLAN)
+				}
+			}
+		case *layers.VXLAN:
+			if vni < 0 {
+				vni = int(l.VNI)
+			}
+		case *layers.IPv4:
+			p.srcIP = l.SrcIP
+			p.dstIP = l.DstIP
+		case *layers.IPv6:
+			p.srcIP = l.SrcIP
+			p.dstIP = l.DstIP
+		case *layers.TCP:
+			p.tcp = l
+			break L
+		}
+	}
+	if p.tcp == nil || p.srcIP == nil {
+		return nil, false
+	}
+
+	if len(vlanIDs) > 0 {
+		p.values = append(p.values, dumper.DumpValue{
+			Key:   "vlan_id",
+			Value: vlanIDs[0],
+		})
+	}
+	if len(vlanIDs) > 1 {
+		p.values = append(p.values, dumper.DumpValue{
+			Key:   "inner_vlan_id",
+			Value: vlanIDs[1],
+		})
+	}
+	if vni >= 0 {
+		p.values = append(p.values, dumper.DumpValue{
+			Key:   "vni",
+			Value: uint32(vni),
+		})
+	}
+	if erspanID >= 0 {
+		p.values = append(p.values, dumper.DumpValue{
+			Key:   "erspan_id",
+			Value: uint16(erspanID),
+		})
+	}
+
+	return p, true
+}
+
+func (p *tcpPacket) srcAddr() string {
+	return net.JoinHostPort(p.srcIP.String(), strconv.Itoa(int(p.tcp.SrcPort)))
+}
+
+func (p *tcpPacket) dstAddr() string {
+	return net.JoinHostPort(p.dstIP.String(), strconv.Itoa(int(p.tcp.DstPort)))
+}
+
+func (p *tcpPacket) srcToDstKey() string {
+	return fmt.Sprintf("%s->%s", p.srcAddr(), p.dstAddr())
+}
+
+func (p *tcpPacket) dstToSrcKey() string {
+	return fmt.Sprintf("%s->%s", p.dstAddr(), p.srcAddr())
+}
@@ -0,0 +1,349 @@
+package reader
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+var (
+	testSrcMAC = net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x53, 0x01}
+	testDstMAC = net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x53, 0x02}
+)
+
+var decodeTCPPacketTests = []struct {
+	description string
+	linkType    linkType
+	data        func(t *testing.T) []byte
+	wantSrc     string
+	wantDst     string
+	wantValues  []dumper.DumpValue
+}{
+	{
+		"Ethernet",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			return concat(ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		nil,
+	},
+	{
+		"Linux cooked capture v1",
+		linkType(layers.LinkTypeLinuxSLL),
+		func(t *testing.T) []byte {
+			sll := []byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x06}
+			sll = append(sll, testSrcMAC...)
+			sll = append(sll, 0x00, 0x00, 0x08, 0x00)
+			return concat(sll, testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		nil,
+	},
+	{
+		"Linux cooked capture v2",
+		linkTypeLinuxSLL2,
+		func(t *testing.T) []byte {
+			sll := []byte{0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00, 0x06}
+			sll = append(sll, testSrcMAC...)
+			sll = append(sll, 0x00, 0x00)
+			return concat(sll, testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		nil,
+	},
+	{
+		"Loopback",
+		linkType(layers.LinkTypeNull),
+		func(t *testing.T) []byte {
+			return concat([]byte{0x02, 0x00, 0x00, 0x00}, testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		nil,
+	},
+	{
+		"Raw IPv4",
+		linkType(layers.LinkTypeRaw),
+		func(t *testing.T) []byte {
+			return testIPv4TCP(t)
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		nil,
+	},
+	{
+		"Raw IPv6",
+		linkType(layers.LinkTypeIPv6),
+		func(t *testing.T) []byte {
+			return testIPv6TCP(t)
+		},
+		"[2001:db8::1]:40000",
+		"[2001:db8::2]:3306",
+		nil,
+	},
+	{
+		"802.1Q VLAN",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			return concat(ethernetHeader(layers.EthernetTypeDot1Q), vlanTag(100, layers.EthernetTypeIPv4), testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "vlan_id", Value: uint16(100)},
+		},
+	},
+	{
+		"QinQ",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			return concat(ethernetHeader(layers.EthernetTypeQinQ), vlanTag(200, layers.EthernetTypeDot1Q), vlanTag(100, layers.EthernetTypeIPv4), testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "vlan_id", Value: uint16(200)},
+			dumper.DumpValue{Key: "inner_vlan_id", Value: uint16(100)},
+		},
+	},
+	{
+		"Legacy QinQ ( 0x9100 )",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			return concat(ethernetHeader(ethernetTypeQinQ9100), vlanTag(200, layers.EthernetTypeDot1Q), vlanTag(100, layers.EthernetTypeIPv4), testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "vlan_id", Value: uint16(200)},
+			dumper.DumpValue{Key: "inner_vlan_id", Value: uint16(100)},
+		},
+	},
+	{
+		"ERSPAN in VXLAN",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			gre := []byte{0x10, 0x00, 0x88, 0xbe, 0x00, 0x00, 0x00, 0x01}
+			erspan := []byte{0x10, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x00}
+			return testVXLAN(t, 51234, 5000, testGRE(t, concat(gre, erspan, ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t)))[14:])
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "vni", Value: uint32(5000)},
+			dumper.DumpValue{Key: "erspan_id", Value: uint16(7)},
+		},
+	},
+	{
+		"VXLAN",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			return testVXLAN(t, 51234, 5000, testIPv4TCP(t))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "vni", Value: uint32(5000)},
+		},
+	},
+	{
+		"GRE ( Transparent Ethernet Bridging )",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			gre := []byte{0x00, 0x00, 0x65, 0x58}
+			return testGRE(t, concat(gre, ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t)))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		nil,
+	},
+	{
+		"ERSPAN Type I",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			gre := []byte{0x00, 0x00, 0x88, 0xbe}
+			return testGRE(t, concat(gre, ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t)))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		nil,
+	},
+	{
+		"ERSPAN Type II",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			gre := []byte{0x10, 0x00, 0x88, 0xbe, 0x00, 0x00, 0x00, 0x01}
+			erspan := []byte{0x10, 0x0a, 0x00, 0x07, 0x00, 0x00, 0x00, 0x00}
+			return testGRE(t, concat(gre, erspan, ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t)))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "vlan_id", Value: uint16(10)},
+			dumper.DumpValue{Key: "erspan_id", Value: uint16(7)},
+		},
+	},
+	{
+		"ERSPAN Type III with sub-header",
+		linkType(layers.LinkTypeEthernet),
+		func(t *testing.T) []byte {
+			gre := []byte{0x10, 0x00, 0x22, 0xeb, 0x00, 0x00, 0x00, 0x01}
+			erspan := []byte{0x20, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+			return testGRE(t, concat(gre, erspan, ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t)))
+		},
+		"10.0.0.1:40000",
+		"10.0.0.2:3306",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "erspan_id", Value: uint16(8)},
+		},
+	},
+}
+
+func TestDecodeTCPPacket(t *testing.T) {
+	for _, tt := range decodeTCPPacketTests {
+		packet := gopacket.NewPacket(tt.data(t), tt.linkType, gopacket.Default)
+		p, ok := decodeTCPPacket(packet)
+		if !ok {
+			t.Errorf("%s: got no TCP packet\n%s", tt.description, packet.Dump())
+			continue
+		}
+		if got := p.srcAddr(); got != tt.wantSrc {
+			t.Errorf("%s: got %v\nwant %v", tt.description, got, tt.wantSrc)
+		}
+		if got := p.dstAddr(); got != tt.wantDst {
+			t.Errorf("%s: got %v\nwant %v", tt.description, got, tt.wantDst)
+		}
+		if !reflect.DeepEqual(p.values, tt.wantValues) {
+			t.Errorf("%s: got %#v\nwant %#v", tt.description, p.values, tt.wantValues)
+		}
+		if got := string(p.tcp.LayerPayload()); got != "SELECT 1" {
+			t.Errorf("%s: got %v\nwant %v", tt.description, got, "SELECT 1")
+		}
+	}
+}
+
+func TestLinkTypeDecoder(t *testing.T) {
+	sll2 := []byte{0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00, 0x06}
+	sll2 = append(sll2, testSrcMAC...)
+	sll2 = append(sll2, 0x00, 0x00)
+	data := concat(sll2, testIPv4TCP(t))
+
+	if _, ok := decodeTCPPacket(gopacket.NewPacket(data, LinkTypeDecoder(276), gopacket.Default)); !ok {
+		t.Errorf("LINKTYPE_LINUX_SLL2: got no TCP packet")
+	}
+	// 276 & 0xff
+	packet := gopacket.NewPacket(data, LinkTypeDecoder(20), gopacket.Default)
+	if packet.Layer(layerTypeLinuxSLL2) != nil {
+		t.Errorf("link type 20: got Linux SLL2 layer")
+	}
+
+	// gopacket globals are not changed
+	for _, lt := range []layers.LinkType{20, layers.LinkTypeIPv4, layers.LinkTypeIPv6} {
+		if got := layers.LinkTypeMetadata[lt].Name; got != "UnknownLinkType" {
+			t.Errorf("LinkTypeMetadata[%d]: got %v\nwant %v", lt, got, "UnknownLinkType")
+		}
+	}
+	for _, et := range []layers.EthernetType{ethernetTypeERSPAN, ethernetTypeERSPAN3, ethernetTypeQinQ9100} {
+		if got := layers.EthernetTypeMetadata[et].Name; got != "UnknownEthernetType" {
+			t.Errorf("EthernetTypeMetadata[%#x]: got %v\nwant %v", uint16(et), got, "UnknownEthernetType")
+		}
+	}
+}
+
+func TestDecodeTCPPacketNotTCP(t *testing.T) {
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+	packet := gopacket.NewPacket(serialize(t, ip, udp, gopacket.Payload("dns")), layers.LinkTypeRaw, gopacket.Default)
+	if _, ok := decodeTCPPacket(packet); ok {
+		t.Errorf("got TCP packet\nwant no TCP packet")
+	}
+}
+
+func TestShardIndexVXLAN(t *testing.T) {
+	n := 4
+	for port := 40000; port < 40100; port++ {
+		// VTEPs choose outer UDP source port by hash per direction
+		req := gopacket.NewPacket(testVXLAN(t, 50000+port%7, 5000, testIPv4TCPPort(t, "10.0.0.1", port, "10.0.0.2", 3306)), layers.LinkTypeEthernet, gopacket.Default)
+		res := gopacket.NewPacket(testVXLAN(t, 60000+port%11, 5000, testIPv4TCPPort(t, "10.0.0.2", 3306, "10.0.0.1", port)), layers.LinkTypeEthernet, gopacket.Default)
+		if got, want := shardIndex(req, n), shardIndex(res, n); got != want {
+			t.Errorf("port %d: got %v\nwant %v", port, got, want)
+		}
+	}
+}
+
+func concat(bs ...[]byte) []byte {
+	out := []byte{}
+	for _, b := range bs {
+		out = append(out, b...)
+	}
+	return out
+}
+
+func serialize(t *testing.T, l ...gopacket.SerializableLayer) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, l...); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func ethernetHeader(ethernetType layers.EthernetType) []byte {
+	h := []byte{}
+	h = append(h, testDstMAC...)
+	h = append(h, testSrcMAC...)
+	return append(h, byte(ethernetType>>8), byte(ethernetType))
+}
+
+func vlanTag(id uint16, ethernetType layers.EthernetType) []byte {
+	return []byte{byte(id >> 8), byte(id), byte(ethernetType >> 8), byte(ethernetType)}
+}
+
+func testIPv4TCP(t *testing.T) []byte {
+	return testIPv4TCPPort(t, "10.0.0.1", 40000, "10.0.0.2", 3306)
+}
+
+func testIPv4TCPPort(t *testing.T, srcIP string, srcPort int, dstIP string, dstPort int) []byte {
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.ParseIP(srcIP).To4(), DstIP: net.ParseIP(dstIP).To4()}
+	tcp := &layers.TCP{SrcPort: layers.TCPPort(srcPort), DstPort: layers.TCPPort(dstPort), ACK: true, PSH: true, Window: 1024}
+	if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+	return serialize(t, ip, tcp, gopacket.Payload("SELECT 1"))
+}
+
+func testIPv6TCP(t *testing.T) []byte {
+	ip := &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolTCP, SrcIP: net.ParseIP("2001:db8::1"), DstIP: net.ParseIP("2001:db8::2")}
+	tcp := &layers.TCP{SrcPort: 40000, DstPort: 3306, ACK: true, PSH: true, Window: 1024}
+	if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+	return serialize(t, ip, tcp, gopacket.Payload("SELECT 1"))
+}
+
+func testVXLAN(t *testing.T, srcPort int, vni uint32, inner []byte) []byte {
+	vxlan := []byte{0x08, 0x00, 0x00, 0x00, byte(vni >> 16), byte(vni >> 8), byte(vni), 0x00}
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{192, 168, 0, 1}, DstIP: net.IP{192, 168, 0, 2}}
+	udp := &layers.UDP{SrcPort: layers.UDPPort(srcPort), DstPort: 4789}
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+	payload := concat(vxlan, ethernetHeader(layers.EthernetTypeIPv4), inner)
+	return concat(ethernetHeader(layers.EthernetTypeIPv4), serialize(t, ip, udp, gopacket.Payload(payload)))
+}
+
+func testGRE(t *testing.T, payload []byte) []byte {
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolGRE, SrcIP: net.IP{192, 168, 0, 1}, DstIP: net.IP{192, 168, 0, 2}}
+	return concat(ethernetHeader(layers.EthernetTypeIPv4), serialize(t, ip, gopacket.Payload(payload)))
+}
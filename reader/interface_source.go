@@ -0,0 +1,69 @@
+package reader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+)
+
+// InterfacePacketSource is packet source of a capture interface
+type InterfacePacketSource struct {
+	Interface    string
+	PacketSource *gopacket.PacketSource
+}
+
+// SetInterfacePacketSources set packet sources of interfaces read instead of packetSource. Packets are dumped with the interface name
+func (r *PacketReader) SetInterfacePacketSources(sources []InterfacePacketSource) {
+	r.interfaceSources = sources
+}
+
+// packets return channel of packets. Packets of interface packet sources are merged to a channel.
+// Merged packets are not sorted across interfaces, so the timestamp of a packet older than the previous one is clamped to the previous one
+// to keep timestamps non-decreasing for idle connection eviction and the ring buffer window
+func (r *PacketReader) packets() chan gopacket.Packet {
+	if len(r.interfaceSources) == 0 {
+		return r.packetSource.Packets()
+	}
+	mergedChan := make(chan gopacket.Packet, 1000)
+	wg := &sync.WaitGroup{}
+	for _, s := range r.interfaceSources {
+		wg.Add(1)
+		go func(s InterfacePacketSource) {
+			defer wg.Done()
+			for packet := range s.PacketSource.Packets() {
+				m := packet.Metadata()
+				m.AncillaryData = append(m.AncillaryData, &captureMetadata{iface: s.Interface})
+				select {
+				case <-r.ctx.Done():
+					return
+				case mergedChan <- packet:
+				}
+			}
+		}(s)
+	}
+	go func() {
+		// all interfaces have reached the end of packets
+		wg.Wait()
+		close(mergedChan)
+	}()
+
+	packetChan := make(chan gopacket.Packet, 1000)
+	go func() {
+		defer close(packetChan)
+		var last time.Time
+		for packet := range mergedChan {
+			m := packet.Metadata()
+			if m.Timestamp.Before(last) {
+				m.Timestamp = last
+			}
+			last = m.Timestamp
+			select {
+			case <-r.ctx.Done():
+				return
+			case packetChan <- packet:
+			}
+		}
+	}()
+	return packetChan
+}
@@ -0,0 +1,73 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+)
+
+// testTimedPacketDataSource return packets with timestamps
+type testTimedPacketDataSource struct {
+	packets    []gopacket.Packet
+	timestamps []time.Time
+}
+
+func (s *testTimedPacketDataSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	if len(s.packets) == 0 {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	data := s.packets[0].Data()
+	ts := s.timestamps[0]
+	s.packets = s.packets[1:]
+	s.timestamps = s.timestamps[1:]
+	return data, gopacket.CaptureInfo{
+		Timestamp:     ts,
+		CaptureLength: len(data),
+		Length:        len(data),
+	}, nil
+}
+
+func TestPacketsTimestampNonDecreasing(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sources := []InterfacePacketSource{}
+	for i, iface := range []string{"eth0", "eth1"} {
+		ps := []gopacket.Packet{}
+		tss := []time.Time{}
+		for j := 0; j < 100; j++ {
+			ps = append(ps, newTestPacket(t, "10.0.0.1", 40000+i, "10.0.0.2", 3306, testTCPData, []byte("a")))
+			// eth1 is behind eth0
+			tss = append(tss, base.Add(time.Duration(j*10-i*55)*time.Millisecond))
+		}
+		sources = append(sources, InterfacePacketSource{
+			Interface:    iface,
+			PacketSource: gopacket.NewPacketSource(&testTimedPacketDataSource{packets: ps, timestamps: tss}, layers.LayerTypeEthernet),
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewPacketReader(ctx, cancel, nil, newTestDumper(), []dumper.DumpValue{}, zap.NewNop(), 10, 1, 0, 0, false, false)
+	r.SetInterfacePacketSources(sources)
+
+	var (
+		last  time.Time
+		count int
+	)
+	for packet := range r.packets() {
+		ts := packet.Metadata().Timestamp
+		if ts.Before(last) {
+			t.Errorf("timestamp %v is before %v", ts, last)
+		}
+		last = ts
+		count++
+	}
+	if count != 200 {
+		t.Errorf("got %d\nwant %d", count, 200)
+	}
+}
@@ -0,0 +1,189 @@
+package reader
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/pkg/errors"
+)
+
+const (
+	linkTypeLinuxSLL2 linkType = 276 // LINKTYPE_LINUX_SLL2 ( tcpdump -i any on libpcap >= 1.10 )
+
+	ethernetTypeERSPAN   layers.EthernetType = 0x88be // ERSPAN Type I / II
+	ethernetTypeERSPAN3  layers.EthernetType = 0x22eb // ERSPAN Type III
+	ethernetTypeQinQ9100 layers.EthernetType = 0x9100 // legacy QinQ
+
+	linuxSLL2HeaderLength  = 20
+	erspan2HeaderLength    = 8
+	erspan3HeaderLength    = 12
+	erspan3SubHeaderLength = 8
+)
+
+var (
+	layerTypeLinuxSLL2 = gopacket.RegisterLayerType(4401, gopacket.LayerTypeMetadata{Name: "LinuxSLL2", Decoder: gopacket.DecodeFunc(decodeLinuxSLL2)})
+	layerTypeERSPAN    = gopacket.RegisterLayerType(4402, gopacket.LayerTypeMetadata{Name: "ERSPAN", Decoder: gopacket.DecodeFunc(decodeERSPAN)})
+)
+
+// linkType is link type of 16 bits. layers.LinkType is uint8, so LINKTYPE_LINUX_SLL2 can not be decoded by gopacket
+type linkType uint16
+
+// LinkTypeDecoder return gopacket.Decoder of link type. Link types not supported by gopacket ( LINKTYPE_LINUX_SLL2, LINKTYPE_IPV4, LINKTYPE_IPV6 ) are decoded without changing gopacket globals
+func LinkTypeDecoder(t uint16) gopacket.Decoder {
+	return linkType(t)
+}
+
+// Decode decode packet by link type
+func (t linkType) Decode(data []byte, p gopacket.PacketBuilder) error {
+	switch {
+	case t == linkTypeLinuxSLL2:
+		return decodeLinuxSLL2(data, p)
+	case t == linkType(layers.LinkTypeIPv4):
+		return layers.LayerTypeIPv4.Decode(data, p)
+	case t == linkType(layers.LinkTypeIPv6):
+		return layers.LayerTypeIPv6.Decode(data, p)
+	case t > 0xff:
+		return gopacket.DecodeUnknown.Decode(data, p)
+	}
+	return layers.LinkType(t).Decode(data, p)
+}
+
+// undecodedPayload return decoder of payload that gopacket can not decode ( ERSPAN, legacy QinQ )
+func undecodedPayload(l gopacket.Layer) (gopacket.Decoder, []byte, bool) {
+	var t layers.EthernetType
+	switch l := l.(type) {
+	case *layers.Ethernet:
+		t = l.EthernetType
+	case *layers.Dot1Q:
+		t = l.Type
+	case *layers.GRE:
+		t = l.Protocol
+	case *layers.LinuxSLL:
+		t = l.EthernetType
+	case *LinuxSLL2:
+		t = l.EthernetType
+	default:
+		return nil, nil, false
+	}
+	switch t {
+	case ethernetTypeERSPAN, ethernetTypeERSPAN3:
+		return gopacket.DecodeFunc(decodeERSPAN), l.LayerPayload(), true
+	case ethernetTypeQinQ9100:
+		return layers.LayerTypeDot1Q, l.LayerPayload(), true
+	}
+	return nil, nil, false
+}
+
+// packetLayers return layers of packet including layers of payload that gopacket can not decode
+func packetLayers(packet gopacket.Packet) []gopacket.Layer {
+	ls := []gopacket.Layer{}
+	for _, l := range packet.Layers() {
+		ls = append(ls, l)
+		if decoder, payload, ok := undecodedPayload(l); ok {
+			return append(ls, packetLayers(gopacket.NewPacket(payload, decoder, gopacket.NoCopy))...)
+		}
+	}
+	return ls
+}
+
+// LinuxSLL2 is Linux cooked capture v2 header
+type LinuxSLL2 struct {
+	layers.BaseLayer
+	EthernetType   layers.EthernetType
+	InterfaceIndex uint32
+	ARPHRDType     uint16
+	PacketType     layers.LinuxSLLPacketType
+	AddrLen        uint8
+	Addr           net.HardwareAddr
+}
+
+// LayerType return layerTypeLinuxSLL2
+func (l *LinuxSLL2) LayerType() gopacket.LayerType { return layerTypeLinuxSLL2 }
+
+// DecodeFromBytes decode Linux cooked capture v2 header
+func (l *LinuxSLL2) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < linuxSLL2HeaderLength {
+		df.SetTruncated()
+		return errors.New("Linux SLL2 packet too small")
+	}
+	l.EthernetType = layers.EthernetType(binary.BigEndian.Uint16(data[0:2]))
+	l.InterfaceIndex = binary.BigEndian.Uint32(data[4:8])
+	l.ARPHRDType = binary.BigEndian.Uint16(data[8:10])
+	l.PacketType = layers.LinuxSLLPacketType(data[10])
+	l.AddrLen = data[11]
+	addrLen := int(l.AddrLen)
+	if addrLen > 8 {
+		addrLen = 8
+	}
+	l.Addr = net.HardwareAddr(data[12 : 12+addrLen])
+	l.BaseLayer = layers.BaseLayer{Contents: data[:linuxSLL2HeaderLength], Payload: data[linuxSLL2HeaderLength:]}
+	return nil
+}
+
+func decodeLinuxSLL2(data []byte, p gopacket.PacketBuilder) error {
+	sll := &LinuxSLL2{}
+	if err := sll.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(sll)
+	return p.NextDecoder(sll.EthernetType)
+}
+
+// ERSPAN is ERSPAN Type II / III header. Type I ( without header ) has Version 0
+type ERSPAN struct {
+	layers.BaseLayer
+	Version   uint8
+	VLAN      uint16
+	SessionID uint16
+}
+
+// LayerType return layerTypeERSPAN
+func (e *ERSPAN) LayerType() gopacket.LayerType { return layerTypeERSPAN }
+
+// DecodeFromBytes decode ERSPAN header
+func (e *ERSPAN) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < erspan2HeaderLength {
+		df.SetTruncated()
+		return errors.New("ERSPAN packet too small")
+	}
+	e.Version = data[0] >> 4
+	headerLength := 0
+	switch e.Version {
+	case 1:
+		headerLength = erspan2HeaderLength
+	case 2:
+		if len(data) < erspan3HeaderLength {
+			df.SetTruncated()
+			return errors.New("ERSPAN packet too small")
+		}
+		headerLength = erspan3HeaderLength
+		if data[11]&0x01 == 0x01 {
+			// platform specific sub-header
+			headerLength = headerLength + erspan3SubHeaderLength
+		}
+		if len(data) < headerLength {
+			df.SetTruncated()
+			return errors.New("ERSPAN packet too small")
+		}
+	default:
+		// Type I: mirrored Ethernet frame without header
+		e.Version = 0
+		e.BaseLayer = layers.BaseLayer{Contents: data[:0], Payload: data}
+		return nil
+	}
+	e.VLAN = binary.BigEndian.Uint16(data[0:2]) & 0x0fff
+	e.SessionID = binary.BigEndian.Uint16(data[2:4]) & 0x03ff
+	e.BaseLayer = layers.BaseLayer{Contents: data[:headerLength], Payload: data[headerLength:]}
+	return nil
+}
+
+func decodeERSPAN(data []byte, p gopacket.PacketBuilder) error {
+	e := &ERSPAN{}
+	if err := e.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(e)
+	return p.NextDecoder(layers.LayerTypeEthernet)
+}
@@ -0,0 +1,108 @@
+package reader
+
+import (
+	"net"
+	"strings"
+
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/pkg/errors"
+)
+
+// MidStreamMapping is static mapping of username / database for connections seen mid-stream ( established before tcpdp starts )
+type MidStreamMapping struct {
+	Server   string // server addr. "host:port", "host" or "port" ( empty: any )
+	Client   string // client IP address or CIDR ( empty: any )
+	Username string
+	Database string
+}
+
+type midStreamMapping struct {
+	server   []TargetHost
+	client   *net.IPNet // nil: any
+	username string
+	database string
+}
+
+// SetMidStreamMappings set static mappings for connections seen mid-stream. The first matched mapping is used
+func (r *PacketReader) SetMidStreamMappings(mappings []MidStreamMapping) error {
+	ms := []midStreamMapping{}
+	for _, m := range mappings {
+		mm := midStreamMapping{
+			username: m.Username,
+			database: m.Database,
+		}
+		if m.Server != "" {
+			t, err := ParseTarget(m.Server)
+			if err != nil {
+				return errors.Wrapf(err, "invalid server of mid-stream mapping: %s", m.Server)
+			}
+			mm.server = t.TargetHosts
+		}
+		if m.Client != "" {
+			c := m.Client
+			if !strings.Contains(c, "/") {
+				if ip := net.ParseIP(c); ip != nil && ip.To4() == nil {
+					c = c + "/128"
+				} else {
+					c = c + "/32"
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(c)
+			if err != nil {
+				return errors.Wrapf(err, "invalid client of mid-stream mapping: %s", m.Client)
+			}
+			mm.client = ipNet
+		}
+		ms = append(ms, mm)
+	}
+	r.midStreamMappings = ms
+	return nil
+}
+
+func (m midStreamMapping) match(serverIP net.IP, serverPort uint16, clientIP net.IP) bool {
+	if len(m.server) > 0 {
+		matched := false
+		for _, h := range m.server {
+			if (h.Host == "" || h.Host == anyIP || h.Host == serverIP.String()) && (h.Port == 0 || h.Port == serverPort) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if m.client != nil && !m.client.Contains(clientIP) {
+		return false
+	}
+	return true
+}
+
+// midStreamValues return values of connection seen mid-stream
+func (r *PacketReader) midStreamValues(serverIP net.IP, serverPort uint16, clientIP net.IP) []dumper.DumpValue {
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "mid_stream",
+			Value: true,
+		},
+	}
+	for _, m := range r.midStreamMappings {
+		if !m.match(serverIP, serverPort, clientIP) {
+			continue
+		}
+		if m.username != "" {
+			values = append(values, dumper.DumpValue{
+				Key:   "username",
+				Value: m.username,
+			})
+		}
+		if m.database != "" {
+			values = append(values, dumper.DumpValue{
+				Key:   "database",
+				Value: m.database,
+			})
+		}
+		break
+	}
+	return values
+}
@@ -0,0 +1,75 @@
+package reader
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+var midStreamValuesTests = []struct {
+	description string
+	serverIP    string
+	serverPort  uint16
+	clientIP    string
+	expected    []dumper.DumpValue
+}{
+	{
+		"Match server and client",
+		"10.0.0.1",
+		3306,
+		"192.168.0.10",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "mid_stream", Value: true},
+			dumper.DumpValue{Key: "username", Value: "app"},
+			dumper.DumpValue{Key: "database", Value: "appdb"},
+		},
+	},
+	{
+		"Fallback to any client",
+		"10.0.0.1",
+		3306,
+		"172.16.0.1",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "mid_stream", Value: true},
+			dumper.DumpValue{Key: "username", Value: "batch"},
+		},
+	},
+	{
+		"No match",
+		"10.0.0.1",
+		5432,
+		"192.168.0.10",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "mid_stream", Value: true},
+		},
+	},
+}
+
+func TestMidStreamValues(t *testing.T) {
+	r := &PacketReader{}
+	err := r.SetMidStreamMappings([]MidStreamMapping{
+		MidStreamMapping{Server: "10.0.0.1:3306", Client: "192.168.0.0/24", Username: "app", Database: "appdb"},
+		MidStreamMapping{Server: "3306", Username: "batch"},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, tt := range midStreamValuesTests {
+		actual := r.midStreamValues(net.ParseIP(tt.serverIP), tt.serverPort, net.ParseIP(tt.clientIP))
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%s\nactual %#v\nwant %#v", tt.description, actual, tt.expected)
+		}
+	}
+}
+
+func TestSetMidStreamMappingsInvalidClient(t *testing.T) {
+	r := &PacketReader{}
+	err := r.SetMidStreamMappings([]MidStreamMapping{
+		MidStreamMapping{Client: "not-an-ip"},
+	})
+	if err == nil {
+		t.Errorf("want error")
+	}
+}
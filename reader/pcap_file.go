@@ -0,0 +1,195 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+var (
+	gzipMagic   = []byte{0x1f, 0x8b}
+	zstdMagic   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+)
+
+const pcapFileHeaderLen = 24
+
+// pcapDataSource is packet data source of a pcap / pcapng stream
+type pcapDataSource struct {
+	gopacket.PacketDataSource
+	decoder func() gopacket.Decoder // decoder of the last read packet
+}
+
+func newPcapDataSource(r io.Reader) (*pcapDataSource, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pcap")
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid gzip")
+		}
+		return newPcapDataSource(gr)
+	case bytes.Equal(magic, zstdMagic):
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid zstd")
+		}
+		return newPcapDataSource(zr)
+	case bytes.Equal(magic, pcapngMagic):
+		ng, err := newNgReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &pcapDataSource{
+			PacketDataSource: ng,
+			decoder:          func() gopacket.Decoder { return ng.linkType },
+		}, nil
+	default:
+		// pcapgo.Reader.LinkType() is truncated to uint8
+		header, _ := br.Peek(pcapFileHeaderLen)
+		pr, err := pcapgo.NewReader(br)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pcap ( magic %x )", binary.BigEndian.Uint32(magic))
+		}
+		lt := pcapLinkType(header)
+		return &pcapDataSource{
+			PacketDataSource: pr,
+			decoder:          func() gopacket.Decoder { return lt },
+		}, nil
+	}
+}
+
+// pcapLinkType return link type of pcap file header. The upper 16 bits are FCS length and reserved
+func pcapLinkType(header []byte) linkType {
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if bytes.Equal(header[0:4], []byte{0xa1, 0xb2, 0xc3, 0xd4}) || bytes.Equal(header[0:4], []byte{0xa1, 0xb2, 0x3c, 0x4d}) {
+		byteOrder = binary.BigEndian
+	}
+	return linkType(byteOrder.Uint32(header[20:24]) & 0xffff)
+}
+
+type pcapPacket struct {
+	data    []byte
+	ci      gopacket.CaptureInfo
+	decoder gopacket.Decoder
+}
+
+// PcapReader is gopacket.PacketDataSource and gopacket.Decoder of pcap / pcapng streams ( gzip / zstd compressed ).
+// Packets of multiple streams are merged in timestamp order
+type PcapReader struct {
+	sources []*pcapDataSource
+	heads   []*pcapPacket
+	done    []bool
+	start   time.Time
+	end     time.Time
+	decoder gopacket.Decoder // decoder of the last read packet
+	err     error
+}
+
+// NewPcapReader return PcapReader. Packets out of [start, end) are skipped ( zero time: unlimited )
+func NewPcapReader(rs []io.Reader, start, end time.Time) (*PcapReader, error) {
+	sources := []*pcapDataSource{}
+	for _, r := range rs {
+		s, err := newPcapDataSource(r)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+	return &PcapReader{
+		sources: sources,
+		heads:   make([]*pcapPacket, len(sources)),
+		done:    make([]bool, len(sources)),
+		start:   start,
+		end:     end,
+	}, nil
+}
+
+// ReadPacketData return the oldest packet of streams
+func (r *PcapReader) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	for {
+		next := -1
+		for i, s := range r.sources {
+			if r.heads[i] == nil && !r.done[i] {
+				data, ci, err := s.ReadPacketData()
+				if err != nil {
+					// a truncated stream ( e.g. being written ) is read up to the last packet
+					if cause := errors.Cause(err); cause != io.EOF && cause != io.ErrUnexpectedEOF && r.err == nil {
+						r.err = err
+					}
+					r.done[i] = true
+					continue
+				}
+				r.heads[i] = &pcapPacket{
+					data:    data,
+					ci:      ci,
+					decoder: s.decoder(),
+				}
+			}
+			if r.heads[i] != nil && (next < 0 || r.heads[i].ci.Timestamp.Before(r.heads[next].ci.Timestamp)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return nil, gopacket.CaptureInfo{}, io.EOF
+		}
+		p := r.heads[next]
+		r.heads[next] = nil
+		if !r.start.IsZero() && p.ci.Timestamp.Before(r.start) {
+			continue
+		}
+		if !r.end.IsZero() && !p.ci.Timestamp.Before(r.end) {
+			// packets are read in timestamp order
+			return nil, gopacket.CaptureInfo{}, io.EOF
+		}
+		r.decoder = p.decoder
+		return p.data, p.ci, nil
+	}
+}
+
+// Decode decode packet by link type of the last read packet
+func (r *PcapReader) Decode(data []byte, p gopacket.PacketBuilder) error {
+	return r.decoder.Decode(data, p)
+}
+
+// Err return the first error of streams other than EOF
+func (r *PcapReader) Err() error {
+	return r.err
+}
+
+// captureValues return values of capture metadata ( interface, capture_comment ) of pcapng
+func captureValues(packet gopacket.Packet) []dumper.DumpValue {
+	values := []dumper.DumpValue{}
+	for _, a := range packet.Metadata().AncillaryData {
+		md, ok := a.(*captureMetadata)
+		if !ok {
+			continue
+		}
+		if md.iface != "" {
+			values = append(values, dumper.DumpValue{
+				Key:   "interface",
+				Value: md.iface,
+			})
+		}
+		if md.comment != "" {
+			values = append(values, dumper.DumpValue{
+				Key:   "capture_comment",
+				Value: md.comment,
+			})
+		}
+	}
+	return values
+}
@@ -0,0 +1,258 @@
+package reader
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/pkg/errors"
+)
+
+const (
+	ngBlockTypeSectionHeader       = 0x0a0d0d0a
+	ngBlockTypeInterfaceDescriptor = 0x00000001
+	ngBlockTypePacket              = 0x00000002 // obsolete
+	ngBlockTypeSimplePacket        = 0x00000003
+	ngBlockTypeEnhancedPacket      = 0x00000006
+	ngByteOrderMagic               = 0x1a2b3c4d
+
+	ngOptionCodeEndOfOptions = 0
+	ngOptionCodeComment      = 1
+	ngOptionCodeIfName       = 2
+	ngOptionCodeIfTsresol    = 9
+	ngOptionCodeIfTsoffset   = 14
+
+	ngMaxBlockLength = 64 * 1024 * 1024
+)
+
+// captureMetadata is metadata of a packet in pcapng ( or interface of InterfacePacketSource ). It is set to gopacket.CaptureInfo.AncillaryData
+type captureMetadata struct {
+	iface   string
+	comment string
+}
+
+type ngInterface struct {
+	name          string
+	linkType      linkType
+	snapLength    uint32
+	unitsPerSec   uint64
+	offsetSeconds int64
+}
+
+// ngReader is pcapng reader that supports multiple interfaces with different link types, timestamp resolutions and packet comments.
+// It is also gopacket.Decoder that decodes a packet by link type of the interface
+type ngReader struct {
+	r         io.Reader
+	byteOrder binary.ByteOrder
+	ifaces    []ngInterface
+	linkType  linkType // link type of the last read packet
+}
+
+func newNgReader(r io.Reader) (*ngReader, error) {
+	ng := &ngReader{
+		r:         r,
+		byteOrder: binary.LittleEndian,
+	}
+	typ, _, err := ng.readBlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pcapng")
+	}
+	if typ != ngBlockTypeSectionHeader {
+		return nil, errors.New("invalid pcapng: first block is not Section Header Block")
+	}
+	return ng, nil
+}
+
+// readBlock return type and body of next block. Section Header Block is handled to detect byte order
+func (ng *ngReader) readBlock() (uint32, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(ng.r, header); err != nil {
+		return 0, nil, err
+	}
+	typ := ng.byteOrder.Uint32(header[0:4])
+	if typ == ngBlockTypeSectionHeader {
+		bom := make([]byte, 4)
+		if _, err := io.ReadFull(ng.r, bom); err != nil {
+			return 0, nil, errors.Wrap(err, "invalid pcapng")
+		}
+		switch {
+		case binary.LittleEndian.Uint32(bom) == ngByteOrderMagic:
+			ng.byteOrder = binary.LittleEndian
+		case binary.BigEndian.Uint32(bom) == ngByteOrderMagic:
+			ng.byteOrder = binary.BigEndian
+		default:
+			return 0, nil, errors.Errorf("invalid pcapng: unknown byte-order magic %x", bom)
+		}
+		ng.ifaces = []ngInterface{}
+		header = append(header, bom...)
+	}
+	length := ng.byteOrder.Uint32(header[4:8])
+	if length < uint32(len(header))+4 || length%4 != 0 || length > ngMaxBlockLength {
+		return 0, nil, errors.Errorf("invalid pcapng: block length %d", length)
+	}
+	rest := make([]byte, int(length)-len(header))
+	if _, err := io.ReadFull(ng.r, rest); err != nil {
+		return 0, nil, errors.Wrap(err, "invalid pcapng")
+	}
+	if ng.byteOrder.Uint32(rest[len(rest)-4:]) != length {
+		return 0, nil, errors.New("invalid pcapng: block length mismatch")
+	}
+	body := append(header[8:], rest[:len(rest)-4]...)
+	return typ, body, nil
+}
+
+// options return options of block
+func (ng *ngReader) options(in []byte) map[uint16][][]byte {
+	opts := map[uint16][][]byte{}
+	for len(in) >= 4 {
+		code := ng.byteOrder.Uint16(in[0:2])
+		length := int(ng.byteOrder.Uint16(in[2:4]))
+		if code == ngOptionCodeEndOfOptions || len(in) < 4+length {
+			break
+		}
+		opts[code] = append(opts[code], in[4:4+length])
+		in = in[4+(length+3)/4*4:]
+	}
+	return opts
+}
+
+func (ng *ngReader) readInterfaceDescriptor(body []byte) error {
+	if len(body) < 8 {
+		return errors.New("invalid pcapng: Interface Description Block too short")
+	}
+	iface := ngInterface{
+		linkType:    linkType(ng.byteOrder.Uint16(body[0:2])),
+		snapLength:  ng.byteOrder.Uint32(body[4:8]),
+		unitsPerSec: 1000000,
+	}
+	opts := ng.options(body[8:])
+	if v, ok := opts[ngOptionCodeIfName]; ok {
+		iface.name = string(v[0])
+	}
+	if v, ok := opts[ngOptionCodeIfTsresol]; ok && len(v[0]) == 1 {
+		r := v[0][0]
+		if r&0x80 == 0x80 && r&0x7f < 64 {
+			iface.unitsPerSec = 1 << (r & 0x7f)
+		} else if r&0x80 == 0 && r <= 19 {
+			iface.unitsPerSec = 1
+			for i := 0; i < int(r); i++ {
+				iface.unitsPerSec = iface.unitsPerSec * 10
+			}
+		} else {
+			return errors.Errorf("invalid pcapng: if_tsresol %x", r)
+		}
+	}
+	if v, ok := opts[ngOptionCodeIfTsoffset]; ok && len(v[0]) == 8 {
+		iface.offsetSeconds = int64(ng.byteOrder.Uint64(v[0]))
+	}
+	ng.ifaces = append(ng.ifaces, iface)
+	return nil
+}
+
+func (ng *ngReader) timestamp(iface ngInterface, high, low uint32) time.Time {
+	ts := uint64(high)<<32 | uint64(low)
+	sec := ts / iface.unitsPerSec
+	hi, lo := bits.Mul64(ts%iface.unitsPerSec, uint64(time.Second))
+	nsec, _ := bits.Div64(hi, lo, iface.unitsPerSec)
+	return time.Unix(int64(sec)+iface.offsetSeconds, int64(nsec)).UTC()
+}
+
+func (ng *ngReader) iface(id int) (ngInterface, error) {
+	if id >= len(ng.ifaces) {
+		return ngInterface{}, errors.Errorf("invalid pcapng: interface id %d not present in section (have only %d interfaces)", id, len(ng.ifaces))
+	}
+	return ng.ifaces[id], nil
+}
+
+// ReadPacketData return next packet of pcapng
+func (ng *ngReader) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	for {
+		typ, body, err := ng.readBlock()
+		if err != nil {
+			return nil, gopacket.CaptureInfo{}, err
+		}
+		var (
+			iface   ngInterface
+			id      int
+			ts      time.Time
+			capLen  int
+			origLen int
+			data    []byte
+			opts    map[uint16][][]byte
+		)
+		switch typ {
+		case ngBlockTypeInterfaceDescriptor:
+			if err := ng.readInterfaceDescriptor(body); err != nil {
+				return nil, gopacket.CaptureInfo{}, err
+			}
+			continue
+		case ngBlockTypeEnhancedPacket, ngBlockTypePacket:
+			if len(body) < 20 {
+				return nil, gopacket.CaptureInfo{}, errors.New("invalid pcapng: packet block too short")
+			}
+			if typ == ngBlockTypeEnhancedPacket {
+				id = int(ng.byteOrder.Uint32(body[0:4]))
+			} else {
+				id = int(ng.byteOrder.Uint16(body[0:2]))
+			}
+			if iface, err = ng.iface(id); err != nil {
+				return nil, gopacket.CaptureInfo{}, err
+			}
+			ts = ng.timestamp(iface, ng.byteOrder.Uint32(body[4:8]), ng.byteOrder.Uint32(body[8:12]))
+			capLen = int(ng.byteOrder.Uint32(body[12:16]))
+			origLen = int(ng.byteOrder.Uint32(body[16:20]))
+			padded := (capLen + 3) / 4 * 4
+			if len(body) < 20+padded {
+				return nil, gopacket.CaptureInfo{}, errors.New("invalid pcapng: packet data too short")
+			}
+			data = body[20 : 20+capLen]
+			opts = ng.options(body[20+padded:])
+		case ngBlockTypeSimplePacket:
+			if len(body) < 4 {
+				return nil, gopacket.CaptureInfo{}, errors.New("invalid pcapng: Simple Packet Block too short")
+			}
+			if iface, err = ng.iface(0); err != nil {
+				return nil, gopacket.CaptureInfo{}, err
+			}
+			origLen = int(ng.byteOrder.Uint32(body[0:4]))
+			capLen = origLen
+			if iface.snapLength > 0 && capLen > int(iface.snapLength) {
+				capLen = int(iface.snapLength)
+			}
+			if capLen > len(body)-4 {
+				capLen = len(body) - 4
+			}
+			data = body[4 : 4+capLen]
+		default:
+			// skip other blocks ( Interface Statistics, Name Resolution, ... )
+			continue
+		}
+
+		md := &captureMetadata{
+			iface: iface.name,
+		}
+		if comments, ok := opts[ngOptionCodeComment]; ok {
+			cs := []string{}
+			for _, c := range comments {
+				cs = append(cs, string(c))
+			}
+			md.comment = strings.Join(cs, "; ")
+		}
+		ng.linkType = iface.linkType
+		return data, gopacket.CaptureInfo{
+			Timestamp:      ts,
+			CaptureLength:  capLen,
+			Length:         origLen,
+			InterfaceIndex: id,
+			AncillaryData:  []interface{}{md},
+		}, nil
+	}
+}
+
+// Decode decode packet by link type of the interface of the last read packet
+func (ng *ngReader) Decode(data []byte, p gopacket.PacketBuilder) error {
+	return ng.linkType.Decode(data, p)
+}
@@ -0,0 +1,326 @@
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/klauspost/compress/zstd"
+)
+
+type ngTestPacket struct {
+	ts         time.Time
+	src        string
+	wantValues []dumper.DumpValue
+}
+
+var ngTestPackets = []ngTestPacket{
+	{
+		time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		"10.0.0.1:40000",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "interface", Value: "eth0"},
+			dumper.DumpValue{Key: "capture_comment", Value: "slow query; retransmitted"},
+		},
+	},
+	{
+		time.Date(2020, 1, 2, 3, 4, 6, 123456000, time.UTC),
+		"[2001:db8::1]:40000",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "interface", Value: "tun0"},
+		},
+	},
+	{
+		time.Time{},
+		"10.0.0.1:40000",
+		[]dumper.DumpValue{
+			dumper.DumpValue{Key: "interface", Value: "eth0"},
+		},
+	},
+}
+
+func TestNgReader(t *testing.T) {
+	for _, bo := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		src := newTestPcapPacketSource(t, bytes.NewReader(testPcapng(t, bo)))
+		testPackets(t, src, ngTestPackets)
+	}
+}
+
+func TestNewPcapReaderCompressed(t *testing.T) {
+	ng := testPcapng(t, binary.LittleEndian)
+
+	gz := new(bytes.Buffer)
+	gw := gzip.NewWriter(gz)
+	if _, err := gw.Write(ng); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zs := new(bytes.Buffer)
+	zw, err := zstd.NewWriter(zs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zw.Write(ng); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, in := range [][]byte{gz.Bytes(), zs.Bytes()} {
+		src := newTestPcapPacketSource(t, bytes.NewReader(in))
+		testPackets(t, src, ngTestPackets)
+	}
+}
+
+func TestNewPcapReaderPcap(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	data := concat(ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t))
+	buf := new(bytes.Buffer)
+	w := pcapgo.NewWriter(buf)
+	if err := w.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePacket(gopacket.CaptureInfo{Timestamp: ts, CaptureLength: len(data), Length: len(data)}, data); err != nil {
+		t.Fatal(err)
+	}
+	src := newTestPcapPacketSource(t, buf)
+	testPackets(t, src, []ngTestPacket{
+		{ts, "10.0.0.1:40000", []dumper.DumpValue{}},
+	})
+}
+
+func TestNewPcapReaderLinkType(t *testing.T) {
+	sll2 := []byte{0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00, 0x06}
+	sll2 = append(sll2, testSrcMAC...)
+	sll2 = append(sll2, 0x00, 0x00)
+	data := concat(sll2, testIPv4TCP(t))
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		linkType uint32
+		wantTCP  bool
+	}{
+		{276, true},
+		{276 | 0x10000000, true}, // with FCS length
+		{20, false},
+	}
+	for _, tt := range tests {
+		le := binary.LittleEndian
+		header := make([]byte, 24)
+		le.PutUint32(header[0:4], 0xa1b2c3d4)
+		le.PutUint16(header[4:6], 2)
+		le.PutUint16(header[6:8], 4)
+		le.PutUint32(header[16:20], 65535)
+		le.PutUint32(header[20:24], tt.linkType)
+		record := make([]byte, 16)
+		le.PutUint32(record[0:4], uint32(ts.Unix()))
+		le.PutUint32(record[8:12], uint32(len(data)))
+		le.PutUint32(record[12:16], uint32(len(data)))
+
+		src := newTestPcapPacketSource(t, bytes.NewReader(concat(header, record, data)))
+		packet, err := src.NextPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := decodeTCPPacket(packet); ok != tt.wantTCP {
+			t.Errorf("link type %#x: got %v\nwant %v", tt.linkType, ok, tt.wantTCP)
+		}
+	}
+}
+
+func TestNgReaderLinkType(t *testing.T) {
+	sll2 := []byte{0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00, 0x06}
+	sll2 = append(sll2, testSrcMAC...)
+	sll2 = append(sll2, 0x00, 0x00)
+	data := concat(sll2, testIPv4TCP(t))
+	le := binary.LittleEndian
+	u16 := func(v uint16) []byte { b := make([]byte, 2); le.PutUint16(b, v); return b }
+	u32 := func(v uint32) []byte { b := make([]byte, 4); le.PutUint32(b, v); return b }
+	block := func(typ uint32, body []byte) []byte {
+		length := uint32(12 + len(body))
+		return concat(u32(typ), u32(length), body, u32(length))
+	}
+	ng := concat(
+		block(ngBlockTypeSectionHeader, concat(u32(ngByteOrderMagic), u16(1), u16(0), u32(0xffffffff), u32(0xffffffff))),
+		block(ngBlockTypeInterfaceDescriptor, concat(u16(276), u16(0), u32(0))),
+		block(ngBlockTypeSimplePacket, concat(u32(uint32(len(data))), data, make([]byte, (4-len(data)%4)%4))),
+	)
+	src := newTestPcapPacketSource(t, bytes.NewReader(ng))
+	packet, err := src.NextPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodeTCPPacket(packet); !ok {
+		t.Errorf("got no TCP packet\n%s", packet.Dump())
+	}
+}
+
+func TestNewPcapReaderInvalid(t *testing.T) {
+	if _, err := NewPcapReader([]io.Reader{bytes.NewReader([]byte("SELECT 1;\n"))}, time.Time{}, time.Time{}); err == nil {
+		t.Errorf("want error")
+	}
+}
+
+var pcapReaderMergeTests = []struct {
+	description string
+	start       time.Time
+	end         time.Time
+	want        []int // seconds of timestamps
+}{
+	{
+		"Merge in timestamp order",
+		time.Time{},
+		time.Time{},
+		[]int{1, 2, 3, 4, 5, 6},
+	},
+	{
+		"Time window",
+		time.Date(2020, 1, 2, 3, 4, 2, 0, time.UTC),
+		time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		[]int{2, 3, 4},
+	},
+}
+
+func TestPcapReaderMerge(t *testing.T) {
+	for _, tt := range pcapReaderMergeTests {
+		// rotated capture set and a capture of another interface
+		rs := []io.Reader{
+			bytes.NewReader(testPcap(t, 1, 3, 6)),
+			bytes.NewReader(testPcap(t, 2, 4, 5)),
+		}
+		pr, err := NewPcapReader(rs, tt.start, tt.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := gopacket.NewPacketSource(pr, pr)
+		got := []int{}
+		for packet := range src.Packets() {
+			got = append(got, packet.Metadata().Timestamp.Second())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s\ngot %v\nwant %v", tt.description, got, tt.want)
+		}
+		if err := pr.Err(); err != nil {
+			t.Errorf("%s: %v", tt.description, err)
+		}
+	}
+}
+
+func TestPcapReaderErr(t *testing.T) {
+	ng := testPcapng(t, binary.LittleEndian)
+	// broken block length
+	broken := append(append([]byte{}, ng...), 0x06, 0x00, 0x00, 0x00, 0x0d, 0x00, 0x00, 0x00)
+	pr, err := NewPcapReader([]io.Reader{bytes.NewReader(broken)}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range gopacket.NewPacketSource(pr, pr).Packets() {
+		n++
+	}
+	if n != len(ngTestPackets) {
+		t.Errorf("got %v\nwant %v", n, len(ngTestPackets))
+	}
+	if pr.Err() == nil {
+		t.Errorf("want error")
+	}
+}
+
+func newTestPcapPacketSource(t *testing.T, r io.Reader) *gopacket.PacketSource {
+	pr, err := NewPcapReader([]io.Reader{r}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gopacket.NewPacketSource(pr, pr)
+}
+
+// testPcap return pcap that has packets captured at 2020-01-02T03:04:{seconds}Z
+func testPcap(t *testing.T, seconds ...int) []byte {
+	data := concat(ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t))
+	buf := new(bytes.Buffer)
+	w := pcapgo.NewWriter(buf)
+	if err := w.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range seconds {
+		ci := gopacket.CaptureInfo{Timestamp: time.Date(2020, 1, 2, 3, 4, s, 0, time.UTC), CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(ci, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func testPackets(t *testing.T, src *gopacket.PacketSource, want []ngTestPacket) {
+	for i, w := range want {
+		packet, err := src.NextPacket()
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if got := packet.Metadata().Timestamp; !got.Equal(w.ts) {
+			t.Errorf("packet %d: got %v\nwant %v", i, got, w.ts)
+		}
+		p, ok := decodeTCPPacket(packet)
+		if !ok {
+			t.Fatalf("packet %d: got no TCP packet\n%s", i, packet.Dump())
+		}
+		if got := p.srcAddr(); got != w.src {
+			t.Errorf("packet %d: got %v\nwant %v", i, got, w.src)
+		}
+		if got := captureValues(packet); !reflect.DeepEqual(got, w.wantValues) {
+			t.Errorf("packet %d: got %#v\nwant %#v", i, got, w.wantValues)
+		}
+	}
+	if _, err := src.NextPacket(); err != io.EOF {
+		t.Errorf("got %v\nwant %v", err, io.EOF)
+	}
+}
+
+// testPcapng return pcapng that has 2 interfaces ( Ethernet with nanosecond resolution, Raw IP with default resolution )
+func testPcapng(t *testing.T, bo binary.ByteOrder) []byte {
+	eth := concat(ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t))
+	raw := testIPv6TCP(t)
+	u16 := func(v uint16) []byte { b := make([]byte, 2); bo.PutUint16(b, v); return b }
+	u32 := func(v uint32) []byte { b := make([]byte, 4); bo.PutUint32(b, v); return b }
+	pad := func(b []byte) []byte { return append(b, make([]byte, (4-len(b)%4)%4)...) }
+	opt := func(code uint16, v []byte) []byte { return concat(u16(code), u16(uint16(len(v))), pad(v)) }
+	endOfOpt := u32(0)
+	block := func(typ uint32, body []byte) []byte {
+		length := uint32(12 + len(body))
+		return concat(u32(typ), u32(length), body, u32(length))
+	}
+	ts := func(t time.Time, unitsPerSec int64) []byte {
+		v := uint64(t.Unix()*unitsPerSec + int64(t.Nanosecond())*unitsPerSec/int64(time.Second))
+		return concat(u32(uint32(v>>32)), u32(uint32(v)))
+	}
+	epb := func(id uint32, t time.Time, unitsPerSec int64, data []byte, opts ...[]byte) []byte {
+		body := concat(u32(id), ts(t, unitsPerSec), u32(uint32(len(data))), u32(uint32(len(data))), pad(append([]byte{}, data...)))
+		if len(opts) > 0 {
+			body = concat(body, concat(opts...), endOfOpt)
+		}
+		return block(ngBlockTypeEnhancedPacket, body)
+	}
+
+	return concat(
+		block(ngBlockTypeSectionHeader, concat(u32(ngByteOrderMagic), u16(1), u16(0), u32(0xffffffff), u32(0xffffffff))),
+		block(ngBlockTypeInterfaceDescriptor, concat(u16(uint16(layers.LinkTypeEthernet)), u16(0), u32(0), opt(ngOptionCodeIfName, []byte("eth0")), opt(ngOptionCodeIfTsresol, []byte{9}), endOfOpt)),
+		block(ngBlockTypeInterfaceDescriptor, concat(u16(uint16(layers.LinkTypeRaw)), u16(0), u32(0), opt(ngOptionCodeIfName, []byte("tun0")), endOfOpt)),
+		epb(0, ngTestPackets[0].ts, 1000000000, eth, opt(ngOptionCodeComment, []byte("slow query")), opt(ngOptionCodeComment, []byte("retransmitted"))),
+		// Interface Statistics Block is skipped
+		block(0x00000005, concat(u32(0), u32(0), u32(0), endOfOpt)),
+		epb(1, ngTestPackets[1].ts, 1000000, raw),
+		block(ngBlockTypeSimplePacket, concat(u32(uint32(len(eth))), pad(append([]byte{}, eth...)))),
+	)
+}
@@ -8,10 +8,10 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/gopacket"
-	"github.com/google/gopacket/layers"
 	"github.com/k1LoW/tcpdp/dumper"
 	"github.com/rs/xid"
 	"go.uber.org/zap"
@@ -102,17 +102,29 @@ func NewBPFFilterString(target Target) string {
 	return fmt.Sprintf("tcp and (%s)", strings.Join(fs, " or "))
 }
 
+// NewEncapsulatedBPFFilterString extend BPF to capture VLAN / QinQ tagged packets and VXLAN / GRE ( ERSPAN ) tunneled packets.
+// Tunneled packets can not be filtered by inner address in BPF, so all of them are captured
+func NewEncapsulatedBPFFilterString(filter string) string {
+	// `vlan` changes the decoding offsets for the remainder of the expression
+	return fmt.Sprintf("(udp port 4789) or (ip proto 47) or (ip6 proto 47) or (%s) or (vlan and ((%s) or (vlan and (%s))))", filter, filter, filter)
+}
+
 // PacketReader struct
 type PacketReader struct {
-	ctx            context.Context
-	cancel         context.CancelFunc
-	packetSource   *gopacket.PacketSource
-	dumper         dumper.Dumper
-	pValues        []dumper.DumpValue
-	logger         *zap.Logger
-	packetBuffer   chan gopacket.Packet
-	proxyProtocol  bool
-	enableInternal bool
+	ctx               context.Context
+	cancel            context.CancelFunc
+	packetSource      *gopacket.PacketSource
+	dumper            dumper.Dumper
+	pValues           []dumper.DumpValue
+	logger            *zap.Logger
+	shards            []*shard
+	maxConnections    int           // 0: unlimited
+	connIdleTimeout   time.Duration // 0: no timeout
+	proxyProtocol     bool
+	enableInternal    bool
+	midStreamMappings []midStreamMapping // static mappings for connections seen mid-stream
+	ringBuffer        *RingBuffer
+	interfaceSources  []InterfacePacketSource
 }
 
 // NewPacketReader return PacketReader
@@ -124,34 +136,68 @@ func NewPacketReader(
 	pValues []dumper.DumpValue,
 	logger *zap.Logger,
 	internalBufferLength int,
+	shards int,
+	maxConnections int,
+	connIdleTimeout time.Duration,
 	proxyProtocol bool,
 	enableInternal bool,
 ) PacketReader {
-	internalPacketBuffer := make(chan gopacket.Packet, internalBufferLength)
+	if shards <= 0 {
+		shards = runtime.NumCPU()
+	}
 
 	reader := PacketReader{
-		ctx:            ctx,
-		cancel:         cancel,
-		packetSource:   packetSource,
-		dumper:         dumper,
-		pValues:        pValues,
-		logger:         logger,
-		packetBuffer:   internalPacketBuffer,
-		proxyProtocol:  proxyProtocol,
-		enableInternal: enableInternal,
+		ctx:             ctx,
+		cancel:          cancel,
+		packetSource:    packetSource,
+		dumper:          dumper,
+		pValues:         pValues,
+		logger:          logger,
+		shards:          newShards(shards, internalBufferLength),
+		maxConnections:  maxConnections,
+		connIdleTimeout: connIdleTimeout,
+		proxyProtocol:   proxyProtocol,
+		enableInternal:  enableInternal,
 	}
 
 	return reader
 }
 
+// SetRingBuffer set RingBuffer that keeps read packets and fires triggers
+func (r *PacketReader) SetRingBuffer(b *RingBuffer) {
+	r.ringBuffer = b
+}
+
 // ReadAndDump from gopacket.PacketSource
 func (r *PacketReader) ReadAndDump(target Target) error {
-	packetChan := r.packetSource.Packets()
+	return r.ReadAndDumpTargets([]TargetDumper{
+		TargetDumper{
+			Target: target,
+			Dumper: r.dumper,
+		},
+	})
+}
 
-	if r.dumper.Name() == "conn" {
-		go r.handleConn(target)
-	} else {
-		go r.handlePacket(target)
+// ReadAndDumpTargets from gopacket.PacketSource. Packets are dumped by the dumper of the first matched target ( or the dumper of PacketReader )
+// Packets are dispatched to shards by connection, so packets of a connection are handled in order
+func (r *PacketReader) ReadAndDumpTargets(tds []TargetDumper) error {
+	packetChan := r.packets()
+
+	wg := &sync.WaitGroup{}
+	for _, s := range r.shards {
+		wg.Add(1)
+		go func(s *shard) {
+			defer wg.Done()
+			_ = r.handlePacket(tds, s)
+		}(s)
+	}
+	go func() {
+		// all shards have handled the end of packets
+		wg.Wait()
+		r.cancel()
+	}()
+	if r.enableInternal {
+		go r.logInternalStats()
 	}
 	go r.checkBufferdPacket(packetChan)
 
@@ -160,94 +206,126 @@ func (r *PacketReader) ReadAndDump(target Target) error {
 		case <-r.ctx.Done():
 			return nil
 		case packet := <-packetChan:
-			r.packetBuffer <- packet
+			if packet == nil {
+				// end of packets. shards handle buffered packets then stop
+				for _, s := range r.shards {
+					close(s.packetBuffer)
+				}
+				<-r.ctx.Done()
+				return nil
+			}
+			if r.ringBuffer != nil {
+				r.ringBuffer.add(packet)
+			}
+			select {
+			case <-r.ctx.Done():
+				return nil
+			case r.shards[shardIndex(packet, len(r.shards))].packetBuffer <- packet:
+			}
 		}
 	}
 }
 
-func (r *PacketReader) handlePacket(target Target) error {
+// maxConnectionsPerShard return max size of connection table of a shard
+func (r *PacketReader) maxConnectionsPerShard() int {
+	if r.maxConnections <= 0 {
+		return 0
+	}
+	return (r.maxConnections + len(r.shards) - 1) / len(r.shards)
+}
+
+func (r *PacketReader) handlePacket(tds []TargetDumper, s *shard) error {
 	innerCtx, cancel := context.WithCancel(r.ctx)
 	defer cancel()
-	mMap := map[string]*dumper.ConnMetadata{} // metadata map per connection
-	mssMap := map[string]int{}                // TCP MSS map per connection
-	pMap := newPayloadBufferManager()         // long payload map per direction
-	var mem runtime.MemStats
+	pMap := newPayloadBufferManager() // long payload map per direction
+	cTable := newConnTable(r.maxConnectionsPerShard(), r.connIdleTimeout, func(e *connEntry, reason string) {
+		pMap.deleteBuffer(e.key)
+		r.logger.Info("evict connection",
+			zap.String("reason", reason),
+			zap.String("conn", e.key),
+			zap.String("conn_id", e.connID()),
+			zap.Time("last_seen", e.lastSeen),
+			zap.Int("shard", s.id))
+	}) // metadata and TCP MSS table per connection
 
 	go pMap.startPurgeTicker(innerCtx, r.logger)
 
+	// packets not matching any target
+	fallback := &TargetDumper{Dumper: r.dumper}
+
+	var statsC <-chan time.Time
 	if r.enableInternal {
-		go func() {
-			t := time.NewTicker(1 * time.Minute)
-			for {
-				select {
-				case <-innerCtx.Done():
-					return
-				case <-t.C:
-					runtime.ReadMemStats(&mem)
-					bSize := 0
-					pMap.lock()
-					for _, b := range pMap.buffers {
-						bSize = bSize + b.Size()
-					}
-					pMap.unlock()
-
-					r.logger.Info("tcpdp internal stats",
-						zap.Uint64("tcpdp Alloc", mem.Alloc),
-						zap.Uint64("tcpdp TotalAlloc", mem.TotalAlloc),
-						zap.Uint64("tcpdp Sys", mem.Sys),
-						zap.Uint64("tcpdp Lookups", mem.Lookups),
-						zap.Uint64("tcpdp Frees", mem.Frees),
-						zap.Uint64("tcpdp HeapAlloc", mem.HeapAlloc),
-						zap.Uint64("tcpdp HeapSys", mem.HeapSys),
-						zap.Uint64("tcpdp HeapIdle", mem.HeapIdle),
-						zap.Uint64("tcpdp HeapInuse", mem.HeapInuse),
-						zap.Uint64("tcpdp HeapReleased", mem.HeapReleased),
-						zap.Uint64("tcpdp HeapObjects", mem.HeapObjects),
-						zap.Uint64("tcpdp StackInuse", mem.StackInuse),
-						zap.Uint64("tcpdp StackSys", mem.StackSys),
-						zap.Int("packet handler metadata cache (mMap) length", len(mMap)),
-						zap.Int("packet handler TCP MSS cache (mssMap) length", len(mssMap)),
-						zap.Int("packet handler payload buffer cache (pMap) length", len(pMap.buffers)),
-						zap.Int("packet handler payload buffer cache (pMap) size", bSize))
-				}
-			}
-		}()
+		t := time.NewTicker(1 * time.Minute)
+		defer t.Stop()
+		statsC = t.C
 	}
 
 	for {
 		select {
 		case <-r.ctx.Done():
 			return nil
-		case packet := <-r.packetBuffer:
+		case <-statsC:
+			bSize := 0
+			pMap.lock()
+			for _, b := range pMap.buffers {
+				bSize = bSize + b.Size()
+			}
+			pLen := len(pMap.buffers)
+			pMap.unlock()
+
+			r.logger.Info("tcpdp internal shard stats",
+				zap.Int("shard", s.id),
+				zap.Int("packet handler queue length", len(s.packetBuffer)),
+				zap.Int("packet handler connection table length", cTable.len()),
+				zap.Int("packet handler evicted connections (max_connections)", cTable.evicted[evictMaxConnections]),
+				zap.Int("packet handler evicted connections (idle_timeout)", cTable.evicted[evictIdleTimeout]),
+				zap.Int("packet handler payload buffer cache (pMap) length", pLen),
+				zap.Int("packet handler payload buffer cache (pMap) size", bSize))
+		case packet := <-s.packetBuffer:
 			if packet == nil {
-				r.cancel()
 				return nil
 			}
-			ipLayer := packet.Layer(layers.LayerTypeIPv4)
-			if ipLayer == nil {
+			p, ok := decodeTCPPacket(packet)
+			if !ok {
 				continue
 			}
-			tcpLayer := packet.Layer(layers.LayerTypeTCP)
-			if tcpLayer == nil {
+			tcp := p.tcp
+
+			td, direction := matchTargetDumper(tds, p.srcIP, uint16(tcp.SrcPort), p.dstIP, uint16(tcp.DstPort))
+			if td == nil {
+				td = fallback
+			}
+			d := td.Dumper
+			if d.Name() == "conn" {
+				if err := r.dumpConn(td, packet, p); err != nil {
+					return err
+				}
 				continue
 			}
-			ip, _ := ipLayer.(*layers.IPv4)
-			tcp, _ := tcpLayer.(*layers.TCP)
+
+			ts := packet.Metadata().CaptureInfo.Timestamp
+			now := ts
+			if now.IsZero() {
+				now = time.Now()
+			}
+			cTable.evictIdle(now)
 
 			var key string
-			var direction dumper.Direction
-			srcToDstKey := fmt.Sprintf("%s:%d->%s:%d", ip.SrcIP.String(), tcp.SrcPort, ip.DstIP.String(), tcp.DstPort)
-			dstToSrcKey := fmt.Sprintf("%s:%d->%s:%d", ip.DstIP.String(), tcp.DstPort, ip.SrcIP.String(), tcp.SrcPort)
-			if target.Match(ip.DstIP.String(), uint16(tcp.DstPort)) {
+			srcToDstKey := p.srcToDstKey()
+			dstToSrcKey := p.dstToSrcKey()
+			switch direction {
+			case dumper.SrcToDst:
 				key = srcToDstKey
-				direction = dumper.SrcToDst
-			} else if target.Match(ip.SrcIP.String(), uint16(tcp.SrcPort)) {
+			case dumper.DstToSrc:
 				key = dstToSrcKey
-				direction = dumper.DstToSrc
-			} else {
-				key = "-"
-				direction = dumper.Unknown
+			default:
+				// direction is unknown, so the connection is keyed by the direction seen first
+				key = srcToDstKey
+				if _, ok := cTable.get(dstToSrcKey); ok {
+					key = dstToSrcKey
+				}
 			}
+			cTable.touch(key, now)
 
 			if tcp.SYN && !tcp.ACK {
 				if direction == dumper.Unknown {
@@ -255,90 +333,105 @@ func (r *PacketReader) handlePacket(target Target) error {
 				}
 
 				// TCP connection start
-				delete(mMap, key)
-				delete(mssMap, key)
+				cTable.delete(key)
 				pMap.deleteBuffer(key)
 
 				// TCP connection start ( hex, mysql, pg )
 				connID := xid.New().String()
 				mss := int(binary.BigEndian.Uint16(tcp.LayerContents()[22:24]))
-				connMetadata := r.dumper.NewConnMetadata()
+				connMetadata := d.NewConnMetadata()
 				connMetadata.DumpValues = []dumper.DumpValue{
 					dumper.DumpValue{
 						Key:   "conn_id",
 						Value: connID,
 					},
 				}
-				mMap[key] = connMetadata
-				mssMap[key] = mss
+				cTable.put(key, connMetadata, now).mss = mss
 				pMap.newBuffer(key, true)
 			} else if tcp.SYN && tcp.ACK {
 				if direction == dumper.Unknown {
 					key = dstToSrcKey
 				}
 
-				if _, ok := mMap[key]; !ok {
+				e, ok := cTable.get(key)
+				if !ok {
 					// TCP connection start ( hex, mysql, pg )
 					connID := xid.New().String()
-					connMetadata := r.dumper.NewConnMetadata()
+					connMetadata := d.NewConnMetadata()
 					connMetadata.DumpValues = []dumper.DumpValue{
 						dumper.DumpValue{
 							Key:   "conn_id",
 							Value: connID,
 						},
 					}
-					mMap[key] = connMetadata
+					e = cTable.put(key, connMetadata, now)
+				} else {
+					cTable.touch(key, now)
 				}
 
 				mss := int(binary.BigEndian.Uint16(tcp.LayerContents()[22:24]))
-				current, ok := mssMap[key]
-				if !ok || mss < current {
-					mssMap[key] = mss
+				if e.mss == 0 || mss < e.mss {
+					e.mss = mss
 				}
-				mMap[key].DumpValues = append(mMap[key].DumpValues, dumper.DumpValue{
+				e.metadata.DumpValues = append(e.metadata.DumpValues, dumper.DumpValue{
 					Key:   "mss",
 					Value: mss,
 				})
 			} else if tcp.FIN {
 				// TCP connection end (FIN=1)
-				delete(mssMap, key)
-				if _, ok := mMap[key]; ok {
-					mMap[key].Fin = true
+				if e, ok := cTable.get(key); ok {
+					e.mss = 0
+					e.metadata.Fin = true
+					cTable.touch(key, now)
 				}
 				pMap.deleteBuffer(key)
-			} else if _, ok := mMap[key]; ok && tcp.ACK && mMap[key].Fin {
+			} else if e, ok := cTable.get(key); ok && tcp.ACK && e.metadata.Fin {
 				// TCP connection end (ACK=1)
-				delete(mMap, key)
+				cTable.delete(key)
 				if direction == dumper.Unknown {
 					for _, key := range []string{srcToDstKey, dstToSrcKey} {
-						delete(mMap, key)
+						cTable.delete(key)
 					}
 				}
 				continue
 			} else if tcp.RST {
-				delete(mssMap, key)
-				delete(mMap, key)
+				cTable.delete(key)
 				if direction == dumper.Unknown {
 					for _, key := range []string{srcToDstKey, dstToSrcKey} {
-						delete(mMap, key)
+						cTable.delete(key)
 					}
 				}
 				pMap.deleteBuffer(key)
 				continue
 			}
 
-			in := tcpLayer.LayerPayload()
+			in := tcp.LayerPayload()
 			if len(in) == 0 {
 				continue
 			}
 
+			if r.ringBuffer != nil {
+				if e, ok := cTable.get(key); ok {
+					r.ringBuffer.checkLatency(e, direction, now, []dumper.DumpValue{
+						dumper.DumpValue{
+							Key:   "src_addr",
+							Value: p.srcAddr(),
+						},
+						dumper.DumpValue{
+							Key:   "dst_addr",
+							Value: p.dstAddr(),
+						},
+					})
+				}
+			}
+
 			pMap.newBuffer(key, false)
 
-			mss, ok := mssMap[key]
-			if ok {
-				maxPacketLen = mss - (len(tcp.LayerContents()) - 20)
+			packetLen := maxPacketLen
+			if e, ok := cTable.get(key); ok && e.mss > 0 {
+				packetLen = e.mss - (len(tcp.LayerContents()) - 20)
 			}
-			if len(in) == maxPacketLen {
+			if len(in) == packetLen {
 				pMap.lock()
 				pMap.buffers[key].Append(direction, in)
 				pMap.unlock()
@@ -352,21 +445,26 @@ func (r *PacketReader) handlePacket(target Target) error {
 			pMap.buffers[key].Delete(direction)
 			pMap.unlock()
 
-			if direction == dumper.Unknown {
-				for _, k := range []string{srcToDstKey, dstToSrcKey} {
-					_, ok := mMap[k]
-					if ok {
-						key = k
-					}
+			var connMetadata *dumper.ConnMetadata
+			if e, ok := cTable.get(key); ok {
+				connMetadata = e.metadata
+			} else {
+				// TCP connection seen mid-stream ( without SYN )
+				connMetadata = d.NewConnMetadata()
+				connMetadata.MidStream = true
+				connMetadata.DumpValues = []dumper.DumpValue{
+					dumper.DumpValue{
+						Key:   "conn_id",
+						Value: xid.New().String(),
+					},
+				}
+				if direction == dumper.DstToSrc {
+					connMetadata.DumpValues = append(connMetadata.DumpValues, r.midStreamValues(p.srcIP, uint16(tcp.SrcPort), p.dstIP)...)
+				} else {
+					connMetadata.DumpValues = append(connMetadata.DumpValues, r.midStreamValues(p.dstIP, uint16(tcp.DstPort), p.srcIP)...)
 				}
 			}
-
-			connMetadata, ok := mMap[key]
-			if !ok {
-				connMetadata = r.dumper.NewConnMetadata()
-			}
-
-			ts := packet.Metadata().CaptureInfo.Timestamp
+			connMetadata.Ts = ts
 
 			values := []dumper.DumpValue{
 				dumper.DumpValue{
@@ -375,15 +473,21 @@ func (r *PacketReader) handlePacket(target Target) error {
 				},
 				dumper.DumpValue{
 					Key:   "src_addr",
-					Value: fmt.Sprintf("%s:%d", ip.SrcIP.String(), tcp.SrcPort),
+					Value: p.srcAddr(),
 				},
 				dumper.DumpValue{
 					Key:   "dst_addr",
-					Value: fmt.Sprintf("%s:%d", ip.DstIP.String(), tcp.DstPort),
+					Value: p.dstAddr(),
 				},
 			}
+			values = append(values, p.values...)
+			values = append(values, captureValues(packet)...)
 
-			var read []dumper.DumpValue
+			if r.ringBuffer != nil && direction == dumper.DstToSrc {
+				r.ringBuffer.checkErrorResponse(d, in, values)
+			}
+
+			var records [][]dumper.DumpValue
 			var err error
 			if r.proxyProtocol {
 				seek, ppValues, err := ParseProxyProtocolHeader(in)
@@ -393,151 +497,160 @@ func (r *PacketReader) handlePacket(target Target) error {
 					return err
 				}
 				connMetadata.DumpValues = append(connMetadata.DumpValues, ppValues...)
-				read, err = r.dumper.Read(in[seek:], direction, connMetadata)
+				records, err = readRecords(d, in[seek:], direction, connMetadata)
 				if err != nil {
 
 					values = append(values, dumper.DumpValue{
 						Key:   "error",
 						Value: err,
 					})
-					values = append(values, read...)
+					if len(records) > 0 {
+						values = append(values, records[0]...)
+					}
 					values = append(values, r.pValues...)
+					values = append(values, td.Values...)
 					values = append(values, connMetadata.DumpValues...)
-					r.dumper.Log(values)
+					d.Log(values)
+					if r.ringBuffer != nil {
+						r.ringBuffer.checkDumperError(values)
+					}
 
 					pMap.deleteBuffer(key)
 					// error but continue
 					continue
 				}
 			} else {
-				read, err = r.dumper.Read(in, direction, connMetadata)
+				records, err = readRecords(d, in, direction, connMetadata)
 				if err != nil {
 
 					values = append(values, dumper.DumpValue{
 						Key:   "error",
 						Value: err,
 					})
-					values = append(values, read...)
+					if len(records) > 0 {
+						values = append(values, records[0]...)
+					}
 					values = append(values, r.pValues...)
+					values = append(values, td.Values...)
 					values = append(values, connMetadata.DumpValues...)
-					r.dumper.Log(values)
+					d.Log(values)
+					if r.ringBuffer != nil {
+						r.ringBuffer.checkDumperError(values)
+					}
 
 					pMap.deleteBuffer(key)
 					// error but continue
 					continue
 				}
 			}
-			mMap[key] = connMetadata
-			if len(read) == 0 {
-				continue
+			cTable.put(key, connMetadata, now)
+			if r.ringBuffer != nil {
+				r.ringBuffer.checkQuery(records, values)
 			}
 
-			values = append(values, read...)
-			values = append(values, r.pValues...)
-			values = append(values, connMetadata.DumpValues...)
+			for _, read := range records {
+				rv := []dumper.DumpValue{}
+				rv = append(rv, values...)
+				rv = append(rv, read...)
+				rv = append(rv, r.pValues...)
+				rv = append(rv, td.Values...)
+				rv = append(rv, connMetadata.DumpValues...)
 
-			r.dumper.Log(values)
+				d.Log(rv)
+			}
 		}
 	}
 }
 
-func (r *PacketReader) handleConn(target Target) error {
-	innerCtx, cancel := context.WithCancel(r.ctx)
-	defer cancel()
-	var mem runtime.MemStats
+// readRecords returns records of a read. A dumper implementing dumper.FrameReader returns a record per frame
+func readRecords(d dumper.Dumper, in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([][]dumper.DumpValue, error) {
+	if fr, ok := d.(dumper.FrameReader); ok {
+		return fr.ReadFrames(in, direction, connMetadata)
+	}
+	read, err := d.Read(in, direction, connMetadata)
+	if len(read) == 0 {
+		return [][]dumper.DumpValue{}, err
+	}
+	return [][]dumper.DumpValue{read}, err
+}
 
-	if r.enableInternal {
-		go func() {
-			t := time.NewTicker(1 * time.Minute)
-			for {
-				select {
-				case <-innerCtx.Done():
-					return
-				case <-t.C:
-					runtime.ReadMemStats(&mem)
-					r.logger.Info("tcpdp internal stats",
-						zap.Uint64("tcpdp Alloc", mem.Alloc),
-						zap.Uint64("tcpdp TotalAlloc", mem.TotalAlloc),
-						zap.Uint64("tcpdp Sys", mem.Sys),
-						zap.Uint64("tcpdp Lookups", mem.Lookups),
-						zap.Uint64("tcpdp Frees", mem.Frees),
-						zap.Uint64("tcpdp HeapAlloc", mem.HeapAlloc),
-						zap.Uint64("tcpdp HeapSys", mem.HeapSys),
-						zap.Uint64("tcpdp HeapIdle", mem.HeapIdle),
-						zap.Uint64("tcpdp HeapInuse", mem.HeapInuse),
-						zap.Uint64("tcpdp HeapReleased", mem.HeapReleased),
-						zap.Uint64("tcpdp HeapObjects", mem.HeapObjects),
-						zap.Uint64("tcpdp StackInuse", mem.StackInuse),
-						zap.Uint64("tcpdp StackSys", mem.StackSys),
-					)
-				}
-			}
-		}()
+// dumpConn dump TCP connection start ( conn )
+func (r *PacketReader) dumpConn(td *TargetDumper, packet gopacket.Packet, p *tcpPacket) error {
+	tcp := p.tcp
+	if !(tcp.SYN && !tcp.ACK) {
+		return nil
 	}
 
+	connID := xid.New().String()
+	connMetadata := td.Dumper.NewConnMetadata()
+	connMetadata.DumpValues = []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "conn_id",
+			Value: connID,
+		},
+	}
+	in := tcp.LayerPayload()
+	ts := packet.Metadata().CaptureInfo.Timestamp
+	values := []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "ts",
+			Value: ts,
+		},
+		dumper.DumpValue{
+			Key:   "src_addr",
+			Value: p.srcAddr(),
+		},
+		dumper.DumpValue{
+			Key:   "dst_addr",
+			Value: p.dstAddr(),
+		},
+	}
+	values = append(values, p.values...)
+	values = append(values, captureValues(packet)...)
+
+	if r.proxyProtocol {
+		_, ppValues, err := ParseProxyProtocolHeader(in)
+		if err != nil {
+			r.cancel()
+			r.logger.WithOptions(zap.AddCaller()).Fatal("error", zap.Error(err))
+			return err
+		}
+		connMetadata.DumpValues = append(connMetadata.DumpValues, ppValues...)
+	}
+	values = append(values, r.pValues...)
+	values = append(values, td.Values...)
+	values = append(values, connMetadata.DumpValues...)
+
+	td.Dumper.Log(values)
+	return nil
+}
+
+func (r *PacketReader) logInternalStats() {
+	var mem runtime.MemStats
+	t := time.NewTicker(1 * time.Minute)
+	defer t.Stop()
 	for {
 		select {
 		case <-r.ctx.Done():
-			return nil
-		case packet := <-r.packetBuffer:
-			if packet == nil {
-				r.cancel()
-				return nil
-			}
-			ipLayer := packet.Layer(layers.LayerTypeIPv4)
-			if ipLayer == nil {
-				continue
-			}
-			tcpLayer := packet.Layer(layers.LayerTypeTCP)
-			if tcpLayer == nil {
-				continue
-			}
-			ip, _ := ipLayer.(*layers.IPv4)
-			tcp, _ := tcpLayer.(*layers.TCP)
-
-			if !(tcp.SYN && !tcp.ACK) {
-				continue
-			}
-
-			// TCP connection start ( hex, mysql, pg )
-			connID := xid.New().String()
-			connMetadata := r.dumper.NewConnMetadata()
-			connMetadata.DumpValues = []dumper.DumpValue{
-				dumper.DumpValue{
-					Key:   "conn_id",
-					Value: connID,
-				},
-			}
-			in := tcpLayer.LayerPayload()
-			ts := packet.Metadata().CaptureInfo.Timestamp
-			values := []dumper.DumpValue{
-				dumper.DumpValue{
-					Key:   "ts",
-					Value: ts,
-				},
-				dumper.DumpValue{
-					Key:   "src_addr",
-					Value: fmt.Sprintf("%s:%d", ip.SrcIP.String(), tcp.SrcPort),
-				},
-				dumper.DumpValue{
-					Key:   "dst_addr",
-					Value: fmt.Sprintf("%s:%d", ip.DstIP.String(), tcp.DstPort),
-				},
-			}
-
-			if r.proxyProtocol {
-				_, ppValues, err := ParseProxyProtocolHeader(in)
-				if err != nil {
-					r.cancel()
-					r.logger.WithOptions(zap.AddCaller()).Fatal("error", zap.Error(err))
-					return err
-				}
-				connMetadata.DumpValues = append(connMetadata.DumpValues, ppValues...)
-			}
-			values = append(values, r.pValues...)
-			values = append(values, connMetadata.DumpValues...)
-
-			r.dumper.Log(values)
+			return
+		case <-t.C:
+			runtime.ReadMemStats(&mem)
+			r.logger.Info("tcpdp internal stats",
+				zap.Uint64("tcpdp Alloc", mem.Alloc),
+				zap.Uint64("tcpdp TotalAlloc", mem.TotalAlloc),
+				zap.Uint64("tcpdp Sys", mem.Sys),
+				zap.Uint64("tcpdp Lookups", mem.Lookups),
+				zap.Uint64("tcpdp Frees", mem.Frees),
+				zap.Uint64("tcpdp HeapAlloc", mem.HeapAlloc),
+				zap.Uint64("tcpdp HeapSys", mem.HeapSys),
+				zap.Uint64("tcpdp HeapIdle", mem.HeapIdle),
+				zap.Uint64("tcpdp HeapInuse", mem.HeapInuse),
+				zap.Uint64("tcpdp HeapReleased", mem.HeapReleased),
+				zap.Uint64("tcpdp HeapObjects", mem.HeapObjects),
+				zap.Uint64("tcpdp StackInuse", mem.StackInuse),
+				zap.Uint64("tcpdp StackSys", mem.StackSys),
+				zap.Int("packet handler shards", len(r.shards)),
+			)
 		}
 	}
 }
@@ -551,9 +664,19 @@ L:
 			break L
 		case <-t.C:
 			packetBuffered := len(packetChan)
-			internalPacketBuffered := len(r.packetBuffer)
-			if internalPacketBuffered > (cap(r.packetBuffer)/10) || packetBuffered > (cap(packetChan)/10) {
-				r.logger.Info("buffered packet stats", zap.Int("internal_buffered", internalPacketBuffered), zap.Int("packet_buffered", packetBuffered))
+			internalPacketBuffered := 0
+			shardBuffered := []int{}
+			full := false
+			for _, s := range r.shards {
+				l := len(s.packetBuffer)
+				internalPacketBuffered = internalPacketBuffered + l
+				shardBuffered = append(shardBuffered, l)
+				if l > (cap(s.packetBuffer) / 10) {
+					full = true
+				}
+			}
+			if full || packetBuffered > (cap(packetChan)/10) {
+				r.logger.Info("buffered packet stats", zap.Int("internal_buffered", internalPacketBuffered), zap.Ints("shard_buffered", shardBuffered), zap.Int("packet_buffered", packetBuffered))
 			}
 		}
 	}
@@ -1,8 +1,14 @@
 package reader
 
 import (
+	"context"
 	"reflect"
 	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
 )
 
 var parseTargetTests = []struct {
@@ -224,3 +230,37 @@ func TestNewBPFFilterString(t *testing.T) {
 		}
 	}
 }
+
+func TestReadAndDumpUnknownDirection(t *testing.T) {
+	// without target, direction of all packets is unknown
+	packets := []gopacket.Packet{
+		newTestPacket(t, "10.0.0.1", 40000, "10.0.0.2", 3306, testTCPData, []byte("conn1 request")),
+		newTestPacket(t, "10.0.0.3", 40001, "10.0.0.2", 3306, testTCPData, []byte("conn2 request")),
+		newTestPacket(t, "10.0.0.2", 3306, "10.0.0.1", 40000, testTCPData, []byte("conn1 response")),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newTestDumper()
+	r := NewPacketReader(ctx, cancel, gopacket.NewPacketSource(&testPacketDataSource{packets: packets}, layers.LayerTypeEthernet), d, []dumper.DumpValue{}, zap.NewNop(), 10, 1, 0, 0, false, false)
+	target, err := ParseTarget("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReadAndDump(target); err != nil {
+		t.Fatal(err)
+	}
+
+	conn1 := d.connIDs["10.0.0.1:40000"]
+	conn2 := d.connIDs["10.0.0.3:40001"]
+	res := d.connIDs["10.0.0.2:3306"]
+	if len(conn1) != 1 || len(conn2) != 1 || len(res) != 1 {
+		t.Fatalf("got %v", d.connIDs)
+	}
+	if conn1[0] == "" || conn1[0] == conn2[0] {
+		t.Errorf("got same conn_id %v for different connections", conn1[0])
+	}
+	if res[0] != conn1[0] {
+		t.Errorf("got %v\nwant %v", res[0], conn1[0])
+	}
+}
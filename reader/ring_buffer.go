@@ -0,0 +1,293 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// triggers of RingBuffer
+const (
+	TriggerSignal       = "signal"
+	triggerDumperError  = "dumper_error"
+	triggerErrorCode    = "error_code"
+	triggerQueryPattern = "query_pattern"
+	triggerLatency      = "latency"
+)
+
+const anyErrorCode = "*"
+
+// pcapRecordHeaderLen is length of record header of packet in pcap file
+const pcapRecordHeaderLen = 16
+
+// RingBufferConfig struct
+type RingBufferConfig struct {
+	Duration            time.Duration // keep packets captured in the last duration. 0 is unlimited
+	Size                int           // keep packets up to size (bytes) of exported pcap file ( packet data and record headers ). 0 is unlimited
+	PostTriggerDuration time.Duration // keep capturing for this duration after trigger before export
+	Cooldown            time.Duration // triggers for this duration after export are not exported
+	Dir                 string
+	DumperError         bool
+	ErrorCodes          []string // MySQL error code or PostgreSQL SQLSTATE of error response. "*" is any error
+	QueryPattern        string
+	Latency             time.Duration // response latency threshold. 0 is disabled
+	Snaplen             int           // snapshot length of capture written in pcap file header. 0 is 65535
+}
+
+// RingBuffer keeps the last raw packets in memory and writes them to pcap file when a trigger fires
+type RingBuffer struct {
+	ctx          context.Context
+	logger       *zap.Logger
+	config       RingBufferConfig
+	linkType     layers.LinkType
+	queryPattern *regexp.Regexp
+	errorCodes   map[string]struct{}
+	triggerChan  chan string
+	done         chan struct{}
+	mutex        sync.Mutex
+	packets      []gopacket.Packet
+	size         int
+	triggerMutex sync.Mutex
+	exporting    bool      // trigger is accepted and packets are not exported yet
+	lastExport   time.Time // triggers until lastExport + Cooldown are skipped
+	skipped      int       // number of skipped triggers since the last accepted trigger
+}
+
+// NewRingBuffer return RingBuffer and start exporter
+func NewRingBuffer(ctx context.Context, logger *zap.Logger, config RingBufferConfig, linkType layers.LinkType) (*RingBuffer, error) {
+	if config.Duration <= 0 && config.Size <= 0 {
+		return nil, errors.New("ring buffer requires duration or size")
+	}
+	var queryPattern *regexp.Regexp
+	if config.QueryPattern != "" {
+		re, err := regexp.Compile(config.QueryPattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid query pattern")
+		}
+		queryPattern = re
+	}
+	errorCodes := map[string]struct{}{}
+	for _, c := range config.ErrorCodes {
+		errorCodes[c] = struct{}{}
+	}
+	b := &RingBuffer{
+		ctx:          ctx,
+		logger:       logger,
+		config:       config,
+		linkType:     linkType,
+		queryPattern: queryPattern,
+		errorCodes:   errorCodes,
+		triggerChan:  make(chan string, 1),
+		done:         make(chan struct{}),
+		packets:      []gopacket.Packet{},
+	}
+	go b.startExporter()
+	return b, nil
+}
+
+// add packet and drop packets out of duration or size
+func (b *RingBuffer) add(packet gopacket.Packet) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.packets = append(b.packets, packet)
+	b.size = b.size + len(packet.Data()) + pcapRecordHeaderLen
+
+	ts := packet.Metadata().Timestamp
+	drop := 0
+	for drop < len(b.packets)-1 {
+		p := b.packets[drop]
+		if (b.config.Size > 0 && b.size > b.config.Size) || (b.config.Duration > 0 && ts.Sub(p.Metadata().Timestamp) > b.config.Duration) {
+			b.size = b.size - len(p.Data()) - pcapRecordHeaderLen
+			b.packets[drop] = nil
+			drop++
+			continue
+		}
+		break
+	}
+	b.packets = b.packets[drop:]
+}
+
+// Trigger export of packets. Triggers while waiting for export or in cooldown are skipped and logged only for signal
+func (b *RingBuffer) Trigger(reason string, values []dumper.DumpValue) {
+	b.triggerMutex.Lock()
+	if b.exporting || (!b.lastExport.IsZero() && time.Since(b.lastExport) < b.config.Cooldown) {
+		b.skipped++
+		b.triggerMutex.Unlock()
+		if reason == TriggerSignal {
+			b.logger.Info("ring buffer trigger skipped ( exporting or cooldown )", zap.String("trigger", reason))
+		}
+		return
+	}
+	b.exporting = true
+	skipped := b.skipped
+	b.skipped = 0
+	b.triggerMutex.Unlock()
+
+	fields := []zap.Field{
+		zap.String("trigger", reason),
+		zap.Int("skipped_triggers", skipped),
+	}
+	for _, kv := range values {
+		fields = append(fields, zap.Any(kv.Key, kv.Value))
+	}
+	b.logger.Info("ring buffer triggered", fields...)
+	select {
+	case b.triggerChan <- reason:
+	default:
+	}
+}
+
+// exported end export and start cooldown
+func (b *RingBuffer) exported(now time.Time) {
+	b.triggerMutex.Lock()
+	defer b.triggerMutex.Unlock()
+	b.exporting = false
+	b.lastExport = now
+}
+
+// Done return channel closed when exporter stops
+func (b *RingBuffer) Done() <-chan struct{} {
+	return b.done
+}
+
+func (b *RingBuffer) startExporter() {
+	defer close(b.done)
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case reason := <-b.triggerChan:
+			if b.config.PostTriggerDuration > 0 {
+				t := time.NewTimer(b.config.PostTriggerDuration)
+				select {
+				case <-b.ctx.Done():
+					// export packets captured until shutdown
+				case <-t.C:
+				}
+				t.Stop()
+			}
+			now := time.Now()
+			path, n, err := b.export(reason, now)
+			b.exported(now)
+			if err != nil {
+				b.logger.WithOptions(zap.AddCaller()).Error("ring buffer export error", zap.String("trigger", reason), zap.Error(err))
+				continue
+			}
+			b.logger.Info("ring buffer exported", zap.String("trigger", reason), zap.String("file", path), zap.Int("packets", n))
+		}
+	}
+}
+
+// export write packets in buffer to pcap file
+func (b *RingBuffer) export(reason string, now time.Time) (string, int, error) {
+	b.mutex.Lock()
+	packets := make([]gopacket.Packet, len(b.packets))
+	copy(packets, b.packets)
+	b.mutex.Unlock()
+
+	path, err := filepath.Abs(filepath.Join(b.config.Dir, fmt.Sprintf("tcpdp_ring_%s_%s.pcap", now.Format("20060102T150405.000"), reason)))
+	if err != nil {
+		return "", 0, err
+	}
+	// #nosec
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	snaplen := b.config.Snaplen
+	if snaplen <= 0 {
+		snaplen = maxPacketLen
+	}
+	w := pcapgo.NewWriter(f)
+	if err := w.WriteFileHeader(uint32(snaplen), b.linkType); err != nil {
+		return "", 0, err
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(p.Metadata().CaptureInfo, p.Data()); err != nil {
+			return "", 0, err
+		}
+	}
+	return path, len(packets), f.Close()
+}
+
+// checkDumperError fire trigger on error of dumper
+func (b *RingBuffer) checkDumperError(values []dumper.DumpValue) {
+	if b.config.DumperError {
+		b.Trigger(triggerDumperError, values)
+	}
+}
+
+// checkErrorResponse fire trigger on error response from server
+func (b *RingBuffer) checkErrorResponse(d dumper.Dumper, in []byte, values []dumper.DumpValue) {
+	if len(b.errorCodes) == 0 {
+		return
+	}
+	er, ok := d.(dumper.ErrorResponseReader)
+	if !ok {
+		return
+	}
+	code, ok := er.ReadErrorResponse(in)
+	if !ok {
+		return
+	}
+	_, match := b.errorCodes[code]
+	_, matchAny := b.errorCodes[anyErrorCode]
+	if match || matchAny {
+		b.Trigger(triggerErrorCode, append(values, dumper.DumpValue{
+			Key:   "error_code",
+			Value: code,
+		}))
+	}
+}
+
+// checkQuery fire trigger on query matching pattern
+func (b *RingBuffer) checkQuery(records [][]dumper.DumpValue, values []dumper.DumpValue) {
+	if b.queryPattern == nil {
+		return
+	}
+	for _, read := range records {
+		for _, kv := range read {
+			if q, ok := kv.Value.(string); ok && kv.Key == "query" && b.queryPattern.MatchString(q) {
+				b.Trigger(triggerQueryPattern, append(values, kv))
+				return
+			}
+		}
+	}
+}
+
+// checkLatency fire trigger when the first response to a request is later than threshold
+func (b *RingBuffer) checkLatency(e *connEntry, direction dumper.Direction, ts time.Time, values []dumper.DumpValue) {
+	if b.config.Latency <= 0 {
+		return
+	}
+	switch direction {
+	case dumper.SrcToDst:
+		if e.requestTs.IsZero() {
+			e.requestTs = ts
+		}
+	case dumper.DstToSrc:
+		if e.requestTs.IsZero() {
+			return
+		}
+		latency := ts.Sub(e.requestTs)
+		e.requestTs = time.Time{}
+		if latency >= b.config.Latency {
+			b.Trigger(triggerLatency, append(values, dumper.DumpValue{
+				Key:   "latency",
+				Value: latency.String(),
+			}))
+		}
+	}
+}
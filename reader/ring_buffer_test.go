@@ -0,0 +1,321 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/k1LoW/tcpdp/dumper/mysql"
+	"github.com/k1LoW/tcpdp/dumper/pg"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var ringBufferAddTests = []struct {
+	description string
+	duration    time.Duration
+	size        int
+	want        []int // seconds of timestamps
+}{
+	{
+		"Duration",
+		3 * time.Second,
+		0,
+		[]int{3, 4, 5, 6},
+	},
+	{
+		"Size",
+		0,
+		3 * (len(testRingPacketData) + pcapRecordHeaderLen),
+		[]int{4, 5, 6},
+	},
+	{
+		"Duration and size",
+		2 * time.Second,
+		4 * (len(testRingPacketData) + pcapRecordHeaderLen),
+		[]int{4, 5, 6},
+	},
+	{
+		"Keep the last packet",
+		0,
+		1,
+		[]int{6},
+	},
+}
+
+var testRingPacketData = concat(ethernetHeader(layers.EthernetTypeIPv4), []byte{0x45, 0x00, 0x00, 0x14})
+
+func TestRingBufferAdd(t *testing.T) {
+	for _, tt := range ringBufferAddTests {
+		b := newTestRingBuffer(RingBufferConfig{Duration: tt.duration, Size: tt.size})
+		for s := 1; s <= 6; s++ {
+			b.add(newTestRingPacket(testRingPacketData, s))
+		}
+		got := []int{}
+		for _, p := range b.packets {
+			got = append(got, p.Metadata().Timestamp.Second())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s\ngot %v\nwant %v", tt.description, got, tt.want)
+		}
+		if want := len(tt.want) * (len(testRingPacketData) + pcapRecordHeaderLen); b.size != want {
+			t.Errorf("%s\ngot %v\nwant %v", tt.description, b.size, want)
+		}
+	}
+}
+
+func TestNewRingBufferInvalid(t *testing.T) {
+	for _, c := range []RingBufferConfig{
+		RingBufferConfig{},
+		RingBufferConfig{Duration: time.Second, QueryPattern: "("},
+	} {
+		if _, err := NewRingBuffer(context.Background(), zap.NewNop(), c, layers.LinkTypeEthernet); err == nil {
+			t.Errorf("%#v: want error", c)
+		}
+	}
+}
+
+func TestRingBufferExport(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b, err := NewRingBuffer(ctx, zap.NewNop(), RingBufferConfig{Duration: time.Minute, Dir: dir, Cooldown: time.Minute, Snaplen: 1518}, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := concat(ethernetHeader(layers.EthernetTypeIPv4), testIPv4TCP(t))
+	for s := 1; s <= 3; s++ {
+		b.add(newTestRingPacket(data, s))
+	}
+	b.Trigger(TriggerSignal, []dumper.DumpValue{})
+
+	var files []string
+	for i := 0; i < 100 && len(files) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		files, _ = filepath.Glob(filepath.Join(dir, "tcpdp_ring_*_signal.pcap"))
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %v\nwant 1 file", files)
+	}
+	// wait for export
+	cancel()
+	<-b.Done()
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pr, err := pcapgo.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pr.Snaplen(); got != 1518 {
+		t.Errorf("got snaplen %v\nwant %v", got, 1518)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	src := newTestPcapPacketSource(t, f)
+	n := 0
+	for {
+		packet, err := src.NextPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := decodeTCPPacket(packet); !ok {
+			t.Errorf("got no TCP packet\n%s", packet.Dump())
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("got %v\nwant %v", n, 3)
+	}
+}
+
+var ringBufferTriggerTests = []struct {
+	description string
+	config      RingBufferConfig
+	fire        func(b *RingBuffer)
+	want        string
+}{
+	{
+		"Dumper error",
+		RingBufferConfig{DumperError: true},
+		func(b *RingBuffer) { b.checkDumperError([]dumper.DumpValue{}) },
+		triggerDumperError,
+	},
+	{
+		"Dumper error disabled",
+		RingBufferConfig{},
+		func(b *RingBuffer) { b.checkDumperError([]dumper.DumpValue{}) },
+		"",
+	},
+	{
+		"MySQL error code",
+		RingBufferConfig{ErrorCodes: []string{"1213"}},
+		func(b *RingBuffer) {
+			b.checkErrorResponse(mysql.NewDumper(), []byte{0x05, 0x00, 0x00, 0x01, 0xff, 0xbd, 0x04, 'E', 'R'}, []dumper.DumpValue{})
+		},
+		triggerErrorCode,
+	},
+	{
+		"MySQL other error code",
+		RingBufferConfig{ErrorCodes: []string{"1062"}},
+		func(b *RingBuffer) {
+			b.checkErrorResponse(mysql.NewDumper(), []byte{0x05, 0x00, 0x00, 0x01, 0xff, 0xbd, 0x04, 'E', 'R'}, []dumper.DumpValue{})
+		},
+		"",
+	},
+	{
+		"PostgreSQL any error",
+		RingBufferConfig{ErrorCodes: []string{anyErrorCode}},
+		func(b *RingBuffer) {
+			b.checkErrorResponse(pg.NewDumper(), []byte{'E', 0x00, 0x00, 0x00, 0x0b, 'C', '4', '0', 'P', '0', '1', 0x00}, []dumper.DumpValue{})
+		},
+		triggerErrorCode,
+	},
+	{
+		"Query pattern",
+		RingBufferConfig{QueryPattern: `(?i)^drop\s`},
+		func(b *RingBuffer) {
+			b.checkQuery([][]dumper.DumpValue{
+				[]dumper.DumpValue{dumper.DumpValue{Key: "query", Value: "SELECT 1"}},
+				[]dumper.DumpValue{dumper.DumpValue{Key: "query", Value: "drop table users"}},
+			}, []dumper.DumpValue{})
+		},
+		triggerQueryPattern,
+	},
+	{
+		"Query not matching pattern",
+		RingBufferConfig{QueryPattern: `(?i)^drop\s`},
+		func(b *RingBuffer) {
+			b.checkQuery([][]dumper.DumpValue{
+				[]dumper.DumpValue{dumper.DumpValue{Key: "query", Value: "SELECT 'drop table'"}},
+			}, []dumper.DumpValue{})
+		},
+		"",
+	},
+	{
+		"Latency",
+		RingBufferConfig{Latency: time.Second},
+		func(b *RingBuffer) {
+			e := &connEntry{}
+			ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			b.checkLatency(e, dumper.SrcToDst, ts, []dumper.DumpValue{})
+			b.checkLatency(e, dumper.SrcToDst, ts.Add(500*time.Millisecond), []dumper.DumpValue{})
+			b.checkLatency(e, dumper.DstToSrc, ts.Add(1500*time.Millisecond), []dumper.DumpValue{})
+		},
+		triggerLatency,
+	},
+	{
+		"Latency under threshold",
+		RingBufferConfig{Latency: time.Second},
+		func(b *RingBuffer) {
+			e := &connEntry{}
+			ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			b.checkLatency(e, dumper.SrcToDst, ts, []dumper.DumpValue{})
+			b.checkLatency(e, dumper.DstToSrc, ts.Add(500*time.Millisecond), []dumper.DumpValue{})
+			b.checkLatency(e, dumper.DstToSrc, ts.Add(1500*time.Millisecond), []dumper.DumpValue{})
+		},
+		"",
+	},
+}
+
+func TestRingBufferTrigger(t *testing.T) {
+	for _, tt := range ringBufferTriggerTests {
+		b := newTestRingBuffer(tt.config)
+		tt.fire(b)
+		got := ""
+		select {
+		case got = <-b.triggerChan:
+		default:
+		}
+		if got != tt.want {
+			t.Errorf("%s\ngot %#v\nwant %#v", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestRingBufferTriggerSkipped(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	b := newTestRingBuffer(RingBufferConfig{Size: 1, Cooldown: time.Hour})
+	b.logger = zap.New(core)
+
+	for i := 0; i < 3; i++ {
+		b.Trigger(triggerDumperError, []dumper.DumpValue{})
+	}
+	if got := <-b.triggerChan; got != triggerDumperError {
+		t.Errorf("got %#v\nwant %#v", got, triggerDumperError)
+	}
+	// exporting
+	b.Trigger(triggerLatency, []dumper.DumpValue{})
+	b.exported(time.Now())
+	// cooldown
+	b.Trigger(triggerDumperError, []dumper.DumpValue{})
+	b.Trigger(TriggerSignal, []dumper.DumpValue{})
+
+	select {
+	case got := <-b.triggerChan:
+		t.Errorf("got %#v\nwant no trigger", got)
+	default:
+	}
+	if got := logs.FilterMessage("ring buffer triggered").Len(); got != 1 {
+		t.Errorf("got %v\nwant %v", got, 1)
+	}
+	if got := logs.FilterMessageSnippet("skipped").Len(); got != 1 {
+		t.Errorf("got %v\nwant %v", got, 1)
+	}
+
+	// cooldown is over
+	b.lastExport = time.Now().Add(-2 * time.Hour)
+	b.Trigger(triggerQueryPattern, []dumper.DumpValue{})
+	if got := <-b.triggerChan; got != triggerQueryPattern {
+		t.Errorf("got %#v\nwant %#v", got, triggerQueryPattern)
+	}
+	entries := logs.FilterMessage("ring buffer triggered").All()
+	if len(entries) != 2 {
+		t.Fatalf("got %v\nwant %v", len(entries), 2)
+	}
+	if got := entries[1].ContextMap()["skipped_triggers"]; got != int64(5) {
+		t.Errorf("got %#v\nwant %#v", got, int64(5))
+	}
+}
+
+// newTestRingBuffer return RingBuffer without exporter
+func newTestRingBuffer(config RingBufferConfig) *RingBuffer {
+	b := &RingBuffer{
+		logger:      zap.NewNop(),
+		config:      config,
+		linkType:    layers.LinkTypeEthernet,
+		errorCodes:  map[string]struct{}{},
+		triggerChan: make(chan string, 1),
+		packets:     []gopacket.Packet{},
+	}
+	if config.QueryPattern != "" {
+		b.queryPattern = regexp.MustCompile(config.QueryPattern)
+	}
+	for _, c := range config.ErrorCodes {
+		b.errorCodes[c] = struct{}{}
+	}
+	return b
+}
+
+func newTestRingPacket(data []byte, second int) gopacket.Packet {
+	packet := gopacket.NewPacket(data, layers.LinkTypeEthernet, gopacket.Default)
+	packet.Metadata().Timestamp = time.Date(2020, 1, 2, 3, 4, second, 0, time.UTC)
+	packet.Metadata().CaptureLength = len(data)
+	packet.Metadata().Length = len(data)
+	return packet
+}
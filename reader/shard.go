@@ -0,0 +1,42 @@
+package reader
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// shard is a packet handler worker. Packets of a TCP connection are always handled by the same shard
+type shard struct {
+	id           int
+	packetBuffer chan gopacket.Packet
+}
+
+func newShards(n int, bufferLength int) []*shard {
+	shards := []*shard{}
+	for i := 0; i < n; i++ {
+		shards = append(shards, &shard{
+			id:           i,
+			packetBuffer: make(chan gopacket.Packet, bufferLength),
+		})
+	}
+	return shards
+}
+
+// shardIndex return index of shard by symmetric hash of the connection ( A->B and B->A have same index ).
+// Encapsulated packets are hashed by the inner TCP flow
+func shardIndex(packet gopacket.Packet, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	p, ok := decodeTCPPacket(packet)
+	if !ok {
+		return 0
+	}
+	endpointType := layers.EndpointIPv6
+	if p.srcIP.To4() != nil {
+		endpointType = layers.EndpointIPv4
+	}
+	network := gopacket.NewFlow(endpointType, p.srcIP, p.dstIP)
+	h := network.FastHash()*31 + p.tcp.TransportFlow().FastHash()
+	return int(h % uint64(n))
+}
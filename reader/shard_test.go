@@ -0,0 +1,224 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+)
+
+func TestShardIndex(t *testing.T) {
+	n := 4
+	used := map[int]struct{}{}
+	for port := 40000; port < 40100; port++ {
+		req := newTestPacket(t, "10.0.0.1", port, "10.0.0.2", 3306, testTCPData, nil)
+		res := newTestPacket(t, "10.0.0.2", 3306, "10.0.0.1", port, testTCPData, nil)
+		got := shardIndex(req, n)
+		if got < 0 || got >= n {
+			t.Fatalf("got %v\nwant 0 <= index < %v", got, n)
+		}
+		if want := shardIndex(res, n); got != want {
+			t.Errorf("port %d: got %v\nwant %v", port, got, want)
+		}
+		used[got] = struct{}{}
+	}
+	if len(used) < 2 {
+		t.Errorf("got %v shards used\nwant connections to be distributed", len(used))
+	}
+}
+
+func TestReadAndDumpWithShards(t *testing.T) {
+	conns := 20
+	seqs := 50
+	packets := []gopacket.Packet{}
+	for c := 0; c < conns; c++ {
+		packets = append(packets, newTestPacket(t, "10.0.0.1", 40000+c, "10.0.0.2", 3306, testTCPSyn, nil))
+	}
+	for s := 0; s < seqs; s++ {
+		for c := 0; c < conns; c++ {
+			packets = append(packets, newTestPacket(t, "10.0.0.1", 40000+c, "10.0.0.2", 3306, testTCPData, []byte(fmt.Sprintf("%d", s))))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newTestDumper()
+	r := NewPacketReader(
+		ctx,
+		cancel,
+		gopacket.NewPacketSource(&testPacketDataSource{packets: packets}, layers.LayerTypeEthernet),
+		d,
+		[]dumper.DumpValue{},
+		zap.NewNop(),
+		10,
+		4,
+		0,
+		0,
+		false,
+		false,
+	)
+	target, err := ParseTarget("3306")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- r.ReadAndDump(target)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	if len(d.logs) != conns {
+		t.Fatalf("got %v connections\nwant %v", len(d.logs), conns)
+	}
+	for addr, got := range d.logs {
+		if len(got) != seqs {
+			t.Fatalf("%s: got %v packets\nwant %v", addr, len(got), seqs)
+		}
+		for i, payload := range got {
+			if want := fmt.Sprintf("%d", i); payload != want {
+				t.Errorf("%s: got %v\nwant %v", addr, payload, want)
+			}
+		}
+	}
+}
+
+const (
+	testTCPSyn = iota
+	testTCPData
+)
+
+func newTestPacket(t *testing.T, srcIP string, srcPort int, dstIP string, dstPort int, kind int, payload []byte) gopacket.Packet {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		DstMAC:       net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x02},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP(srcIP),
+		DstIP:    net.ParseIP(dstIP),
+	}
+	tcp := &layers.TCP{
+		SrcPort: layers.TCPPort(srcPort),
+		DstPort: layers.TCPPort(dstPort),
+		Window:  65535,
+	}
+	switch kind {
+	case testTCPSyn:
+		tcp.SYN = true
+		tcp.Options = []layers.TCPOption{
+			layers.TCPOption{
+				OptionType:   layers.TCPOptionKindMSS,
+				OptionLength: 4,
+				OptionData:   []byte{0x05, 0xb4},
+			},
+		}
+	case testTCPData:
+		tcp.ACK = true
+		tcp.PSH = true
+	}
+	if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(payload)); err != nil {
+		t.Fatal(err)
+	}
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+}
+
+type testPacketDataSource struct {
+	packets []gopacket.Packet
+}
+
+func (s *testPacketDataSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	if len(s.packets) == 0 {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	data := s.packets[0].Data()
+	s.packets = s.packets[1:]
+	return data, gopacket.CaptureInfo{
+		Timestamp:     time.Now(),
+		CaptureLength: len(data),
+		Length:        len(data),
+	}, nil
+}
+
+// testDumper logs payloads per src_addr
+type testDumper struct {
+	logs    map[string][]string
+	connIDs map[string][]string // conn_id of logs
+	ifaces  []string            // interface of logs
+	mutex   *sync.Mutex
+}
+
+func newTestDumper() *testDumper {
+	return &testDumper{
+		logs:    map[string][]string{},
+		connIDs: map[string][]string{},
+		mutex:   new(sync.Mutex),
+	}
+}
+
+func (d *testDumper) Name() string {
+	return "test"
+}
+
+func (d *testDumper) Dump(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata, additional []dumper.DumpValue) error {
+	return nil
+}
+
+func (d *testDumper) Read(in []byte, direction dumper.Direction, connMetadata *dumper.ConnMetadata) ([]dumper.DumpValue, error) {
+	return []dumper.DumpValue{
+		dumper.DumpValue{
+			Key:   "payload",
+			Value: string(in),
+		},
+	}, nil
+}
+
+func (d *testDumper) Log(values []dumper.DumpValue) {
+	var addr, payload, connID string
+	for _, v := range values {
+		switch v.Key {
+		case "src_addr":
+			addr = v.Value.(string)
+		case "payload":
+			payload = v.Value.(string)
+		case "conn_id":
+			connID = v.Value.(string)
+		case "interface":
+			d.mutex.Lock()
+			d.ifaces = append(d.ifaces, v.Value.(string))
+			d.mutex.Unlock()
+		}
+	}
+	d.mutex.Lock()
+	d.logs[addr] = append(d.logs[addr], payload)
+	d.connIDs[addr] = append(d.connIDs[addr], connID)
+	d.mutex.Unlock()
+}
+
+func (d *testDumper) NewConnMetadata() *dumper.ConnMetadata {
+	return &dumper.ConnMetadata{
+		DumpValues: []dumper.DumpValue{},
+	}
+}
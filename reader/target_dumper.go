@@ -0,0 +1,40 @@
+package reader
+
+import (
+	"net"
+
+	"github.com/k1LoW/tcpdp/dumper"
+)
+
+// TargetDumper is target and dumper of packets to / from the target
+type TargetDumper struct {
+	Target Target
+	Dumper dumper.Dumper
+	Values []dumper.DumpValue // appended to dump of the target
+}
+
+// matchTargetDumper return TargetDumper and direction of packet. Destination is matched prior to source
+func matchTargetDumper(tds []TargetDumper, srcIP net.IP, srcPort uint16, dstIP net.IP, dstPort uint16) (*TargetDumper, dumper.Direction) {
+	for i := range tds {
+		if tds[i].Target.Match(dstIP.String(), dstPort) {
+			return &tds[i], dumper.SrcToDst
+		}
+	}
+	for i := range tds {
+		if tds[i].Target.Match(srcIP.String(), srcPort) {
+			return &tds[i], dumper.DstToSrc
+		}
+	}
+	return nil, dumper.Unknown
+}
+
+// MergeTargets return Target that matches any of targets
+func MergeTargets(targets []Target) Target {
+	hosts := []TargetHost{}
+	for _, t := range targets {
+		hosts = append(hosts, t.TargetHosts...)
+	}
+	return Target{
+		TargetHosts: hosts,
+	}
+}
@@ -0,0 +1,120 @@
+package reader
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+)
+
+func TestReadAndDumpTargets(t *testing.T) {
+	mysqlDumper := newTestDumper()
+	pgDumper := newTestDumper()
+	defaultDumper := newTestDumper()
+	tds := []TargetDumper{
+		TargetDumper{Target: testTarget(t, "3306"), Dumper: mysqlDumper},
+		TargetDumper{Target: testTarget(t, "5432"), Dumper: pgDumper},
+	}
+
+	eth0 := []gopacket.Packet{
+		newTestPacket(t, "10.0.0.1", 40000, "10.0.0.2", 3306, testTCPData, []byte("mysql")),
+		newTestPacket(t, "10.0.0.2", 5432, "10.0.0.1", 40001, testTCPData, []byte("pg response")),
+	}
+	eth1 := []gopacket.Packet{
+		newTestPacket(t, "10.0.1.1", 40002, "10.0.1.2", 5432, testTCPData, []byte("pg")),
+		newTestPacket(t, "10.0.1.1", 40003, "10.0.1.2", 6379, testTCPData, []byte("redis")),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewPacketReader(ctx, cancel, nil, defaultDumper, []dumper.DumpValue{}, zap.NewNop(), 10, 2, 0, 0, false, false)
+	r.SetInterfacePacketSources([]InterfacePacketSource{
+		InterfacePacketSource{
+			Interface:    "eth0",
+			PacketSource: gopacket.NewPacketSource(&testPacketDataSource{packets: eth0}, layers.LayerTypeEthernet),
+		},
+		InterfacePacketSource{
+			Interface:    "eth1",
+			PacketSource: gopacket.NewPacketSource(&testPacketDataSource{packets: eth1}, layers.LayerTypeEthernet),
+		},
+	})
+	done := make(chan error)
+	go func() {
+		done <- r.ReadAndDumpTargets(tds)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	tests := []struct {
+		description string
+		d           *testDumper
+		want        map[string][]string
+	}{
+		{"mysql", mysqlDumper, map[string][]string{"10.0.0.1:40000": []string{"mysql"}}},
+		{"pg", pgDumper, map[string][]string{"10.0.0.2:5432": []string{"pg response"}, "10.0.1.1:40002": []string{"pg"}}},
+		{"not matching any target", defaultDumper, map[string][]string{"10.0.1.1:40003": []string{"redis"}}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.d.logs, tt.want) {
+			t.Errorf("%s\ngot %v\nwant %v", tt.description, tt.d.logs, tt.want)
+		}
+	}
+	ifaces := append(append(mysqlDumper.ifaces, pgDumper.ifaces...), defaultDumper.ifaces...)
+	sort.Strings(ifaces)
+	if want := []string{"eth0", "eth0", "eth1", "eth1"}; !reflect.DeepEqual(ifaces, want) {
+		t.Errorf("got %v\nwant %v", ifaces, want)
+	}
+}
+
+var matchTargetDumperTests = []struct {
+	description   string
+	src           string
+	srcPort       uint16
+	dst           string
+	dstPort       uint16
+	wantTarget    int // index of TargetDumper. -1 is not matched
+	wantDirection dumper.Direction
+}{
+	{"Request", "10.0.0.1", 40000, "10.0.0.2", 5432, 1, dumper.SrcToDst},
+	{"Response", "10.0.0.2", 3306, "10.0.0.1", 40000, 0, dumper.DstToSrc},
+	{"Destination is prior to source", "10.0.0.2", 3306, "10.0.0.3", 5432, 1, dumper.SrcToDst},
+	{"Not matched", "10.0.0.1", 40000, "10.0.0.2", 6379, -1, dumper.Unknown},
+}
+
+func TestMatchTargetDumper(t *testing.T) {
+	tds := []TargetDumper{
+		TargetDumper{Target: testTarget(t, "3306")},
+		TargetDumper{Target: testTarget(t, "5432")},
+	}
+	for _, tt := range matchTargetDumperTests {
+		td, direction := matchTargetDumper(tds, net.ParseIP(tt.src), tt.srcPort, net.ParseIP(tt.dst), tt.dstPort)
+		var want *TargetDumper
+		if tt.wantTarget >= 0 {
+			want = &tds[tt.wantTarget]
+		}
+		if td != want || direction != tt.wantDirection {
+			t.Errorf("%s\ngot %v %v\nwant %v %v", tt.description, td, direction, want, tt.wantDirection)
+		}
+	}
+}
+
+func testTarget(t *testing.T, target string) Target {
+	tt, err := ParseTarget(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tt
+}
@@ -0,0 +1,210 @@
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/afpacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/net/bpf"
+)
+
+const afpacketMaxFramesPerBlock = 128
+
+// afpacketPollTimeout bounds blocking read so that Close does not unmap the ring while polling
+const afpacketPollTimeout = 100 * time.Millisecond
+
+const sysClassNet = "/sys/class/net"
+
+var afpacketFanoutTypes = map[string]afpacket.FanoutType{
+	"hash":     afpacket.FanoutHashWithDefrag,
+	"lb":       afpacket.FanoutLoadBalance,
+	"cpu":      afpacket.FanoutCPU,
+	"rollover": afpacket.FanoutRollover,
+	"random":   afpacket.FanoutRandom,
+	"qm":       afpacket.FanoutQueueMapping,
+}
+
+// afpacketHandle is captureHandle using AF_PACKET socket with TPACKET_V3 ring buffer
+type afpacketHandle struct {
+	mutex    sync.Mutex
+	tp       *afpacket.TPacket
+	linkType layers.LinkType
+	closed   bool
+}
+
+func (h *afpacketHandle) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	return h.tp.ReadPacketData()
+}
+
+func (h *afpacketHandle) LinkType() layers.LinkType {
+	return h.linkType
+}
+
+func (h *afpacketHandle) stats() (captureStats, error) {
+	_, stats, err := h.tp.SocketStats()
+	if err != nil {
+		return captureStats{}, err
+	}
+	return captureStats{
+		received: int(stats.Packets()),
+		dropped:  int(stats.Drops()),
+	}, nil
+}
+
+func (h *afpacketHandle) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	h.tp.Close()
+}
+
+func (s *ProbeServer) openAfpacketHandle(device string, fanoutGroup, snapshotLength, bufferSize int, pValues []dumper.DumpValue) (captureHandle, error) {
+	linkType, err := afpacketLinkType(device, sysClassNet)
+	if err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("afpacket create error (interface)", fields...)
+		return nil, err
+	}
+	frameSize, blockSize, numBlocks, err := afpacketRingSize(bufferSize, snapshotLength, os.Getpagesize())
+	if err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("afpacket create error (buffer_size)", fields...)
+		return nil, err
+	}
+	blockTimeout := afpacket.DefaultBlockTimeout
+	if s.pcapConfig.ImmediateMode {
+		blockTimeout = time.Millisecond
+	}
+	opts := []interface{}{
+		afpacket.TPacketVersion3,
+		afpacket.OptFrameSize(frameSize),
+		afpacket.OptBlockSize(blockSize),
+		afpacket.OptNumBlocks(numBlocks),
+		afpacket.OptBlockTimeout(blockTimeout),
+		afpacket.OptPollTimeout(afpacketPollTimeout),
+		afpacket.OptAddVLANHeader(true),
+		afpacket.OptInterface(device),
+	}
+	tp, err := afpacket.NewTPacket(opts...)
+	if err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("afpacket create error", fields...)
+		return nil, err
+	}
+	h := &afpacketHandle{
+		tp:       tp,
+		linkType: linkType,
+	}
+
+	filter, err := afpacketBPF(s.pcapConfig.Filter, linkType, snapshotLength)
+	if err == nil {
+		err = tp.SetBPF(filter)
+	}
+	if err != nil {
+		h.Close()
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("Set BPF error", fields...)
+		return nil, err
+	}
+
+	if fanoutGroup > 0 {
+		fanoutType, ok := afpacketFanoutTypes[s.pcapConfig.FanoutType]
+		if !ok {
+			err = errors.Errorf("invalid probe.afpacket.fanoutType: %s", s.pcapConfig.FanoutType)
+		} else if fanoutGroup > 0xFFFF {
+			err = errors.Errorf("invalid probe.afpacket.fanoutGroup: %d", fanoutGroup)
+		} else {
+			err = tp.SetFanout(fanoutType, uint16(fanoutGroup))
+		}
+		if err != nil {
+			h.Close()
+			fields := s.fieldsWithErrorAndValues(err, pValues)
+			s.logger.WithOptions(zap.AddCaller()).Fatal("afpacket fanout error", fields...)
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// afpacketRingSize return frame size, block size and number of blocks of ring buffer up to bufferSize
+func afpacketRingSize(bufferSize, snaplen, pageSize int) (int, int, int, error) {
+	frameSize := pageSize
+	if snaplen > pageSize {
+		frameSize = (snaplen/pageSize + 1) * pageSize
+	}
+	framesPerBlock := bufferSize / frameSize
+	if framesPerBlock > afpacketMaxFramesPerBlock {
+		framesPerBlock = afpacketMaxFramesPerBlock
+	}
+	if framesPerBlock == 0 {
+		return 0, 0, 0, errors.Errorf("buffer size %d is smaller than frame size %d", bufferSize, frameSize)
+	}
+	blockSize := frameSize * framesPerBlock
+	return frameSize, blockSize, bufferSize / blockSize, nil
+}
+
+// afpacketLinkType return link type of frames read from device with AF_PACKET socket ( SOCK_RAW )
+// Frames of "any" have link-layer headers of each interface, and non-Ethernet interfaces are not supported
+func afpacketLinkType(device, sysfs string) (layers.LinkType, error) {
+	if device == "" || device == "any" {
+		return 0, errors.Errorf("afpacket backend does not support interface \"%s\". Use libpcap backend", device)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(sysfs, device, "type")) // #nosec
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	arphrd, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	switch arphrd {
+	case syscall.ARPHRD_ETHER, syscall.ARPHRD_LOOPBACK:
+		// loopback frames have Ethernet header with zero addresses
+		return layers.LinkTypeEthernet, nil
+	default:
+		return 0, errors.Errorf("afpacket backend does not support interface %s ( ARPHRD type %d ). Use libpcap backend", device, arphrd)
+	}
+}
+
+// afpacketBPF compile filter for frames of linkType as kernel BPF
+func afpacketBPF(filter string, linkType layers.LinkType, snaplen int) ([]bpf.RawInstruction, error) {
+	instructions, err := pcap.CompileBPFFilter(linkType, snaplen, filter)
+	if err != nil {
+		return nil, err
+	}
+	raw := []bpf.RawInstruction{}
+	for _, i := range instructions {
+		raw = append(raw, bpf.RawInstruction{
+			Op: i.Code,
+			Jt: i.Jt,
+			Jf: i.Jf,
+			K:  i.K,
+		})
+	}
+	return raw, nil
+}
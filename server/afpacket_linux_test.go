@@ -0,0 +1,123 @@
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+)
+
+var afpacketRingSizeTests = []struct {
+	description   string
+	bufferSize    int
+	snaplen       int
+	wantFrameSize int
+	wantBlockSize int
+	wantNumBlocks int
+	wantErr       bool
+}{
+	{
+		"MTU snaplen",
+		256 * 1024 * 1024,
+		1518,
+		4096,
+		4096 * 128,
+		512,
+		false,
+	},
+	{
+		"Max snaplen",
+		256 * 1024 * 1024,
+		0xFFFF,
+		16 * 4096,
+		16 * 4096 * 128,
+		32,
+		false,
+	},
+	{
+		"Buffer smaller than block",
+		2 * 1024 * 1024,
+		0xFFFF,
+		16 * 4096,
+		2 * 1024 * 1024,
+		1,
+		false,
+	},
+	{
+		"Buffer smaller than frame",
+		4095,
+		1518,
+		0,
+		0,
+		0,
+		true,
+	},
+}
+
+func TestAfpacketRingSize(t *testing.T) {
+	for _, tt := range afpacketRingSizeTests {
+		frameSize, blockSize, numBlocks, err := afpacketRingSize(tt.bufferSize, tt.snaplen, 4096)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", tt.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.description, err)
+		}
+		if frameSize != tt.wantFrameSize || blockSize != tt.wantBlockSize || numBlocks != tt.wantNumBlocks {
+			t.Errorf("%s\ngot %v %v %v\nwant %v %v %v", tt.description, frameSize, blockSize, numBlocks, tt.wantFrameSize, tt.wantBlockSize, tt.wantNumBlocks)
+		}
+		if blockSize%4096 != 0 || blockSize%frameSize != 0 {
+			t.Errorf("%s: block size %d is not multiple of page size and frame size", tt.description, blockSize)
+		}
+	}
+}
+
+var afpacketLinkTypeTests = []struct {
+	device  string
+	arphrd  string
+	want    layers.LinkType
+	wantErr bool
+}{
+	{"eth0", "1\n", layers.LinkTypeEthernet, false},
+	{"lo", "772\n", layers.LinkTypeEthernet, false},
+	{"tun0", "65534\n", 0, true},
+	{"ppp0", "512\n", 0, true},
+	{"any", "", 0, true},
+	{"", "", 0, true},
+	{"eth1", "", 0, true}, // not found
+}
+
+func TestAfpacketLinkType(t *testing.T) {
+	sysfs := t.TempDir()
+	for _, tt := range afpacketLinkTypeTests {
+		if tt.arphrd != "" {
+			if err := os.MkdirAll(filepath.Join(sysfs, tt.device), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(sysfs, tt.device, "type"), []byte(tt.arphrd), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := afpacketLinkType(tt.device, sysfs)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", tt.device)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.device, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %v\nwant %v", tt.device, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package server
+
+import (
+	"github.com/k1LoW/tcpdp/dumper"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func (s *ProbeServer) openAfpacketHandle(device string, fanoutGroup, snapshotLength, bufferSize int, pValues []dumper.DumpValue) (captureHandle, error) {
+	err := errors.New("afpacket backend is only supported on Linux")
+	fields := s.fieldsWithErrorAndValues(err, pValues)
+	s.logger.WithOptions(zap.AddCaller()).Fatal("afpacket create error", fields...)
+	return nil, err
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/k1LoW/tcpdp/dumper"
+	"go.uber.org/zap"
+)
+
+// capture backends of probe
+const (
+	BackendLibpcap  = "libpcap"
+	BackendAfpacket = "afpacket"
+)
+
+// captureHandle is packet source of capture backend
+type captureHandle interface {
+	gopacket.PacketDataSource
+	LinkType() layers.LinkType
+	stats() (captureStats, error)
+	Close()
+}
+
+type captureStats struct {
+	received  int
+	dropped   int
+	ifDropped int
+}
+
+// pcapHandle is captureHandle using libpcap
+type pcapHandle struct {
+	*pcap.Handle
+}
+
+func (h *pcapHandle) stats() (captureStats, error) {
+	stats, err := h.Stats()
+	if err != nil {
+		return captureStats{}, err
+	}
+	return captureStats{
+		received:  stats.PacketsReceived,
+		dropped:   stats.PacketsDropped,
+		ifDropped: stats.PacketsIfDropped,
+	}, nil
+}
+
+func (s *ProbeServer) openPcapHandle(device string, snapshotLength, bufferSize int, pValues []dumper.DumpValue) (captureHandle, error) {
+	inactiveHandle, err := pcap.NewInactiveHandle(device)
+	if err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error", fields...)
+		return nil, err
+	}
+	defer inactiveHandle.CleanUp()
+	if err := inactiveHandle.SetSnapLen(snapshotLength); err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (snaplen)", fields...)
+		return nil, err
+	}
+	if err := inactiveHandle.SetPromisc(s.pcapConfig.Promiscuous); err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (promiscuous)", fields...)
+		return nil, err
+	}
+	if err := inactiveHandle.SetTimeout(s.pcapConfig.Timeout); err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (timeout)", fields...)
+		return nil, err
+	}
+	if err := inactiveHandle.SetBufferSize(bufferSize); err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (pcap_buffer_size)", fields...)
+		return nil, err
+	}
+	if err := inactiveHandle.SetImmediateMode(s.pcapConfig.ImmediateMode); err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (pcap_set_immediate_mode)", fields...)
+		return nil, err
+	}
+
+	handle, err := inactiveHandle.Activate()
+	if err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap handle activate error", fields...)
+		return nil, err
+	}
+
+	if err := handle.SetBPFFilter(s.pcapConfig.Filter); err != nil {
+		handle.Close()
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("Set BPF error", fields...)
+		return nil, err
+	}
+
+	return &pcapHandle{handle}, nil
+}
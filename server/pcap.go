@@ -0,0 +1,142 @@
+package server
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	l "github.com/k1LoW/tcpdp/logger"
+)
+
+const (
+	pcapFlowClient = 0
+	pcapFlowServer = 1
+	pcapFlowMSS    = 1460
+)
+
+// pcapFlow writes a TCP connection between 2 addresses as synthetic TCP/IP packets
+type pcapFlow struct {
+	mutex  sync.Mutex
+	logger *l.PcapLogger
+	addrs  [2]*net.TCPAddr
+	seq    [2]uint32
+}
+
+func newPcapFlow(pl *l.PcapLogger, client, server net.Addr) *pcapFlow {
+	return &pcapFlow{
+		logger: pl,
+		addrs:  [2]*net.TCPAddr{client.(*net.TCPAddr), server.(*net.TCPAddr)},
+		seq:    [2]uint32{rand.Uint32(), rand.Uint32()}, // #nosec
+	}
+}
+
+// open writes 3-way handshake
+func (f *pcapFlow) open() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	mss := []layers.TCPOption{
+		layers.TCPOption{
+			OptionType:   layers.TCPOptionKindMSS,
+			OptionLength: 4,
+			OptionData:   []byte{byte(pcapFlowMSS >> 8), byte(pcapFlowMSS & 0xff)},
+		},
+	}
+	if err := f.writeSegment(pcapFlowClient, &layers.TCP{SYN: true, Options: mss}, nil); err != nil {
+		return err
+	}
+	if err := f.writeSegment(pcapFlowServer, &layers.TCP{SYN: true, ACK: true, Options: mss}, nil); err != nil {
+		return err
+	}
+	return f.writeSegment(pcapFlowClient, &layers.TCP{ACK: true}, nil)
+}
+
+// write writes payload sent from client ( pcapFlowClient ) or server ( pcapFlowServer ) as segments of MSS
+func (f *pcapFlow) write(from int, payload []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for len(payload) > 0 {
+		n := len(payload)
+		if n > pcapFlowMSS {
+			n = pcapFlowMSS
+		}
+		if err := f.writeSegment(from, &layers.TCP{PSH: true, ACK: true}, payload[:n]); err != nil {
+			return err
+		}
+		payload = payload[n:]
+	}
+	return nil
+}
+
+// close writes 4-way handshake
+func (f *pcapFlow) close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.writeSegment(pcapFlowClient, &layers.TCP{FIN: true, ACK: true}, nil); err != nil {
+		return err
+	}
+	if err := f.writeSegment(pcapFlowServer, &layers.TCP{FIN: true, ACK: true}, nil); err != nil {
+		return err
+	}
+	return f.writeSegment(pcapFlowClient, &layers.TCP{ACK: true}, nil)
+}
+
+func (f *pcapFlow) writeSegment(from int, tcp *layers.TCP, payload []byte) error {
+	src := f.addrs[from]
+	dst := f.addrs[1-from]
+	tcp.SrcPort = layers.TCPPort(src.Port)
+	tcp.DstPort = layers.TCPPort(dst.Port)
+	tcp.Seq = f.seq[from]
+	if tcp.ACK {
+		tcp.Ack = f.seq[1-from]
+	}
+	tcp.Window = 0xFFFF
+
+	var ip gopacket.SerializableLayer
+	if src.IP.To4() != nil && dst.IP.To4() != nil {
+		ipv4 := &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Flags:    layers.IPv4DontFragment,
+			Protocol: layers.IPProtocolTCP,
+			SrcIP:    src.IP.To4(),
+			DstIP:    dst.IP.To4(),
+		}
+		if err := tcp.SetNetworkLayerForChecksum(ipv4); err != nil {
+			return err
+		}
+		ip = ipv4
+	} else {
+		ipv6 := &layers.IPv6{
+			Version:    6,
+			HopLimit:   64,
+			NextHeader: layers.IPProtocolTCP,
+			SrcIP:      src.IP.To16(),
+			DstIP:      dst.IP.To16(),
+		}
+		if err := tcp.SetNetworkLayerForChecksum(ipv6); err != nil {
+			return err
+		}
+		ip = ipv6
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	if err := gopacket.SerializeLayers(buf, opts, ip, tcp, gopacket.Payload(payload)); err != nil {
+		return err
+	}
+	if err := f.logger.WritePacket(time.Now(), buf.Bytes()); err != nil {
+		return err
+	}
+
+	f.seq[from] += uint32(len(payload))
+	if tcp.SYN || tcp.FIN {
+		f.seq[from]++
+	}
+	return nil
+}
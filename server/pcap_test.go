@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	l "github.com/k1LoW/tcpdp/logger"
+	"github.com/k1LoW/tcpdp/reader"
+	"github.com/spf13/viper"
+)
+
+var pcapFlowTests = []struct {
+	client string
+	server string
+}{
+	{"127.0.0.1:40000", "127.0.0.1:3306"},
+	{"[::1]:40000", "[::1]:3306"},
+	{"127.0.0.1:40000", "[::1]:3306"},
+}
+
+func TestPcapFlow(t *testing.T) {
+	for _, tt := range pcapFlowTests {
+		path := filepath.Join(newTestPcapLogDir(t), "dump.pcap")
+		pl, err := l.NewPcapLogger()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, _ := net.ResolveTCPAddr("tcp", tt.client)
+		server, _ := net.ResolveTCPAddr("tcp", tt.server)
+		f := newPcapFlow(pl, client, server)
+
+		query := bytes.Repeat([]byte("SELECT 1;"), 500) // split into segments
+		result := []byte("OK")
+		if err := f.open(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.write(pcapFlowClient, query); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.write(pcapFlowServer, result); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.close(); err != nil {
+			t.Fatal(err)
+		}
+
+		payloads := map[string][]byte{}
+		nextSeq := map[string]uint32{}
+		n := 0
+		for _, packet := range readTestPcap(t, path) {
+			n++
+			tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+			if !ok {
+				t.Fatalf("%s: got no TCP packet\n%s", tt.client, packet.Dump())
+			}
+			if errLayer := packet.ErrorLayer(); errLayer != nil {
+				t.Fatalf("%s: %v", tt.client, errLayer.Error())
+			}
+			src := fmt.Sprintf("%s:%d", packet.NetworkLayer().NetworkFlow().Src(), tcp.SrcPort)
+			if s, ok := nextSeq[src]; ok && s != tcp.Seq {
+				t.Errorf("%s: got %v\nwant %v", src, tcp.Seq, s)
+			}
+			nextSeq[src] = tcp.Seq + uint32(len(tcp.Payload))
+			if tcp.SYN || tcp.FIN {
+				nextSeq[src]++
+			}
+			payloads[src] = append(payloads[src], tcp.Payload...)
+		}
+		// handshake ( 3 ) + query ( 4 ) + result ( 1 ) + close ( 3 )
+		if want := 11; n != want {
+			t.Errorf("%s: got %v\nwant %v", tt.client, n, want)
+		}
+		for _, want := range [][]byte{query, result} {
+			found := false
+			for _, got := range payloads {
+				if bytes.Equal(got, want) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s: payload %q not found", tt.client, want[:2])
+			}
+		}
+	}
+}
+
+func newTestPcapLogDir(t *testing.T) string {
+	dir := t.TempDir()
+	viper.Set("pcapLog.dir", dir)
+	viper.Set("pcapLog.fileName", "dump.pcap")
+	viper.Set("pcapLog.format", "pcapng")
+	viper.Set("pcapLog.rotateEnable", false)
+	return dir
+}
+
+func readTestPcap(t *testing.T, path string) []gopacket.Packet {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pr, err := reader.NewPcapReader([]io.Reader{f}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets := []gopacket.Packet{}
+	for packet := range gopacket.NewPacketSource(pr, pr).Packets() {
+		packets = append(packets, packet)
+	}
+	if err := pr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return packets
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -16,11 +17,9 @@ import (
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/pcap"
 	"github.com/k1LoW/tcpdp/dumper"
-	"github.com/k1LoW/tcpdp/dumper/conn"
-	"github.com/k1LoW/tcpdp/dumper/hex"
-	"github.com/k1LoW/tcpdp/dumper/mysql"
-	"github.com/k1LoW/tcpdp/dumper/pg"
+	"github.com/k1LoW/tcpdp/dumper/all"
 	"github.com/k1LoW/tcpdp/reader"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -40,6 +39,9 @@ type PcapConfig struct {
 	Promiscuous    bool
 	Timeout        time.Duration
 	Filter         string
+	Backend        string
+	FanoutGroup    int // afpacket fanout group id. 0 is disabled
+	FanoutType     string
 }
 
 // ProbeServer struct
@@ -51,9 +53,40 @@ type ProbeServer struct {
 	ClosedChan    chan struct{}
 	logger        *zap.Logger
 	dumper        dumper.Dumper
-	target        reader.Target
+	targets       []ProbeTarget
+	targetDumpers []reader.TargetDumper
+	multiTarget   bool
 	pcapConfig    PcapConfig
 	proxyProtocol bool
+	ringConfig    *reader.RingBufferConfig // nil: ring buffer disabled
+	ringBuffer    *reader.RingBuffer
+	mutex         sync.Mutex
+}
+
+// ProbeTarget is target and dumper of probe
+type ProbeTarget struct {
+	Target string
+	Dumper string
+}
+
+// ParseProbeTargets parse "target=dumper" ( e.g. "3306=mysql" )
+func ParseProbeTargets(ss []string) ([]ProbeTarget, error) {
+	targets := []ProbeTarget{}
+	for _, s := range ss {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, errors.Errorf("invalid target and dumper: %s", s)
+		}
+		pt := ProbeTarget{
+			Target: strings.TrimSpace(kv[0]),
+			Dumper: strings.TrimSpace(kv[1]),
+		}
+		if !all.Valid(pt.Dumper) {
+			return nil, errors.Errorf("unknown dumper: %s", s)
+		}
+		targets = append(targets, pt)
+	}
+	return targets, nil
 }
 
 // NewProbeServer returns a new Server
@@ -61,20 +94,12 @@ func NewProbeServer(ctx context.Context, logger *zap.Logger) (*ProbeServer, erro
 	innerCtx, shutdown := context.WithCancel(ctx)
 	closedChan := make(chan struct{})
 
-	var d dumper.Dumper
 	dumpType := viper.GetString("tcpdp.dumper")
-
-	switch dumpType {
-	case "hex":
-		d = hex.NewDumper()
-	case "pg":
-		d = pg.NewDumper()
-	case "mysql":
-		d = mysql.NewDumper()
-	case "conn":
-		d = conn.NewDumper()
-	default:
-		d = hex.NewDumper()
+	d, err := newDumper(dumpType, logger)
+	if err != nil {
+		logger.WithOptions(zap.AddCaller()).Fatal(fmt.Sprintf("%s dumper config error", dumpType), zap.Error(err))
+		shutdown()
+		return nil, err
 	}
 
 	pidfile, err := filepath.Abs(viper.GetString("tcpdp.pidfile"))
@@ -84,20 +109,93 @@ func NewProbeServer(ctx context.Context, logger *zap.Logger) (*ProbeServer, erro
 		return nil, err
 	}
 
-	target := viper.GetString("probe.target")
-	t, err := reader.ParseTarget(target)
-	if err != nil {
-		logger.WithOptions(zap.AddCaller()).Fatal("parse target error", zap.Error(err))
+	targets := []ProbeTarget{}
+	if err := viper.UnmarshalKey("probe.targets", &targets); err != nil {
+		logger.WithOptions(zap.AddCaller()).Fatal("parse targets error", zap.Error(err))
 		shutdown()
 		return nil, err
 	}
+	// probe.targets is set. probe_target_addr is dumped per target
+	multiTarget := len(targets) > 0
+	if !multiTarget {
+		targets = []ProbeTarget{
+			ProbeTarget{
+				Target: viper.GetString("probe.target"),
+				Dumper: dumpType,
+			},
+		}
+	}
+	dumpers := map[string]dumper.Dumper{
+		dumpType: d,
+	}
+	tds := []reader.TargetDumper{}
+	ts := []reader.Target{}
+	for i, pt := range targets {
+		if pt.Dumper == "" {
+			pt.Dumper = dumpType
+			targets[i] = pt
+		}
+		t, err := reader.ParseTarget(pt.Target)
+		if err != nil {
+			logger.WithOptions(zap.AddCaller()).Fatal("parse target error", zap.Error(err), zap.String("probe_target_addr", pt.Target))
+			shutdown()
+			return nil, err
+		}
+		td, ok := dumpers[pt.Dumper]
+		if !ok {
+			td, err = newDumper(pt.Dumper, logger)
+			if err != nil {
+				logger.WithOptions(zap.AddCaller()).Fatal(fmt.Sprintf("%s dumper config error", pt.Dumper), zap.Error(err))
+				shutdown()
+				return nil, err
+			}
+			dumpers[pt.Dumper] = td
+		}
+		values := []dumper.DumpValue{}
+		if multiTarget {
+			values = append(values, dumper.DumpValue{
+				Key:   "probe_target_addr",
+				Value: pt.Target,
+			})
+		}
+		tds = append(tds, reader.TargetDumper{
+			Target: t,
+			Dumper: td,
+			Values: values,
+		})
+		ts = append(ts, t)
+	}
 
 	filter := viper.GetString("probe.filter")
 	if filter == "" {
-		filter = reader.NewBPFFilterString(t)
+		filter = reader.NewBPFFilterString(reader.MergeTargets(ts))
 	} else {
 		filter = fmt.Sprintf("tcp and (%s)", filter)
 	}
+	if viper.GetBool("probe.encapsulated") {
+		filter = reader.NewEncapsulatedBPFFilterString(filter)
+	}
+
+	var ringConfig *reader.RingBufferConfig
+	if viper.GetBool("probe.ringBuffer.enable") {
+		c, err := newRingBufferConfig()
+		if err != nil {
+			logger.WithOptions(zap.AddCaller()).Fatal("ringBuffer config error", zap.Error(err))
+			shutdown()
+			return nil, err
+		}
+		ringConfig = &c
+	}
+
+	backend := viper.GetString("probe.backend")
+	switch backend {
+	case BackendLibpcap, BackendAfpacket:
+	default:
+		err := errors.Errorf("invalid probe.backend: %s", backend)
+		logger.WithOptions(zap.AddCaller()).Fatal("backend config error", zap.Error(err))
+		shutdown()
+		return nil, err
+	}
 
 	pcapConfig := PcapConfig{
 		Device:         viper.GetString("probe.interface"),
@@ -107,6 +205,9 @@ func NewProbeServer(ctx context.Context, logger *zap.Logger) (*ProbeServer, erro
 		Promiscuous:    promiscuous,
 		Timeout:        timeout,
 		Filter:         filter,
+		Backend:        backend,
+		FanoutGroup:    viper.GetInt("probe.afpacket.fanoutGroup"),
+		FanoutType:     viper.GetString("probe.afpacket.fanoutType"),
 	}
 
 	return &ProbeServer{
@@ -116,9 +217,38 @@ func NewProbeServer(ctx context.Context, logger *zap.Logger) (*ProbeServer, erro
 		ClosedChan:    closedChan,
 		logger:        logger,
 		dumper:        d,
-		target:        t,
+		targets:       targets,
+		targetDumpers: tds,
+		multiTarget:   multiTarget,
 		pcapConfig:    pcapConfig,
 		proxyProtocol: viper.GetBool("tcpdp.proxyProtocol"),
+		ringConfig:    ringConfig,
+	}, nil
+}
+
+func newRingBufferConfig() (reader.RingBufferConfig, error) {
+	size, err := byteFormat(viper.GetString("probe.ringBuffer.size"))
+	if err != nil {
+		return reader.RingBufferConfig{}, err
+	}
+	durations := map[string]time.Duration{}
+	for _, k := range []string{"probe.ringBuffer.duration", "probe.ringBuffer.postTriggerDuration", "probe.ringBuffer.cooldown", "probe.ringBuffer.trigger.latency"} {
+		d, err := time.ParseDuration(viper.GetString(k))
+		if err != nil {
+			return reader.RingBufferConfig{}, errors.Wrap(err, k)
+		}
+		durations[k] = d
+	}
+	return reader.RingBufferConfig{
+		Duration:            durations["probe.ringBuffer.duration"],
+		Size:                size,
+		PostTriggerDuration: durations["probe.ringBuffer.postTriggerDuration"],
+		Cooldown:            durations["probe.ringBuffer.cooldown"],
+		Dir:                 viper.GetString("probe.ringBuffer.dir"),
+		DumperError:         viper.GetBool("probe.ringBuffer.trigger.dumperError"),
+		ErrorCodes:          viper.GetStringSlice("probe.ringBuffer.trigger.errorCodes"),
+		QueryPattern:        viper.GetString("probe.ringBuffer.trigger.queryPattern"),
+		Latency:             durations["probe.ringBuffer.trigger.latency"],
 	}, nil
 }
 
@@ -139,15 +269,20 @@ func (s *ProbeServer) Start() error {
 		s.logger.WithOptions(zap.AddCaller()).Fatal("parse buffer-size error", zap.Error(err))
 		return err
 	}
-	immediateMode := s.pcapConfig.ImmediateMode
 	snapshotLength, err := byteFormat(s.pcapConfig.SnapshotLength)
 	if err != nil {
 		s.logger.WithOptions(zap.AddCaller()).Fatal("parse snapshot-length error", zap.Error(err))
 		return err
 	}
 	internalBufferLength := viper.GetInt("probe.internalBufferLength")
+	shards := viper.GetInt("probe.shards")
+	maxConnections := viper.GetInt("probe.maxConnections")
+	connIdleTimeout := viper.GetDuration("probe.connIdleTimeout")
 
-	target := viper.GetString("probe.target")
+	targets := []string{}
+	for _, t := range s.targets {
+		targets = append(targets, t.Target)
+	}
 
 	pValues := []dumper.DumpValue{
 		dumper.DumpValue{
@@ -156,79 +291,117 @@ func (s *ProbeServer) Start() error {
 		},
 		dumper.DumpValue{
 			Key:   "probe_target_addr",
-			Value: target,
+			Value: strings.Join(targets, ","),
 		},
 	}
 
-	inactiveHandle, err := pcap.NewInactiveHandle(s.pcapConfig.Device)
-	if err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error", fields...)
-		return err
-	}
-	if err := inactiveHandle.SetSnapLen(int(snapshotLength)); err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (snaplen)", fields...)
-		return err
-	}
-	if err := inactiveHandle.SetPromisc(s.pcapConfig.Promiscuous); err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (promiscuous)", fields...)
-		return err
-	}
-	if err := inactiveHandle.SetTimeout(s.pcapConfig.Timeout); err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (timeout)", fields...)
-		return err
-	}
-	if err := inactiveHandle.SetBufferSize(int(pcapBufferSize)); err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (pcap_buffer_size)", fields...)
-		return err
-	}
-	if err := inactiveHandle.SetImmediateMode(immediateMode); err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap create error (pcap_set_immediate_mode)", fields...)
-		return err
-	}
-
-	handle, err := inactiveHandle.Activate()
-	if err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("pcap handle activate error", fields...)
-		return err
-	}
-
-	s.checkStats(handle)
+	devices := s.pcapConfig.devices()
+	handles := []captureHandle{}
 	defer func() {
-		stats, _ := handle.Stats()
-		s.logger.Info("pcap Stats", zap.Int("packet_received", stats.PacketsReceived), zap.Int("packet_dropped", stats.PacketsDropped), zap.Int("packet_if_dropped", stats.PacketsIfDropped))
-		handle.Close()
+		for i, handle := range handles {
+			stats, _ := handle.stats()
+			s.logger.Info("pcap Stats", zap.String("interface", devices[i]), zap.Int("packet_received", stats.received), zap.Int("packet_dropped", stats.dropped), zap.Int("packet_if_dropped", stats.ifDropped))
+			handle.Close()
+		}
 	}()
-
-	if err := handle.SetBPFFilter(s.pcapConfig.Filter); err != nil {
-		fields := s.fieldsWithErrorAndValues(err, pValues)
-		s.logger.WithOptions(zap.AddCaller()).Fatal("Set BPF error", fields...)
-		return err
+	sources := []reader.InterfacePacketSource{}
+	for i, device := range devices {
+		var handle captureHandle
+		switch s.pcapConfig.Backend {
+		case BackendAfpacket:
+			// a fanout group is bound to an interface
+			fanoutGroup := 0
+			if s.pcapConfig.FanoutGroup > 0 {
+				fanoutGroup = s.pcapConfig.FanoutGroup + i
+			}
+			handle, err = s.openAfpacketHandle(device, fanoutGroup, snapshotLength, pcapBufferSize, pValues)
+		default:
+			handle, err = s.openPcapHandle(device, snapshotLength, pcapBufferSize, pValues)
+		}
+		if err != nil {
+			return err
+		}
+		handles = append(handles, handle)
+		s.checkStats(device, handle)
+		sources = append(sources, reader.InterfacePacketSource{
+			Interface:    device,
+			PacketSource: gopacket.NewPacketSource(handle, reader.LinkTypeDecoder(uint16(handle.LinkType()))),
+		})
 	}
 
 	proxyProtocol := viper.GetBool("tcpdp.proxyProtocol")
 	enableInternal := viper.GetBool("log.enableInternal")
 
-	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
+	// interface of packets from multiple interfaces is dumped per packet
+	var packetSource *gopacket.PacketSource
+	rValues := []dumper.DumpValue{}
+	if len(sources) == 1 {
+		packetSource = sources[0].PacketSource
+		rValues = append(rValues, pValues[0]) // interface
+	}
+	if !s.multiTarget {
+		rValues = append(rValues, pValues[1]) // probe_target_addr
+	}
 	r := reader.NewPacketReader(
 		s.ctx,
 		s.shutdown,
 		packetSource,
 		s.dumper,
-		pValues,
+		rValues,
 		s.logger,
 		internalBufferLength,
+		shards,
+		maxConnections,
+		connIdleTimeout,
 		proxyProtocol,
 		enableInternal,
 	)
+	if len(sources) > 1 {
+		r.SetInterfacePacketSources(sources)
+	}
+
+	mappings := []reader.MidStreamMapping{}
+	if err := viper.UnmarshalKey("probe.midStreamMapping", &mappings); err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("parse midStreamMapping error", fields...)
+		return err
+	}
+	if err := r.SetMidStreamMappings(mappings); err != nil {
+		fields := s.fieldsWithErrorAndValues(err, pValues)
+		s.logger.WithOptions(zap.AddCaller()).Fatal("parse midStreamMapping error", fields...)
+		return err
+	}
+
+	if s.ringConfig != nil {
+		linkType := handles[0].LinkType()
+		for _, h := range handles {
+			if h.LinkType() != linkType {
+				err = errors.New("ring buffer requires the same link type of interfaces")
+				break
+			}
+		}
+		var rb *reader.RingBuffer
+		if err == nil {
+			c := *s.ringConfig
+			c.Snaplen = snapshotLength
+			rb, err = reader.NewRingBuffer(s.ctx, s.logger, c, linkType)
+		}
+		if err != nil {
+			fields := s.fieldsWithErrorAndValues(err, pValues)
+			s.logger.WithOptions(zap.AddCaller()).Fatal("ringBuffer config error", fields...)
+			return err
+		}
+		s.mutex.Lock()
+		s.ringBuffer = rb
+		s.mutex.Unlock()
+		r.SetRingBuffer(rb)
+		defer func() {
+			// wait for export triggered before shutdown
+			<-rb.Done()
+		}()
+	}
 
-	if err := r.ReadAndDump(s.target); err != nil {
+	if err := r.ReadAndDumpTargets(s.targetDumpers); err != nil {
 		fields := s.fieldsWithErrorAndValues(err, pValues)
 		s.logger.WithOptions(zap.AddCaller()).Fatal("ReadAndDump error", fields...)
 		return err
@@ -242,7 +415,18 @@ func (s *ProbeServer) Shutdown() {
 	s.shutdown()
 }
 
-func (s *ProbeServer) checkStats(handle *pcap.Handle) {
+// TriggerRingBuffer export packets in ring buffer
+func (s *ProbeServer) TriggerRingBuffer() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ringBuffer == nil {
+		s.logger.Info("ring buffer is not enabled")
+		return
+	}
+	s.ringBuffer.Trigger(reader.TriggerSignal, []dumper.DumpValue{})
+}
+
+func (s *ProbeServer) checkStats(device string, handle captureHandle) {
 	go func() {
 		t := time.NewTicker(1 * time.Second)
 		packetsDropped := 0
@@ -253,12 +437,12 @@ func (s *ProbeServer) checkStats(handle *pcap.Handle) {
 			case <-s.ctx.Done():
 				break L
 			case <-t.C:
-				stats, _ := handle.Stats()
-				if stats.PacketsDropped > packetsDropped || stats.PacketsIfDropped > packetsIfDropped {
-					s.logger.Error("pcap packets dropped", zap.Int("packet_received", stats.PacketsReceived), zap.Int("packet_dropped", stats.PacketsDropped), zap.Int("packet_if_dropped", stats.PacketsIfDropped))
+				stats, _ := handle.stats()
+				if stats.dropped > packetsDropped || stats.ifDropped > packetsIfDropped {
+					s.logger.Error("pcap packets dropped", zap.String("interface", device), zap.Int("packet_received", stats.received), zap.Int("packet_dropped", stats.dropped), zap.Int("packet_if_dropped", stats.ifDropped))
 				}
-				packetsDropped = stats.PacketsDropped
-				packetsIfDropped = stats.PacketsIfDropped
+				packetsDropped = stats.dropped
+				packetsIfDropped = stats.ifDropped
 			}
 		}
 		t.Stop()
@@ -297,6 +481,20 @@ func (s *ProbeServer) fieldsWithErrorAndValues(err error, pValues []dumper.DumpV
 	return fields
 }
 
+// devices return interfaces of Device ( comma separated )
+func (c PcapConfig) devices() []string {
+	devices := []string{}
+	for _, d := range strings.Split(c.Device, ",") {
+		devices = append(devices, strings.TrimSpace(d))
+	}
+	return devices
+}
+
+// Targets return targets and dumpers of ProbeServer
+func (s *ProbeServer) Targets() []ProbeTarget {
+	return s.targets
+}
+
 // PcapConfig return ProbeServer.pcapConfig
 func (s *ProbeServer) PcapConfig() PcapConfig {
 	return s.pcapConfig
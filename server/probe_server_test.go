@@ -0,0 +1,71 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+var parseProbeTargetsTests = []struct {
+	in      []string
+	want    []ProbeTarget
+	wantErr bool
+}{
+	{
+		[]string{"3306=mysql", "db.example.com:5432 = pg"},
+		[]ProbeTarget{
+			ProbeTarget{Target: "3306", Dumper: "mysql"},
+			ProbeTarget{Target: "db.example.com:5432", Dumper: "pg"},
+		},
+		false,
+	},
+	{
+		[]string{},
+		[]ProbeTarget{},
+		false,
+	},
+	{
+		[]string{"3306"},
+		nil,
+		true,
+	},
+	{
+		[]string{"3306="},
+		nil,
+		true,
+	},
+	{
+		[]string{"3306=mysql", "5432=pgsql"},
+		nil,
+		true,
+	},
+	{
+		[]string{"6379=redis"},
+		nil,
+		true,
+	},
+}
+
+func TestParseProbeTargets(t *testing.T) {
+	for _, tt := range parseProbeTargetsTests {
+		got, err := ParseProbeTargets(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%v: want error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("got %#v\nwant %#v", got, tt.want)
+		}
+	}
+}
+
+func TestPcapConfigDevices(t *testing.T) {
+	c := PcapConfig{Device: "eth0, eth1"}
+	if got, want := c.devices(), []string{"eth0", "eth1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
@@ -27,6 +27,7 @@ type Proxy struct {
 	connMetadata  *dumper.ConnMetadata
 	seqNum        uint64
 	proxyProtocol bool
+	pcapFlows     []*pcapFlow // client <-> proxy_listen, proxy_client <-> remote
 }
 
 // NewProxy returns a new Proxy
@@ -59,6 +60,14 @@ func NewProxy(s *Server, conn, remoteConn *net.TCPConn) *Proxy {
 		},
 	}
 
+	var pcapFlows []*pcapFlow
+	if s.pcapLogger != nil {
+		pcapFlows = []*pcapFlow{
+			newPcapFlow(s.pcapLogger, conn.RemoteAddr(), conn.LocalAddr()),
+			newPcapFlow(s.pcapLogger, remoteConn.LocalAddr(), remoteConn.RemoteAddr()),
+		}
+	}
+
 	return &Proxy{
 		server:        s,
 		ctx:           innerCtx,
@@ -69,6 +78,7 @@ func NewProxy(s *Server, conn, remoteConn *net.TCPConn) *Proxy {
 		connMetadata:  connMetadata,
 		seqNum:        0,
 		proxyProtocol: viper.GetBool("tcpdp.proxyProtocol"),
+		pcapFlows:     pcapFlows,
 	}
 }
 
@@ -83,6 +93,19 @@ func (p *Proxy) Start() {
 		}
 	}()
 
+	for _, f := range p.pcapFlows {
+		if err := f.open(); err != nil {
+			p.server.logger.WithOptions(zap.AddCaller()).Error("pcapLog write error", p.fieldsWithErrorAndDirection(err, dumper.Unknown)...)
+		}
+	}
+	defer func() {
+		for _, f := range p.pcapFlows {
+			if err := f.close(); err != nil {
+				p.server.logger.WithOptions(zap.AddCaller()).Error("pcapLog write error", p.fieldsWithErrorAndDirection(err, dumper.Unknown)...)
+			}
+		}
+	}()
+
 	go p.pipe(p.conn, p.remoteConn)
 	go p.pipe(p.remoteConn, p.conn)
 
@@ -120,6 +143,23 @@ func (p *Proxy) dump(b []byte, direction dumper.Direction) error {
 	return p.server.dumper.Dump(b, direction, p.connMetadata, kvs)
 }
 
+// writePcap writes b to pcapLog as packets client -> proxy_listen -> proxy_client -> remote ( or reverse )
+func (p *Proxy) writePcap(b []byte, direction dumper.Direction) error {
+	if len(p.pcapFlows) == 0 {
+		return nil
+	}
+	if direction == dumper.ClientToRemote {
+		if err := p.pcapFlows[0].write(pcapFlowClient, b); err != nil {
+			return err
+		}
+		return p.pcapFlows[1].write(pcapFlowClient, b)
+	}
+	if err := p.pcapFlows[1].write(pcapFlowServer, b); err != nil {
+		return err
+	}
+	return p.pcapFlows[0].write(pcapFlowServer, b)
+}
+
 func (p *Proxy) pipe(srcConn, destConn *net.TCPConn) {
 	defer p.Close()
 
@@ -143,6 +183,10 @@ func (p *Proxy) pipe(srcConn, destConn *net.TCPConn) {
 		}
 
 		b := buff[:n]
+		if err := p.writePcap(b, direction); err != nil {
+			fields := p.fieldsWithErrorAndDirection(err, direction)
+			p.server.logger.WithOptions(zap.AddCaller()).Error("pcapLog write error", fields...)
+		}
 		if n == maxPacketLen && buff[n-1] != 0x00 {
 			longB = append(longB, b...)
 		} else {
@@ -13,10 +13,8 @@ import (
 	"syscall"
 
 	"github.com/k1LoW/tcpdp/dumper"
-	"github.com/k1LoW/tcpdp/dumper/conn"
-	"github.com/k1LoW/tcpdp/dumper/hex"
-	"github.com/k1LoW/tcpdp/dumper/mysql"
-	"github.com/k1LoW/tcpdp/dumper/pg"
+	"github.com/k1LoW/tcpdp/dumper/all"
+	l "github.com/k1LoW/tcpdp/logger"
 	"github.com/lestrrat-go/server-starter/listener"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -35,6 +33,7 @@ type Server struct {
 	listener   *net.TCPListener
 	logger     *zap.Logger
 	dumper     dumper.Dumper
+	pcapLogger *l.PcapLogger
 }
 
 // NewServer returns a new Server
@@ -43,20 +42,18 @@ func NewServer(ctx context.Context, lAddr, rAddr *net.TCPAddr, logger *zap.Logge
 	wg := &sync.WaitGroup{}
 	closedChan := make(chan struct{})
 
-	var d dumper.Dumper
 	dumpType := viper.GetString("tcpdp.dumper")
+	d, err := newDumper(dumpType, logger)
+	if err != nil {
+		logger.WithOptions(zap.AddCaller()).Fatal(fmt.Sprintf("%s dumper config error", dumpType), zap.Error(err))
+	}
 
-	switch dumpType {
-	case "hex":
-		d = hex.NewDumper()
-	case "pg":
-		d = pg.NewDumper()
-	case "mysql":
-		d = mysql.NewDumper()
-	case "conn":
-		d = conn.NewDumper()
-	default:
-		d = hex.NewDumper()
+	var pl *l.PcapLogger
+	if viper.GetBool(fmt.Sprintf("%s.enable", l.LogTypePcapLog)) {
+		pl, err = l.NewPcapLogger()
+		if err != nil {
+			logger.WithOptions(zap.AddCaller()).Fatal("pcapLog config error", zap.Error(err))
+		}
 	}
 
 	pidfile, err := filepath.Abs(viper.GetString("tcpdp.pidfile"))
@@ -74,6 +71,7 @@ func NewServer(ctx context.Context, lAddr, rAddr *net.TCPAddr, logger *zap.Logge
 		ClosedChan: closedChan,
 		logger:     logger,
 		dumper:     d,
+		pcapLogger: pl,
 	}
 }
 
@@ -203,3 +201,12 @@ func (s *Server) deletePID() {
 		s.logger.WithOptions(zap.AddCaller()).Fatal(fmt.Sprintf("can not delete %s", s.pidfile), zap.Error(err))
 	}
 }
+
+// newDumper return dumper of dumpType. Unknown dumpType is hex
+func newDumper(dumpType string, logger *zap.Logger) (dumper.Dumper, error) {
+	if !all.Valid(dumpType) {
+		logger.Warn(fmt.Sprintf("unknown dumper %s, use hex dumper", dumpType))
+		dumpType = "hex"
+	}
+	return all.New(dumpType)
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewDumper(t *testing.T) {
+	tests := []struct {
+		dumpType string
+		want     string
+		wantWarn int
+	}{
+		{"mysql", "mysql", 0},
+		{"hex", "hex", 0},
+		{"pgsql", "hex", 1},
+		{"", "hex", 1},
+	}
+	for _, tt := range tests {
+		core, logs := observer.New(zap.WarnLevel)
+		d, err := newDumper(tt.dumpType, zap.New(core))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.dumpType, err)
+		}
+		if d.Name() != tt.want {
+			t.Errorf("%s: got %s\nwant %s", tt.dumpType, d.Name(), tt.want)
+		}
+		if got := logs.Len(); got != tt.wantWarn {
+			t.Errorf("%s: got %d warnings\nwant %d", tt.dumpType, got, tt.wantWarn)
+		}
+	}
+}